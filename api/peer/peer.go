@@ -48,6 +48,26 @@ type Identifier interface {
 	Identifier() string
 }
 
+// WeightedIdentifier is an Identifier that carries the relative capacity of
+// the peer it identifies. Weight-aware peer lists send proportionally more
+// requests to peers with higher weights.
+type WeightedIdentifier interface {
+	Identifier
+
+	// Weight returns the relative weight of the peer. Weights below 1 are
+	// treated as 1.
+	Weight() int
+}
+
+// WeightOf returns the weight of the given identifier, or 1 if the identifier
+// does not carry a weight.
+func WeightOf(pid Identifier) int {
+	if w, ok := pid.(WeightedIdentifier); ok && w.Weight() > 1 {
+		return w.Weight()
+	}
+	return 1
+}
+
 // StatusPeer captures a concrete peer implementation for a particular
 // transport, exposing its Identifier and Status.
 // StatusPeer provides observability without mutability.
@@ -29,6 +29,45 @@ import (
 	opentracinglog "github.com/opentracing/opentracing-go/log"
 )
 
+// ContextTracer is implemented by OpenTracing tracers that also track spans
+// in the context natively, like the OpenTelemetry tracer provided by
+// go.uber.org/yarpc/yarpcotel.
+//
+// Transports use it through SpanFromContext and ContextWithSpan so that spans
+// started with either API are visible to both.
+type ContextTracer interface {
+	opentracing.Tracer
+
+	// ContextWithSpan returns a copy of ctx that natively carries span.
+	ContextWithSpan(ctx context.Context, span opentracing.Span) context.Context
+
+	// SpanFromContext returns the span natively carried by ctx, or nil.
+	SpanFromContext(ctx context.Context) opentracing.Span
+}
+
+// SpanFromContext returns the OpenTracing span carried by ctx. If there is
+// none, and tracer is a ContextTracer, the span natively carried by ctx is
+// returned instead. Returns nil if ctx carries no span.
+func SpanFromContext(ctx context.Context, tracer opentracing.Tracer) opentracing.Span {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		return span
+	}
+	if t, ok := tracer.(ContextTracer); ok {
+		return t.SpanFromContext(ctx)
+	}
+	return nil
+}
+
+// ContextWithSpan returns a copy of ctx that carries span for OpenTracing
+// and, if tracer is a ContextTracer, natively.
+func ContextWithSpan(ctx context.Context, tracer opentracing.Tracer, span opentracing.Span) context.Context {
+	ctx = opentracing.ContextWithSpan(ctx, span)
+	if t, ok := tracer.(ContextTracer); ok {
+		ctx = t.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
+
 // CreateOpenTracingSpan creates a new context with a started span
 type CreateOpenTracingSpan struct {
 	Tracer        opentracing.Tracer
@@ -44,7 +83,7 @@ func (c *CreateOpenTracingSpan) Do(
 	req *Request,
 ) (context.Context, opentracing.Span) {
 	var parent opentracing.SpanContext
-	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+	if parentSpan := SpanFromContext(ctx, c.Tracer); parentSpan != nil {
 		parent = parentSpan.Context()
 	}
 
@@ -66,7 +105,7 @@ func (c *CreateOpenTracingSpan) Do(
 	ext.PeerService.Set(span, req.Service)
 	ext.SpanKindRPCClient.Set(span)
 
-	ctx = opentracing.ContextWithSpan(ctx, span)
+	ctx = ContextWithSpan(ctx, c.Tracer, span)
 	return ctx, span
 }
 
@@ -106,7 +145,7 @@ func (e *ExtractOpenTracingSpan) Do(
 	ext.PeerService.Set(span, req.Caller)
 	ext.SpanKindRPCServer.Set(span)
 
-	ctx = opentracing.ContextWithSpan(ctx, span)
+	ctx = ContextWithSpan(ctx, e.Tracer, span)
 	return ctx, span
 }
 
@@ -36,4 +36,7 @@ type ChooserStatus struct {
 type PeerStatus struct {
 	Identifier string `json:"identifier"`
 	State      string `json:"state"`
+	// Ejected indicates that the peer list stopped choosing the peer
+	// because it failed too many requests.
+	Ejected bool `json:"ejected,omitempty"`
 }
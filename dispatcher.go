@@ -84,16 +84,17 @@ func NewDispatcher(cfg Config) *Dispatcher {
 	cfg = addFirstOutboundMiddleware(cfg)
 
 	return &Dispatcher{
-		name:              cfg.Name,
-		table:             middleware.ApplyRouteTable(NewMapRouter(cfg.Name), cfg.RouterMiddleware),
-		inbounds:          cfg.Inbounds,
-		outbounds:         convertOutbounds(cfg.Outbounds, cfg.OutboundMiddleware),
-		transports:        collectTransports(cfg.Inbounds, cfg.Outbounds),
-		inboundMiddleware: cfg.InboundMiddleware,
-		log:               logger,
-		meter:             meter,
-		stopMeter:         stopMeter,
-		once:              lifecycle.NewOnce(),
+		name:               cfg.Name,
+		table:              middleware.ApplyRouteTable(NewMapRouter(cfg.Name), cfg.RouterMiddleware),
+		inbounds:           cfg.Inbounds,
+		outbounds:          convertOutbounds(cfg.Outbounds, cfg.OutboundMiddleware),
+		transports:         collectTransports(cfg.Inbounds, cfg.Outbounds),
+		inboundMiddleware:  cfg.InboundMiddleware,
+		outboundMiddleware: cfg.OutboundMiddleware,
+		log:                logger,
+		meter:              meter,
+		stopMeter:          stopMeter,
+		once:               lifecycle.NewOnce(),
 	}
 }
 
@@ -253,7 +254,8 @@ type Dispatcher struct {
 	outbounds  Outbounds
 	transports []transport.Transport
 
-	inboundMiddleware InboundMiddleware
+	inboundMiddleware  InboundMiddleware
+	outboundMiddleware OutboundMiddleware
 
 	log       *zap.Logger
 	meter     *metrics.Scope
@@ -29,6 +29,7 @@ import (
 	thriftrw "go.uber.org/thriftrw/version"
 	xintrospection "go.uber.org/yarpc/api/x/introspection"
 	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/internal/outboundmiddleware"
 	"google.golang.org/grpc"
 )
 
@@ -78,14 +79,21 @@ func (d *Dispatcher) Introspect() introspection.DispatcherStatus {
 
 	sort.Sort(outboundStatuses(outbounds)) // keep debug pages deterministic
 
+	outboundMiddleware := outboundmiddleware.Introspect(
+		d.outboundMiddleware.Unary,
+		d.outboundMiddleware.Oneway,
+		d.outboundMiddleware.Stream,
+	)
+
 	procedures := introspection.IntrospectProcedures(d.table.Procedures())
 	return introspection.DispatcherStatus{
-		Name:            d.name,
-		ID:              fmt.Sprintf("%p", d),
-		Procedures:      procedures,
-		Inbounds:        inbounds,
-		Outbounds:       outbounds,
-		PackageVersions: PackageVersions,
+		Name:               d.name,
+		ID:                 fmt.Sprintf("%p", d),
+		Procedures:         procedures,
+		Inbounds:           inbounds,
+		Outbounds:          outbounds,
+		OutboundMiddleware: outboundMiddleware,
+		PackageVersions:    PackageVersions,
 	}
 }
 
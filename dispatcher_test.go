@@ -36,6 +36,7 @@ import (
 	"go.uber.org/yarpc/internal/observability"
 	"go.uber.org/yarpc/transport/http"
 	"go.uber.org/yarpc/transport/tchannel"
+	"go.uber.org/yarpc/x/circuitbreaker"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -915,6 +916,29 @@ func TestIntrospect(t *testing.T) {
 	checkPackageVersion(t, packageNameToVersion, "go", runtime.Version())
 }
 
+func TestIntrospectOutboundMiddleware(t *testing.T) {
+	breaker := circuitbreaker.NewOutboundMiddleware()
+	httpOutbound := http.NewTransport().NewSingleOutbound("http://127.0.0.1:1234")
+
+	dispatcher := NewDispatcher(Config{
+		Name: "test",
+		Outbounds: Outbounds{
+			"test-client": {
+				Unary:  httpOutbound,
+				Oneway: httpOutbound,
+			},
+		},
+		OutboundMiddleware: OutboundMiddleware{
+			Unary:  breaker,
+			Oneway: breaker,
+		},
+	})
+
+	assert.Equal(t, []introspection.MiddlewareStatus{
+		breaker.Introspect(),
+	}, dispatcher.Introspect().OutboundMiddleware, "middleware shared between RPC types should be reported once")
+}
+
 func getInboundStatus(t *testing.T, inbounds []introspection.InboundStatus, transport string, endpoint string) introspection.InboundStatus {
 	for _, inboundStatus := range inbounds {
 		if inboundStatus.Transport == transport && inboundStatus.Endpoint == endpoint {
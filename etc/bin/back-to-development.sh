@@ -25,7 +25,7 @@ set_and_verify_version() {
   local arr=($(echo "${1}" | tr '.' '\n'))
   local next_ver="${arr[0]}.$((arr[1]+1)).0-dev"
 
-  sed -i '' -e "s/^const Version =.*/const Version = \"${next_ver}\"/" version.go
+  sed -i '' -e "s/^const Version =.*/const Version = \"${next_ver}\"/" internal/version/version.go
   SUPPRESS_DOCKER=1 make verifyversion
 }
 
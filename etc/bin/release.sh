@@ -41,7 +41,7 @@ set_release_to_changelog() {
 
 # $1: new version
 set_and_verify_version() {
-  sed -i '' -e "s/^const Version =.*/const Version = \"${1}\"/" version.go
+  sed -i '' -e "s/^const Version =.*/const Version = \"${1}\"/" internal/version/version.go
   SUPPRESS_DOCKER=1 make verifyversion
 }
 
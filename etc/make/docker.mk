@@ -61,7 +61,7 @@ verifycodecovignores: deps ## check verifycodecovignores
 	PATH=$$PATH:$(BIN) docker run $(DOCKER_RUN_FLAGS) $(DOCKER_IMAGE) make verifycodecovignores
 
 .PHONY: verifyversion
-verifyversion: deps ## verify the version in the changelog is the same as in version.go
+verifyversion: deps ## verify the version in the changelog is the same as in the code
 	PATH=$$PATH:$(BIN) docker run $(DOCKER_RUN_FLAGS) $(DOCKER_IMAGE) make verifyversion
 
 .PHONY: basiclint
@@ -110,16 +110,16 @@ errcheck: $(ERRCHECK) __eval_packages __eval_go_files ## check errcheck
 	@[ ! -s "$(ERRCHECK_LOG)" ] || (echo "errcheck failed:" | cat - $(ERRCHECK_LOG) && false)
 
 .PHONY: verifyversion
-verifyversion: ## verify the version in the changelog is the same as in version.go
+verifyversion: ## verify the version in the changelog is the same as in the code
 	@echo "verifyversion"
 	$(eval CHANGELOG_VERSION := $(shell perl -ne '/^## \[(\S+?)\]/ && print "v$$1\n"' CHANGELOG.md | head -n1))
-	$(eval INTHECODE_VERSION := $(shell perl -ne '/^const Version.*"([^"]+)".*$$/ && print "v$$1\n"' version.go))
+	$(eval INTHECODE_VERSION := $(shell perl -ne '/^const Version.*"([^"]+)".*$$/ && print "v$$1\n"' internal/version/version.go))
 	@if [ "$(INTHECODE_VERSION)" = "$(CHANGELOG_VERSION)" ]; then \
 		echo "yarpc-go: $(CHANGELOG_VERSION)"; \
 	elif [ "$(CHANGELOG_VERSION)" = "vUnreleased" ]; then \
 		echo "yarpc-go (development): $(INTHECODE_VERSION)"; \
 	else \
-		echo "Version number in version.go does not match CHANGELOG.md"; \
+		echo "Version number in internal/version/version.go does not match CHANGELOG.md"; \
 		echo "version.go: $(INTHECODE_VERSION)"; \
 		echo "CHANGELOG : $(CHANGELOG_VERSION)"; \
 		exit 1; \
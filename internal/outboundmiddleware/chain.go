@@ -74,6 +74,21 @@ type unaryChainExec struct {
 	Final transport.UnaryOutbound
 }
 
+// UnaryFinal returns the outbound at the end of the middleware chain that out
+// belongs to, or out itself if it is not part of a chain.
+//
+// Middleware may use this to identify the transport outbound a request is
+// sent through.
+func UnaryFinal(out transport.UnaryOutbound) transport.UnaryOutbound {
+	for {
+		x, ok := out.(unaryChainExec)
+		if !ok {
+			return out
+		}
+		out = x.Final
+	}
+}
+
 func (x unaryChainExec) TransportName() string {
 	var name string
 	if namer, ok := x.Final.(transport.Namer); ok {
@@ -211,6 +211,24 @@ func TestUnaryChainExec(t *testing.T) {
 	assert.NoError(t, chain.Stop(), "unexpected error stopping outbound")
 }
 
+func TestUnaryFinal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	out := transporttest.NewMockUnaryOutbound(ctrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil)
+
+	assert.Equal(t, out, UnaryFinal(out), "outbounds outside of a chain must be returned as is")
+
+	var got []transport.UnaryOutbound
+	record := middleware.UnaryOutboundFunc(func(ctx context.Context, req *transport.Request, o transport.UnaryOutbound) (*transport.Response, error) {
+		got = append(got, UnaryFinal(o))
+		return o.Call(ctx, req)
+	})
+	chain := UnaryChain(record, &countOutboundMiddleware{}, record)
+	_, err := middleware.ApplyUnaryOutbound(out, chain).Call(context.Background(), &transport.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, []transport.UnaryOutbound{out, out}, got)
+}
+
 func TestOnewayChainExec(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	out := transporttest.NewMockOnewayOutbound(ctrl)
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package outboundmiddleware
+
+import (
+	"reflect"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/x/introspection"
+)
+
+// Introspect returns the status of every introspectable middleware among the
+// given unary, oneway and stream middleware, looking inside chains.
+//
+// Middleware that are used for more than one RPC type are reported once.
+func Introspect(unary middleware.UnaryOutbound, oneway middleware.OnewayOutbound, stream middleware.StreamOutbound) []introspection.MiddlewareStatus {
+	var all []interface{}
+	if c, ok := unary.(unaryChain); ok {
+		for _, m := range c {
+			all = append(all, m)
+		}
+	} else {
+		all = append(all, unary)
+	}
+	if c, ok := oneway.(onewayChain); ok {
+		for _, m := range c {
+			all = append(all, m)
+		}
+	} else {
+		all = append(all, oneway)
+	}
+	if c, ok := stream.(streamChain); ok {
+		for _, m := range c {
+			all = append(all, m)
+		}
+	} else {
+		all = append(all, stream)
+	}
+
+	var (
+		statuses []introspection.MiddlewareStatus
+		seen     = make(map[interface{}]struct{})
+	)
+	for _, m := range all {
+		im, ok := m.(introspection.IntrospectableMiddleware)
+		if !ok {
+			continue
+		}
+		if reflect.TypeOf(im).Comparable() {
+			if _, ok := seen[im]; ok {
+				continue
+			}
+			seen[im] = struct{}{}
+		}
+		statuses = append(statuses, im.Introspect())
+	}
+	return statuses
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package outboundmiddleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/x/introspection"
+)
+
+type introspectableMiddleware struct{ name string }
+
+func (m *introspectableMiddleware) Call(ctx context.Context, req *transport.Request, o transport.UnaryOutbound) (*transport.Response, error) {
+	return o.Call(ctx, req)
+}
+
+func (m *introspectableMiddleware) CallOneway(ctx context.Context, req *transport.Request, o transport.OnewayOutbound) (transport.Ack, error) {
+	return o.CallOneway(ctx, req)
+}
+
+func (m *introspectableMiddleware) Introspect() introspection.MiddlewareStatus {
+	return introspection.MiddlewareStatus{Name: m.name}
+}
+
+func TestIntrospectMiddleware(t *testing.T) {
+	var (
+		a     = &introspectableMiddleware{name: "a"}
+		b     = &introspectableMiddleware{name: "b"}
+		plain = &countOutboundMiddleware{}
+	)
+
+	tests := []struct {
+		desc   string
+		unary  middleware.UnaryOutbound
+		oneway middleware.OnewayOutbound
+		stream middleware.StreamOutbound
+		want   []string
+	}{
+		{
+			desc: "none",
+		},
+		{
+			desc:   "not introspectable",
+			unary:  plain,
+			oneway: plain,
+			stream: plain,
+		},
+		{
+			desc:  "single",
+			unary: a,
+			want:  []string{"a"},
+		},
+		{
+			desc:   "chains",
+			unary:  UnaryChain(plain, a, retryUnaryOutbound),
+			oneway: OnewayChain(b, plain),
+			want:   []string{"a", "b"},
+		},
+		{
+			desc:   "shared between rpc types",
+			unary:  UnaryChain(a, b),
+			oneway: OnewayChain(b, a),
+			want:   []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var names []string
+			for _, status := range Introspect(tt.unary, tt.oneway, tt.stream) {
+				names = append(names, status.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
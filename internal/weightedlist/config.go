@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedlist
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Configuration describes how to build a weighted peer list.
+type Configuration struct {
+	Capacity *int `config:"capacity"`
+	FailFast bool `config:"failFast"`
+	// DefaultChooseTimeout specifies the deadline to add to Choose calls if not
+	// present. This enables calls without deadlines, ie streaming, to choose
+	// peers without waiting indefinitely.
+	DefaultChooseTimeout *time.Duration `config:"defaultChooseTimeout"`
+	// OutlierDetection ejects peers that fail too many requests.
+	OutlierDetection *abstractlist.OutlierDetectionConfig `config:"outlierDetection"`
+	// Weights assigns weights to peers by address. Peers without a weight
+	// default to a weight of 1.
+	Weights map[string]int `config:"weights"`
+}
+
+// Spec returns the configuration specification of the weighted peer list
+// with the given name.
+// The list is built with the options applied by apply, overridden by the
+// configuration.
+func Spec(name string, apply func(*Config), newList func(peer.Transport, Config) peer.ChooserList) yarpcconfig.PeerListSpec {
+	return yarpcconfig.PeerListSpec{
+		Name: name,
+		BuildPeerList: func(c Configuration, t peer.Transport, k *yarpcconfig.Kit) (peer.ChooserList, error) {
+			cfg := DefaultConfig()
+			apply(&cfg)
+
+			if c.Capacity != nil {
+				if *c.Capacity <= 0 {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						fmt.Sprintf("Capacity must be greater than 0. Got: %d.", *c.Capacity))
+				}
+				cfg.Capacity = *c.Capacity
+			}
+			if c.FailFast {
+				cfg.FailFast = true
+			}
+			if c.DefaultChooseTimeout != nil {
+				cfg.DefaultChooseTimeout = c.DefaultChooseTimeout
+			}
+			if c.OutlierDetection != nil {
+				if err := c.OutlierDetection.Validate(); err != nil {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"invalid outlierDetection: %v", err)
+				}
+				cfg.OutlierDetection = c.OutlierDetection
+			}
+			if len(c.Weights) > 0 {
+				for addr, weight := range c.Weights {
+					if weight <= 0 {
+						return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+							"weight of peer %q must be greater than 0. Got: %d.", addr, weight)
+					}
+				}
+				cfg.Weights = c.Weights
+			}
+
+			return newList(t, cfg), nil
+		},
+	}
+}
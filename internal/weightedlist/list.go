@@ -0,0 +1,185 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package weightedlist holds the parts of the weighted peer lists,
+// weightedroundrobin and weightedpending, that do not depend on how they
+// choose peers: their options, the weights of their peers and their
+// configuration.
+package weightedlist
+
+import (
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
+	"go.uber.org/zap"
+)
+
+// Config holds the options of a weighted peer list.
+type Config struct {
+	Capacity             int
+	Shuffle              bool
+	FailFast             bool
+	DefaultChooseTimeout *time.Duration
+	Seed                 int64
+	Logger               *zap.Logger
+	OutlierDetection     *abstractlist.OutlierDetectionConfig
+	Weights              map[string]int
+}
+
+// DefaultConfig returns the options of a weighted peer list built without
+// options.
+func DefaultConfig() Config {
+	return Config{
+		Capacity: 10,
+		Shuffle:  true,
+		Seed:     time.Now().UnixNano(),
+	}
+}
+
+// Option customizes a Config.
+type Option func(*Config)
+
+// Capacity sets Config.Capacity.
+func Capacity(capacity int) Option {
+	return func(c *Config) {
+		c.Capacity = capacity
+	}
+}
+
+// FailFast sets Config.FailFast.
+func FailFast() Option {
+	return func(c *Config) {
+		c.FailFast = true
+	}
+}
+
+// Logger sets Config.Logger.
+func Logger(logger *zap.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// Seed sets Config.Seed.
+func Seed(seed int64) Option {
+	return func(c *Config) {
+		c.Seed = seed
+	}
+}
+
+// DefaultChooseTimeout sets Config.DefaultChooseTimeout.
+func DefaultChooseTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.DefaultChooseTimeout = &timeout
+	}
+}
+
+// OutlierDetection sets Config.OutlierDetection.
+func OutlierDetection(outlierDetection abstractlist.OutlierDetectionConfig) Option {
+	return func(c *Config) {
+		c.OutlierDetection = &outlierDetection
+	}
+}
+
+// Weights sets Config.Weights.
+func Weights(weights map[string]int) Option {
+	return func(c *Config) {
+		c.Weights = weights
+	}
+}
+
+// List is an abstractlist.List that attaches configured weights to the
+// peers added to it.
+type List struct {
+	*abstractlist.List
+
+	weights map[string]int
+}
+
+// New builds a weighted peer list with the given name, choosing peers with
+// the implementation built for the configured capacity.
+func New(name string, transport peer.Transport, cfg Config, newImplementation func(capacity int) abstractlist.Implementation) *List {
+	plOpts := []abstractlist.Option{
+		abstractlist.Capacity(cfg.Capacity),
+		abstractlist.Seed(cfg.Seed),
+	}
+	if cfg.Logger != nil {
+		plOpts = append(plOpts, abstractlist.Logger(cfg.Logger))
+	}
+	if !cfg.Shuffle {
+		plOpts = append(plOpts, abstractlist.NoShuffle())
+	}
+	if cfg.FailFast {
+		plOpts = append(plOpts, abstractlist.FailFast())
+	}
+	if cfg.DefaultChooseTimeout != nil {
+		plOpts = append(plOpts, abstractlist.DefaultChooseTimeout(*cfg.DefaultChooseTimeout))
+	}
+	if cfg.OutlierDetection != nil {
+		plOpts = append(plOpts, abstractlist.OutlierDetection(*cfg.OutlierDetection))
+	}
+
+	return &List{
+		List:    abstractlist.New(name, transport, newImplementation(cfg.Capacity), plOpts...),
+		weights: cfg.Weights,
+	}
+}
+
+// Update adds and removes peers, attaching configured weights to the added
+// peers whose identifiers do not carry their own weight.
+func (l *List) Update(updates peer.ListUpdates) error {
+	return l.List.Update(peer.ListUpdates{
+		Additions: weigh(l.weights, updates.Additions),
+		Removals:  updates.Removals,
+	})
+}
+
+// weightedIdentifier attaches a configured weight to a peer identifier.
+type weightedIdentifier struct {
+	pid    peer.Identifier
+	weight int
+}
+
+func (w weightedIdentifier) Identifier() string {
+	return w.pid.Identifier()
+}
+
+func (w weightedIdentifier) Weight() int {
+	return w.weight
+}
+
+// weigh attaches configured weights to identifiers without their own weight.
+func weigh(weights map[string]int, pids []peer.Identifier) []peer.Identifier {
+	if len(weights) == 0 {
+		return pids
+	}
+	weighed := make([]peer.Identifier, len(pids))
+	for i, pid := range pids {
+		weighed[i] = pid
+		if _, ok := pid.(peer.WeightedIdentifier); ok {
+			continue
+		}
+		if w, ok := weights[pid.Identifier()]; ok {
+			weighed[i] = weightedIdentifier{pid: pid, weight: w}
+		}
+	}
+	return weighed
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedlist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/hostport"
+)
+
+func TestWeigh(t *testing.T) {
+	pids := []peer.Identifier{
+		hostport.Identify("a"),
+		hostport.Identify("b"),
+		hostport.IdentifyWeighted("c", 2),
+	}
+
+	assert.Equal(t, pids, weigh(nil, pids), "identifiers must be unchanged without weights")
+
+	weighed := weigh(map[string]int{"b": 3, "c": 5}, pids)
+	assert.Equal(t, []peer.Identifier{
+		hostport.Identify("a"),
+		weightedIdentifier{pid: hostport.Identify("b"), weight: 3},
+		hostport.IdentifyWeighted("c", 2),
+	}, weighed, "weights of identifiers must take precedence over configured weights")
+	assert.Equal(t, "b", weighed[1].Identifier())
+	assert.Equal(t, 3, weighed[1].(peer.WeightedIdentifier).Weight())
+}
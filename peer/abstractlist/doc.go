@@ -48,4 +48,9 @@
 // transport (which sees it as a bank of peer.Subscriber).
 // By taking care of concurrency, the abstract peer list frees the
 // Implementation from the concern of thread safety.
+//
+// With the OutlierDetection option, the abstract peer list also ejects peers
+// that fail too many requests, removing them from the Implementation for a
+// while as if they had become unavailable.
+// This works with any Implementation.
 package abstractlist
@@ -22,6 +22,7 @@ package abstractlist
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"sort"
@@ -80,6 +81,20 @@ type Subscriber interface {
 	UpdatePendingRequestCount(int)
 }
 
+// LatencySubscriber is a Subscriber that also observes the latency of every
+// request sent to its peer, from the time the list chose the peer until the
+// request finished.
+//
+// Implementations that return LatencySubscribers from Add receive
+// UpdateLatency calls before the UpdatePendingRequestCount call for each
+// finished request.
+// UpdateLatency is called under the list lock.
+type LatencySubscriber interface {
+	Subscriber
+
+	UpdateLatency(time.Duration)
+}
+
 type options struct {
 	capacity             int
 	defaultChooseTimeout time.Duration
@@ -87,6 +102,7 @@ type options struct {
 	failFast             bool
 	seed                 int64
 	logger               *zap.Logger
+	outlierDetection     *OutlierDetectionConfig
 }
 
 var defaultOptions = options{
@@ -182,6 +198,7 @@ func New(name string, transport peer.Transport, implementation Implementation, o
 		failFast:           options.failFast,
 		randSrc:            rand.NewSource(options.seed),
 		peerAvailableEvent: make(chan struct{}, 1),
+		outlierDetection:   options.outlierDetection,
 	}
 }
 
@@ -220,6 +237,10 @@ type List struct {
 	noShuffle            bool
 	failFast             bool
 	randSrc              rand.Source
+
+	// outlierDetection is nil unless the list ejects outliers.
+	outlierDetection *OutlierDetectionConfig
+	numEjected       int
 }
 
 // Name returns the name of the list.
@@ -262,8 +283,28 @@ func (pl *List) Update(updates peer.ListUpdates) error {
 
 // updateOnline must be run under a list lock.
 func (pl *List) updateOnline(updates peer.ListUpdates) error {
+	// A peer that is both removed and added by the same update is replaced
+	// in place, so that changes to its identifier (like its weight) reach
+	// the implementation without the transport releasing the peer.
+	added := make(map[string]struct{}, len(updates.Additions))
+	for _, id := range updates.Additions {
+		added[id.Identifier()] = struct{}{}
+	}
+	replaced := make(map[string]struct{})
+	for _, id := range updates.Removals {
+		if _, ok := added[id.Identifier()]; !ok {
+			continue
+		}
+		if _, ok := pl.peers[id.Identifier()]; ok {
+			replaced[id.Identifier()] = struct{}{}
+		}
+	}
+
 	var errs error
 	for _, id := range updates.Removals {
+		if _, ok := replaced[id.Identifier()]; ok {
+			continue
+		}
 		errs = multierr.Append(errs, pl.remove(id))
 	}
 
@@ -273,6 +314,10 @@ func (pl *List) updateOnline(updates peer.ListUpdates) error {
 	}
 
 	for _, id := range add {
+		if _, ok := replaced[id.Identifier()]; ok {
+			errs = multierr.Append(errs, pl.replace(id))
+			continue
+		}
 		errs = multierr.Append(errs, pl.add(id))
 	}
 	return errs
@@ -394,7 +439,9 @@ func (pl *List) remove(id peer.Identifier) error {
 		return peer.ErrPeerRemoveNotInList(addr)
 	}
 
-	if pf.status.ConnectionStatus == peer.Available {
+	if pf.outlier.ejected {
+		pl.unejectLocked(pf)
+	} else if pf.status.ConnectionStatus == peer.Available {
 		pl.numAvailable.Dec()
 		pl.implementation.Remove(pf, pf.id, pf.subscriber)
 		pf.subscriber = nil
@@ -408,6 +455,29 @@ func (pl *List) remove(id peer.Identifier) error {
 	return pl.transport.ReleasePeer(id, pf)
 }
 
+// replace swaps the identifier of a retained peer, re-adding the peer to the
+// implementation if it is in rotation.
+//
+// replace must be run under a list lock.
+func (pl *List) replace(id peer.Identifier) error {
+	addr := id.Identifier()
+
+	pf, ok := pl.peers[addr]
+	if !ok {
+		return peer.ErrPeerRemoveNotInList(addr)
+	}
+
+	if !pf.outlier.ejected && pf.status.ConnectionStatus == peer.Available {
+		pl.implementation.Remove(pf, pf.id, pf.subscriber)
+		pf.id = id
+		pf.subscriber = pl.implementation.Add(pf, pf.id)
+		return nil
+	}
+
+	pf.id = id
+	return nil
+}
+
 func (pl *List) removeOffline(id peer.Identifier) error {
 	addr := id.Identifier()
 
@@ -421,6 +491,13 @@ func (pl *List) removeOffline(id peer.Identifier) error {
 	return nil
 }
 
+// ErrRequestNotSent may be passed to the function returned by Choose when no
+// request was sent to the chosen peer, like when a wrapping chooser rejects
+// the peer and chooses another.
+// The list releases the peer without recording the latency or the outcome of
+// a request.
+var ErrRequestNotSent = errors.New("request not sent to the chosen peer")
+
 // Choose selects the next available peer in the peer list.
 func (pl *List) Choose(ctx context.Context, req *transport.Request) (peer.Peer, func(error), error) {
 	if _, ok := ctx.Deadline(); !ok {
@@ -456,7 +533,12 @@ func (pl *List) Choose(ctx context.Context, req *transport.Request) (peer.Peer,
 			// must trigger the rest to resume.
 			pl.notifyPeerAvailable()
 			pf := p.(*peerFacade)
-			pl.onStart(pf)
+			if pl.onStart(pf) {
+				// Latency subscribers need a closure for each request to
+				// remember when it started.
+				start := _timeNow()
+				return pf.peer, func(err error) { pl.onFinishLatency(pf, err, start) }, nil
+			}
 			return pf.peer, pf.onFinish, nil
 		}
 		if pl.failFast {
@@ -481,7 +563,9 @@ func (pl *List) choose(req *transport.Request) peer.StatusPeer {
 	return pl.implementation.Choose(req)
 }
 
-func (pl *List) onStart(pf *peerFacade) {
+// onStart reports whether the subscriber of the peer observes the latency of
+// its requests.
+func (pl *List) onStart(pf *peerFacade) bool {
 	pl.lock.Lock()
 	defer pl.lock.Unlock()
 
@@ -489,16 +573,38 @@ func (pl *List) onStart(pf *peerFacade) {
 	if pf.subscriber != nil {
 		pf.subscriber.UpdatePendingRequestCount(pf.status.PendingRequestCount)
 	}
+	_, ok := pf.subscriber.(LatencySubscriber)
+	return ok
 }
 
 func (pl *List) onFinish(pf *peerFacade, err error) {
 	pl.lock.Lock()
 	defer pl.lock.Unlock()
 
+	pl.finishLocked(pf, err)
+}
+
+func (pl *List) onFinishLatency(pf *peerFacade, err error, start time.Time) {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	// The subscriber may have changed since the request started if the peer
+	// left and returned to rotation, but it still belongs to the same peer.
+	if sub, ok := pf.subscriber.(LatencySubscriber); ok && !errors.Is(err, ErrRequestNotSent) {
+		sub.UpdateLatency(_timeNow().Sub(start))
+	}
+	pl.finishLocked(pf, err)
+}
+
+// finishLocked must be run under a list lock.
+func (pl *List) finishLocked(pf *peerFacade, err error) {
 	pf.status.PendingRequestCount--
 	if pf.subscriber != nil {
 		pf.subscriber.UpdatePendingRequestCount(pf.status.PendingRequestCount)
 	}
+	if pl.outlierDetection != nil && !errors.Is(err, ErrRequestNotSent) {
+		pl.observeOutcome(pf, err)
+	}
 }
 
 func (pl *List) onFinishFunc(pf *peerFacade) func(error) {
@@ -543,6 +649,11 @@ func (pl *List) notifyStatusChanged(pf *peerFacade) {
 	status := pf.peer.Status().ConnectionStatus
 	if pf.status.ConnectionStatus != status {
 		pf.status.ConnectionStatus = status
+		if pf.outlier.ejected {
+			// Ejected peers return to rotation when their ejection ends, if
+			// they are available then.
+			return
+		}
 		switch status {
 		case peer.Available:
 			sub := pf.list.implementation.Add(pf, pf.id)
@@ -611,6 +722,7 @@ func (pl *List) unavailablePeersMessage(err error) string {
 }
 
 // NumAvailable returns how many peers are available.
+// Peers ejected as outliers are not available.
 func (pl *List) NumAvailable() int {
 	return int(pl.numAvailable.Load())
 }
@@ -641,7 +753,7 @@ func (pl *List) Available(pid peer.Identifier) bool {
 	defer pl.lock.RUnlock()
 
 	if pf, ok := pl.peers[pid.Identifier()]; ok {
-		return pf.status.ConnectionStatus == peer.Available
+		return pf.status.ConnectionStatus == peer.Available && !pf.outlier.ejected
 	}
 	return false
 }
@@ -708,7 +820,7 @@ func (pl *List) Introspect() introspection.ChooserStatus {
 	available := 0
 	unavailable := 0
 	for _, pf := range pl.peers {
-		if pf.status.ConnectionStatus == peer.Available {
+		if pf.status.ConnectionStatus == peer.Available && !pf.outlier.ejected {
 			available++
 		} else {
 			unavailable++
@@ -720,11 +832,16 @@ func (pl *List) Introspect() introspection.ChooserStatus {
 
 	buildPeerStatus := func(pf *peerFacade) introspection.PeerStatus {
 		ps := pf.status
+		state := fmt.Sprintf("%s, %d pending request(s)",
+			ps.ConnectionStatus.String(),
+			ps.PendingRequestCount)
+		if pf.outlier.ejected {
+			state += ", ejected until " + pf.outlier.ejectedUntil.Format(time.RFC3339)
+		}
 		return introspection.PeerStatus{
 			Identifier: pf.peer.Identifier(),
-			State: fmt.Sprintf("%s, %d pending request(s)",
-				ps.ConnectionStatus.String(),
-				ps.PendingRequestCount),
+			State:      state,
+			Ejected:    pf.outlier.ejected,
 		}
 	}
 
@@ -732,10 +849,16 @@ func (pl *List) Introspect() introspection.ChooserStatus {
 		peerStatuses = append(peerStatuses, buildPeerStatus(pf))
 	}
 
+	state := fmt.Sprintf("%s (%d/%d available)", pl.once.State(), available,
+		available+unavailable)
+	if pl.outlierDetection != nil {
+		state = fmt.Sprintf("%s (%d/%d available, %d ejected)", pl.once.State(), available,
+			available+unavailable, pl.numEjected)
+	}
+
 	return introspection.ChooserStatus{
-		Name: pl.name,
-		State: fmt.Sprintf("%s (%d/%d available)", pl.once.State(), available,
-			available+unavailable),
+		Name:  pl.name,
+		State: state,
 		Peers: peerStatuses,
 	}
 }
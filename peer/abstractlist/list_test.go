@@ -22,6 +22,7 @@ package abstractlist
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"testing"
 	"time"
@@ -251,6 +252,143 @@ func TestPeerList(t *testing.T) {
 	}))
 }
 
+// weightList records the weights of the peers added to the list.
+type weightList struct {
+	mraList
+
+	weights map[string]int
+}
+
+func (l *weightList) Add(p peer.StatusPeer, pid peer.Identifier) Subscriber {
+	l.weights[pid.Identifier()] = peer.WeightOf(pid)
+	return l.mraList.Add(p, pid)
+}
+
+func (l *weightList) Remove(p peer.StatusPeer, pid peer.Identifier, ps Subscriber) {
+	delete(l.weights, pid.Identifier())
+	l.mraList.Remove(p, pid, ps)
+}
+
+func TestReplacePeer(t *testing.T) {
+	fake := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	impl := &weightList{weights: make(map[string]int)}
+	list := New("weights", fake, impl, NoShuffle())
+
+	require.NoError(t, list.Start())
+	defer func() { assert.NoError(t, list.Stop()) }()
+
+	require.NoError(t, list.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{hostport.IdentifyWeighted("1.1.1.1:4040", 2)},
+	}))
+	fake.Flush()
+	assert.Equal(t, map[string]int{"1.1.1.1:4040": 2}, impl.weights)
+
+	// Replacing a peer in place must not release it from the transport.
+	fake.SimulateReleaseError(hostport.Identify("1.1.1.1:4040"), errors.New("released"))
+	require.NoError(t, list.Update(peer.ListUpdates{
+		Removals:  []peer.Identifier{hostport.IdentifyWeighted("1.1.1.1:4040", 2)},
+		Additions: []peer.Identifier{hostport.IdentifyWeighted("1.1.1.1:4040", 5)},
+	}))
+	assert.Equal(t, map[string]int{"1.1.1.1:4040": 5}, impl.weights)
+	assert.Equal(t, 1, list.NumAvailable())
+
+	// Unavailable peers take their new identifier when they return.
+	fake.SimulateDisconnect(hostport.Identify("1.1.1.1:4040"))
+	assert.Empty(t, impl.weights)
+	require.NoError(t, list.Update(peer.ListUpdates{
+		Removals:  []peer.Identifier{hostport.Identify("1.1.1.1:4040")},
+		Additions: []peer.Identifier{hostport.Identify("1.1.1.1:4040")},
+	}))
+	assert.Empty(t, impl.weights)
+	fake.SimulateConnect(hostport.Identify("1.1.1.1:4040"))
+	assert.Equal(t, map[string]int{"1.1.1.1:4040": 1}, impl.weights)
+
+	fake.SimulateReleaseError(hostport.Identify("1.1.1.1:4040"), nil)
+}
+
+// latencyList records the latencies observed for its most recently added
+// peer.
+type latencyList struct {
+	mraList
+
+	sub *latencySub
+}
+
+func (l *latencyList) Add(p peer.StatusPeer, pid peer.Identifier) Subscriber {
+	l.mraList.Add(p, pid)
+	l.sub = &latencySub{}
+	return l.sub
+}
+
+type latencySub struct {
+	latencies []time.Duration
+	pending   []int
+}
+
+func (s *latencySub) UpdatePendingRequestCount(pending int) {
+	s.pending = append(s.pending, pending)
+}
+
+func (s *latencySub) UpdateLatency(latency time.Duration) {
+	s.latencies = append(s.latencies, latency)
+}
+
+func TestLatencySubscriber(t *testing.T) {
+	now := time.Now()
+	_timeNow = func() time.Time { return now }
+	defer func() { _timeNow = time.Now }()
+
+	fake := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	impl := &latencyList{}
+	list := New("latency", fake, impl)
+
+	require.NoError(t, list.Start())
+	defer func() { assert.NoError(t, list.Stop()) }()
+	require.NoError(t, list.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{abstractpeer.Identify("1.1.1.1:4040")},
+	}))
+	fake.Flush()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	_, finish1, err := list.Choose(ctx, &transport.Request{})
+	require.NoError(t, err)
+	now = now.Add(time.Second)
+	_, finish2, err := list.Choose(ctx, &transport.Request{})
+	require.NoError(t, err)
+	now = now.Add(2 * time.Second)
+
+	finish2(nil)
+	finish1(errors.New("great sadness"))
+
+	assert.Equal(t, []time.Duration{2 * time.Second, 3 * time.Second}, impl.sub.latencies)
+	assert.Equal(t, []int{1, 2, 1, 0}, impl.sub.pending)
+}
+
+func TestLatencySubscriberRequestNotSent(t *testing.T) {
+	fake := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	impl := &latencyList{}
+	list := New("latency", fake, impl)
+
+	require.NoError(t, list.Start())
+	defer func() { assert.NoError(t, list.Stop()) }()
+	require.NoError(t, list.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{abstractpeer.Identify("1.1.1.1:4040")},
+	}))
+	fake.Flush()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	_, finish, err := list.Choose(ctx, &transport.Request{})
+	require.NoError(t, err)
+	finish(ErrRequestNotSent)
+
+	assert.Empty(t, impl.sub.latencies, "requests not sent must not report latency")
+	assert.Equal(t, []int{1, 0}, impl.sub.pending)
+}
+
 func TestFailWait(t *testing.T) {
 	fake := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
 	impl := &mraList{}
@@ -0,0 +1,342 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package abstractlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+const (
+	_defaultOutlierInterval         = 10 * time.Second
+	_defaultOutlierMinimumRequests  = 20
+	_defaultBaseEjectionTime        = 30 * time.Second
+	_defaultMaxEjectionTime         = 300 * time.Second
+	_defaultMaxEjectionPercent      = 10
+	_maxEjectionTimeMultiplierShift = 16
+)
+
+var _timeNow = time.Now // for tests
+
+// OutlierDetectionConfig configures the passive detection of peers that fail
+// too many requests.
+//
+// The list counts the failures that each peer reports through the onFinish
+// callback returned by Choose.
+// When a peer exceeds either threshold, the list ejects it, no longer
+// choosing it until its ejection time elapses.
+// Every subsequent ejection of the same peer lasts twice as long as the
+// previous, up to MaxEjectionTime, and the ejection time shrinks back each
+// Interval the peer spends in rotation.
+//
+// Only errors that suggest a problem with the peer count as failures:
+// unknown, internal, unavailable, deadline-exceeded and data-loss errors,
+// including errors that are not YARPC errors.
+// Errors that the caller causes, like invalid-argument or cancelled errors,
+// do not count.
+//
+// The fields with zero values take their default values, except for the
+// thresholds: a zero threshold disables the corresponding check.
+type OutlierDetectionConfig struct {
+	// ConsecutiveFailures ejects a peer after this many of its requests fail
+	// in a row.
+	ConsecutiveFailures int `config:"consecutiveFailures"`
+
+	// FailurePercentage ejects a peer once at least this percentage of its
+	// requests in an Interval fail, from 1 to 100.
+	FailurePercentage int `config:"failurePercentage"`
+
+	// MinimumRequests is the number of requests a peer must finish in an
+	// Interval before its failure percentage is considered.
+	//
+	// Defaults to 20.
+	MinimumRequests int `config:"minimumRequests"`
+
+	// Interval is the period over which failure percentages are computed.
+	//
+	// Defaults to 10s.
+	Interval time.Duration `config:"interval"`
+
+	// BaseEjectionTime is how long the first ejection of a peer lasts.
+	//
+	// Defaults to 30s.
+	BaseEjectionTime time.Duration `config:"baseEjectionTime"`
+
+	// MaxEjectionTime bounds how long a peer may be ejected.
+	//
+	// Defaults to 300s.
+	MaxEjectionTime time.Duration `config:"maxEjectionTime"`
+
+	// MaxEjectionPercent is the maximum percentage of the peers of the list
+	// that may be ejected at the same time.
+	// At least one peer may be ejected regardless, but the list never ejects
+	// all of its peers.
+	//
+	// Defaults to 10.
+	MaxEjectionPercent int `config:"maxEjectionPercent"`
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c OutlierDetectionConfig) Validate() error {
+	switch {
+	case c.ConsecutiveFailures < 0:
+		return fmt.Errorf("consecutiveFailures must not be negative, got %d", c.ConsecutiveFailures)
+	case c.FailurePercentage < 0 || c.FailurePercentage > 100:
+		return fmt.Errorf("failurePercentage must be between 0 and 100, got %d", c.FailurePercentage)
+	case c.MinimumRequests < 0:
+		return fmt.Errorf("minimumRequests must not be negative, got %d", c.MinimumRequests)
+	case c.Interval < 0:
+		return fmt.Errorf("interval must not be negative, got %v", c.Interval)
+	case c.BaseEjectionTime < 0:
+		return fmt.Errorf("baseEjectionTime must not be negative, got %v", c.BaseEjectionTime)
+	case c.MaxEjectionTime < 0:
+		return fmt.Errorf("maxEjectionTime must not be negative, got %v", c.MaxEjectionTime)
+	case c.MaxEjectionPercent < 0 || c.MaxEjectionPercent > 100:
+		return fmt.Errorf("maxEjectionPercent must be between 0 and 100, got %d", c.MaxEjectionPercent)
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the configuration with defaults in place of
+// zero values, ignoring invalid values.
+func (c OutlierDetectionConfig) withDefaults() OutlierDetectionConfig {
+	if c.ConsecutiveFailures < 0 {
+		c.ConsecutiveFailures = 0
+	}
+	if c.FailurePercentage < 0 || c.FailurePercentage > 100 {
+		c.FailurePercentage = 0
+	}
+	if c.MinimumRequests <= 0 {
+		c.MinimumRequests = _defaultOutlierMinimumRequests
+	}
+	if c.Interval <= 0 {
+		c.Interval = _defaultOutlierInterval
+	}
+	if c.BaseEjectionTime <= 0 {
+		c.BaseEjectionTime = _defaultBaseEjectionTime
+	}
+	if c.MaxEjectionTime <= 0 {
+		c.MaxEjectionTime = _defaultMaxEjectionTime
+	}
+	if c.MaxEjectionTime < c.BaseEjectionTime {
+		c.MaxEjectionTime = c.BaseEjectionTime
+	}
+	if c.MaxEjectionPercent <= 0 || c.MaxEjectionPercent > 100 {
+		c.MaxEjectionPercent = _defaultMaxEjectionPercent
+	}
+	return c
+}
+
+// OutlierDetection enables the ejection of peers that fail too many
+// requests.
+// See OutlierDetectionConfig for details.
+//
+// Defaults to disabled.
+func OutlierDetection(cfg OutlierDetectionConfig) Option {
+	return optionFunc(func(options *options) {
+		cfg := cfg.withDefaults()
+		options.outlierDetection = &cfg
+	})
+}
+
+// outlierState tracks the failures of a single peer.
+type outlierState struct {
+	intervalStart       time.Time
+	requests            int
+	failures            int
+	consecutiveFailures int
+
+	// ejections is the number of times the peer was ejected recently, which
+	// determines how long its next ejection lasts.
+	ejections    int
+	ejected      bool
+	ejectedUntil time.Time
+	timer        *time.Timer
+}
+
+// isOutlierFailure returns whether a request error suggests that the peer
+// is unhealthy.
+func isOutlierFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch yarpcerrors.FromError(err).Code() {
+	case yarpcerrors.CodeUnknown,
+		yarpcerrors.CodeInternal,
+		yarpcerrors.CodeUnavailable,
+		yarpcerrors.CodeDeadlineExceeded,
+		yarpcerrors.CodeDataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// observeOutcome records the outcome of a request to a peer, ejecting the
+// peer if it fails too many requests.
+//
+// observeOutcome must be run under a list lock.
+func (pl *List) observeOutcome(pf *peerFacade, err error) {
+	if pl.peers[pf.id.Identifier()] != pf {
+		// The request finished after the peer was removed.
+		return
+	}
+
+	cfg := pl.outlierDetection
+	o := &pf.outlier
+	now := _timeNow()
+
+	if now.Sub(o.intervalStart) >= cfg.Interval {
+		if !o.ejected && o.ejections > 0 {
+			o.ejections--
+		}
+		o.intervalStart = now
+		o.requests = 0
+		o.failures = 0
+	}
+
+	o.requests++
+	if isOutlierFailure(err) {
+		o.failures++
+		o.consecutiveFailures++
+	} else {
+		o.consecutiveFailures = 0
+	}
+
+	if o.ejected {
+		// Requests chosen before the ejection may still finish.
+		return
+	}
+
+	switch {
+	case cfg.ConsecutiveFailures > 0 && o.consecutiveFailures >= cfg.ConsecutiveFailures:
+		pl.eject(pf, now, "consecutive failures")
+	case cfg.FailurePercentage > 0 && o.requests >= cfg.MinimumRequests &&
+		o.failures*100 >= cfg.FailurePercentage*o.requests:
+		pl.eject(pf, now, "failure percentage")
+	}
+}
+
+// canEject returns whether another peer may be ejected without exceeding the
+// maximum ejection percentage.
+//
+// canEject must be run under a list lock.
+func (pl *List) canEject() bool {
+	numPeers := len(pl.peers)
+	limit := numPeers * pl.outlierDetection.MaxEjectionPercent / 100
+	if limit < 1 {
+		limit = 1
+	}
+	if limit >= numPeers {
+		limit = numPeers - 1
+	}
+	return pl.numEjected < limit
+}
+
+// eject removes a peer from rotation until its ejection time elapses.
+//
+// eject must be run under a list lock.
+func (pl *List) eject(pf *peerFacade, now time.Time, reason string) {
+	if !pl.canEject() {
+		pl.logger.Debug("peer is an outlier but too many peers are already ejected",
+			zap.String("peer", pf.id.Identifier()),
+			zap.String("reason", reason))
+		return
+	}
+
+	o := &pf.outlier
+	d := ejectionTime(pl.outlierDetection, o.ejections)
+	o.ejections++
+	o.ejected = true
+	o.ejectedUntil = now.Add(d)
+	o.timer = time.AfterFunc(d, func() { pl.restoreEjected(pf) })
+	pl.numEjected++
+
+	if pf.status.ConnectionStatus == peer.Available {
+		pl.numAvailable.Dec()
+		pl.implementation.Remove(pf, pf.id, pf.subscriber)
+		pf.subscriber = nil
+	}
+
+	pl.logger.Info("ejected outlier peer",
+		zap.String("peer", pf.id.Identifier()),
+		zap.String("reason", reason),
+		zap.Duration("duration", d))
+}
+
+// ejectionTime returns how long to eject a peer that was recently ejected
+// the given number of times.
+func ejectionTime(cfg *OutlierDetectionConfig, ejections int) time.Duration {
+	if ejections > _maxEjectionTimeMultiplierShift {
+		return cfg.MaxEjectionTime
+	}
+	d := cfg.BaseEjectionTime << uint(ejections)
+	if d <= 0 || d > cfg.MaxEjectionTime {
+		return cfg.MaxEjectionTime
+	}
+	return d
+}
+
+// restoreEjected returns an ejected peer to rotation.
+func (pl *List) restoreEjected(pf *peerFacade) {
+	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
+	if pl.peers[pf.id.Identifier()] != pf || !pf.outlier.ejected {
+		return
+	}
+	pl.unejectLocked(pf)
+
+	o := &pf.outlier
+	o.intervalStart = _timeNow()
+	o.requests = 0
+	o.failures = 0
+	o.consecutiveFailures = 0
+
+	if pf.status.ConnectionStatus == peer.Available {
+		pf.subscriber = pl.implementation.Add(pf, pf.id)
+		pl.numAvailable.Inc()
+		pl.notifyPeerAvailable()
+	}
+
+	pl.logger.Info("restored ejected peer", zap.String("peer", pf.id.Identifier()))
+}
+
+// unejectLocked clears the ejection of a peer, without returning it to
+// rotation.
+//
+// unejectLocked must be run under a list lock.
+func (pl *List) unejectLocked(pf *peerFacade) {
+	o := &pf.outlier
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	o.ejected = false
+	o.ejectedUntil = time.Time{}
+	pl.numEjected--
+}
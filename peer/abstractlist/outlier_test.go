@@ -0,0 +1,424 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package abstractlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// setList is a peer list implementation that chooses the available peer with
+// the lowest identifier.
+type setList struct {
+	peers map[string]peer.StatusPeer
+}
+
+var _ Implementation = (*setList)(nil)
+
+func newSetList() *setList {
+	return &setList{peers: make(map[string]peer.StatusPeer)}
+}
+
+func (l *setList) Add(p peer.StatusPeer, pid peer.Identifier) Subscriber {
+	l.peers[pid.Identifier()] = p
+	return &mraSub{}
+}
+
+func (l *setList) Remove(p peer.StatusPeer, pid peer.Identifier, ps Subscriber) {
+	delete(l.peers, pid.Identifier())
+}
+
+func (l *setList) Choose(*transport.Request) peer.StatusPeer {
+	ids := l.ids()
+	if len(ids) == 0 {
+		return nil
+	}
+	return l.peers[ids[0]]
+}
+
+func (l *setList) ids() []string {
+	ids := make([]string, 0, len(l.peers))
+	for id := range l.peers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// newOutlierList returns a running list of available peers with the given
+// identifiers.
+func newOutlierList(t *testing.T, cfg OutlierDetectionConfig, ids ...peer.Identifier) (*List, *setList, *yarpctest.FakeTransport) {
+	fake := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	impl := newSetList()
+	list := New("outliers", fake, impl, OutlierDetection(cfg), NoShuffle())
+	require.NoError(t, list.Start())
+	require.NoError(t, list.Update(peer.ListUpdates{Additions: ids}))
+	t.Cleanup(func() { assert.NoError(t, list.Stop()) })
+	return list, impl, fake
+}
+
+// finish simulates a request to the given peer.
+func finish(list *List, id peer.Identifier, err error) {
+	list.lock.RLock()
+	pf := list.peers[id.Identifier()]
+	list.lock.RUnlock()
+
+	list.onStart(pf)
+	pf.onFinish(err)
+}
+
+func TestIsOutlierFailure(t *testing.T) {
+	tests := []struct {
+		give error
+		want bool
+	}{
+		{give: nil, want: false},
+		{give: errors.New("connection refused"), want: true},
+		{give: yarpcerrors.InternalErrorf("great sadness"), want: true},
+		{give: yarpcerrors.UnavailableErrorf("draining"), want: true},
+		{give: yarpcerrors.DeadlineExceededErrorf("too slow"), want: true},
+		{give: yarpcerrors.UnknownErrorf("what"), want: true},
+		{give: yarpcerrors.DataLossErrorf("oops"), want: true},
+		{give: yarpcerrors.InvalidArgumentErrorf("bad request"), want: false},
+		{give: yarpcerrors.NotFoundErrorf("no such thing"), want: false},
+		{give: yarpcerrors.ResourceExhaustedErrorf("slow down"), want: false},
+		{give: yarpcerrors.CancelledErrorf("cancelled"), want: false},
+		{give: context.Canceled, want: false},
+		{give: fmt.Errorf("call failed: %w", context.Canceled), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.give), func(t *testing.T) {
+			assert.Equal(t, tt.want, isOutlierFailure(tt.give))
+		})
+	}
+}
+
+func TestOutlierDetectionConfigValidate(t *testing.T) {
+	tests := []struct {
+		desc    string
+		give    OutlierDetectionConfig
+		wantErr string
+	}{
+		{desc: "empty"},
+		{
+			desc: "valid",
+			give: OutlierDetectionConfig{
+				ConsecutiveFailures: 5,
+				FailurePercentage:   80,
+				MinimumRequests:     10,
+				Interval:            time.Second,
+				BaseEjectionTime:    time.Second,
+				MaxEjectionTime:     time.Minute,
+				MaxEjectionPercent:  50,
+			},
+		},
+		{
+			desc:    "negative consecutive failures",
+			give:    OutlierDetectionConfig{ConsecutiveFailures: -1},
+			wantErr: "consecutiveFailures must not be negative, got -1",
+		},
+		{
+			desc:    "failure percentage too large",
+			give:    OutlierDetectionConfig{FailurePercentage: 101},
+			wantErr: "failurePercentage must be between 0 and 100, got 101",
+		},
+		{
+			desc:    "negative interval",
+			give:    OutlierDetectionConfig{Interval: -time.Second},
+			wantErr: "interval must not be negative, got -1s",
+		},
+		{
+			desc:    "negative max ejection percent",
+			give:    OutlierDetectionConfig{MaxEjectionPercent: -1},
+			wantErr: "maxEjectionPercent must be between 0 and 100, got -1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			err := tt.give.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOutlierDetectionConfigDefaults(t *testing.T) {
+	assert.Equal(t, OutlierDetectionConfig{
+		ConsecutiveFailures: 5,
+		MinimumRequests:     20,
+		Interval:            10 * time.Second,
+		BaseEjectionTime:    30 * time.Second,
+		MaxEjectionTime:     300 * time.Second,
+		MaxEjectionPercent:  10,
+	}, OutlierDetectionConfig{ConsecutiveFailures: 5}.withDefaults())
+
+	assert.Equal(t, time.Minute,
+		OutlierDetectionConfig{BaseEjectionTime: time.Minute, MaxEjectionTime: time.Second}.withDefaults().MaxEjectionTime,
+		"max ejection time must not be less than the base ejection time")
+}
+
+func TestEjectionTime(t *testing.T) {
+	cfg := OutlierDetectionConfig{
+		BaseEjectionTime: time.Second,
+		MaxEjectionTime:  10 * time.Second,
+	}
+	for ejections, want := range []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+	} {
+		assert.Equal(t, want, ejectionTime(&cfg, ejections), "ejection %d", ejections)
+	}
+	assert.Equal(t, 10*time.Second, ejectionTime(&cfg, 100))
+}
+
+func TestOutlierConsecutiveFailures(t *testing.T) {
+	list, impl, _ := newOutlierList(t, OutlierDetectionConfig{
+		ConsecutiveFailures: 2,
+		BaseEjectionTime:    10 * testtime.Millisecond,
+		MaxEjectionPercent:  50,
+	}, id1, id2)
+
+	unavailable := yarpcerrors.UnavailableErrorf("unavailable")
+	finish(list, id1, unavailable)
+	finish(list, id1, nil)
+	finish(list, id1, unavailable)
+	assert.True(t, list.Available(id1), "a success resets consecutive failures")
+
+	finish(list, id1, unavailable)
+	assert.False(t, list.Available(id1), "peer should be ejected")
+	assert.Equal(t, []string{id2.Identifier()}, impl.ids())
+	assert.Equal(t, 1, list.NumAvailable())
+	assert.Equal(t, 1, list.NumUnavailable())
+
+	status := list.Introspect()
+	assert.Equal(t, "Running (1/2 available, 1 ejected)", status.State)
+	for _, ps := range status.Peers {
+		assert.Equal(t, ps.Identifier == id1.Identifier(), ps.Ejected, "peer %v", ps.Identifier)
+		if ps.Ejected {
+			assert.Contains(t, ps.State, "Available, 0 pending request(s), ejected until ")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+	p, _, err := list.Choose(ctx, &transport.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, id2.Identifier(), p.Identifier(), "must not choose ejected peer")
+
+	assert.Eventually(t, func() bool { return list.Available(id1) },
+		testtime.Second, testtime.Millisecond, "peer should return after its ejection")
+	assert.Equal(t, []string{id1.Identifier(), id2.Identifier()}, impl.ids())
+	assert.Equal(t, "Running (2/2 available, 0 ejected)", list.Introspect().State)
+}
+
+func TestOutlierIgnoresRequestsNotSent(t *testing.T) {
+	list, _, _ := newOutlierList(t, OutlierDetectionConfig{
+		ConsecutiveFailures: 2,
+		BaseEjectionTime:    testtime.Second,
+		MaxEjectionPercent:  50,
+	}, id1, id2)
+
+	unavailable := yarpcerrors.UnavailableErrorf("unavailable")
+	finish(list, id1, unavailable)
+	finish(list, id1, ErrRequestNotSent)
+	assert.True(t, list.Available(id1))
+	assert.Equal(t, 0, list.peers[id1.Identifier()].status.PendingRequestCount)
+
+	finish(list, id1, unavailable)
+	assert.False(t, list.Available(id1), "a request not sent must not reset consecutive failures")
+}
+
+func TestOutlierFailurePercentage(t *testing.T) {
+	list, _, _ := newOutlierList(t, OutlierDetectionConfig{
+		FailurePercentage:  50,
+		MinimumRequests:    4,
+		BaseEjectionTime:   time.Hour,
+		MaxEjectionPercent: 50,
+	}, id1, id2)
+
+	internal := yarpcerrors.InternalErrorf("internal")
+	finish(list, id1, internal)
+	finish(list, id1, yarpcerrors.InvalidArgumentErrorf("bad request"))
+	finish(list, id1, nil)
+	assert.True(t, list.Available(id1), "too few requests to compute a failure percentage")
+
+	finish(list, id1, nil)
+	assert.True(t, list.Available(id1), "client errors are not failures")
+
+	finish(list, id1, internal)
+	assert.True(t, list.Available(id1))
+	finish(list, id1, internal)
+	assert.False(t, list.Available(id1), "peer should be ejected")
+}
+
+func TestOutlierFailurePercentageInterval(t *testing.T) {
+	now := time.Now()
+	_timeNow = func() time.Time { return now }
+	defer func() { _timeNow = time.Now }()
+
+	list, _, _ := newOutlierList(t, OutlierDetectionConfig{
+		FailurePercentage:  50,
+		MinimumRequests:    2,
+		Interval:           time.Minute,
+		BaseEjectionTime:   time.Hour,
+		MaxEjectionPercent: 50,
+	}, id1, id2)
+
+	finish(list, id1, nil)
+	finish(list, id1, nil)
+	finish(list, id1, nil)
+	now = now.Add(time.Minute)
+
+	// The successes of the previous interval are forgotten.
+	finish(list, id1, yarpcerrors.InternalErrorf("internal"))
+	assert.True(t, list.Available(id1))
+	finish(list, id1, nil)
+	assert.False(t, list.Available(id1), "peer should be ejected")
+}
+
+func TestOutlierEjectionGrowsAndDecays(t *testing.T) {
+	now := time.Now()
+	_timeNow = func() time.Time { return now }
+	defer func() { _timeNow = time.Now }()
+
+	list, _, _ := newOutlierList(t, OutlierDetectionConfig{
+		ConsecutiveFailures: 1,
+		Interval:            time.Minute,
+		BaseEjectionTime:    time.Hour,
+		MaxEjectionTime:     24 * time.Hour,
+		MaxEjectionPercent:  50,
+	}, id1, id2)
+	pf := list.peers[id1.Identifier()]
+	fail := yarpcerrors.InternalErrorf("internal")
+
+	finish(list, id1, fail)
+	require.False(t, list.Available(id1))
+	assert.Equal(t, now.Add(time.Hour), pf.outlier.ejectedUntil)
+
+	list.restoreEjected(pf)
+	require.True(t, list.Available(id1))
+	finish(list, id1, fail)
+	require.False(t, list.Available(id1))
+	assert.Equal(t, now.Add(2*time.Hour), pf.outlier.ejectedUntil, "second ejection should last twice as long")
+
+	list.restoreEjected(pf)
+	now = now.Add(time.Minute)
+	finish(list, id1, nil)
+	assert.Equal(t, 1, pf.outlier.ejections, "ejections should decay after an interval in rotation")
+}
+
+func TestOutlierMaxEjectionPercent(t *testing.T) {
+	list, _, _ := newOutlierList(t, OutlierDetectionConfig{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    time.Hour,
+	}, id1, id2, id3)
+
+	fail := yarpcerrors.InternalErrorf("internal")
+	finish(list, id1, fail)
+	finish(list, id2, fail)
+
+	assert.False(t, list.Available(id1), "at least one peer may be ejected")
+	assert.True(t, list.Available(id2), "no more than 10% of the peers may be ejected")
+}
+
+func TestOutlierNeverEjectsAllPeers(t *testing.T) {
+	list, _, _ := newOutlierList(t, OutlierDetectionConfig{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    time.Hour,
+		MaxEjectionPercent:  100,
+	}, id1, id2)
+
+	fail := yarpcerrors.InternalErrorf("internal")
+	finish(list, id1, fail)
+	finish(list, id2, fail)
+
+	assert.False(t, list.Available(id1))
+	assert.True(t, list.Available(id2), "the last peer must not be ejected")
+}
+
+func TestOutlierRemovedWhileEjected(t *testing.T) {
+	list, impl, _ := newOutlierList(t, OutlierDetectionConfig{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    time.Hour,
+		MaxEjectionPercent:  50,
+	}, id1, id2)
+
+	finish(list, id1, yarpcerrors.InternalErrorf("internal"))
+	require.False(t, list.Available(id1))
+	pf := list.peers[id1.Identifier()]
+
+	require.NoError(t, list.Update(peer.ListUpdates{Removals: []peer.Identifier{id1}}))
+	assert.Equal(t, 0, list.numEjected)
+	assert.Nil(t, pf.outlier.timer, "ejection timer should be stopped")
+
+	// Requests that finish after the removal are ignored.
+	pf.onFinish(yarpcerrors.InternalErrorf("internal"))
+
+	require.NoError(t, list.Update(peer.ListUpdates{Additions: []peer.Identifier{id1}}))
+	assert.True(t, list.Available(id1), "re-added peer should not be ejected")
+	assert.Equal(t, []string{id1.Identifier(), id2.Identifier()}, impl.ids())
+}
+
+func TestOutlierStatusChangeWhileEjected(t *testing.T) {
+	list, impl, fake := newOutlierList(t, OutlierDetectionConfig{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    time.Hour,
+		MaxEjectionPercent:  50,
+	}, id1, id2)
+
+	finish(list, id1, yarpcerrors.InternalErrorf("internal"))
+	require.False(t, list.Available(id1))
+	pf := list.peers[id1.Identifier()]
+
+	fake.SimulateStatusChange(id1, peer.Unavailable)
+	fake.SimulateConnect(id1)
+	assert.Equal(t, []string{id2.Identifier()}, impl.ids(), "ejected peer must stay out of rotation")
+	assert.Equal(t, 1, list.NumAvailable())
+
+	fake.SimulateStatusChange(id1, peer.Unavailable)
+	list.restoreEjected(pf)
+	assert.Equal(t, []string{id2.Identifier()}, impl.ids(), "unavailable peer must not return to rotation")
+	assert.Equal(t, 1, list.NumAvailable())
+
+	fake.SimulateConnect(id1)
+	assert.Equal(t, []string{id1.Identifier(), id2.Identifier()}, impl.ids())
+	assert.Equal(t, 2, list.NumAvailable())
+}
@@ -40,6 +40,9 @@ type peerFacade struct {
 	status     peer.Status
 	subscriber Subscriber
 	onFinish   func(error)
+
+	// outlier is only used if the list detects outliers.
+	outlier outlierState
 }
 
 // StartRequest is vestigial.
@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+// Configuration describes how to resolve peers with DNS.
+//
+//	name: myservice.example.com
+//	port: 8080
+//	interval: 30s
+//	timeout: 5s
+//
+// To resolve SRV records, which carry the port of every peer, set srv
+// instead of port.
+//
+//	name: _myservice._tcp.example.com
+//	srv: true
+type Configuration struct {
+	Name     string        `config:"name,interpolate"`
+	Port     uint16        `config:"port,interpolate"`
+	SRV      bool          `config:"srv"`
+	Interval time.Duration `config:"interval"`
+	Timeout  time.Duration `config:"timeout"`
+}
+
+// Spec returns a configuration specification for the DNS peer list updater,
+// making it possible to discover the peers of any peer list with DNS.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerListUpdater(dns.Spec())
+//
+// This enables the dns updater:
+//
+//	outbounds:
+//	  myservice:
+//	    http:
+//	      round-robin:
+//	        dns:
+//	          name: myservice.example.com
+//	          port: 8080
+//
+// See Configuration for the full shape of the configuration.
+// Options take precedence over the configuration.
+func Spec(opts ...Option) yarpcconfig.PeerListUpdaterSpec {
+	return yarpcconfig.PeerListUpdaterSpec{
+		Name: "dns",
+		BuildPeerListUpdater: func(cfg Configuration, _ *yarpcconfig.Kit) (peer.Binder, error) {
+			cfgOpts, err := cfg.options()
+			if err != nil {
+				return nil, err
+			}
+			return Binder(cfg.Name, append(cfgOpts, opts...)...), nil
+		},
+	}
+}
+
+func (c Configuration) options() ([]Option, error) {
+	switch {
+	case c.Name == "":
+		return nil, errors.New("dns peer list updater requires a name")
+	case c.SRV && c.Port != 0:
+		return nil, fmt.Errorf("dns peer list updater for %q cannot set a port when resolving SRV records", c.Name)
+	case !c.SRV && c.Port == 0:
+		return nil, fmt.Errorf("dns peer list updater for %q requires a port unless it resolves SRV records", c.Name)
+	case c.Interval < 0:
+		return nil, fmt.Errorf("dns peer list updater interval must not be negative, got %v", c.Interval)
+	case c.Timeout < 0:
+		return nil, fmt.Errorf("dns peer list updater timeout must not be negative, got %v", c.Timeout)
+	}
+
+	opts := []Option{
+		Port(c.Port),
+		RefreshInterval(c.Interval),
+		Timeout(c.Timeout),
+	}
+	if c.SRV {
+		opts = append(opts, SRV())
+	}
+	return opts, nil
+}
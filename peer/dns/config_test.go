@@ -0,0 +1,156 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpctest"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSpec(t *testing.T) {
+	resolver := &fakeResolver{}
+
+	tests := []struct {
+		desc    string
+		give    string
+		env     map[string]string
+		want    options
+		wantErr string
+	}{
+		{
+			desc: "host",
+			give: `
+				name: myservice.example.com
+				port: ${PORT}
+			`,
+			env: map[string]string{"PORT": "8080"},
+			want: options{
+				port:     8080,
+				interval: 30 * time.Second,
+				timeout:  5 * time.Second,
+			},
+		},
+		{
+			desc: "srv",
+			give: `
+				name: _myservice._tcp.example.com
+				srv: true
+				interval: 1m
+				timeout: 1s
+			`,
+			want: options{
+				srv:      true,
+				interval: time.Minute,
+				timeout:  time.Second,
+			},
+		},
+		{
+			desc:    "no name",
+			give:    `port: 80`,
+			wantErr: "dns peer list updater requires a name",
+		},
+		{
+			desc:    "no port",
+			give:    `name: myservice`,
+			wantErr: `dns peer list updater for "myservice" requires a port unless it resolves SRV records`,
+		},
+		{
+			desc: "srv with port",
+			give: `
+				name: myservice
+				srv: true
+				port: 80
+			`,
+			wantErr: `dns peer list updater for "myservice" cannot set a port when resolving SRV records`,
+		},
+		{
+			desc: "negative interval",
+			give: `
+				name: myservice
+				port: 80
+				interval: -1s
+			`,
+			wantErr: "dns peer list updater interval must not be negative, got -1s",
+		},
+		{
+			desc: "negative timeout",
+			give: `
+				name: myservice
+				port: 80
+				timeout: -1s
+			`,
+			wantErr: "dns peer list updater timeout must not be negative, got -1s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			configurator := yarpctest.NewFakeConfigurator(yarpcconfig.InterpolationResolver(
+				func(key string) (string, bool) {
+					v, ok := tt.env[key]
+					return v, ok
+				},
+			))
+			require.NoError(t, configurator.RegisterPeerListUpdater(Spec(WithResolver(resolver))))
+
+			var updater map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(tt.give)), &updater))
+
+			cfg, err := configurator.LoadConfig("myservice", map[string]interface{}{
+				"outbounds": map[string]interface{}{
+					"theirservice": map[string]interface{}{
+						"unary": map[string]interface{}{
+							"fake-transport": map[string]interface{}{
+								"fake-list": map[string]interface{}{"dns": updater},
+							},
+						},
+					},
+				},
+			})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			out, ok := cfg.Outbounds["theirservice"].Unary.(*yarpctest.FakeOutbound)
+			require.True(t, ok, "unary outbound must be a fake outbound")
+			chooser, ok := out.Chooser().(*peer.BoundChooser)
+			require.True(t, ok, "chooser must be a bound chooser")
+			u, ok := chooser.Updater().(*Updater)
+			require.True(t, ok, "updater must be a dns updater")
+
+			want := tt.want
+			want.resolver = resolver
+			want.logger = u.opts.logger
+			assert.Equal(t, want, u.opts)
+		})
+	}
+}
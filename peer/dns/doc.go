@@ -0,0 +1,58 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package dns provides a peer list updater that discovers peers by
+// periodically resolving a DNS name.
+//
+// By default, the updater resolves the A and AAAA records of a host name and
+// pairs every address with a configured port.
+//
+//	chooser := peer.Bind(
+//		roundrobin.New(transport),
+//		dns.Binder("myservice.example.com", dns.Port(8080)),
+//	)
+//
+// Alternatively, the updater resolves SRV records, which carry the port of
+// every peer.
+//
+//	dns.Binder("_myservice._tcp.example.com", dns.SRV())
+//
+// After every resolution, the updater adds peers that appeared to the peer
+// list and removes peers that disappeared.
+// If a resolution fails or returns no records, the updater keeps the peers
+// it found previously.
+//
+// The updater may also be configured with yarpcconfig by registering Spec
+// with the Configurator.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerListUpdater(dns.Spec())
+//
+// This enables the dns updater for any peer list:
+//
+//	outbounds:
+//	  myservice:
+//	    http:
+//	      round-robin:
+//	        dns:
+//	          name: myservice.example.com
+//	          port: 8080
+//	          interval: 30s
+package dns
@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dns
+
+import (
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	port     uint16
+	srv      bool
+	interval time.Duration
+	timeout  time.Duration
+	resolver Resolver
+	logger   *zap.Logger
+}
+
+func newOptions() options {
+	return options{
+		interval: 30 * time.Second,
+		timeout:  5 * time.Second,
+		resolver: net.DefaultResolver,
+		logger:   zap.NewNop(),
+	}
+}
+
+// Option customizes the behavior of a DNS peer list updater.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(opts *options) { f(opts) }
+
+// Port is the port of every peer resolved from A and AAAA records.
+// It is required unless the updater resolves SRV records.
+func Port(port uint16) Option {
+	return optionFunc(func(opts *options) {
+		opts.port = port
+	})
+}
+
+// SRV resolves SRV records instead of A and AAAA records.
+// The name must be the full name of the SRV records, like
+// "_myservice._tcp.example.com", and every record provides the host and port
+// of a peer.
+func SRV() Option {
+	return optionFunc(func(opts *options) {
+		opts.srv = true
+	})
+}
+
+// RefreshInterval is how often the updater resolves the name.
+//
+// Defaults to 30s.
+func RefreshInterval(d time.Duration) Option {
+	return optionFunc(func(opts *options) {
+		if d > 0 {
+			opts.interval = d
+		}
+	})
+}
+
+// Timeout bounds the duration of every resolution.
+//
+// Defaults to 5s.
+func Timeout(d time.Duration) Option {
+	return optionFunc(func(opts *options) {
+		if d > 0 {
+			opts.timeout = d
+		}
+	})
+}
+
+// WithResolver sets the resolver used to look up records.
+//
+// Defaults to net.DefaultResolver.
+func WithResolver(r Resolver) Option {
+	return optionFunc(func(opts *options) {
+		if r != nil {
+			opts.resolver = r
+		}
+	})
+}
+
+// Logger sets a logger for the updater.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(opts *options) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/pkg/lifecycle"
+	"go.uber.org/zap"
+)
+
+// Binder returns a peer.Binder that binds a peer list to the peers found by
+// resolving the given name, suitable as an argument to peer.Bind.
+func Binder(name string, opts ...Option) peer.Binder {
+	return func(pl peer.List) transport.Lifecycle {
+		return NewUpdater(pl, name, opts...)
+	}
+}
+
+// Updater is a peer list updater that periodically resolves a DNS name and
+// updates a peer list with the peers it finds.
+type Updater struct {
+	once *lifecycle.Once
+	list peer.List
+	name string
+	opts options
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{}
+
+	// peers holds the addresses of the peers in the list.
+	// It is only accessed by the goroutine that refreshes the list, and
+	// after that goroutine exits.
+	peers map[string]struct{}
+}
+
+// NewUpdater creates a new DNS peer list updater that feeds the peers found
+// by resolving the given name to the given peer list once started.
+func NewUpdater(list peer.List, name string, opts ...Option) *Updater {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Updater{
+		once:    lifecycle.NewOnce(),
+		list:    list,
+		name:    name,
+		opts:    options,
+		ctx:     ctx,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+		peers:   make(map[string]struct{}),
+	}
+}
+
+// Start resolves the name and adds the peers it finds to the peer list, and
+// then keeps resolving it until the updater stops.
+//
+// Start does not fail if the first resolution fails; the updater keeps
+// trying at every refresh interval.
+func (u *Updater) Start() error {
+	return u.once.Start(u.start)
+}
+
+func (u *Updater) start() error {
+	if u.name == "" {
+		return errors.New("dns peer list updater requires a name to resolve")
+	}
+	if !u.opts.srv && u.opts.port == 0 {
+		return fmt.Errorf("dns peer list updater for %q requires a port unless it resolves SRV records", u.name)
+	}
+
+	u.refresh()
+	go u.run()
+	return nil
+}
+
+func (u *Updater) run() {
+	defer close(u.stopped)
+
+	ticker := time.NewTicker(u.opts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			u.refresh()
+		case <-u.ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop stops resolving the name and removes all peers it added from the peer
+// list.
+func (u *Updater) Stop() error {
+	return u.once.Stop(u.stop)
+}
+
+func (u *Updater) stop() error {
+	u.cancel()
+	<-u.stopped
+
+	removals := identifiers(u.peers)
+	u.peers = make(map[string]struct{})
+	if len(removals) == 0 {
+		return nil
+	}
+	return u.list.Update(peer.ListUpdates{Removals: removals})
+}
+
+// IsRunning returns whether the updater is running.
+func (u *Updater) IsRunning() bool {
+	return u.once.IsRunning()
+}
+
+// refresh resolves the name and updates the peer list with the difference
+// between the peers found and the peers in the list.
+func (u *Updater) refresh() {
+	ctx, cancel := context.WithTimeout(u.ctx, u.opts.timeout)
+	defer cancel()
+
+	addrs, err := u.resolve(ctx)
+	if err != nil {
+		u.opts.logger.Warn("Failed to resolve peers, keeping previous peers.",
+			zap.String("name", u.name), zap.Error(err))
+		return
+	}
+	if len(addrs) == 0 {
+		u.opts.logger.Warn("Resolved no peers, keeping previous peers.", zap.String("name", u.name))
+		return
+	}
+
+	var updates peer.ListUpdates
+	for addr := range addrs {
+		if _, ok := u.peers[addr]; !ok {
+			updates.Additions = append(updates.Additions, hostport.PeerIdentifier(addr))
+		}
+	}
+	for addr := range u.peers {
+		if _, ok := addrs[addr]; !ok {
+			updates.Removals = append(updates.Removals, hostport.PeerIdentifier(addr))
+		}
+	}
+	if len(updates.Additions) == 0 && len(updates.Removals) == 0 {
+		return
+	}
+	sortIdentifiers(updates.Additions)
+	sortIdentifiers(updates.Removals)
+
+	// Peer lists apply the updates they can even if others fail, so the
+	// resolved peers become the new peers either way.
+	u.peers = addrs
+	if err := u.list.Update(updates); err != nil {
+		u.opts.logger.Error("Failed to update peer list.", zap.String("name", u.name), zap.Error(err))
+	}
+}
+
+// resolve returns the set of host:port addresses the name resolves to.
+func (u *Updater) resolve(ctx context.Context) (map[string]struct{}, error) {
+	addrs := make(map[string]struct{})
+
+	if u.opts.srv {
+		_, records, err := u.opts.resolver.LookupSRV(ctx, "", "", u.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			host := strings.TrimSuffix(r.Target, ".")
+			addrs[net.JoinHostPort(host, strconv.Itoa(int(r.Port)))] = struct{}{}
+		}
+		return addrs, nil
+	}
+
+	hosts, err := u.opts.resolver.LookupHost(ctx, u.name)
+	if err != nil {
+		return nil, err
+	}
+	port := strconv.Itoa(int(u.opts.port))
+	for _, host := range hosts {
+		addrs[net.JoinHostPort(host, port)] = struct{}{}
+	}
+	return addrs, nil
+}
+
+func identifiers(addrs map[string]struct{}) []peer.Identifier {
+	ids := make([]peer.Identifier, 0, len(addrs))
+	for addr := range addrs {
+		ids = append(ids, hostport.PeerIdentifier(addr))
+	}
+	sortIdentifiers(ids)
+	return ids
+}
+
+func sortIdentifiers(ids []peer.Identifier) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Identifier() < ids[j].Identifier()
+	})
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/hostport"
+)
+
+// fakeResolver is a stand-in resolver that answers with preset records.
+type fakeResolver struct {
+	mu      sync.Mutex
+	hosts   map[string][]string
+	srvs    map[string][]*net.SRV
+	err     error
+	lookups int
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.hosts[host], nil
+}
+
+func (r *fakeResolver) LookupSRV(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	if service != "" || proto != "" {
+		return "", nil, errors.New("service and proto must be empty")
+	}
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	return name, r.srvs[name], nil
+}
+
+func (r *fakeResolver) set(f func(r *fakeResolver)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(r)
+}
+
+// recordingList is a peer.List that records the updates it receives.
+type recordingList struct {
+	mu      sync.Mutex
+	updates []peer.ListUpdates
+	err     error
+}
+
+func (l *recordingList) Update(updates peer.ListUpdates) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updates = append(l.updates, updates)
+	return l.err
+}
+
+func (l *recordingList) Updates() []peer.ListUpdates {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]peer.ListUpdates(nil), l.updates...)
+}
+
+func ids(addrs ...string) []peer.Identifier {
+	if len(addrs) == 0 {
+		return nil
+	}
+	out := make([]peer.Identifier, len(addrs))
+	for i, addr := range addrs {
+		out[i] = hostport.PeerIdentifier(addr)
+	}
+	return out
+}
+
+func TestUpdaterHost(t *testing.T) {
+	resolver := &fakeResolver{hosts: map[string][]string{
+		"myservice": {"10.0.0.2", "10.0.0.1", "::1"},
+	}}
+	list := &recordingList{}
+	u := NewUpdater(list, "myservice", Port(8080), WithResolver(resolver), RefreshInterval(time.Hour))
+
+	require.NoError(t, u.Start())
+	assert.True(t, u.IsRunning())
+	assert.Equal(t, []peer.ListUpdates{
+		{Additions: ids("10.0.0.1:8080", "10.0.0.2:8080", "[::1]:8080")},
+	}, list.Updates(), "start must resolve the name")
+
+	resolver.set(func(r *fakeResolver) { r.hosts["myservice"] = []string{"10.0.0.2", "10.0.0.3"} })
+	u.refresh()
+	assert.Equal(t, peer.ListUpdates{
+		Additions: ids("10.0.0.3:8080"),
+		Removals:  ids("10.0.0.1:8080", "[::1]:8080"),
+	}, list.Updates()[1])
+
+	u.refresh()
+	assert.Len(t, list.Updates(), 2, "unchanged records must not update the list")
+
+	require.NoError(t, u.Stop())
+	assert.False(t, u.IsRunning())
+	assert.Equal(t, peer.ListUpdates{
+		Removals: ids("10.0.0.2:8080", "10.0.0.3:8080"),
+	}, list.Updates()[2], "stop must remove all peers")
+}
+
+func TestUpdaterSRV(t *testing.T) {
+	resolver := &fakeResolver{srvs: map[string][]*net.SRV{
+		"_myservice._tcp.example.com": {
+			{Target: "b.example.com.", Port: 8080},
+			{Target: "a.example.com.", Port: 9090},
+			{Target: "a.example.com.", Port: 9090},
+		},
+	}}
+	list := &recordingList{}
+	u := NewUpdater(list, "_myservice._tcp.example.com", SRV(), WithResolver(resolver), RefreshInterval(time.Hour))
+
+	require.NoError(t, u.Start())
+	defer u.Stop()
+
+	assert.Equal(t, []peer.ListUpdates{
+		{Additions: ids("a.example.com:9090", "b.example.com:8080")},
+	}, list.Updates())
+}
+
+func TestUpdaterKeepsPeersOnFailure(t *testing.T) {
+	resolver := &fakeResolver{hosts: map[string][]string{"myservice": {"10.0.0.1"}}}
+	list := &recordingList{}
+	u := NewUpdater(list, "myservice", Port(80), WithResolver(resolver), RefreshInterval(time.Hour))
+	require.NoError(t, u.Start())
+	require.Len(t, list.Updates(), 1)
+
+	resolver.set(func(r *fakeResolver) { r.err = errors.New("great sadness") })
+	u.refresh()
+	assert.Len(t, list.Updates(), 1, "failed resolutions must not update the list")
+
+	resolver.set(func(r *fakeResolver) {
+		r.err = nil
+		r.hosts["myservice"] = nil
+	})
+	u.refresh()
+	assert.Len(t, list.Updates(), 1, "empty resolutions must not update the list")
+
+	require.NoError(t, u.Stop())
+	assert.Equal(t, peer.ListUpdates{Removals: ids("10.0.0.1:80")}, list.Updates()[1])
+}
+
+func TestUpdaterStartFailsResolution(t *testing.T) {
+	resolver := &fakeResolver{err: errors.New("great sadness")}
+	list := &recordingList{}
+	u := NewUpdater(list, "myservice", Port(80), WithResolver(resolver), RefreshInterval(time.Hour))
+
+	require.NoError(t, u.Start(), "start must not fail if the name does not resolve")
+	assert.Empty(t, list.Updates())
+	require.NoError(t, u.Stop())
+	assert.Empty(t, list.Updates(), "stop must not update the list without peers")
+}
+
+func TestUpdaterListUpdateError(t *testing.T) {
+	resolver := &fakeResolver{hosts: map[string][]string{"myservice": {"10.0.0.1"}}}
+	list := &recordingList{err: errors.New("great sadness")}
+	u := NewUpdater(list, "myservice", Port(80), WithResolver(resolver), RefreshInterval(time.Hour))
+
+	require.NoError(t, u.Start())
+	assert.Len(t, list.Updates(), 1)
+
+	err := u.Stop()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "great sadness")
+	assert.Equal(t, peer.ListUpdates{Removals: ids("10.0.0.1:80")}, list.Updates()[1],
+		"peers must be removed even if adding them failed")
+}
+
+func TestUpdaterRefreshes(t *testing.T) {
+	resolver := &fakeResolver{hosts: map[string][]string{"myservice": {"10.0.0.1"}}}
+	list := &recordingList{}
+	u := NewUpdater(list, "myservice", Port(80), WithResolver(resolver), RefreshInterval(time.Millisecond))
+
+	require.NoError(t, u.Start())
+	resolver.set(func(r *fakeResolver) { r.hosts["myservice"] = []string{"10.0.0.2"} })
+
+	require.Eventually(t, func() bool {
+		return len(list.Updates()) == 2
+	}, time.Second, time.Millisecond, "updater must refresh periodically")
+	assert.Equal(t, peer.ListUpdates{
+		Additions: ids("10.0.0.2:80"),
+		Removals:  ids("10.0.0.1:80"),
+	}, list.Updates()[1])
+
+	require.NoError(t, u.Stop())
+	resolver.mu.Lock()
+	lookups := resolver.lookups
+	resolver.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	resolver.mu.Lock()
+	assert.Equal(t, lookups, resolver.lookups, "updater must stop resolving once stopped")
+	resolver.mu.Unlock()
+}
+
+func TestUpdaterInvalid(t *testing.T) {
+	tests := []struct {
+		desc    string
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{
+			desc:    "no name",
+			opts:    []Option{Port(80)},
+			wantErr: "dns peer list updater requires a name to resolve",
+		},
+		{
+			desc:    "no port",
+			name:    "myservice",
+			wantErr: `dns peer list updater for "myservice" requires a port unless it resolves SRV records`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			resolver := &fakeResolver{}
+			u := NewUpdater(&recordingList{}, tt.name, append(tt.opts, WithResolver(resolver))...)
+			assert.EqualError(t, u.Start(), tt.wantErr)
+			assert.Zero(t, resolver.lookups)
+			assert.EqualError(t, u.Stop(), tt.wantErr)
+		})
+	}
+}
+
+func TestNewUpdaterDefaults(t *testing.T) {
+	u := NewUpdater(&recordingList{}, "myservice", WithResolver(nil), Logger(nil), RefreshInterval(0), Timeout(-1))
+	assert.Equal(t, net.DefaultResolver, u.opts.resolver)
+	assert.NotNil(t, u.opts.logger)
+	assert.Equal(t, 30*time.Second, u.opts.interval)
+	assert.Equal(t, 5*time.Second, u.opts.timeout)
+}
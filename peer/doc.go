@@ -31,6 +31,9 @@
 // choose the peer with the fewest pending requests).
 // Assuming you choose `peerheap`, to bind multiple peers you would pass
 // `peer.Bind(peerheap.New(transport), peer.BindPeers(ids))`.
+// To discover peers by resolving a DNS name instead, bind the list with
+// `"go.uber.org/yarpc/peer/dns".Binder`, or to follow a file listing peers,
+// with `"go.uber.org/yarpc/peer/peersfile".Binder`.
 //
 // Each transport can define its own domain of peer identifiers, but most will
 // use a host:port address for the remote listening socket.
@@ -25,8 +25,10 @@ import (
 
 	"go.uber.org/net/metrics"
 	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
 	"go.uber.org/yarpc/peer/hashring32/internal/farmhashring"
 	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
 	"go.uber.org/zap"
 )
 
@@ -79,6 +81,17 @@ type Config struct {
 	// present. This enables calls without deadlines, ie streaming, to choose
 	// peers without waiting indefinitely.
 	DefaultChooseTimeout *time.Duration `config:"defaultChooseTimeout"`
+
+	// OutlierDetection ejects peers that fail too many requests, moving their
+	// shards to other peers until they return.
+	OutlierDetection *abstractlist.OutlierDetectionConfig `config:"outlierDetection"`
+
+	// BoundedLoad enables consistent hashing with bounded loads, sending
+	// requests to the next peer in the ring if the peer for a shard would
+	// carry more than (1+boundedLoad) times the average pending requests.
+	//
+	// Default is 0, which disables bounded loads.
+	BoundedLoad float64 `config:"boundedLoad"`
 }
 
 // Spec returns a configuration specification for the hashed peer list
@@ -114,6 +127,22 @@ func Spec(logger *zap.Logger, meter *metrics.Scope) yarpcconfig.PeerListSpec {
 				opts = append(opts, OffsetGeneratorValue(c.OffsetGeneratorValue))
 			}
 
+			if c.OutlierDetection != nil {
+				if err := c.OutlierDetection.Validate(); err != nil {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"invalid outlierDetection: %v", err)
+				}
+				opts = append(opts, OutlierDetection(*c.OutlierDetection))
+			}
+
+			if c.BoundedLoad < 0 {
+				return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+					"boundedLoad must not be negative, got %v", c.BoundedLoad)
+			}
+			if c.BoundedLoad > 0 {
+				opts = append(opts, BoundedLoad(c.BoundedLoad))
+			}
+
 			return New(
 				t,
 				farmhashring.Fingerprint32,
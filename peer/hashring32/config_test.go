@@ -26,6 +26,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
 	"go.uber.org/yarpc/peer/hostport"
 	"go.uber.org/yarpc/yarpcconfig"
 	"go.uber.org/yarpc/yarpctest"
@@ -66,3 +67,29 @@ func TestOffsetGeneratorValueConfig(t *testing.T) {
 	assert.NoError(t, err, "must construct a peer list")
 	pl.Update(peer.ListUpdates{Additions: []peer.Identifier{hostport.PeerIdentifier("127.0.0.1:8080")}})
 }
+
+func TestOutlierDetectionConfig(t *testing.T) {
+	s := Spec(nil, nil)
+	build := s.BuildPeerList.(func(c Config, t peer.Transport, k *yarpcconfig.Kit) (peer.ChooserList, error))
+
+	_, err := build(Config{
+		OutlierDetection: &abstractlist.OutlierDetectionConfig{ConsecutiveFailures: 5},
+	}, yarpctest.NewFakeTransport(), nil)
+	assert.NoError(t, err, "must construct a peer list")
+
+	_, err = build(Config{
+		OutlierDetection: &abstractlist.OutlierDetectionConfig{Interval: -time.Second},
+	}, yarpctest.NewFakeTransport(), nil)
+	assert.Error(t, err, "must not construct a peer list")
+}
+
+func TestBoundedLoadConfig(t *testing.T) {
+	s := Spec(nil, nil)
+	build := s.BuildPeerList.(func(c Config, t peer.Transport, k *yarpcconfig.Kit) (peer.ChooserList, error))
+
+	_, err := build(Config{BoundedLoad: 0.25}, yarpctest.NewFakeTransport(), nil)
+	assert.NoError(t, err, "must construct a peer list")
+
+	_, err = build(Config{BoundedLoad: -1}, yarpctest.NewFakeTransport(), nil)
+	assert.Error(t, err, "must not construct a peer list")
+}
@@ -37,10 +37,12 @@ import (
 type options struct {
 	offsetHeader            string
 	offsetGeneratorValue    int
+	boundedLoad             float64
 	peerOverrideHeader      string
 	alternateShardKeyHeader string
 	peerRingOptions         []hashring32.Option
 	defaultChooseTimeout    *time.Duration
+	outlierDetection        *abstractlist.OutlierDetectionConfig
 	logger                  *zap.Logger
 }
 
@@ -205,6 +207,42 @@ func DefaultChooseTimeout(timeout time.Duration) Option {
 	})
 }
 
+// OutlierDetection ejects peers that fail too many requests from rotation
+// for an exponentially growing period.
+// See abstractlist.OutlierDetectionConfig for details.
+//
+// An ejected peer leaves the hash ring, so its shards move to other peers
+// until it returns.
+//
+// Defaults to disabled.
+func OutlierDetection(outlierDetection abstractlist.OutlierDetectionConfig) Option {
+	return optionFunc(func(options *options) {
+		options.outlierDetection = &outlierDetection
+	})
+}
+
+// BoundedLoad enables consistent hashing with bounded loads.
+//
+// No peer may carry more than (1+epsilon) times the average number of
+// pending requests across the ring.
+// If the peer for a shard key is at capacity, the request walks the ring to
+// the next peer with room to spare, so hot keys spill over to the same few
+// neighbors instead of overloading a single peer.
+// Smaller values balance load more tightly at the expense of affinity.
+//
+// The walk starts from the peer selected by OffsetHeader or
+// OffsetGeneratorValue, and uses the key from AlternateShardKeyHeader if
+// present.
+// Requests deflected with PeerOverrideHeader are not subject to the bound.
+//
+// Defaults to disabled.
+// Values less than or equal to zero disable bounded loads.
+func BoundedLoad(epsilon float64) Option {
+	return optionFunc(func(options *options) {
+		options.boundedLoad = epsilon
+	})
+}
+
 type optionFunc func(*options)
 
 func (f optionFunc) apply(options *options) { f(options) }
@@ -227,6 +265,7 @@ func New(transport peer.Transport, hashFunc hashring32.HashFunc32, opts ...Optio
 		options.peerOverrideHeader,
 		options.alternateShardKeyHeader,
 		options.offsetGeneratorValue,
+		options.boundedLoad,
 		logger,
 		options.peerRingOptions...,
 	)
@@ -236,6 +275,9 @@ func New(transport peer.Transport, hashFunc hashring32.HashFunc32, opts ...Optio
 	if options.defaultChooseTimeout != nil {
 		plOpts = append(plOpts, abstractlist.DefaultChooseTimeout(*options.defaultChooseTimeout))
 	}
+	if options.outlierDetection != nil {
+		plOpts = append(plOpts, abstractlist.OutlierDetection(*options.outlierDetection))
+	}
 
 	return &List{
 		list: abstractlist.New("hashring32", transport, ring, plOpts...),
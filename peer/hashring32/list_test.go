@@ -22,6 +22,7 @@ package hashring32
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
@@ -229,3 +230,118 @@ func TestAddRemoveAndChooseWithOffsetGeneratorValue(t *testing.T) {
 	}
 
 }
+
+func TestBoundedLoad(t *testing.T) {
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	pl := New(
+		trans,
+		farmhashring.Fingerprint32,
+		OffsetHeader("test"),
+		PeerOverrideHeader("poTest"),
+		BoundedLoad(0.25),
+		Logger(zaptest.NewLogger(t)),
+		NumReplicas(5),
+		NumPeersEstimate(3),
+	)
+
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	require.NoError(t, pl.Update(
+		peer.ListUpdates{
+			Additions: []peer.Identifier{
+				&FakeShardIdentifier{id: "id1", shard: "shard-1"},
+				&FakeShardIdentifier{id: "id2", shard: "shard-2"},
+				&FakeShardIdentifier{id: "id3", shard: "shard-3"},
+			},
+		},
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// With no load, the shard key alone decides.
+	owner, onFinish, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1"})
+	require.NoError(t, err)
+	onFinish(nil)
+
+	// The owner holds one of one pending requests, which exceeds
+	// ceil(1.25 * 2 / 3) = 1, so the next request spills over.
+	_, finishOwner, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1"})
+	require.NoError(t, err)
+
+	spill, finishSpill, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1"})
+	require.NoError(t, err)
+	assert.NotEqual(t, owner.Identifier(), spill.Identifier(), "must spill over to another peer")
+
+	// Spilled requests for the same shard land on the same neighbor.
+	finishSpill(nil)
+	again, finishAgain, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1"})
+	require.NoError(t, err)
+	assert.Equal(t, spill.Identifier(), again.Identifier(), "must spill over to the same peer")
+	finishAgain(nil)
+
+	// The override header bypasses the bound.
+	headers := transport.NewHeaders().With("poTest", "shard-"+owner.Identifier()[len("id"):])
+	overridden, finishOverridden, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1", Headers: headers})
+	require.NoError(t, err)
+	assert.Equal(t, owner.Identifier(), overridden.Identifier(), "override must not be bounded")
+	finishOverridden(nil)
+
+	// Once the owner drains, it receives its shard again.
+	finishOwner(nil)
+	r, onFinish, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1"})
+	require.NoError(t, err)
+	assert.Equal(t, owner.Identifier(), r.Identifier(), "must return to the owner")
+	onFinish(nil)
+}
+
+func TestBoundedLoadWithOffset(t *testing.T) {
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	pl := New(
+		trans,
+		farmhashring.Fingerprint32,
+		OffsetHeader("test"),
+		BoundedLoad(0.25),
+		NumReplicas(5),
+		NumPeersEstimate(3),
+	)
+
+	require.NoError(t, pl.Start())
+	defer pl.Stop()
+
+	require.NoError(t, pl.Update(
+		peer.ListUpdates{
+			Additions: []peer.Identifier{
+				&FakeShardIdentifier{id: "id1", shard: "shard-1"},
+				&FakeShardIdentifier{id: "id2", shard: "shard-2"},
+				&FakeShardIdentifier{id: "id3", shard: "shard-3"},
+			},
+		},
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var seen []string
+	for n := 0; n < 3; n++ {
+		headers := transport.NewHeaders().With("test", strconv.Itoa(n))
+		r, onFinish, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1", Headers: headers})
+		require.NoError(t, err)
+		onFinish(nil)
+		seen = append(seen, r.Identifier())
+	}
+
+	// Load the peer at offset 1, then expect requests for that offset to
+	// continue along the ring to the peer at offset 2.
+	headers := transport.NewHeaders().With("test", "1")
+	r, finish, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1", Headers: headers})
+	require.NoError(t, err)
+	assert.Equal(t, seen[1], r.Identifier())
+	defer finish(nil)
+
+	r, onFinish, err := pl.Choose(ctx, &transport.Request{ShardKey: "foo1", Headers: headers})
+	require.NoError(t, err)
+	assert.Equal(t, seen[2], r.Identifier(), "must walk the ring from the offset")
+	onFinish(nil)
+}
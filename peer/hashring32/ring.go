@@ -21,6 +21,7 @@
 package hashring32
 
 import (
+	"math"
 	"math/rand"
 	"strconv"
 	"sync"
@@ -52,18 +53,20 @@ func NewImplementation(opts ...Option) abstractlist.Implementation {
 		options.peerOverrideHeader,
 		options.alternateShardKeyHeader,
 		options.offsetGeneratorValue,
+		options.boundedLoad,
 		options.logger,
 		options.peerRingOptions...,
 	)
 }
 
 // newPeerRing creates a new peerRing with an initial capacity
-func newPeerRing(hashFunc hashring32.HashFunc32, offsetHeader, peerOverrideHeader, alternateShardKeyHeader string, offsetGeneratorValue int, logger *zap.Logger, option ...hashring32.Option) *peerRing {
+func newPeerRing(hashFunc hashring32.HashFunc32, offsetHeader, peerOverrideHeader, alternateShardKeyHeader string, offsetGeneratorValue int, boundedLoad float64, logger *zap.Logger, option ...hashring32.Option) *peerRing {
 	return &peerRing{
 		ring:                    hashring32.New(hashFunc, option...),
 		subscribers:             make(map[string]*subscriber),
 		offsetHeader:            offsetHeader,
 		offsetGeneratorValue:    offsetGeneratorValue,
+		boundedLoad:             boundedLoad,
 		peerOverrideHeader:      peerOverrideHeader,
 		logger:                  logger,
 		alternateShardKeyHeader: alternateShardKeyHeader,
@@ -72,10 +75,19 @@ func newPeerRing(hashFunc hashring32.HashFunc32, offsetHeader, peerOverrideHeade
 }
 
 type subscriber struct {
-	peer peer.StatusPeer
+	peer    peer.StatusPeer
+	shardID string
+	// ring is nil unless the ring bounds loads, in which case the subscriber
+	// reports pending request counts back to the ring.
+	ring    *peerRing
+	pending int
 }
 
-func (s *subscriber) UpdatePendingRequestCount(int) {}
+func (s *subscriber) UpdatePendingRequestCount(pending int) {
+	if s.ring != nil {
+		s.ring.updatePending(s, pending)
+	}
+}
 
 // peerRing provides a safe way to interact (Add/Remove/Get) with a potentially
 // changing list of peer objects
@@ -84,6 +96,8 @@ type peerRing struct {
 	subscribers             map[string]*subscriber
 	offsetHeader            string
 	offsetGeneratorValue    int
+	boundedLoad             float64
+	totalPending            int
 	peerOverrideHeader      string
 	alternateShardKeyHeader string
 	logger                  *zap.Logger
@@ -106,8 +120,11 @@ func (pr *peerRing) Add(p peer.StatusPeer, pid peer.Identifier) abstractlist.Sub
 	pr.m.Lock()
 	defer pr.m.Unlock()
 
-	sub := &subscriber{peer: p}
 	shardID := getShardID(pid)
+	sub := &subscriber{peer: p, shardID: shardID}
+	if pr.boundedLoad > 0 {
+		sub.ring = pr
+	}
 	pr.ring.Add(shardID)
 	pr.subscribers[shardID] = sub
 
@@ -138,6 +155,21 @@ func (pr *peerRing) Remove(p peer.StatusPeer, pid peer.Identifier, s abstractlis
 	pr.ring.Remove(shardID)
 	// Peerlist's responsibility to make sure this is thread-safe.
 	delete(pr.subscribers, shardID)
+	pr.totalPending -= sub.pending
+}
+
+// updatePending records the pending request count of a peer in the ring,
+// keeping the total for the ring in step.
+func (pr *peerRing) updatePending(sub *subscriber, pending int) {
+	pr.m.Lock()
+	defer pr.m.Unlock()
+
+	// Ignore stale subscribers for peers that have since left the ring.
+	if pr.subscribers[sub.shardID] != sub {
+		return
+	}
+	pr.totalPending += pending - sub.pending
+	sub.pending = pending
 }
 
 func (pr *peerRing) getPeerOverride(req *transport.Request) peer.StatusPeer {
@@ -181,10 +213,11 @@ func (pr *peerRing) Choose(req *transport.Request) peer.StatusPeer {
 		shardKey, _ = req.Headers.Get(pr.alternateShardKeyHeader)
 	}
 
-	ids, err := pr.ring.Choose(hashring32.Shard{
+	shard := hashring32.Shard{
 		Key: shardKey,
 		N:   n,
-	})
+	}
+	ids, err := pr.ring.Choose(shard)
 	if err != nil {
 		return nil
 	}
@@ -196,9 +229,47 @@ func (pr *peerRing) Choose(req *transport.Request) peer.StatusPeer {
 		return nil
 	}
 
+	if pr.boundedLoad > 0 && pr.overloaded(sub) {
+		return pr.chooseBounded(shard, sub)
+	}
 	return sub.peer
 }
 
+// overloaded returns whether the peer would exceed its share of the load,
+// (1+ε) times the average pending requests per peer, if it received one more
+// request.
+//
+// overloaded must be called with the ring locked.
+func (pr *peerRing) overloaded(sub *subscriber) bool {
+	capacity := math.Ceil((1 + pr.boundedLoad) * float64(pr.totalPending+1) / float64(len(pr.subscribers)))
+	return float64(sub.pending) >= capacity
+}
+
+// chooseBounded walks the ring past the peer for the shard, returning the
+// first peer that is not overloaded.
+// The walk starts after the requested offset and wraps around to the peers
+// before it, so requests for the same shard spill over to the same peers.
+//
+// Falls back to the overloaded peer if every peer is overloaded.
+//
+// chooseBounded must be called with the ring locked.
+func (pr *peerRing) chooseBounded(shard hashring32.Shard, fallback *subscriber) peer.StatusPeer {
+	n := shard.N
+	shard.N = len(pr.subscribers) - 1
+	ids, err := pr.ring.Choose(shard)
+	if err != nil || len(ids) <= n {
+		return fallback.peer
+	}
+
+	for i := 1; i < len(ids); i++ {
+		sub, ok := pr.subscribers[ids[(n+i)%len(ids)]]
+		if ok && !pr.overloaded(sub) {
+			return sub.peer
+		}
+	}
+	return fallback.peer
+}
+
 // getShardID returns the shardID from a StatusPeer.
 func getShardID(p peer.Identifier) string {
 	sp, ok := p.(shardIdentifier)
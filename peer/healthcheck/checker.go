@@ -0,0 +1,225 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	peerbind "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/yarpcerrors"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	_grpcHealthProcedure = "grpc.health.v1.Health::Check"
+	_protoEncoding       = transport.Encoding("proto")
+	_rawEncoding         = transport.Encoding("raw")
+)
+
+// Checker checks the health of peers.
+type Checker interface {
+	// NewPeerCheck prepares to check the health of the identified peer of
+	// the given transport.
+	// The Transport calls it once it retains the peer, and stops the
+	// returned PeerCheck once it releases the peer.
+	NewPeerCheck(t peer.Transport, pid peer.Identifier) (PeerCheck, error)
+}
+
+// PeerCheck checks the health of a single peer.
+type PeerCheck interface {
+	// Check sends a health check to the peer, returning an error if the peer
+	// is unhealthy.
+	Check(ctx context.Context) error
+
+	// Stop releases the resources used to check the peer.
+	Stop() error
+}
+
+// CheckerFunc adapts a function into a Checker that keeps no resources
+// between the checks of a peer.
+type CheckerFunc func(context.Context, peer.Transport, peer.Identifier) error
+
+// NewPeerCheck implements Checker.
+func (f CheckerFunc) NewPeerCheck(t peer.Transport, pid peer.Identifier) (PeerCheck, error) {
+	return funcCheck{f: f, t: t, pid: pid}, nil
+}
+
+type funcCheck struct {
+	f   CheckerFunc
+	t   peer.Transport
+	pid peer.Identifier
+}
+
+func (c funcCheck) Check(ctx context.Context) error { return c.f(ctx, c.t, c.pid) }
+
+func (c funcCheck) Stop() error { return nil }
+
+// OutboundFunc builds a unary outbound that sends requests to the peers
+// chosen by the given chooser, like the NewOutbound method of the HTTP,
+// gRPC and TChannel transports.
+//
+//	func(c peer.Chooser) transport.UnaryOutbound {
+//		return httpTransport.NewOutbound(c)
+//	}
+type OutboundFunc func(peer.Chooser) transport.UnaryOutbound
+
+// Request describes the health check request that a procedure Checker
+// sends to peers.
+type Request struct {
+	// Caller is the name of the service sending health checks.
+	Caller string
+
+	// Service is the name of the service of the peers.
+	Service string
+
+	// Procedure is the name of the health check procedure.
+	Procedure string
+
+	// Encoding is the encoding of the request.
+	//
+	// Defaults to raw.
+	Encoding transport.Encoding
+
+	// Body is the body of the request.
+	//
+	// Defaults to an empty body.
+	Body []byte
+}
+
+// NewProcedureChecker returns a Checker that calls a procedure of each
+// peer, considering the peer healthy if the call succeeds.
+//
+// The Checker builds an outbound for every peer when the Transport starts
+// checking it, and stops it when the Transport releases the peer.
+func NewProcedureChecker(newOutbound OutboundFunc, req Request) Checker {
+	if req.Encoding == "" {
+		req.Encoding = _rawEncoding
+	}
+	return &outboundChecker{
+		newOutbound: newOutbound,
+		req:         req,
+		decode:      func([]byte) error { return nil },
+	}
+}
+
+// NewGRPCChecker returns a Checker that calls the standard gRPC health
+// checking procedure, grpc.health.v1.Health/Check, of each peer, considering
+// the peer healthy if it reports that the given gRPC service is serving.
+// An empty grpcService checks the overall health of the server.
+//
+// The caller and service are the names of the service sending health checks
+// and of the service of the peers.
+// Like NewProcedureChecker, the Checker keeps an outbound for every peer.
+func NewGRPCChecker(newOutbound OutboundFunc, caller, service, grpcService string) Checker {
+	// Marshaling a request with a single string field cannot fail.
+	body, _ := proto.Marshal(&grpc_health_v1.HealthCheckRequest{Service: grpcService})
+	return &outboundChecker{
+		newOutbound: newOutbound,
+		req: Request{
+			Caller:    caller,
+			Service:   service,
+			Procedure: _grpcHealthProcedure,
+			Encoding:  _protoEncoding,
+			Body:      body,
+		},
+		decode: func(resBody []byte) error {
+			var res grpc_health_v1.HealthCheckResponse
+			if err := proto.Unmarshal(resBody, &res); err != nil {
+				return yarpcerrors.InternalErrorf("failed to decode health check response: %v", err)
+			}
+			if res.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+				return yarpcerrors.UnavailableErrorf("service %q is %v", grpcService, res.Status)
+			}
+			return nil
+		},
+	}
+}
+
+// outboundChecker checks the health of peers by sending a request to each
+// peer through an outbound of its own.
+type outboundChecker struct {
+	newOutbound OutboundFunc
+	req         Request
+
+	// decode checks the body of a successful response.
+	decode func([]byte) error
+}
+
+func (c *outboundChecker) NewPeerCheck(t peer.Transport, pid peer.Identifier) (PeerCheck, error) {
+	out := c.newOutbound(peerbind.NewSingle(pid, t))
+	if err := out.Start(); err != nil {
+		return nil, err
+	}
+	return &outboundCheck{checker: c, out: out}, nil
+}
+
+// outboundCheck checks the health of a single peer with an outbound bound
+// to that peer.
+type outboundCheck struct {
+	checker *outboundChecker
+	out     transport.UnaryOutbound
+}
+
+func (c *outboundCheck) Check(ctx context.Context) error {
+	body, err := c.call(ctx)
+	if err != nil {
+		return err
+	}
+	return c.checker.decode(body)
+}
+
+func (c *outboundCheck) Stop() error {
+	return c.out.Stop()
+}
+
+// call sends the health check request to the peer, returning the body of
+// the response.
+func (c *outboundCheck) call(ctx context.Context) (_ []byte, err error) {
+	req := c.checker.req
+	res, err := c.out.Call(ctx, &transport.Request{
+		Caller:    req.Caller,
+		Service:   req.Service,
+		Procedure: req.Procedure,
+		Encoding:  req.Encoding,
+		Body:      bytes.NewReader(req.Body),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.ApplicationError {
+		err = fmt.Errorf("health check procedure %q failed with an application error", req.Procedure)
+	}
+	if res.Body == nil {
+		return nil, err
+	}
+	defer func() { err = multierr.Append(err, res.Body.Close()) }()
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(res.Body)
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package healthcheck_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/healthcheck"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// fakeOutbounds builds fake outbounds that answer calls with the given
+// function, recording the outbounds they build and the requests they
+// receive.
+type fakeOutbounds struct {
+	call      yarpctest.OutboundCallable
+	outbounds []*yarpctest.FakeOutbound
+	requests  []*transport.Request
+	peers     []string
+}
+
+func (f *fakeOutbounds) newOutbound(trans *yarpctest.FakeTransport) healthcheck.OutboundFunc {
+	return func(c peer.Chooser) transport.UnaryOutbound {
+		out := trans.NewOutbound(c, yarpctest.OutboundCallOverride(
+			func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+				p, onFinish, err := c.Choose(ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				defer onFinish(nil)
+
+				f.peers = append(f.peers, p.Identifier())
+				f.requests = append(f.requests, req)
+				return f.call(ctx, req)
+			}))
+		f.outbounds = append(f.outbounds, out)
+		return out
+	}
+}
+
+// check runs a single health check of the given peer.
+func check(t *testing.T, checker healthcheck.Checker, trans peer.Transport, pid peer.Identifier) error {
+	check, err := checker.NewPeerCheck(trans, pid)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, check.Stop()) }()
+	return check.Check(context.Background())
+}
+
+func TestProcedureChecker(t *testing.T) {
+	tests := []struct {
+		desc    string
+		req     healthcheck.Request
+		res     *transport.Response
+		err     error
+		wantReq *transport.Request
+		wantErr string
+	}{
+		{
+			desc: "healthy",
+			req: healthcheck.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Procedure: "health",
+			},
+			res: &transport.Response{Body: io.NopCloser(bytes.NewReader([]byte("ok")))},
+			wantReq: &transport.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Procedure: "health",
+				Encoding:  "raw",
+			},
+		},
+		{
+			desc: "custom encoding and body",
+			req: healthcheck.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Procedure: "health",
+				Encoding:  "json",
+				Body:      []byte("{}"),
+			},
+			res: &transport.Response{},
+			wantReq: &transport.Request{
+				Caller:    "caller",
+				Service:   "service",
+				Procedure: "health",
+				Encoding:  "json",
+			},
+		},
+		{
+			desc:    "error",
+			req:     healthcheck.Request{Procedure: "health"},
+			err:     errors.New("great sadness"),
+			wantErr: "great sadness",
+		},
+		{
+			desc: "application error",
+			req:  healthcheck.Request{Procedure: "health"},
+			res: &transport.Response{
+				ApplicationError: true,
+				Body:             io.NopCloser(bytes.NewReader(nil)),
+			},
+			wantErr: `health check procedure "health" failed with an application error`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			trans := yarpctest.NewFakeTransport()
+			outbounds := &fakeOutbounds{
+				call: func(context.Context, *transport.Request) (*transport.Response, error) {
+					return tt.res, tt.err
+				},
+			}
+			checker := healthcheck.NewProcedureChecker(outbounds.newOutbound(trans), tt.req)
+
+			pid := hostport.PeerIdentifier("127.0.0.1:8080")
+			err := check(t, checker, trans, pid)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{pid.Identifier()}, outbounds.peers, "must call the checked peer")
+			require.Len(t, outbounds.requests, 1)
+			req := outbounds.requests[0]
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, string(tt.req.Body), string(body))
+			req.Body = nil
+			assert.Equal(t, tt.wantReq, req)
+		})
+	}
+}
+
+func TestGRPCChecker(t *testing.T) {
+	tests := []struct {
+		desc    string
+		status  grpc_health_v1.HealthCheckResponse_ServingStatus
+		body    []byte
+		wantErr string
+	}{
+		{
+			desc:   "serving",
+			status: grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			desc:    "not serving",
+			status:  grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+			wantErr: `code:unavailable message:service "users.Users" is NOT_SERVING`,
+		},
+		{
+			desc:    "invalid response",
+			body:    []byte{0xff},
+			wantErr: "code:internal message:failed to decode health check response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			trans := yarpctest.NewFakeTransport()
+			outbounds := &fakeOutbounds{
+				call: func(_ context.Context, req *transport.Request) (*transport.Response, error) {
+					var healthReq grpc_health_v1.HealthCheckRequest
+					body, err := io.ReadAll(req.Body)
+					require.NoError(t, err)
+					require.NoError(t, proto.Unmarshal(body, &healthReq))
+					assert.Equal(t, "users.Users", healthReq.Service)
+
+					resBody := tt.body
+					if resBody == nil {
+						resBody, err = proto.Marshal(&grpc_health_v1.HealthCheckResponse{Status: tt.status})
+						require.NoError(t, err)
+					}
+					return &transport.Response{Body: io.NopCloser(bytes.NewReader(resBody))}, nil
+				},
+			}
+			checker := healthcheck.NewGRPCChecker(outbounds.newOutbound(trans), "caller", "service", "users.Users")
+
+			err := check(t, checker, trans, hostport.PeerIdentifier("127.0.0.1:8080"))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			require.Len(t, outbounds.requests, 1)
+			req := outbounds.requests[0]
+			assert.Equal(t, "caller", req.Caller)
+			assert.Equal(t, "service", req.Service)
+			assert.Equal(t, "grpc.health.v1.Health::Check", req.Procedure)
+			assert.Equal(t, transport.Encoding("proto"), req.Encoding)
+		})
+	}
+}
+
+func TestProcedureCheckerReusesOutbound(t *testing.T) {
+	trans := yarpctest.NewFakeTransport()
+	outbounds := &fakeOutbounds{
+		call: func(context.Context, *transport.Request) (*transport.Response, error) {
+			return &transport.Response{}, nil
+		},
+	}
+	checker := healthcheck.NewProcedureChecker(outbounds.newOutbound(trans), healthcheck.Request{Procedure: "health"})
+
+	check, err := checker.NewPeerCheck(trans, hostport.PeerIdentifier("127.0.0.1:8080"))
+	require.NoError(t, err)
+	require.Len(t, outbounds.outbounds, 1)
+	out := outbounds.outbounds[0]
+	assert.True(t, out.IsRunning(), "outbound must be started with the check")
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, check.Check(context.Background()))
+	}
+	assert.Len(t, outbounds.requests, 3)
+	assert.Len(t, outbounds.outbounds, 1, "checks of a peer must share its outbound")
+
+	require.NoError(t, check.Stop())
+	assert.False(t, out.IsRunning(), "stopping the check must stop its outbound")
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package healthcheck
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/zap"
+)
+
+// Config describes how to check the health of the peers of an outbound.
+//
+// Config is the shape of the healthCheck section of the peer chooser of an
+// outbound in yarpcconfig.
+// Health checks either call a procedure of the peers:
+//
+//	healthCheck:
+//	  procedure: health
+//	  interval: 5s
+//	  timeout: 1s
+//	  healthyThreshold: 2
+//	  unhealthyThreshold: 3
+//
+// Or call the standard gRPC health checking procedure:
+//
+//	healthCheck:
+//	  grpc:
+//	    service: users.Users
+//
+// The intervals, timeouts and thresholds are optional and default to the
+// defaults of the corresponding options.
+type Config struct {
+	// Procedure is the name of the procedure to call, with an empty raw
+	// request.
+	Procedure string `config:"procedure,interpolate"`
+
+	// GRPC calls the standard gRPC health checking procedure instead.
+	GRPC *GRPCConfig `config:"grpc"`
+
+	Interval           time.Duration `config:"interval"`
+	Timeout            time.Duration `config:"timeout"`
+	HealthyThreshold   int           `config:"healthyThreshold"`
+	UnhealthyThreshold int           `config:"unhealthyThreshold"`
+}
+
+// GRPCConfig configures health checks with the standard gRPC health
+// checking procedure.
+type GRPCConfig struct {
+	// Service is the name of the gRPC service whose health to check.
+	// An empty service checks the overall health of the server.
+	Service string `config:"service,interpolate"`
+}
+
+// NewTransport wraps a peer.Transport to check the health of its peers as
+// configured, sending health checks through outbounds built by newOutbound.
+// The caller and service are the names of the service sending health checks
+// and of the service of the peers.
+func (c Config) NewTransport(t peer.Transport, newOutbound OutboundFunc, caller, service string, logger *zap.Logger) (*Transport, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	var checker Checker
+	if c.GRPC != nil {
+		checker = NewGRPCChecker(newOutbound, caller, service, c.GRPC.Service)
+	} else {
+		checker = NewProcedureChecker(newOutbound, Request{
+			Caller:    caller,
+			Service:   service,
+			Procedure: c.Procedure,
+		})
+	}
+
+	return NewTransport(t, checker,
+		Interval(c.Interval),
+		Timeout(c.Timeout),
+		HealthyThreshold(c.HealthyThreshold),
+		UnhealthyThreshold(c.UnhealthyThreshold),
+		Logger(logger),
+	), nil
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.Procedure == "" && c.GRPC == nil:
+		return errors.New("health check must specify a procedure or grpc")
+	case c.Procedure != "" && c.GRPC != nil:
+		return errors.New("health check must not specify both a procedure and grpc")
+	case c.Interval < 0:
+		return fmt.Errorf("health check interval must not be negative, got %v", c.Interval)
+	case c.Timeout < 0:
+		return fmt.Errorf("health check timeout must not be negative, got %v", c.Timeout)
+	case c.HealthyThreshold < 0:
+		return fmt.Errorf("health check healthyThreshold must not be negative, got %d", c.HealthyThreshold)
+	case c.UnhealthyThreshold < 0:
+		return fmt.Errorf("health check unhealthyThreshold must not be negative, got %d", c.UnhealthyThreshold)
+	}
+	return nil
+}
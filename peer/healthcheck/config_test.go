@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package healthcheck_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/healthcheck"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+func TestConfigNewTransport(t *testing.T) {
+	tests := []struct {
+		desc    string
+		cfg     healthcheck.Config
+		wantErr string
+	}{
+		{
+			desc: "procedure",
+			cfg: healthcheck.Config{
+				Procedure:          "health",
+				Interval:           time.Second,
+				Timeout:            time.Second,
+				HealthyThreshold:   1,
+				UnhealthyThreshold: 1,
+			},
+		},
+		{
+			desc: "grpc",
+			cfg:  healthcheck.Config{GRPC: &healthcheck.GRPCConfig{}},
+		},
+		{
+			desc:    "empty",
+			wantErr: "health check must specify a procedure or grpc",
+		},
+		{
+			desc: "procedure and grpc",
+			cfg: healthcheck.Config{
+				Procedure: "health",
+				GRPC:      &healthcheck.GRPCConfig{Service: "users.Users"},
+			},
+			wantErr: "health check must not specify both a procedure and grpc",
+		},
+		{
+			desc:    "negative interval",
+			cfg:     healthcheck.Config{Procedure: "health", Interval: -time.Second},
+			wantErr: "health check interval must not be negative, got -1s",
+		},
+		{
+			desc:    "negative timeout",
+			cfg:     healthcheck.Config{Procedure: "health", Timeout: -time.Second},
+			wantErr: "health check timeout must not be negative, got -1s",
+		},
+		{
+			desc:    "negative healthy threshold",
+			cfg:     healthcheck.Config{Procedure: "health", HealthyThreshold: -1},
+			wantErr: "health check healthyThreshold must not be negative, got -1",
+		},
+		{
+			desc:    "negative unhealthy threshold",
+			cfg:     healthcheck.Config{Procedure: "health", UnhealthyThreshold: -1},
+			wantErr: "health check unhealthyThreshold must not be negative, got -1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			fake := yarpctest.NewFakeTransport()
+			newOutbound := func(c peer.Chooser) transport.UnaryOutbound {
+				return fake.NewOutbound(c)
+			}
+
+			trans, err := tt.cfg.NewTransport(fake, newOutbound, "caller", "service", nil)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, trans)
+		})
+	}
+}
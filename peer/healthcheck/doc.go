@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package healthcheck actively checks the health of peers, so that peer
+// lists only send requests to peers that pass their health checks.
+//
+// A health checking Transport wraps the peer.Transport of an outbound and is
+// given to the peer list in its stead.
+// For every peer the list retains, the Transport periodically sends a health
+// check with a Checker and reports the peer as unavailable while it fails
+// its checks.
+//
+//	checker := healthcheck.NewProcedureChecker(
+//		func(c peer.Chooser) transport.UnaryOutbound { return httpTransport.NewOutbound(c) },
+//		healthcheck.Request{Caller: "myservice", Service: "users", Procedure: "health"},
+//	)
+//	list := roundrobin.New(healthcheck.NewTransport(httpTransport, checker))
+//
+// NewProcedureChecker considers a peer healthy if a procedure call to it
+// succeeds, and NewGRPCChecker if it reports that a service is serving
+// through the standard gRPC health checking procedure.
+// Both send checks through an outbound of their own for every peer, built
+// once the Transport retains the peer and stopped once it releases it.
+//
+// Health checks may also be configured with yarpcconfig for HTTP, gRPC and
+// TChannel outbounds, with the healthCheck section of their peer chooser.
+//
+//	outbounds:
+//	  users:
+//	    http:
+//	      round-robin:
+//	        peers:
+//	          - 127.0.0.1:8080
+//	          - 127.0.0.1:8081
+//	      healthCheck:
+//	        procedure: health
+//	        interval: 5s
+//	        timeout: 1s
+package healthcheck
@@ -0,0 +1,403 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package healthcheck
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/zap"
+)
+
+type options struct {
+	interval           time.Duration
+	timeout            time.Duration
+	healthyThreshold   int
+	unhealthyThreshold int
+	logger             *zap.Logger
+}
+
+var defaultOptions = options{
+	interval:           5 * time.Second,
+	timeout:            time.Second,
+	healthyThreshold:   2,
+	unhealthyThreshold: 3,
+}
+
+// Option customizes the behavior of a health checking Transport.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(options *options) { f(options) }
+
+// Interval is how long to wait between two health checks of a peer.
+//
+// Defaults to 5s.
+func Interval(d time.Duration) Option {
+	return optionFunc(func(options *options) {
+		if d > 0 {
+			options.interval = d
+		}
+	})
+}
+
+// Timeout bounds the duration of each health check.
+//
+// Defaults to 1s.
+func Timeout(d time.Duration) Option {
+	return optionFunc(func(options *options) {
+		if d > 0 {
+			options.timeout = d
+		}
+	})
+}
+
+// HealthyThreshold is the number of consecutive health checks an unhealthy
+// peer must pass to become healthy again.
+//
+// Defaults to 2.
+func HealthyThreshold(n int) Option {
+	return optionFunc(func(options *options) {
+		if n > 0 {
+			options.healthyThreshold = n
+		}
+	})
+}
+
+// UnhealthyThreshold is the number of consecutive health checks a healthy
+// peer must fail to become unhealthy.
+//
+// Defaults to 3.
+func UnhealthyThreshold(n int) Option {
+	return optionFunc(func(options *options) {
+		if n > 0 {
+			options.unhealthyThreshold = n
+		}
+	})
+}
+
+// Logger specifies a logger.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(options *options) {
+		options.logger = logger
+	})
+}
+
+var _ peer.Transport = (*Transport)(nil)
+
+// Transport is a peer.Transport that checks the health of the peers it
+// retains, reporting unhealthy peers as unavailable.
+//
+// Transport wraps the peer.Transport of an outbound, like an HTTP or gRPC
+// transport, and is passed to the peer list of the outbound in its stead.
+//
+//	checker := healthcheck.NewProcedureChecker(
+//		func(c peer.Chooser) transport.UnaryOutbound { return httpTransport.NewOutbound(c) },
+//		healthcheck.Request{Caller: "myservice", Service: "users", Procedure: "health"},
+//	)
+//	list := roundrobin.New(healthcheck.NewTransport(httpTransport, checker))
+//	outbound := httpTransport.NewOutbound(list)
+//
+// Peers are unavailable until they pass their first health check, and
+// thereafter change health after HealthyThreshold consecutive successful
+// checks or UnhealthyThreshold consecutive failed checks.
+// While healthy, a peer reports the connection status of the underlying
+// transport.
+type Transport struct {
+	transport peer.Transport
+	checker   Checker
+	opts      options
+	logger    *zap.Logger
+
+	lock  sync.Mutex
+	peers map[string]*healthPeer
+}
+
+// NewTransport wraps a peer.Transport, checking the health of its peers
+// with the given Checker.
+func NewTransport(transport peer.Transport, checker Checker, opts ...Option) *Transport {
+	options := defaultOptions
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	logger := options.logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &Transport{
+		transport: transport,
+		checker:   checker,
+		opts:      options,
+		logger:    logger,
+		peers:     make(map[string]*healthPeer),
+	}
+}
+
+// RetainPeer retains the peer from the underlying transport and starts
+// checking its health, if no other subscriber retains it already.
+func (t *Transport) RetainPeer(pid peer.Identifier, sub peer.Subscriber) (peer.Peer, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hp, ok := t.peers[pid.Identifier()]
+	if ok {
+		hp.subscribe(sub)
+		return hp, nil
+	}
+
+	hp = &healthPeer{
+		transport:   t,
+		id:          pid,
+		stop:        make(chan struct{}),
+		subscribers: make(map[peer.Subscriber]struct{}),
+	}
+	p, err := t.transport.RetainPeer(pid, hp)
+	if err != nil {
+		return nil, err
+	}
+	hp.peer = p
+	hp.subscribe(sub)
+	t.peers[pid.Identifier()] = hp
+
+	go hp.run()
+	return hp, nil
+}
+
+// ReleasePeer releases the peer, and once no subscriber retains it, stops
+// checking its health and releases it from the underlying transport.
+func (t *Transport) ReleasePeer(pid peer.Identifier, sub peer.Subscriber) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hp, ok := t.peers[pid.Identifier()]
+	if !ok {
+		return peer.ErrTransportHasNoReferenceToPeer{
+			TransportName:  "healthcheck.Transport",
+			PeerIdentifier: pid.Identifier(),
+		}
+	}
+	if hp.unsubscribe(sub) > 0 {
+		return nil
+	}
+
+	delete(t.peers, pid.Identifier())
+	// Stopping must not wait for the check loop, which may be notifying
+	// subscribers.
+	close(hp.stop)
+	return t.transport.ReleasePeer(pid, hp)
+}
+
+var (
+	_ peer.Peer       = (*healthPeer)(nil)
+	_ peer.Subscriber = (*healthPeer)(nil)
+)
+
+// healthPeer is a peer of the underlying transport that only reports that
+// it is available while it is healthy.
+type healthPeer struct {
+	transport *Transport
+	id        peer.Identifier
+	peer      peer.Peer
+	stop      chan struct{}
+
+	lock        sync.Mutex
+	subscribers map[peer.Subscriber]struct{}
+	healthy     bool
+	wasHealthy  bool
+	successes   int
+	failures    int
+}
+
+func (hp *healthPeer) subscribe(sub peer.Subscriber) {
+	hp.lock.Lock()
+	defer hp.lock.Unlock()
+
+	hp.subscribers[sub] = struct{}{}
+}
+
+func (hp *healthPeer) unsubscribe(sub peer.Subscriber) int {
+	hp.lock.Lock()
+	defer hp.lock.Unlock()
+
+	delete(hp.subscribers, sub)
+	return len(hp.subscribers)
+}
+
+// Identifier returns the identifier of the peer.
+func (hp *healthPeer) Identifier() string {
+	return hp.peer.Identifier()
+}
+
+// Status returns the status of the underlying peer, but unavailable while
+// the peer is unhealthy.
+func (hp *healthPeer) Status() peer.Status {
+	status := hp.peer.Status()
+	if !hp.isHealthy() {
+		status.ConnectionStatus = peer.Unavailable
+	}
+	return status
+}
+
+// StartRequest forwards to the underlying peer.
+func (hp *healthPeer) StartRequest() {
+	hp.peer.StartRequest()
+}
+
+// EndRequest forwards to the underlying peer.
+func (hp *healthPeer) EndRequest() {
+	hp.peer.EndRequest()
+}
+
+// NotifyStatusChanged forwards status changes of the underlying peer to the
+// subscribers of the peer.
+func (hp *healthPeer) NotifyStatusChanged(peer.Identifier) {
+	hp.notifySubscribers()
+}
+
+func (hp *healthPeer) isHealthy() bool {
+	hp.lock.Lock()
+	defer hp.lock.Unlock()
+
+	return hp.healthy
+}
+
+func (hp *healthPeer) notifySubscribers() {
+	hp.lock.Lock()
+	subs := make([]peer.Subscriber, 0, len(hp.subscribers))
+	for sub := range hp.subscribers {
+		subs = append(subs, sub)
+	}
+	hp.lock.Unlock()
+
+	for _, sub := range subs {
+		sub.NotifyStatusChanged(hp.id)
+	}
+}
+
+// run checks the health of the peer until it is released.
+func (hp *healthPeer) run() {
+	opts := hp.transport.opts
+	logger := hp.transport.logger.With(zap.String("peer", hp.id.Identifier()))
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	// The check is prepared with the first health check rather than when
+	// the peer is retained, so that retaining the peer does not wait for it,
+	// and prepared again at the next check if it fails.
+	var check PeerCheck
+	defer func() {
+		if check == nil {
+			return
+		}
+		if err := check.Stop(); err != nil {
+			logger.Warn("failed to stop health checks", zap.Error(err))
+		}
+	}()
+
+	for {
+		select {
+		case <-hp.stop:
+			return
+		case <-timer.C:
+		}
+
+		var err error
+		if check == nil {
+			check, err = hp.transport.checker.NewPeerCheck(hp.transport.transport, hp.id)
+		}
+		if err == nil {
+			err = hp.check(check)
+		}
+
+		select {
+		case <-hp.stop:
+			return
+		default:
+		}
+
+		if hp.observe(err) {
+			hp.notifySubscribers()
+		}
+		timer.Reset(opts.interval)
+	}
+}
+
+// check runs a single health check, abandoning it if the peer is released.
+func (hp *healthPeer) check(check PeerCheck) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hp.transport.opts.timeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-hp.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return check.Check(ctx)
+}
+
+// observe records the result of a health check, returning whether the
+// health of the peer changed.
+func (hp *healthPeer) observe(err error) bool {
+	opts := hp.transport.opts
+	logger := hp.transport.logger.With(zap.String("peer", hp.id.Identifier()))
+
+	hp.lock.Lock()
+	defer hp.lock.Unlock()
+
+	if err != nil {
+		hp.successes = 0
+		hp.failures++
+		logger.Debug("peer failed health check", zap.Error(err))
+	} else {
+		hp.failures = 0
+		hp.successes++
+	}
+
+	switch {
+	case !hp.wasHealthy:
+		// New peers become healthy as soon as they pass a check.
+		if err != nil {
+			return false
+		}
+		hp.healthy = true
+		hp.wasHealthy = true
+		logger.Info("peer is healthy")
+		return true
+	case hp.healthy && hp.failures >= opts.unhealthyThreshold:
+		hp.healthy = false
+		logger.Info("peer became unhealthy", zap.Error(err))
+		return true
+	case !hp.healthy && hp.successes >= opts.healthyThreshold:
+		hp.healthy = true
+		logger.Info("peer became healthy")
+		return true
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package healthcheck_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"go.uber.org/goleak"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/healthcheck"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// scriptedChecker hands each health check to the test, which decides its
+// result.
+type scriptedChecker struct {
+	checks  chan chan error
+	stopped atomic.Int32
+}
+
+func newScriptedChecker() *scriptedChecker {
+	return &scriptedChecker{checks: make(chan chan error)}
+}
+
+func (c *scriptedChecker) NewPeerCheck(peer.Transport, peer.Identifier) (healthcheck.PeerCheck, error) {
+	return c, nil
+}
+
+func (c *scriptedChecker) Stop() error {
+	c.stopped.Inc()
+	return nil
+}
+
+func (c *scriptedChecker) Check(ctx context.Context) error {
+	result := make(chan error)
+	select {
+	case c.checks <- result:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// script replies to health checks in order, waiting for the next check to
+// begin so that the result of each check has been observed when it returns.
+type script struct {
+	t       *testing.T
+	checker *scriptedChecker
+	next    chan error
+}
+
+func (c *scriptedChecker) script(t *testing.T) *script {
+	return &script{t: t, checker: c, next: c.wait(t)}
+}
+
+func (c *scriptedChecker) wait(t *testing.T) chan error {
+	select {
+	case next := <-c.checks:
+		return next
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for a health check")
+		return nil
+	}
+}
+
+func (s *script) reply(err error) {
+	s.next <- err
+	s.next = s.checker.wait(s.t)
+}
+
+type recordingSubscriber struct {
+	mu            sync.Mutex
+	notifications int
+}
+
+func (s *recordingSubscriber) NotifyStatusChanged(peer.Identifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifications++
+}
+
+func (s *recordingSubscriber) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notifications
+}
+
+func TestHealthTransitions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	checker := newScriptedChecker()
+	trans := healthcheck.NewTransport(yarpctest.NewFakeTransport(), checker,
+		healthcheck.Interval(time.Millisecond),
+		healthcheck.Timeout(time.Minute),
+	)
+
+	sub := &recordingSubscriber{}
+	pid := hostport.PeerIdentifier("127.0.0.1:8080")
+	p, err := trans.RetainPeer(pid, sub)
+	require.NoError(t, err)
+	assert.Equal(t, pid.Identifier(), p.Identifier())
+
+	steps := []struct {
+		desc              string
+		err               error
+		wantStatus        peer.ConnectionStatus
+		wantNotifications int
+	}{
+		{"new peer fails", errors.New("great sadness"), peer.Unavailable, 0},
+		{"new peer passes", nil, peer.Available, 1},
+		{"first failure", errors.New("great sadness"), peer.Available, 1},
+		{"second failure", errors.New("great sadness"), peer.Available, 1},
+		{"third failure", errors.New("great sadness"), peer.Unavailable, 2},
+		{"first success", nil, peer.Unavailable, 2},
+		{"failure resets successes", errors.New("great sadness"), peer.Unavailable, 2},
+		{"success again", nil, peer.Unavailable, 2},
+		{"second success", nil, peer.Available, 3},
+	}
+
+	assert.Equal(t, peer.Unavailable, p.Status().ConnectionStatus, "new peers are unavailable")
+	s := checker.script(t)
+	for _, step := range steps {
+		s.reply(step.err)
+		assert.Equal(t, step.wantStatus, p.Status().ConnectionStatus, step.desc)
+		assert.Equal(t, step.wantNotifications, sub.count(), step.desc)
+	}
+
+	assert.NoError(t, trans.ReleasePeer(pid, sub))
+}
+
+func TestUnderlyingStatus(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	fake := yarpctest.NewFakeTransport()
+	checker := newScriptedChecker()
+	trans := healthcheck.NewTransport(fake, checker,
+		healthcheck.Interval(time.Millisecond),
+		healthcheck.Timeout(time.Minute),
+	)
+
+	sub := &recordingSubscriber{}
+	pid := hostport.PeerIdentifier("127.0.0.1:8080")
+	p, err := trans.RetainPeer(pid, sub)
+	require.NoError(t, err)
+
+	checker.script(t).reply(nil)
+	assert.Equal(t, peer.Available, p.Status().ConnectionStatus)
+
+	fake.SimulateDisconnect(pid)
+	assert.Equal(t, peer.Unavailable, p.Status().ConnectionStatus,
+		"healthy peers report the status of the underlying peer")
+	assert.Equal(t, 2, sub.count(), "status changes of the underlying peer are forwarded")
+
+	p.StartRequest()
+	assert.Equal(t, 1, p.Status().PendingRequestCount, "requests are forwarded")
+	p.EndRequest()
+	assert.Equal(t, 0, p.Status().PendingRequestCount, "requests are forwarded")
+
+	assert.NoError(t, trans.ReleasePeer(pid, sub))
+}
+
+func TestSharedPeers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var (
+		mu     sync.Mutex
+		checks = make(map[string]int)
+	)
+	checker := healthcheck.CheckerFunc(func(_ context.Context, _ peer.Transport, pid peer.Identifier) error {
+		mu.Lock()
+		defer mu.Unlock()
+		checks[pid.Identifier()]++
+		return nil
+	})
+	trans := healthcheck.NewTransport(yarpctest.NewFakeTransport(), checker,
+		healthcheck.Interval(time.Hour),
+	)
+
+	pid := hostport.PeerIdentifier("127.0.0.1:8080")
+	sub1 := &recordingSubscriber{}
+	sub2 := &recordingSubscriber{}
+
+	p1, err := trans.RetainPeer(pid, sub1)
+	require.NoError(t, err)
+	p2, err := trans.RetainPeer(pid, sub2)
+	require.NoError(t, err)
+	assert.True(t, p1 == p2, "subscribers must share peers")
+
+	require.Eventually(t, func() bool {
+		return p1.Status().ConnectionStatus == peer.Available
+	}, 5*time.Second, time.Millisecond, "peer never became healthy")
+	assert.Eventually(t, func() bool {
+		return sub1.count() == 1 && sub2.count() == 1
+	}, 5*time.Second, time.Millisecond, "all subscribers must be notified")
+
+	mu.Lock()
+	assert.Equal(t, map[string]int{pid.Identifier(): 1}, checks, "peers must be checked once per interval")
+	mu.Unlock()
+
+	assert.NoError(t, trans.ReleasePeer(pid, sub1))
+	assert.NoError(t, trans.ReleasePeer(pid, sub2))
+	assert.Error(t, trans.ReleasePeer(pid, sub2), "released peers must not be released again")
+}
+
+func TestReleaseUnknownPeer(t *testing.T) {
+	trans := healthcheck.NewTransport(yarpctest.NewFakeTransport(), newScriptedChecker())
+
+	err := trans.ReleasePeer(hostport.PeerIdentifier("127.0.0.1:8080"), &recordingSubscriber{})
+	assert.Equal(t, peer.ErrTransportHasNoReferenceToPeer{
+		TransportName:  "healthcheck.Transport",
+		PeerIdentifier: "127.0.0.1:8080",
+	}, err)
+}
+
+func TestRetainError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	fake := yarpctest.NewFakeTransport(
+		yarpctest.RetainErrors(errors.New("great sadness"), []string{"127.0.0.1:8080"}),
+	)
+	trans := healthcheck.NewTransport(fake, newScriptedChecker())
+
+	_, err := trans.RetainPeer(hostport.PeerIdentifier("127.0.0.1:8080"), &recordingSubscriber{})
+	assert.EqualError(t, err, "great sadness")
+}
+
+func TestReleaseAbandonsCheck(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	checker := newScriptedChecker()
+	trans := healthcheck.NewTransport(yarpctest.NewFakeTransport(), checker,
+		healthcheck.Timeout(time.Minute),
+	)
+
+	pid := hostport.PeerIdentifier("127.0.0.1:8080")
+	sub := &recordingSubscriber{}
+	_, err := trans.RetainPeer(pid, sub)
+	require.NoError(t, err)
+
+	// Wait for the check to begin, then release the peer without replying.
+	checker.wait(t)
+	assert.NoError(t, trans.ReleasePeer(pid, sub))
+	assert.Eventually(t, func() bool { return checker.stopped.Load() == 1 },
+		5*time.Second, time.Millisecond, "releasing the peer must stop its check")
+}
+
+// failingChecker fails to prepare the check of a peer a number of times.
+type failingChecker struct {
+	*scriptedChecker
+
+	failures atomic.Int32
+}
+
+func (c *failingChecker) NewPeerCheck(t peer.Transport, pid peer.Identifier) (healthcheck.PeerCheck, error) {
+	if c.failures.Dec() >= 0 {
+		return nil, errors.New("great sadness")
+	}
+	return c.scriptedChecker.NewPeerCheck(t, pid)
+}
+
+func TestNewPeerCheckError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	checker := &failingChecker{scriptedChecker: newScriptedChecker()}
+	checker.failures.Store(2)
+	trans := healthcheck.NewTransport(yarpctest.NewFakeTransport(), checker,
+		healthcheck.Interval(time.Millisecond),
+	)
+
+	pid := hostport.PeerIdentifier("127.0.0.1:8080")
+	sub := &recordingSubscriber{}
+	p, err := trans.RetainPeer(pid, sub)
+	require.NoError(t, err)
+
+	// The check is prepared again until it succeeds.
+	checker.script(t).reply(nil)
+	assert.Equal(t, peer.Available, p.Status().ConnectionStatus)
+	assert.Equal(t, int32(-1), checker.failures.Load(), "check must be prepared once it succeeds")
+
+	assert.NoError(t, trans.ReleasePeer(pid, sub))
+	assert.Eventually(t, func() bool { return checker.stopped.Load() == 1 },
+		5*time.Second, time.Millisecond, "releasing the peer must stop its check")
+}
@@ -40,6 +40,26 @@ func Identify(peer string) peer.Identifier {
 	return PeerIdentifier(peer)
 }
 
+// WeightedPeerIdentifier is a PeerIdentifier that carries the relative weight
+// of the peer, for use with weight-aware peer lists.
+type WeightedPeerIdentifier struct {
+	PeerIdentifier
+
+	weight int
+}
+
+var _ peer.WeightedIdentifier = WeightedPeerIdentifier{}
+
+// IdentifyWeighted coerces a string and a weight to a WeightedPeerIdentifier
+func IdentifyWeighted(peer string, weight int) peer.WeightedIdentifier {
+	return WeightedPeerIdentifier{PeerIdentifier: PeerIdentifier(peer), weight: weight}
+}
+
+// Weight returns the relative weight of the peer.
+func (p WeightedPeerIdentifier) Weight() int {
+	return p.weight
+}
+
 // NewPeer creates a new hostport.Peer from a hostport.PeerIdentifier, peer.Transport, and peer.Subscriber
 func NewPeer(pid PeerIdentifier, transport peer.Transport) *Peer {
 	p := &Peer{
@@ -52,6 +52,26 @@ func TestPeerIdentifier(t *testing.T) {
 	}
 }
 
+func TestWeightedPeerIdentifier(t *testing.T) {
+	tests := []struct {
+		msg        string
+		pid        peer.Identifier
+		wantWeight int
+	}{
+		{"unweighted", Identify("localhost:12345"), 1},
+		{"weighted", IdentifyWeighted("localhost:12345", 3), 3},
+		{"zero weight", IdentifyWeighted("localhost:12345", 0), 1},
+		{"negative weight", IdentifyWeighted("localhost:12345", -2), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, "localhost:12345", tt.pid.Identifier())
+			assert.Equal(t, tt.wantWeight, peer.WeightOf(tt.pid))
+		})
+	}
+}
+
 func TestPeer(t *testing.T) {
 	type testStruct struct {
 		msg string
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package maglev
+
+import (
+	"fmt"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Configuration describes how to construct a Maglev peer list.
+type Configuration struct {
+	Capacity *int `config:"capacity"`
+	FailFast bool `config:"failFast"`
+	// OutlierDetection ejects peers that fail too many requests.
+	OutlierDetection *abstractlist.OutlierDetectionConfig `config:"outlierDetection"`
+	// TableSize specifies the number of slots in the lookup table, which must
+	// be a prime number.
+	TableSize *int `config:"tableSize"`
+}
+
+// Spec returns a configuration specification for the Maglev peer list
+// implementation, making it possible to route requests by their shard key
+// with transports that use outbound peer list configuration (like HTTP).
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerList(maglev.Spec())
+//
+// This enables the Maglev peer list:
+//
+//	outbounds:
+//	  otherservice:
+//	    unary:
+//	      http:
+//	        url: https://host:port/rpc
+//	        maglev:
+//	          peers:
+//	            - 127.0.0.1:8080
+//	            - 127.0.0.1:8081
+//
+// Larger lookup tables move fewer shards when peers change.
+//
+//	maglev:
+//	  peers:
+//	    - 127.0.0.1:8080
+//	  tableSize: 655373
+func Spec() yarpcconfig.PeerListSpec {
+	return SpecWithOptions()
+}
+
+// SpecWithOptions accepts additional list constructor options.
+func SpecWithOptions(options ...ListOption) yarpcconfig.PeerListSpec {
+	return yarpcconfig.PeerListSpec{
+		Name: "maglev",
+		BuildPeerList: func(cfg Configuration, t peer.Transport, k *yarpcconfig.Kit) (peer.ChooserList, error) {
+			opts := make([]ListOption, 0, len(options)+4)
+
+			opts = append(opts, options...)
+
+			if cfg.Capacity != nil {
+				if *cfg.Capacity <= 0 {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						fmt.Sprintf("Capacity must be greater than 0. Got: %d.", *cfg.Capacity))
+				}
+				opts = append(opts, Capacity(*cfg.Capacity))
+			}
+
+			if cfg.FailFast {
+				opts = append(opts, FailFast())
+			}
+
+			if cfg.OutlierDetection != nil {
+				if err := cfg.OutlierDetection.Validate(); err != nil {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"invalid outlierDetection: %v", err)
+				}
+				opts = append(opts, OutlierDetection(*cfg.OutlierDetection))
+			}
+
+			if cfg.TableSize != nil {
+				if !isPrime(*cfg.TableSize) {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"tableSize must be a prime number. Got: %d.", *cfg.TableSize)
+				}
+				opts = append(opts, TableSize(*cfg.TableSize))
+			}
+
+			return New(t, opts...), nil
+		},
+	}
+}
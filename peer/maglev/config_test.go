@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package maglev
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+type attrs map[string]interface{}
+
+func TestConfig(t *testing.T) {
+	cfg := yarpcconfig.New()
+	cfg.RegisterPeerList(Spec())
+	cfg.RegisterTransport(yarpctest.FakeTransportSpec())
+
+	load := func(list attrs) error {
+		_, err := cfg.LoadConfig("our-service", attrs{
+			"outbounds": attrs{
+				"their-service": attrs{
+					"fake-transport": attrs{
+						"maglev": list,
+					},
+				},
+			},
+		})
+		return err
+	}
+
+	assert.NoError(t, load(attrs{
+		"peers":     []string{"1.1.1.1:1111", "2.2.2.2:2222"},
+		"capacity":  5,
+		"failFast":  true,
+		"tableSize": 1021,
+		"outlierDetection": attrs{
+			"consecutiveFailures": 5,
+		},
+	}))
+
+	err := load(attrs{
+		"peers":     []string{"1.1.1.1:1111"},
+		"tableSize": 1000,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tableSize must be a prime number")
+
+	err = load(attrs{
+		"peers":    []string{"1.1.1.1:1111"},
+		"capacity": -1,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Capacity must be greater than 0")
+
+	err = load(attrs{
+		"peers":            []string{"1.1.1.1:1111"},
+		"outlierDetection": attrs{"failurePercentage": 101},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid outlierDetection")
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package maglev provides a peer list that routes requests by their shard key
+// with a Maglev lookup table, as described in "Maglev: A Fast and Reliable
+// Software Network Load Balancer" (Eisenbud et al., NSDI 2016).
+//
+// Every peer fills slots of a table of prime size in the order of its own
+// permutation of the slots, derived from a fingerprint of its identifier,
+// taking turns with the other peers until the table is full.
+// Requests go to the peer owning the slot for the fingerprint of their shard
+// key, so lookups take constant time and every peer owns an almost equal
+// share of the table.
+// Requests without a shard key go to a random slot.
+//
+// When peers join or leave, most slots keep their owner, although a small
+// fraction of the shards of remaining peers may move as well.
+// Use the rendezvous peer list instead if shards must never move between
+// peers that remain in the list.
+//
+// The table is rebuilt lazily on the first request after a membership
+// change, taking time proportional to the table size.
+package maglev
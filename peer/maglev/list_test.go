@@ -0,0 +1,85 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package maglev
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+func TestMaglevList(t *testing.T) {
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	l := New(trans, Seed(0), TableSize(1021))
+
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{
+			hostport.Identify("1.1.1.1:1111"),
+			hostport.Identify("2.2.2.2:2222"),
+			hostport.Identify("3.3.3.3:3333"),
+		},
+	}))
+	require.NoError(t, l.Start())
+	defer func() { assert.NoError(t, l.Stop()) }()
+	trans.Flush()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	chosen := make(map[string]string)
+	for _, key := range shardKeys(100) {
+		p, onFinish, err := l.Choose(ctx, &transport.Request{ShardKey: key})
+		require.NoError(t, err)
+		onFinish(nil)
+		chosen[key] = p.Identifier()
+	}
+	for _, key := range shardKeys(100) {
+		p, onFinish, err := l.Choose(ctx, &transport.Request{ShardKey: key})
+		require.NoError(t, err)
+		onFinish(nil)
+		assert.Equal(t, chosen[key], p.Identifier(), "shard key %q must go to the same peer", key)
+	}
+
+	// Peers that become unavailable give up their shards.
+	trans.SimulateDisconnect(hostport.Identify("2.2.2.2:2222"))
+	for _, key := range shardKeys(100) {
+		p, onFinish, err := l.Choose(ctx, &transport.Request{ShardKey: key})
+		require.NoError(t, err)
+		onFinish(nil)
+		assert.NotEqual(t, "2.2.2.2:2222", p.Identifier())
+		if chosen[key] != "2.2.2.2:2222" {
+			assert.Equal(t, chosen[key], p.Identifier(), "shard key %q must stay with its peer", key)
+		}
+	}
+}
+
+func TestNewDefaultTableSize(t *testing.T) {
+	l := New(yarpctest.NewFakeTransport(), TableSize(65536))
+	assert.NotNil(t, l)
+	assert.Equal(t, "maglev", l.Introspect().Name)
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package maglev
+
+import (
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgryski/go-farm"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/abstractlist"
+)
+
+// _defaultTableSize is the prime table size recommended by the Maglev paper
+// for up to a few hundred peers.
+const _defaultTableSize = 65537
+
+type maglevList struct {
+	m sync.Mutex
+
+	nodes     []*node
+	tableSize int
+	// table maps slots to nodes, and is nil if membership changed since it
+	// was last populated.
+	table  []*node
+	random *rand.Rand
+}
+
+// Option configures the peer list implementation constructor.
+type Option interface {
+	apply(*options)
+}
+
+type options struct{}
+
+// NewImplementation creates a new Maglev abstractlist.Implementation.
+//
+// Use this constructor instead of New, when wanting to do custom peer
+// connection management.
+func NewImplementation(opts ...Option) abstractlist.Implementation {
+	return newMaglevList(10, _defaultTableSize, rand.NewSource(time.Now().UnixNano()))
+}
+
+func newMaglevList(cap int, tableSize int, source rand.Source) *maglevList {
+	return &maglevList{
+		nodes:     make([]*node, 0, cap),
+		tableSize: tableSize,
+		random:    rand.New(source),
+	}
+}
+
+func (l *maglevList) Add(peer peer.StatusPeer, pid peer.Identifier) abstractlist.Subscriber {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	// Fingerprints are stable across processes and releases, so all clients
+	// with the same peers build the same table.
+	offset, skip := farm.Fingerprint128([]byte(pid.Identifier()))
+	n := &node{
+		index:  len(l.nodes),
+		peer:   peer,
+		id:     pid.Identifier(),
+		offset: offset % uint64(l.tableSize),
+		skip:   skip%uint64(l.tableSize-1) + 1,
+	}
+	l.nodes = append(l.nodes, n)
+	l.table = nil
+	return n
+}
+
+func (l *maglevList) Remove(peer peer.StatusPeer, _ peer.Identifier, ps abstractlist.Subscriber) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	n, ok := ps.(*node)
+	if !ok || n.index >= len(l.nodes) || l.nodes[n.index] != n {
+		return
+	}
+	index := n.index
+	last := len(l.nodes) - 1
+	l.nodes[index] = l.nodes[last]
+	l.nodes[index].index = index
+	l.nodes[last] = nil
+	l.nodes = l.nodes[0:last]
+	l.table = nil
+}
+
+func (l *maglevList) Choose(req *transport.Request) peer.StatusPeer {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if len(l.nodes) == 0 {
+		return nil
+	}
+	if l.table == nil {
+		l.populate()
+	}
+
+	var slot int
+	if req.ShardKey == "" {
+		slot = l.random.Intn(len(l.table))
+	} else {
+		slot = int(farm.Fingerprint64([]byte(req.ShardKey)) % uint64(len(l.table)))
+	}
+	return l.table[slot].peer
+}
+
+// populate fills the lookup table, with each peer in turn claiming the next
+// free slot in its permutation of the table.
+//
+// Peers take turns in the order of their identifiers rather than the order
+// they joined, so that the table does not depend on the history of updates.
+//
+// populate must be called with the list locked and at least one node.
+func (l *maglevList) populate() {
+	nodes := make([]*node, len(l.nodes))
+	copy(nodes, l.nodes)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].id < nodes[j].id })
+
+	size := uint64(l.tableSize)
+	table := make([]*node, size)
+	next := make([]uint64, len(nodes))
+	for filled := uint64(0); ; {
+		for i, n := range nodes {
+			slot := (n.offset + next[i]*n.skip) % size
+			for table[slot] != nil {
+				next[i]++
+				slot = (n.offset + next[i]*n.skip) % size
+			}
+			table[slot] = n
+			next[i]++
+			filled++
+			if filled == size {
+				l.table = table
+				return
+			}
+		}
+	}
+}
+
+// isPrime returns whether the table size is a prime number, which Maglev
+// requires for every permutation to cover the whole table.
+func isPrime(n int) bool {
+	return n > 1 && big.NewInt(int64(n)).ProbablyPrime(0)
+}
+
+// node is the subscriber for a peer in the list.
+// Its fields are guarded by the list lock.
+type node struct {
+	index  int
+	peer   peer.StatusPeer
+	id     string
+	offset uint64
+	skip   uint64
+}
+
+func (n *node) UpdatePendingRequestCount(int) {}
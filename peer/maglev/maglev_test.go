@@ -0,0 +1,170 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package maglev
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/abstractlist"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// owners returns the peer that owns each of the given shard keys.
+func owners(l *maglevList, keys []string) map[string]string {
+	owners := make(map[string]string, len(keys))
+	for _, key := range keys {
+		owners[key] = l.Choose(&transport.Request{ShardKey: key}).Identifier()
+	}
+	return owners
+}
+
+func shardKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	return keys
+}
+
+func TestMaglev(t *testing.T) {
+	trans := yarpctest.NewFakeTransport()
+	add := func(l *maglevList, id string) abstractlist.Subscriber {
+		pid := hostport.Identify(id)
+		return l.Add(trans.Peer(pid), pid)
+	}
+	peers := func(n int) []string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("10.0.0.%d:8080", i)
+		}
+		return ids
+	}
+	keys := shardKeys(10000)
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Nil(t, newMaglevList(0, 7, rand.NewSource(0)).Choose(&transport.Request{}))
+	})
+
+	t.Run("table", func(t *testing.T) {
+		l := newMaglevList(0, 7, rand.NewSource(0))
+		add(l, "a")
+		add(l, "b")
+		add(l, "c")
+		l.Choose(&transport.Request{})
+
+		counts := make(map[string]int)
+		for _, n := range l.table {
+			require.NotNil(t, n, "every slot must have an owner")
+			counts[n.id]++
+		}
+		assert.Equal(t, map[string]int{"a": 3, "b": 2, "c": 2}, counts,
+			"peers must take turns claiming slots in the order of their identifiers")
+	})
+
+	t.Run("independent of insertion order", func(t *testing.T) {
+		ids := peers(10)
+		forward := newMaglevList(0, 1021, rand.NewSource(0))
+		backward := newMaglevList(0, 1021, rand.NewSource(0))
+		for i := range ids {
+			add(forward, ids[i])
+			add(backward, ids[len(ids)-1-i])
+		}
+		assert.Equal(t, owners(forward, keys), owners(backward, keys))
+	})
+
+	t.Run("balanced", func(t *testing.T) {
+		l := newMaglevList(0, _defaultTableSize, rand.NewSource(0))
+		for _, id := range peers(10) {
+			add(l, id)
+		}
+		l.Choose(&transport.Request{})
+
+		counts := make(map[string]int)
+		for _, n := range l.table {
+			counts[n.id]++
+		}
+		for id, count := range counts {
+			assert.InDelta(t, _defaultTableSize/10, count, 1, "peer %v owns an unfair share of the table", id)
+		}
+	})
+
+	t.Run("minimal disruption", func(t *testing.T) {
+		ids := peers(10)
+		l := newMaglevList(0, _defaultTableSize, rand.NewSource(0))
+		subs := make(map[string]abstractlist.Subscriber)
+		for _, id := range ids {
+			subs[id] = add(l, id)
+		}
+		before := owners(l, keys)
+
+		removed := ids[3]
+		pid := hostport.Identify(removed)
+		l.Remove(trans.Peer(pid), pid, subs[removed])
+		after := owners(l, keys)
+
+		moved := 0
+		for _, key := range keys {
+			assert.NotEqual(t, removed, after[key], "removed peer must not own shards")
+			if before[key] != removed && before[key] != after[key] {
+				moved++
+			}
+		}
+		assert.True(t, moved < len(keys)/100,
+			"expected few shards of remaining peers to move, moved %d of %d", moved, len(keys))
+
+		add(l, removed)
+		assert.Equal(t, before, owners(l, keys), "restoring a peer must restore the table")
+	})
+
+	t.Run("remove unknown subscriber", func(t *testing.T) {
+		l := newMaglevList(0, 7, rand.NewSource(0))
+		add(l, "a")
+		pid := hostport.Identify("b")
+		l.Remove(trans.Peer(pid), pid, nil)
+		assert.Equal(t, "a", l.Choose(&transport.Request{ShardKey: "foo"}).Identifier())
+	})
+
+	t.Run("no shard key", func(t *testing.T) {
+		l := newMaglevList(0, 7, rand.NewSource(0))
+		add(l, "a")
+		add(l, "b")
+		chosen := make(map[string]struct{})
+		for i := 0; i < 100; i++ {
+			chosen[l.Choose(&transport.Request{}).Identifier()] = struct{}{}
+		}
+		assert.Len(t, chosen, 2, "requests without a shard key must spread across peers")
+	})
+}
+
+func TestIsPrime(t *testing.T) {
+	assert.True(t, isPrime(2))
+	assert.True(t, isPrime(_defaultTableSize))
+	assert.False(t, isPrime(1))
+	assert.False(t, isPrime(0))
+	assert.False(t, isPrime(-7))
+	assert.False(t, isPrime(65536))
+}
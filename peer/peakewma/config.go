@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peakewma
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Configuration describes how to construct a peak EWMA peer list.
+type Configuration struct {
+	Capacity *int `config:"capacity"`
+	FailFast bool `config:"failFast"`
+	// OutlierDetection ejects peers that fail too many requests.
+	OutlierDetection *abstractlist.OutlierDetectionConfig `config:"outlierDetection"`
+	// DecayTime specifies how quickly the moving average latency of a peer
+	// decays toward lower latencies.
+	DecayTime *time.Duration `config:"decayTime"`
+}
+
+// Spec returns a configuration specification for the peak EWMA peer list
+// implementation, making it possible to select the faster of two random peers
+// with transports that use outbound peer list configuration (like HTTP).
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerList(peakewma.Spec())
+//
+// This enables the peak EWMA peer list:
+//
+//	outbounds:
+//	  otherservice:
+//	    unary:
+//	      http:
+//	        url: https://host:port/rpc
+//	        peak-ewma:
+//	          peers:
+//	            - 127.0.0.1:8080
+//	            - 127.0.0.1:8081
+//
+// The decay time controls how quickly slow peers recover.
+//
+//	peak-ewma:
+//	  peers:
+//	    - 127.0.0.1:8080
+//	  decayTime: 10s
+//	  outlierDetection:
+//	    consecutiveFailures: 5
+func Spec() yarpcconfig.PeerListSpec {
+	return SpecWithOptions()
+}
+
+// SpecWithOptions accepts additional list constructor options.
+func SpecWithOptions(options ...ListOption) yarpcconfig.PeerListSpec {
+	return yarpcconfig.PeerListSpec{
+		Name: "peak-ewma",
+		BuildPeerList: func(cfg Configuration, t peer.Transport, k *yarpcconfig.Kit) (peer.ChooserList, error) {
+			opts := make([]ListOption, 0, len(options)+4)
+
+			opts = append(opts, options...)
+
+			if cfg.Capacity != nil {
+				if *cfg.Capacity <= 0 {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						fmt.Sprintf("Capacity must be greater than 0. Got: %d.", *cfg.Capacity))
+				}
+				opts = append(opts, Capacity(*cfg.Capacity))
+			}
+
+			if cfg.FailFast {
+				opts = append(opts, FailFast())
+			}
+
+			if cfg.OutlierDetection != nil {
+				if err := cfg.OutlierDetection.Validate(); err != nil {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"invalid outlierDetection: %v", err)
+				}
+				opts = append(opts, OutlierDetection(*cfg.OutlierDetection))
+			}
+
+			if cfg.DecayTime != nil {
+				if *cfg.DecayTime <= 0 {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"decayTime must be greater than 0. Got: %v.", *cfg.DecayTime)
+				}
+				opts = append(opts, DecayTime(*cfg.DecayTime))
+			}
+
+			return New(t, opts...), nil
+		},
+	}
+}
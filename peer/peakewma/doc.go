@@ -0,0 +1,34 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package peakewma provides a load balancer implementation that picks two
+// peers at random and chooses the one with the lower cost, where the cost of
+// a peer is the peak exponentially weighted moving average (peak EWMA) of its
+// response latency multiplied by its pending requests.
+//
+// The moving average follows latency increases immediately and decays toward
+// lower latencies over time, including while a peer receives no traffic, so
+// that slow peers recover.
+// Peers with pending requests but no observed latency, like newly added
+// peers, are penalized until their first response.
+//
+// Failures often return quickly and may make failing peers appear fast.
+// Combine this list with outlier detection to stop choosing failing peers.
+package peakewma
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peakewma
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+func TestPeakEWMAList(t *testing.T) {
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	l := New(trans, Seed(0))
+
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{
+			hostport.Identify("fast"),
+			hostport.Identify("slow"),
+		},
+	}))
+	require.NoError(t, l.Start())
+	defer func() { assert.NoError(t, l.Stop()) }()
+	trans.Flush()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		p, onFinish, err := l.Choose(ctx, &transport.Request{})
+		require.NoError(t, err)
+		counts[p.Identifier()]++
+		if p.Identifier() == "slow" {
+			time.Sleep(5 * time.Millisecond)
+		}
+		onFinish(nil)
+	}
+	assert.True(t, counts["fast"] >= 90, "expected most requests to go to the fast peer, got %v", counts)
+}
+
+func TestNewDefaultDecayTime(t *testing.T) {
+	l := New(yarpctest.NewFakeTransport(), DecayTime(0))
+	assert.NotNil(t, l)
+	assert.Equal(t, "peak-ewma", l.Introspect().Name)
+}
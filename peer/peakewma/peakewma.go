@@ -0,0 +1,178 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peakewma
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/abstractlist"
+)
+
+var _timeNow = time.Now // for tests
+
+// _penalty is the cost, in nanoseconds, of a peer with pending requests but
+// no observed latency.
+const _penalty = float64(math.MaxInt64 >> 16)
+
+type peakEWMAList struct {
+	m sync.Mutex
+
+	nodes     []*node
+	random    *rand.Rand
+	decayTime time.Duration
+}
+
+// Option configures the peer list implementation constructor.
+type Option interface {
+	apply(*options)
+}
+
+type options struct{}
+
+// NewImplementation creates a new peak EWMA abstractlist.Implementation.
+//
+// Use this constructor instead of New, when wanting to do custom peer
+// connection management.
+func NewImplementation(opts ...Option) abstractlist.Implementation {
+	return newPeakEWMAList(10, rand.NewSource(time.Now().UnixNano()), _defaultDecayTime)
+}
+
+func newPeakEWMAList(cap int, source rand.Source, decayTime time.Duration) *peakEWMAList {
+	return &peakEWMAList{
+		nodes:     make([]*node, 0, cap),
+		random:    rand.New(source),
+		decayTime: decayTime,
+	}
+}
+
+func (l *peakEWMAList) Add(peer peer.StatusPeer, _ peer.Identifier) abstractlist.Subscriber {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	n := &node{
+		list:  l,
+		index: len(l.nodes),
+		peer:  peer,
+		stamp: _timeNow(),
+	}
+	l.nodes = append(l.nodes, n)
+	return n
+}
+
+func (l *peakEWMAList) Remove(peer peer.StatusPeer, _ peer.Identifier, ps abstractlist.Subscriber) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	n, ok := ps.(*node)
+	if !ok || n.index >= len(l.nodes) || l.nodes[n.index] != n {
+		return
+	}
+	index := n.index
+	last := len(l.nodes) - 1
+	l.nodes[index] = l.nodes[last]
+	l.nodes[index].index = index
+	l.nodes[last] = nil
+	l.nodes = l.nodes[0:last]
+}
+
+func (l *peakEWMAList) Choose(_ *transport.Request) peer.StatusPeer {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	numNodes := len(l.nodes)
+	if numNodes == 0 {
+		return nil
+	}
+	if numNodes == 1 {
+		return l.nodes[0].peer
+	}
+	i := l.random.Intn(numNodes)
+	j := i + 1 + l.random.Intn(numNodes-1)
+	if j >= numNodes {
+		j -= numNodes
+	}
+	now := _timeNow()
+	if l.nodes[i].cost(now) > l.nodes[j].cost(now) {
+		i = j
+	}
+	return l.nodes[i].peer
+}
+
+// node tracks the pending requests and peak EWMA latency of a peer.
+// Its fields are guarded by the list lock.
+type node struct {
+	list    *peakEWMAList
+	index   int
+	peer    peer.StatusPeer
+	pending int
+
+	// ewma is the moving average latency in nanoseconds as of stamp.
+	ewma  float64
+	stamp time.Time
+}
+
+var _ abstractlist.LatencySubscriber = (*node)(nil)
+
+func (n *node) UpdatePendingRequestCount(pendingRequestCount int) {
+	n.list.m.Lock()
+	n.pending = pendingRequestCount
+	n.list.m.Unlock()
+}
+
+func (n *node) UpdateLatency(latency time.Duration) {
+	n.list.m.Lock()
+	n.observe(_timeNow(), float64(latency))
+	n.list.m.Unlock()
+}
+
+// observe folds a latency into the moving average.
+// Higher latencies replace the average outright, while lower latencies weigh
+// in more the longer it has been since the last observation.
+func (n *node) observe(now time.Time, latency float64) {
+	elapsed := now.Sub(n.stamp)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	n.stamp = now
+
+	if latency > n.ewma {
+		n.ewma = latency
+		return
+	}
+	w := math.Exp(-float64(elapsed) / float64(n.list.decayTime))
+	n.ewma = n.ewma*w + latency*(1-w)
+}
+
+// cost returns the expected latency of the peer weighted by its pending
+// requests, decaying the moving average toward zero for the time since the
+// last observation.
+func (n *node) cost(now time.Time) float64 {
+	n.observe(now, 0)
+	if n.ewma == 0 && n.pending != 0 {
+		return _penalty + float64(n.pending)
+	}
+	return n.ewma * float64(n.pending+1)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peakewma
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+func TestCost(t *testing.T) {
+	now := time.Now()
+	_timeNow = func() time.Time { return now }
+	defer func() { _timeNow = time.Now }()
+
+	trans := yarpctest.NewFakeTransport()
+	l := newPeakEWMAList(0, rand.NewSource(0), 10*time.Second)
+	n := l.Add(trans.Peer(hostport.Identify("a")), hostport.Identify("a")).(*node)
+
+	assert.Equal(t, float64(0), n.cost(now), "idle peers without latency are free")
+
+	n.UpdatePendingRequestCount(2)
+	assert.Equal(t, _penalty+2, n.cost(now), "busy peers without latency are penalized")
+
+	n.UpdateLatency(100 * time.Millisecond)
+	assert.Equal(t, float64(300*time.Millisecond), n.cost(now), "latency weighted by pending requests")
+
+	n.UpdateLatency(300 * time.Millisecond)
+	assert.Equal(t, float64(900*time.Millisecond), n.cost(now), "peak latency replaces the average")
+
+	// The average decays toward zero while no latencies are observed.
+	now = now.Add(10 * time.Second)
+	n.UpdatePendingRequestCount(0)
+	assert.InDelta(t, float64(300*time.Millisecond)/2.718281828, n.cost(now), float64(time.Millisecond))
+
+	// Idle peers recover.
+	now = now.Add(time.Minute)
+	assert.InDelta(t, 0, n.cost(now), float64(time.Millisecond))
+}
+
+func TestChoose(t *testing.T) {
+	now := time.Now()
+	_timeNow = func() time.Time { return now }
+	defer func() { _timeNow = time.Now }()
+
+	trans := yarpctest.NewFakeTransport()
+	l := newPeakEWMAList(0, rand.NewSource(0), 10*time.Second)
+	assert.Nil(t, l.Choose(nil))
+
+	a := l.Add(trans.Peer(hostport.Identify("a")), hostport.Identify("a")).(*node)
+	assert.Equal(t, "a", l.Choose(nil).Identifier(), "only peer")
+
+	b := l.Add(trans.Peer(hostport.Identify("b")), hostport.Identify("b")).(*node)
+	a.UpdateLatency(100 * time.Millisecond)
+	b.UpdateLatency(10 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "b", l.Choose(nil).Identifier(), "faster peer")
+	}
+
+	b.UpdatePendingRequestCount(10)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "a", l.Choose(nil).Identifier(), "less loaded peer")
+	}
+
+	l.Remove(nil, nil, a)
+	l.Remove(nil, nil, a)
+	assert.Equal(t, "b", l.Choose(nil).Identifier(), "remaining peer")
+}
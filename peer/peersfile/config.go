@@ -0,0 +1,87 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peersfile
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+// Configuration describes the peers file to follow.
+//
+//	path: /etc/myservice/peers.yaml
+//	format: yaml
+//	interval: 5s
+//
+// The format is one of json, yaml and lines, and is inferred from the file
+// extension if omitted.
+type Configuration struct {
+	Path     string        `config:"path,interpolate"`
+	Format   Format        `config:"format"`
+	Interval time.Duration `config:"interval"`
+}
+
+// Spec returns a configuration specification for the peers file updater,
+// making it possible to follow a file listing the peers of any peer list.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerListUpdater(peersfile.Spec())
+//
+// This enables the peers-file updater:
+//
+//	outbounds:
+//	  myservice:
+//	    http:
+//	      round-robin:
+//	        peers-file:
+//	          path: /etc/myservice/peers.json
+//
+// See Configuration for the full shape of the configuration.
+// Options take precedence over the configuration.
+func Spec(opts ...Option) yarpcconfig.PeerListUpdaterSpec {
+	return yarpcconfig.PeerListUpdaterSpec{
+		Name: "peers-file",
+		BuildPeerListUpdater: func(cfg Configuration, _ *yarpcconfig.Kit) (peer.Binder, error) {
+			cfgOpts, err := cfg.options()
+			if err != nil {
+				return nil, err
+			}
+			return Binder(cfg.Path, append(cfgOpts, opts...)...), nil
+		},
+	}
+}
+
+func (c Configuration) options() ([]Option, error) {
+	if c.Path == "" {
+		return nil, errors.New("peers file updater requires a path")
+	}
+	if err := c.Format.validate(); err != nil {
+		return nil, fmt.Errorf("peers file updater for %q: %v", c.Path, err)
+	}
+	if c.Interval < 0 {
+		return nil, fmt.Errorf("peers file updater interval must not be negative, got %v", c.Interval)
+	}
+	return []Option{WithFormat(c.Format), PollInterval(c.Interval)}, nil
+}
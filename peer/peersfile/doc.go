@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package peersfile provides a peer list updater that follows a file listing
+// host:port peers.
+//
+// The updater polls the file and, whenever its contents change, adds peers
+// that appeared to the peer list and removes peers that disappeared.
+// The file may be a JSON or YAML list of peers,
+//
+//	["10.0.0.1:8080", "10.0.0.2:8080"]
+//
+// or list one peer per line, ignoring blank lines and lines starting with #.
+//
+//	# myservice
+//	10.0.0.1:8080
+//	10.0.0.2:8080
+//
+// If the file cannot be read or parsed, for example because it is being
+// rewritten, or lists no peers, the updater keeps the peers it found
+// previously and logs the error.
+//
+//	chooser := peer.Bind(
+//		roundrobin.New(transport),
+//		peersfile.Binder("/etc/myservice/peers.yaml"),
+//	)
+//
+// The updater may also be configured with yarpcconfig by registering Spec
+// with the Configurator.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerListUpdater(peersfile.Spec())
+//
+// This enables the peers-file updater for any peer list:
+//
+//	outbounds:
+//	  myservice:
+//	    http:
+//	      round-robin:
+//	        peers-file:
+//	          path: /etc/myservice/peers.json
+//	          format: json
+//	          interval: 5s
+package peersfile
@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peersfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Format is the format of a peers file.
+type Format string
+
+const (
+	// FormatAuto infers the format of the file from its extension: JSON for
+	// .json files, YAML for .yaml and .yml files, and one peer per line
+	// otherwise.
+	FormatAuto Format = ""
+
+	// FormatJSON is a JSON list of host:port peers.
+	FormatJSON Format = "json"
+
+	// FormatYAML is a YAML list of host:port peers.
+	FormatYAML Format = "yaml"
+
+	// FormatLines lists one host:port peer per line. Blank lines and lines
+	// starting with # are ignored.
+	FormatLines Format = "lines"
+)
+
+func (f Format) validate() error {
+	switch f {
+	case FormatAuto, FormatJSON, FormatYAML, FormatLines:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q, expected one of %q, %q or %q", string(f), FormatJSON, FormatYAML, FormatLines)
+}
+
+// resolve returns the format of the file at the given path.
+func (f Format) resolve(path string) Format {
+	if f != FormatAuto {
+		return f
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatLines
+}
+
+// parse returns the set of peers listed in the given contents.
+func (f Format) parse(contents []byte) (map[string]struct{}, error) {
+	var peers []string
+	switch f {
+	case FormatJSON:
+		if err := json.Unmarshal(contents, &peers); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON list of peers: %v", err)
+		}
+	case FormatYAML:
+		if err := yaml.UnmarshalStrict(contents, &peers); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML list of peers: %v", err)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(contents))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			peers = append(peers, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read list of peers: %v", err)
+		}
+	}
+
+	set := make(map[string]struct{}, len(peers))
+	for _, p := range peers {
+		host, port, err := net.SplitHostPort(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer %q: %v", p, err)
+		}
+		if host == "" || strings.ContainsAny(host, " \t") {
+			return nil, fmt.Errorf("invalid peer %q: expected host:port", p)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return nil, fmt.Errorf("invalid peer %q: invalid port %q", p, port)
+		}
+		set[p] = struct{}{}
+	}
+	return set, nil
+}
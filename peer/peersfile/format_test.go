@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peersfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatResolve(t *testing.T) {
+	tests := []struct {
+		format Format
+		path   string
+		want   Format
+	}{
+		{FormatAuto, "/etc/peers.json", FormatJSON},
+		{FormatAuto, "/etc/peers.YAML", FormatYAML},
+		{FormatAuto, "/etc/peers.yml", FormatYAML},
+		{FormatAuto, "/etc/peers.txt", FormatLines},
+		{FormatAuto, "/etc/peers", FormatLines},
+		{FormatYAML, "/etc/peers.json", FormatYAML},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.format.resolve(tt.path), "format of %q", tt.path)
+	}
+}
+
+func TestFormatValidate(t *testing.T) {
+	for _, f := range []Format{FormatAuto, FormatJSON, FormatYAML, FormatLines} {
+		assert.NoError(t, f.validate())
+	}
+	assert.EqualError(t, Format("xml").validate(), `unknown format "xml", expected one of "json", "yaml" or "lines"`)
+}
+
+func TestFormatParse(t *testing.T) {
+	tests := []struct {
+		desc    string
+		format  Format
+		give    string
+		want    []string
+		wantErr string
+	}{
+		{
+			desc:   "json",
+			format: FormatJSON,
+			give:   `["10.0.0.1:80", "[::1]:80", "host:80", "10.0.0.1:80"]`,
+			want:   []string{"10.0.0.1:80", "[::1]:80", "host:80"},
+		},
+		{
+			desc:    "json truncated",
+			format:  FormatJSON,
+			give:    `["10.0.0.1:80", "10.0`,
+			wantErr: "failed to parse JSON list of peers",
+		},
+		{
+			desc:    "json object",
+			format:  FormatJSON,
+			give:    `{"peers": []}`,
+			wantErr: "failed to parse JSON list of peers",
+		},
+		{
+			desc:   "yaml",
+			format: FormatYAML,
+			give:   "- 10.0.0.1:80\n- 10.0.0.2:80\n",
+			want:   []string{"10.0.0.1:80", "10.0.0.2:80"},
+		},
+		{
+			desc:   "yaml empty",
+			format: FormatYAML,
+			give:   "",
+		},
+		{
+			desc:    "yaml malformed",
+			format:  FormatYAML,
+			give:    "- 10.0.0.1:80\n- [10.0",
+			wantErr: "failed to parse YAML list of peers",
+		},
+		{
+			desc:   "lines",
+			format: FormatLines,
+			give:   "# peers\n10.0.0.1:80\n\n  10.0.0.2:80  \r\n",
+			want:   []string{"10.0.0.1:80", "10.0.0.2:80"},
+		},
+		{
+			desc:    "missing port",
+			format:  FormatLines,
+			give:    "10.0.0.1:80\n10.0.0.2\n",
+			wantErr: `invalid peer "10.0.0.2"`,
+		},
+		{
+			desc:    "invalid port",
+			format:  FormatYAML,
+			give:    "- 10.0.0.1:80\n  - 10.0\n",
+			wantErr: `invalid peer "10.0.0.1:80 - 10.0": invalid port "80 - 10.0"`,
+		},
+		{
+			desc:    "named port",
+			format:  FormatLines,
+			give:    "10.0.0.1:http\n",
+			wantErr: `invalid peer "10.0.0.1:http": invalid port "http"`,
+		},
+		{
+			desc:    "host with spaces",
+			format:  FormatLines,
+			give:    "10.0.0.1 10.0.0.2:80\n",
+			wantErr: `invalid peer "10.0.0.1 10.0.0.2:80": expected host:port`,
+		},
+		{
+			desc:    "missing host",
+			format:  FormatLines,
+			give:    ":80\n",
+			wantErr: `invalid peer ":80": expected host:port`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := tt.format.parse([]byte(tt.give))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			want := make(map[string]struct{}, len(tt.want))
+			for _, p := range tt.want {
+				want[p] = struct{}{}
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peersfile
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type options struct {
+	format   Format
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func newOptions() options {
+	return options{
+		format:   FormatAuto,
+		interval: 5 * time.Second,
+		logger:   zap.NewNop(),
+	}
+}
+
+// Option customizes the behavior of a peers file updater.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(opts *options) { f(opts) }
+
+// WithFormat sets the format of the peers file.
+//
+// Defaults to FormatAuto, which infers the format from the file extension.
+func WithFormat(f Format) Option {
+	return optionFunc(func(opts *options) {
+		opts.format = f
+	})
+}
+
+// PollInterval is how often the updater checks the file for changes.
+//
+// Defaults to 5s.
+func PollInterval(d time.Duration) Option {
+	return optionFunc(func(opts *options) {
+		if d > 0 {
+			opts.interval = d
+		}
+	})
+}
+
+// Logger sets a logger for the updater.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(opts *options) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peersfile
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/pkg/lifecycle"
+	"go.uber.org/zap"
+)
+
+// Binder returns a peer.Binder that binds a peer list to the peers listed in
+// the file at the given path, suitable as an argument to peer.Bind.
+func Binder(path string, opts ...Option) peer.Binder {
+	return func(pl peer.List) transport.Lifecycle {
+		return NewUpdater(pl, path, opts...)
+	}
+}
+
+// Updater is a peer list updater that follows a file listing peers and
+// updates a peer list as the file changes.
+type Updater struct {
+	once   *lifecycle.Once
+	list   peer.List
+	path   string
+	format Format
+	opts   options
+
+	done    chan struct{}
+	stopped chan struct{}
+
+	// contents holds the contents of the file when it was last read,
+	// unreadable whether the last read failed, and peers the addresses of
+	// the peers in the list.
+	// They are only accessed by the goroutine that polls the file, and after
+	// that goroutine exits.
+	contents   []byte
+	unreadable bool
+	peers      map[string]struct{}
+}
+
+// NewUpdater creates a new peers file updater that feeds the peers listed in
+// the file at the given path to the given peer list once started.
+func NewUpdater(list peer.List, path string, opts ...Option) *Updater {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	return &Updater{
+		once:    lifecycle.NewOnce(),
+		list:    list,
+		path:    path,
+		format:  options.format.resolve(path),
+		opts:    options,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		peers:   make(map[string]struct{}),
+	}
+}
+
+// Start reads the file and adds the peers it lists to the peer list, and
+// then follows the file until the updater stops.
+//
+// Start does not fail if the file cannot be read yet; the updater keeps
+// polling it.
+func (u *Updater) Start() error {
+	return u.once.Start(u.start)
+}
+
+func (u *Updater) start() error {
+	if u.path == "" {
+		return errors.New("peers file updater requires a path")
+	}
+	if err := u.format.validate(); err != nil {
+		return err
+	}
+
+	u.refresh()
+	go u.run()
+	return nil
+}
+
+func (u *Updater) run() {
+	defer close(u.stopped)
+
+	ticker := time.NewTicker(u.opts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			u.refresh()
+		case <-u.done:
+			return
+		}
+	}
+}
+
+// Stop stops following the file and removes all peers it added from the
+// peer list.
+func (u *Updater) Stop() error {
+	return u.once.Stop(u.stop)
+}
+
+func (u *Updater) stop() error {
+	close(u.done)
+	<-u.stopped
+
+	removals := identifiers(u.peers)
+	u.peers = make(map[string]struct{})
+	if len(removals) == 0 {
+		return nil
+	}
+	return u.list.Update(peer.ListUpdates{Removals: removals})
+}
+
+// IsRunning returns whether the updater is running.
+func (u *Updater) IsRunning() bool {
+	return u.once.IsRunning()
+}
+
+// refresh reads the file and, if it changed, updates the peer list with the
+// difference between the peers it lists and the peers in the list.
+func (u *Updater) refresh() {
+	contents, err := os.ReadFile(u.path)
+	if err != nil {
+		// Log once until the file becomes readable again.
+		if !u.unreadable {
+			u.opts.logger.Warn("Failed to read peers file, keeping previous peers.",
+				zap.String("path", u.path), zap.Error(err))
+		}
+		u.unreadable = true
+		u.contents = nil
+		return
+	}
+	u.unreadable = false
+	if bytes.Equal(contents, u.contents) {
+		return
+	}
+	u.contents = contents
+
+	addrs, err := u.format.parse(contents)
+	if err != nil {
+		u.opts.logger.Warn("Failed to parse peers file, keeping previous peers.",
+			zap.String("path", u.path), zap.Error(err))
+		return
+	}
+	if len(addrs) == 0 {
+		u.opts.logger.Warn("Peers file lists no peers, keeping previous peers.", zap.String("path", u.path))
+		return
+	}
+
+	var updates peer.ListUpdates
+	for addr := range addrs {
+		if _, ok := u.peers[addr]; !ok {
+			updates.Additions = append(updates.Additions, hostport.PeerIdentifier(addr))
+		}
+	}
+	for addr := range u.peers {
+		if _, ok := addrs[addr]; !ok {
+			updates.Removals = append(updates.Removals, hostport.PeerIdentifier(addr))
+		}
+	}
+	if len(updates.Additions) == 0 && len(updates.Removals) == 0 {
+		return
+	}
+	sortIdentifiers(updates.Additions)
+	sortIdentifiers(updates.Removals)
+
+	// Peer lists apply the updates they can even if others fail, so the
+	// listed peers become the new peers either way.
+	u.peers = addrs
+	if err := u.list.Update(updates); err != nil {
+		u.opts.logger.Error("Failed to update peer list.", zap.String("path", u.path), zap.Error(err))
+	}
+}
+
+func identifiers(addrs map[string]struct{}) []peer.Identifier {
+	ids := make([]peer.Identifier, 0, len(addrs))
+	for addr := range addrs {
+		ids = append(ids, hostport.PeerIdentifier(addr))
+	}
+	sortIdentifiers(ids)
+	return ids
+}
+
+func sortIdentifiers(ids []peer.Identifier) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Identifier() < ids[j].Identifier()
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peersfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recordingList is a peer.List that records the updates it receives.
+type recordingList struct {
+	mu      sync.Mutex
+	updates []peer.ListUpdates
+	err     error
+}
+
+func (l *recordingList) Update(updates peer.ListUpdates) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.updates = append(l.updates, updates)
+	return l.err
+}
+
+func (l *recordingList) Updates() []peer.ListUpdates {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]peer.ListUpdates(nil), l.updates...)
+}
+
+func ids(addrs ...string) []peer.Identifier {
+	if len(addrs) == 0 {
+		return nil
+	}
+	out := make([]peer.Identifier, len(addrs))
+	for i, addr := range addrs {
+		out[i] = hostport.PeerIdentifier(addr)
+	}
+	return out
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+}
+
+func TestUpdater(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	path := filepath.Join(t.TempDir(), "peers.yaml")
+	writeFile(t, path, "- 10.0.0.2:80\n- 10.0.0.1:80\n")
+
+	list := &recordingList{}
+	u := NewUpdater(list, path, PollInterval(time.Hour), Logger(zap.New(core)))
+
+	require.NoError(t, u.Start())
+	assert.True(t, u.IsRunning())
+	assert.Equal(t, []peer.ListUpdates{
+		{Additions: ids("10.0.0.1:80", "10.0.0.2:80")},
+	}, list.Updates(), "start must read the file")
+
+	writeFile(t, path, "- 10.0.0.2:80\n- 10.0.0.3:80\n")
+	u.refresh()
+	assert.Equal(t, peer.ListUpdates{
+		Additions: ids("10.0.0.3:80"),
+		Removals:  ids("10.0.0.1:80"),
+	}, list.Updates()[1], "only the difference must be applied")
+
+	writeFile(t, path, "- 10.0.0.3:80\n- 10.0.0.2:80\n")
+	u.refresh()
+	assert.Len(t, list.Updates(), 2, "reordering peers must not update the list")
+
+	writeFile(t, path, "- 10.0.0.3:80\n- [10.0")
+	u.refresh()
+	u.refresh()
+	assert.Len(t, list.Updates(), 2, "malformed files must not update the list")
+	assert.Equal(t, 1, logs.FilterMessage("Failed to parse peers file, keeping previous peers.").Len(),
+		"malformed files must be logged once")
+
+	writeFile(t, path, "")
+	u.refresh()
+	assert.Len(t, list.Updates(), 2, "empty files must not update the list")
+	assert.Equal(t, 1, logs.FilterMessage("Peers file lists no peers, keeping previous peers.").Len())
+
+	require.NoError(t, os.Remove(path))
+	u.refresh()
+	u.refresh()
+	assert.Len(t, list.Updates(), 2, "missing files must not update the list")
+	assert.Equal(t, 1, logs.FilterMessage("Failed to read peers file, keeping previous peers.").Len(),
+		"missing files must be logged once")
+
+	writeFile(t, path, "- 10.0.0.4:80\n")
+	u.refresh()
+	assert.Equal(t, peer.ListUpdates{
+		Additions: ids("10.0.0.4:80"),
+		Removals:  ids("10.0.0.2:80", "10.0.0.3:80"),
+	}, list.Updates()[2], "changes must be applied once the file is valid again")
+
+	require.NoError(t, u.Stop())
+	assert.False(t, u.IsRunning())
+	assert.Equal(t, peer.ListUpdates{Removals: ids("10.0.0.4:80")}, list.Updates()[3],
+		"stop must remove all peers")
+}
+
+func TestUpdaterMissingFileAtStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers")
+	list := &recordingList{}
+	u := NewUpdater(list, path, PollInterval(time.Millisecond))
+
+	require.NoError(t, u.Start(), "start must not fail if the file does not exist yet")
+	assert.Empty(t, list.Updates())
+
+	writeFile(t, path, "10.0.0.1:80\n")
+	require.Eventually(t, func() bool {
+		return len(list.Updates()) == 1
+	}, time.Second, time.Millisecond, "updater must poll the file")
+	assert.Equal(t, peer.ListUpdates{Additions: ids("10.0.0.1:80")}, list.Updates()[0])
+
+	require.NoError(t, u.Stop())
+	assert.Len(t, list.Updates(), 2)
+}
+
+func TestUpdaterListUpdateError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	writeFile(t, path, `["10.0.0.1:80"]`)
+
+	list := &recordingList{err: errors.New("great sadness")}
+	u := NewUpdater(list, path, PollInterval(time.Hour))
+	require.NoError(t, u.Start())
+
+	err := u.Stop()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "great sadness")
+	assert.Equal(t, []peer.ListUpdates{
+		{Additions: ids("10.0.0.1:80")},
+		{Removals: ids("10.0.0.1:80")},
+	}, list.Updates(), "peers must be removed even if adding them failed")
+}
+
+func TestUpdaterInvalid(t *testing.T) {
+	u := NewUpdater(&recordingList{}, "")
+	assert.EqualError(t, u.Start(), "peers file updater requires a path")
+
+	u = NewUpdater(&recordingList{}, "peers", WithFormat("xml"))
+	assert.EqualError(t, u.Start(), `unknown format "xml", expected one of "json", "yaml" or "lines"`)
+}
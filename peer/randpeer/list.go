@@ -38,6 +38,7 @@ type listOptions struct {
 	failFast             bool
 	defaultChooseTimeout *time.Duration
 	logger               *zap.Logger
+	outlierDetection     *abstractlist.OutlierDetectionConfig
 }
 
 var defaultListOptions = listOptions{
@@ -106,6 +107,17 @@ func DefaultChooseTimeout(timeout time.Duration) ListOption {
 	})
 }
 
+// OutlierDetection ejects peers that fail too many requests from rotation
+// for an exponentially growing period.
+// See abstractlist.OutlierDetectionConfig for details.
+//
+// Defaults to disabled.
+func OutlierDetection(outlierDetection abstractlist.OutlierDetectionConfig) ListOption {
+	return listOptionFunc(func(options *listOptions) {
+		options.outlierDetection = &outlierDetection
+	})
+}
+
 // New creates a new random peer list.
 func New(transport peer.Transport, opts ...ListOption) *List {
 	options := defaultListOptions
@@ -128,6 +140,9 @@ func New(transport peer.Transport, opts ...ListOption) *List {
 	if options.failFast {
 		plOpts = append(plOpts, abstractlist.FailFast())
 	}
+	if options.outlierDetection != nil {
+		plOpts = append(plOpts, abstractlist.OutlierDetection(*options.outlierDetection))
+	}
 	if options.defaultChooseTimeout != nil {
 		plOpts = append(plOpts, abstractlist.DefaultChooseTimeout(*options.defaultChooseTimeout))
 	}
@@ -0,0 +1,36 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package rendezvous provides a peer list that routes requests by their shard
+// key with rendezvous hashing, also known as highest random weight (HRW)
+// hashing.
+//
+// For every request, each peer receives a score from a fingerprint of the
+// shard key seeded with a fingerprint of the peer identifier, and the request
+// goes to the peer with the highest score.
+// Requests without a shard key go to a random peer.
+//
+// When a peer leaves, only its own shards move, spreading evenly over the
+// remaining peers, and when a peer joins, it only takes shards from the
+// others.
+// No shard ever moves between two peers that remain in the list.
+// Choosing a peer takes time proportional to the number of peers, so the
+// Maglev peer list may be preferable for very large lists.
+package rendezvous
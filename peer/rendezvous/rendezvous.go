@@ -0,0 +1,130 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rendezvous
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dgryski/go-farm"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/abstractlist"
+)
+
+type rendezvousList struct {
+	m sync.Mutex
+
+	nodes  []*node
+	random *rand.Rand
+}
+
+// Option configures the peer list implementation constructor.
+type Option interface {
+	apply(*options)
+}
+
+type options struct{}
+
+// NewImplementation creates a new rendezvous hashing
+// abstractlist.Implementation.
+//
+// Use this constructor instead of New, when wanting to do custom peer
+// connection management.
+func NewImplementation(opts ...Option) abstractlist.Implementation {
+	return newRendezvousList(10, rand.NewSource(time.Now().UnixNano()))
+}
+
+func newRendezvousList(cap int, source rand.Source) *rendezvousList {
+	return &rendezvousList{
+		nodes:  make([]*node, 0, cap),
+		random: rand.New(source),
+	}
+}
+
+func (l *rendezvousList) Add(peer peer.StatusPeer, pid peer.Identifier) abstractlist.Subscriber {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	n := &node{
+		index: len(l.nodes),
+		peer:  peer,
+		id:    pid.Identifier(),
+		// Fingerprints are stable across processes and releases, so all
+		// clients agree on the scores of every peer.
+		seed: farm.Fingerprint64([]byte(pid.Identifier())),
+	}
+	l.nodes = append(l.nodes, n)
+	return n
+}
+
+func (l *rendezvousList) Remove(peer peer.StatusPeer, _ peer.Identifier, ps abstractlist.Subscriber) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	n, ok := ps.(*node)
+	if !ok || n.index >= len(l.nodes) || l.nodes[n.index] != n {
+		return
+	}
+	index := n.index
+	last := len(l.nodes) - 1
+	l.nodes[index] = l.nodes[last]
+	l.nodes[index].index = index
+	l.nodes[last] = nil
+	l.nodes = l.nodes[0:last]
+}
+
+func (l *rendezvousList) Choose(req *transport.Request) peer.StatusPeer {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if len(l.nodes) == 0 {
+		return nil
+	}
+	if req.ShardKey == "" {
+		return l.nodes[l.random.Intn(len(l.nodes))].peer
+	}
+
+	key := []byte(req.ShardKey)
+	var best *node
+	var bestScore uint64
+	for _, n := range l.nodes {
+		score := farm.Hash64WithSeed(key, n.seed)
+		// Break ties by identifier so that the choice does not depend on the
+		// order peers joined.
+		if best == nil || score > bestScore || (score == bestScore && n.id < best.id) {
+			best, bestScore = n, score
+		}
+	}
+	return best.peer
+}
+
+// node is the subscriber for a peer in the list.
+// Its fields are guarded by the list lock.
+type node struct {
+	index int
+	peer  peer.StatusPeer
+	id    string
+	seed  uint64
+}
+
+func (n *node) UpdatePendingRequestCount(int) {}
@@ -0,0 +1,141 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rendezvous
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/abstractlist"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// owners returns the peer that owns each of the given shard keys.
+func owners(l *rendezvousList, keys []string) map[string]string {
+	owners := make(map[string]string, len(keys))
+	for _, key := range keys {
+		owners[key] = l.Choose(&transport.Request{ShardKey: key}).Identifier()
+	}
+	return owners
+}
+
+func shardKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	return keys
+}
+
+func TestRendezvous(t *testing.T) {
+	trans := yarpctest.NewFakeTransport()
+	add := func(l *rendezvousList, id string) abstractlist.Subscriber {
+		pid := hostport.Identify(id)
+		return l.Add(trans.Peer(pid), pid)
+	}
+	peers := func(n int) []string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("10.0.0.%d:8080", i)
+		}
+		return ids
+	}
+	keys := shardKeys(10000)
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Nil(t, newRendezvousList(0, rand.NewSource(0)).Choose(&transport.Request{}))
+	})
+
+	t.Run("independent of insertion order", func(t *testing.T) {
+		ids := peers(10)
+		forward := newRendezvousList(0, rand.NewSource(0))
+		backward := newRendezvousList(0, rand.NewSource(0))
+		for i := range ids {
+			add(forward, ids[i])
+			add(backward, ids[len(ids)-1-i])
+		}
+		assert.Equal(t, owners(forward, keys), owners(backward, keys))
+	})
+
+	t.Run("balanced", func(t *testing.T) {
+		l := newRendezvousList(0, rand.NewSource(0))
+		for _, id := range peers(10) {
+			add(l, id)
+		}
+		counts := make(map[string]int)
+		for _, owner := range owners(l, keys) {
+			counts[owner]++
+		}
+		assert.Len(t, counts, 10)
+		for id, count := range counts {
+			assert.InDelta(t, len(keys)/10, count, float64(len(keys))/50, "peer %v owns an unfair share of shards", id)
+		}
+	})
+
+	t.Run("minimal disruption", func(t *testing.T) {
+		ids := peers(10)
+		l := newRendezvousList(0, rand.NewSource(0))
+		subs := make(map[string]abstractlist.Subscriber)
+		for _, id := range ids {
+			subs[id] = add(l, id)
+		}
+		before := owners(l, keys)
+
+		removed := ids[3]
+		pid := hostport.Identify(removed)
+		l.Remove(trans.Peer(pid), pid, subs[removed])
+		after := owners(l, keys)
+
+		for _, key := range keys {
+			if before[key] == removed {
+				assert.NotEqual(t, removed, after[key], "removed peer must not own shards")
+			} else {
+				assert.Equal(t, before[key], after[key], "shards of remaining peers must not move")
+			}
+		}
+
+		add(l, removed)
+		assert.Equal(t, before, owners(l, keys), "restoring a peer must restore its shards")
+	})
+
+	t.Run("remove unknown subscriber", func(t *testing.T) {
+		l := newRendezvousList(0, rand.NewSource(0))
+		add(l, "a")
+		pid := hostport.Identify("b")
+		l.Remove(trans.Peer(pid), pid, nil)
+		assert.Equal(t, "a", l.Choose(&transport.Request{ShardKey: "foo"}).Identifier())
+	})
+
+	t.Run("no shard key", func(t *testing.T) {
+		l := newRendezvousList(0, rand.NewSource(0))
+		add(l, "a")
+		add(l, "b")
+		chosen := make(map[string]struct{})
+		for i := 0; i < 100; i++ {
+			chosen[l.Choose(&transport.Request{}).Identifier()] = struct{}{}
+		}
+		assert.Len(t, chosen, 2, "requests without a shard key must spread across peers")
+	})
+}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
 	"go.uber.org/yarpc/yarpcconfig"
 	"go.uber.org/yarpc/yarpcerrors"
 )
@@ -37,6 +38,8 @@ type Configuration struct {
 	// present. This enables calls without deadlines, ie streaming, to choose
 	// peers without waiting indefinitely.
 	DefaultChooseTimeout *time.Duration `config:"defaultChooseTimeout"`
+	// OutlierDetection ejects peers that fail too many requests.
+	OutlierDetection *abstractlist.OutlierDetectionConfig `config:"outlierDetection"`
 }
 
 // Spec returns a configuration specification for the round-robin peer list
@@ -66,6 +69,8 @@ type Configuration struct {
 // peers are available (connected) at the time the request is sent.
 // The default choose timeout enables calls without deadlines, ie streaming, to
 // choose peers without waiting indefinitely.
+// Outlier detection stops choosing peers that fail too many requests for a
+// while.
 //
 //	round-robin:
 //	  peers:
@@ -73,6 +78,9 @@ type Configuration struct {
 //	  capacity: 1
 //	  failFast: true
 //	  defaultChooseTimeout: 1s
+//	  outlierDetection:
+//	    consecutiveFailures: 5
+//	    baseEjectionTime: 30s
 func Spec() yarpcconfig.PeerListSpec {
 	return SpecWithOptions()
 }
@@ -99,6 +107,14 @@ func SpecWithOptions(options ...ListOption) yarpcconfig.PeerListSpec {
 			if cfg.DefaultChooseTimeout != nil {
 				opts = append(opts, DefaultChooseTimeout(*cfg.DefaultChooseTimeout))
 			}
+			if cfg.OutlierDetection != nil {
+				if err := cfg.OutlierDetection.Validate(); err != nil {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"invalid outlierDetection: %v", err)
+				}
+				opts = append(opts, OutlierDetection(*cfg.OutlierDetection))
+			}
+
 			return New(t, opts...), nil
 		},
 	}
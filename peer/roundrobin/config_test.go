@@ -25,6 +25,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
 	"go.uber.org/yarpc/peer/hostport"
 	"go.uber.org/yarpc/yarpcconfig"
 	"go.uber.org/yarpc/yarpctest"
@@ -60,6 +61,23 @@ func TestPendingHeapConfig(t *testing.T) {
 				Capacity: &twenty,
 			},
 		},
+		{
+			name: "outlier detection",
+			cfg: Configuration{
+				OutlierDetection: &abstractlist.OutlierDetectionConfig{
+					ConsecutiveFailures: 5,
+				},
+			},
+		},
+		{
+			name: "invalid outlier detection",
+			cfg: Configuration{
+				OutlierDetection: &abstractlist.OutlierDetectionConfig{
+					FailurePercentage: 101,
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	s := Spec()
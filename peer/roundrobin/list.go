@@ -38,6 +38,7 @@ type listConfig struct {
 	defaultChooseTimeout *time.Duration
 	seed                 int64
 	logger               *zap.Logger
+	outlierDetection     *abstractlist.OutlierDetectionConfig
 }
 
 var defaultListConfig = listConfig{
@@ -88,6 +89,17 @@ func DefaultChooseTimeout(timeout time.Duration) ListOption {
 	}
 }
 
+// OutlierDetection ejects peers that fail too many requests from rotation
+// for an exponentially growing period.
+// See abstractlist.OutlierDetectionConfig for details.
+//
+// Defaults to disabled.
+func OutlierDetection(outlierDetection abstractlist.OutlierDetectionConfig) ListOption {
+	return func(c *listConfig) {
+		c.outlierDetection = &outlierDetection
+	}
+}
+
 // New creates a new round robin peer list.
 func New(transport peer.Transport, opts ...ListOption) *List {
 	cfg := defaultListConfig
@@ -111,6 +123,9 @@ func New(transport peer.Transport, opts ...ListOption) *List {
 	if cfg.defaultChooseTimeout != nil {
 		plOpts = append(plOpts, abstractlist.DefaultChooseTimeout(*cfg.defaultChooseTimeout))
 	}
+	if cfg.outlierDetection != nil {
+		plOpts = append(plOpts, abstractlist.OutlierDetection(*cfg.outlierDetection))
+	}
 
 	return &List{
 		list: abstractlist.New(
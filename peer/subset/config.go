@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package subset
+
+import (
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Configuration describes how to construct a subset peer list.
+type Configuration struct {
+	// Size is the maximum number of peers in the subset.
+	Size int `config:"size"`
+	// ClientID identifies the caller instance, which determines its subset.
+	ClientID string `config:"clientID,interpolate"`
+	// List is the configuration of the peer list for the subset, without
+	// peers or a peer list updater.
+	List map[string]interface{} `config:"list"`
+}
+
+// Spec returns a configuration specification for the subset peer list,
+// making it possible to connect to a stable subset of peers with transports
+// that use outbound peer list configuration (like HTTP).
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerList(subset.Spec())
+//
+// This enables the subset peer list, which wraps any other registered peer
+// list:
+//
+//	outbounds:
+//	  otherservice:
+//	    unary:
+//	      http:
+//	        url: https://host:port/rpc
+//	        subset:
+//	          size: 25
+//	          clientID: ${HOSTNAME}
+//	          list:
+//	            round-robin:
+//	              capacity: 25
+//	          dns:
+//	            name: otherservice.example.com
+//
+// Other than a specific peer or peers list, use any peer list updater
+// registered with a yarpc Configurator.
+func Spec() yarpcconfig.PeerListSpec {
+	return SpecWithOptions()
+}
+
+// SpecWithOptions accepts additional list constructor options.
+func SpecWithOptions(options ...ListOption) yarpcconfig.PeerListSpec {
+	return yarpcconfig.PeerListSpec{
+		Name: "subset",
+		BuildPeerList: func(cfg Configuration, t peer.Transport, k *yarpcconfig.Kit) (peer.ChooserList, error) {
+			if cfg.Size <= 0 {
+				return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+					"size must be greater than 0. Got: %d.", cfg.Size)
+			}
+			if len(cfg.List) == 0 {
+				return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument, "list is required")
+			}
+
+			opts := make([]ListOption, 0, len(options)+1)
+
+			opts = append(opts, options...)
+
+			if cfg.ClientID != "" {
+				opts = append(opts, ClientID(cfg.ClientID))
+			}
+
+			list, err := k.BuildPeerList(t, cfg.List)
+			if err != nil {
+				return nil, err
+			}
+			return New(list, cfg.Size, opts...), nil
+		},
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package subset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	peerbind "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+type attrs map[string]interface{}
+
+func TestConfig(t *testing.T) {
+	cfg := yarpcconfig.New()
+	cfg.RegisterPeerList(Spec())
+	cfg.RegisterPeerList(roundrobin.Spec())
+	cfg.RegisterTransport(yarpctest.FakeTransportSpec())
+
+	load := func(list attrs) (yarpc.Config, error) {
+		return cfg.LoadConfig("our-service", attrs{
+			"outbounds": attrs{
+				"their-service": attrs{
+					"fake-transport": attrs{
+						"subset": list,
+					},
+				},
+			},
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		config, err := load(attrs{
+			"size":     2,
+			"clientID": "${CLIENT_ID:client}",
+			"list":     attrs{"round-robin": attrs{}},
+			"peers":    []string{"1.1.1.1:1111", "2.2.2.2:2222", "3.3.3.3:3333"},
+		})
+		require.NoError(t, err)
+
+		chooser := config.Outbounds["their-service"].Unary.(*yarpctest.FakeOutbound).Chooser().(*peerbind.BoundChooser)
+		require.NoError(t, chooser.Start())
+		defer func() { assert.NoError(t, chooser.Stop()) }()
+
+		list := chooser.ChooserList().(*List)
+		assert.Equal(t, "client", list.clientID)
+		assert.Len(t, list.Subset(), 2)
+		assert.Len(t, list.list.(*roundrobin.List).Peers(), 2)
+	})
+
+	tests := []struct {
+		msg     string
+		list    attrs
+		wantErr string
+	}{
+		{
+			msg:     "missing size",
+			list:    attrs{"list": attrs{"round-robin": attrs{}}, "peers": []string{"1.1.1.1:1111"}},
+			wantErr: "size must be greater than 0",
+		},
+		{
+			msg:     "missing list",
+			list:    attrs{"size": 1, "peers": []string{"1.1.1.1:1111"}},
+			wantErr: "list is required",
+		},
+		{
+			msg:     "invalid list",
+			list:    attrs{"size": 1, "list": attrs{"round-robin": attrs{"capacity": -1}}, "peers": []string{"1.1.1.1:1111"}},
+			wantErr: "Capacity must be greater than 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			_, err := load(tt.list)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
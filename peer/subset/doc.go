@@ -0,0 +1,33 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package subset provides a peer list that forwards a stable subset of its
+// peers to another peer list, so that callers of a service with thousands of
+// peers connect to a bounded number of them.
+//
+// The subset list uses rendezvous hashing: it ranks every peer by a hash of
+// the identifier of the caller instance and the identifier of the peer, and
+// forwards the highest ranked peers to the underlying list.
+// Callers with different identifiers select different subsets, spreading
+// load across all peers, while membership changes only change the subset by
+// the peers that joined or left it.
+// Adding a peer replaces at most one peer of the subset, and removing a peer
+// of the subset replaces it with the next ranked peer.
+package subset
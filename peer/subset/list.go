@@ -0,0 +1,239 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package subset
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgryski/go-farm"
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/x/introspection"
+)
+
+type listOptions struct {
+	clientID string
+}
+
+// ListOption customizes the behavior of a subset peer list.
+type ListOption interface {
+	apply(*listOptions)
+}
+
+type listOptionFunc func(*listOptions)
+
+func (f listOptionFunc) apply(options *listOptions) { f(options) }
+
+// ClientID specifies the identifier of the caller instance, which determines
+// the subset of peers it selects.
+// Callers should use an identifier that is unique to their instance and
+// stable across restarts, like a host name or a pod name, for their subsets
+// to remain stable across restarts.
+//
+// Defaults to a random identifier.
+func ClientID(clientID string) ListOption {
+	return listOptionFunc(func(options *listOptions) {
+		options.clientID = clientID
+	})
+}
+
+// New creates a peer list that forwards a subset of at most size of its
+// peers to the given peer list.
+func New(list peer.ChooserList, size int, opts ...ListOption) *List {
+	var options listOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	if options.clientID == "" {
+		options.clientID = fmt.Sprintf("%016x", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64())
+	}
+
+	return &List{
+		list:     list,
+		size:     size,
+		clientID: options.clientID,
+		members:  make(map[string]*member),
+		subset:   make(map[string]*member),
+	}
+}
+
+var (
+	_ peer.ChooserList                    = (*List)(nil)
+	_ introspection.IntrospectableChooser = (*List)(nil)
+)
+
+// List is a peer list that forwards a stable subset of its peers to another
+// peer list.
+type List struct {
+	list     peer.ChooserList
+	size     int
+	clientID string
+
+	// mu guards members and subset, and serializes updates to the underlying
+	// list.
+	mu      sync.Mutex
+	members map[string]*member
+	subset  map[string]*member
+}
+
+type member struct {
+	pid  peer.Identifier
+	rank uint64
+}
+
+// before returns whether m ranks before o.
+func (m *member) before(o *member) bool {
+	if m.rank != o.rank {
+		return m.rank > o.rank
+	}
+	return m.pid.Identifier() < o.pid.Identifier()
+}
+
+func (l *List) rank(pid peer.Identifier) uint64 {
+	return farm.Fingerprint64([]byte(l.clientID + "/" + pid.Identifier()))
+}
+
+// Start starts the underlying peer list.
+func (l *List) Start() error {
+	return l.list.Start()
+}
+
+// Stop stops the underlying peer list.
+func (l *List) Stop() error {
+	return l.list.Stop()
+}
+
+// IsRunning returns whether the underlying peer list is running.
+func (l *List) IsRunning() bool {
+	return l.list.IsRunning()
+}
+
+// Choose returns a peer of the subset from the underlying peer list.
+func (l *List) Choose(ctx context.Context, req *transport.Request) (peer.Peer, func(error), error) {
+	return l.list.Choose(ctx, req)
+}
+
+// Update adds and removes peers from the list, forwarding the changes to the
+// subset to the underlying peer list.
+//
+// Removing and adding the same peer in a single update forwards both to the
+// underlying list if the peer is in the subset, to update its identifier in
+// place.
+func (l *List) Update(updates peer.ListUpdates) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var errs error
+	for _, pid := range updates.Removals {
+		id := pid.Identifier()
+		if _, ok := l.members[id]; !ok {
+			errs = multierr.Append(errs, peer.ErrPeerRemoveNotInList(id))
+			continue
+		}
+		delete(l.members, id)
+	}
+	for _, pid := range updates.Additions {
+		id := pid.Identifier()
+		if _, ok := l.members[id]; ok {
+			errs = multierr.Append(errs, peer.ErrPeerAddAlreadyInList(id))
+			continue
+		}
+		l.members[id] = &member{pid: pid, rank: l.rank(pid)}
+	}
+
+	subset := l.selectSubset()
+
+	var forward peer.ListUpdates
+	for id, m := range l.subset {
+		if n, ok := subset[id]; !ok || n != m {
+			forward.Removals = append(forward.Removals, m.pid)
+		}
+	}
+	for id, m := range subset {
+		if n, ok := l.subset[id]; !ok || n != m {
+			forward.Additions = append(forward.Additions, m.pid)
+		}
+	}
+	l.subset = subset
+
+	if len(forward.Additions) > 0 || len(forward.Removals) > 0 {
+		sortIdentifiers(forward.Removals)
+		sortIdentifiers(forward.Additions)
+		errs = multierr.Append(errs, l.list.Update(forward))
+	}
+	return errs
+}
+
+// selectSubset returns the highest ranked members.
+//
+// selectSubset must be run with the list lock.
+func (l *List) selectSubset() map[string]*member {
+	ranked := make([]*member, 0, len(l.members))
+	for _, m := range l.members {
+		ranked = append(ranked, m)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].before(ranked[j])
+	})
+	if len(ranked) > l.size {
+		ranked = ranked[:l.size]
+	}
+
+	subset := make(map[string]*member, len(ranked))
+	for _, m := range ranked {
+		subset[m.pid.Identifier()] = m
+	}
+	return subset
+}
+
+// Subset returns the identifiers of the peers in the subset, in no particular
+// order.
+func (l *List) Subset() []peer.Identifier {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pids := make([]peer.Identifier, 0, len(l.subset))
+	for _, m := range l.subset {
+		pids = append(pids, m.pid)
+	}
+	return pids
+}
+
+// Introspect reveals information about the underlying list to the internal
+// YARPC introspection system.
+func (l *List) Introspect() introspection.ChooserStatus {
+	if ic, ok := l.list.(introspection.IntrospectableChooser); ok {
+		return ic.Introspect()
+	}
+	return introspection.ChooserStatus{Name: "subset"}
+}
+
+func sortIdentifiers(pids []peer.Identifier) {
+	sort.Slice(pids, func(i, j int) bool {
+		return pids[i].Identifier() < pids[j].Identifier()
+	})
+}
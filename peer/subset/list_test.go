@@ -0,0 +1,224 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package subset
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// recordingList records the updates it receives.
+type recordingList struct {
+	*yarpctest.FakePeerList
+
+	updates []peer.ListUpdates
+}
+
+func newRecordingList() *recordingList {
+	return &recordingList{FakePeerList: yarpctest.NewFakePeerList()}
+}
+
+func (l *recordingList) Update(updates peer.ListUpdates) error {
+	l.updates = append(l.updates, updates)
+	return nil
+}
+
+func (l *recordingList) last() peer.ListUpdates {
+	if len(l.updates) == 0 {
+		return peer.ListUpdates{}
+	}
+	return l.updates[len(l.updates)-1]
+}
+
+func identify(ids ...string) []peer.Identifier {
+	pids := make([]peer.Identifier, len(ids))
+	for i, id := range ids {
+		pids[i] = hostport.Identify(id)
+	}
+	return pids
+}
+
+func hosts(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("10.0.0.%d:80", i)
+	}
+	return ids
+}
+
+func subsetOf(l *List) []string {
+	var ids []string
+	for _, pid := range l.Subset() {
+		ids = append(ids, pid.Identifier())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSubset(t *testing.T) {
+	inner := newRecordingList()
+	l := New(inner, 3, ClientID("client"))
+
+	require.NoError(t, l.Update(peer.ListUpdates{Additions: identify(hosts(10)...)}))
+	subset := subsetOf(l)
+	require.Len(t, subset, 3)
+	require.Len(t, inner.updates, 1)
+	assert.Len(t, inner.last().Additions, 3)
+	assert.Empty(t, inner.last().Removals)
+
+	t.Run("deterministic", func(t *testing.T) {
+		other := New(newRecordingList(), 3, ClientID("client"))
+		ids := hosts(10)
+		for i := len(ids) - 1; i >= 0; i-- {
+			require.NoError(t, other.Update(peer.ListUpdates{Additions: identify(ids[i])}))
+		}
+		assert.Equal(t, subset, subsetOf(other), "subsets must not depend on the order of updates")
+
+		another := New(newRecordingList(), 3, ClientID("another client"))
+		require.NoError(t, another.Update(peer.ListUpdates{Additions: identify(hosts(10)...)}))
+		assert.NotEqual(t, subset, subsetOf(another), "callers must select different subsets")
+	})
+
+	t.Run("remove peer outside the subset", func(t *testing.T) {
+		for _, id := range hosts(10) {
+			if !contains(subset, id) {
+				n := len(inner.updates)
+				require.NoError(t, l.Update(peer.ListUpdates{Removals: identify(id)}))
+				assert.Len(t, inner.updates, n, "must not update the underlying list")
+				assert.Equal(t, subset, subsetOf(l))
+				require.NoError(t, l.Update(peer.ListUpdates{Additions: identify(id)}))
+				assert.Len(t, inner.updates, n, "must not update the underlying list")
+				return
+			}
+		}
+	})
+
+	t.Run("remove peer in the subset", func(t *testing.T) {
+		require.NoError(t, l.Update(peer.ListUpdates{Removals: identify(subset[0])}))
+		assert.Equal(t, identify(subset[0]), inner.last().Removals)
+		require.Len(t, inner.last().Additions, 1)
+		assert.NotContains(t, subset, inner.last().Additions[0].Identifier())
+
+		require.NoError(t, l.Update(peer.ListUpdates{Additions: identify(subset[0])}))
+		assert.Equal(t, identify(subset[0]), inner.last().Additions)
+		assert.Len(t, inner.last().Removals, 1)
+		assert.Equal(t, subset, subsetOf(l), "restores the original subset")
+	})
+
+	t.Run("replace peer in the subset", func(t *testing.T) {
+		weighted := hostport.IdentifyWeighted(subset[1], 5)
+		require.NoError(t, l.Update(peer.ListUpdates{
+			Removals:  identify(subset[1]),
+			Additions: []peer.Identifier{weighted},
+		}))
+		assert.Equal(t, peer.ListUpdates{
+			Removals:  identify(subset[1]),
+			Additions: []peer.Identifier{weighted},
+		}, inner.last())
+		assert.Equal(t, subset, subsetOf(l))
+	})
+
+	t.Run("invalid updates", func(t *testing.T) {
+		err := l.Update(peer.ListUpdates{
+			Removals:  identify("unknown:80"),
+			Additions: identify(subset[2]),
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `can't remove peer (unknown:80) because it is not in peerlist`)
+		assert.Contains(t, err.Error(), fmt.Sprintf(`can't add peer %q because is already in peerlist`, subset[2]))
+	})
+}
+
+func TestMinimalChanges(t *testing.T) {
+	inner := newRecordingList()
+	l := New(inner, 10, ClientID("client"))
+	require.NoError(t, l.Update(peer.ListUpdates{Additions: identify(hosts(100)...)}))
+
+	for i := 100; i < 150; i++ {
+		before := subsetOf(l)
+		require.NoError(t, l.Update(peer.ListUpdates{Additions: identify(fmt.Sprintf("10.0.0.%d:80", i))}))
+		after := subsetOf(l)
+		require.Len(t, after, 10)
+		assert.True(t, len(diff(before, after)) <= 1, "adding a peer must replace at most one peer of the subset")
+	}
+}
+
+func TestSpread(t *testing.T) {
+	ids := identify(hosts(100)...)
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		l := New(newRecordingList(), 10, ClientID(fmt.Sprintf("client-%d", i)))
+		require.NoError(t, l.Update(peer.ListUpdates{Additions: ids}))
+		for _, pid := range l.Subset() {
+			counts[pid.Identifier()]++
+		}
+	}
+
+	// Each peer is in 100 subsets on average.
+	require.Len(t, counts, 100)
+	for id, count := range counts {
+		assert.InDelta(t, 100, count, 50, "peer %q is in %d subsets", id, count)
+	}
+}
+
+func TestUnderlyingList(t *testing.T) {
+	trans := yarpctest.NewFakeTransport()
+	rr := roundrobin.New(trans)
+	l := New(rr, 2)
+
+	require.NoError(t, l.Start())
+	assert.True(t, l.IsRunning())
+	require.NoError(t, l.Update(peer.ListUpdates{Additions: identify(hosts(5)...)}))
+	assert.Len(t, rr.Peers(), 2)
+	assert.Equal(t, "round-robin", l.Introspect().Name)
+	require.NoError(t, l.Stop())
+	assert.False(t, l.IsRunning())
+
+	assert.Equal(t, "subset", New(newRecordingList(), 1).Introspect().Name)
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// diff returns the identifiers in after that are not in before.
+func diff(before, after []string) []string {
+	var added []string
+	for _, id := range after {
+		if !contains(before, id) {
+			added = append(added, id)
+		}
+	}
+	return added
+}
@@ -24,6 +24,7 @@ import (
 	"fmt"
 
 	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/abstractlist"
 	"go.uber.org/yarpc/yarpcconfig"
 	"go.uber.org/yarpc/yarpcerrors"
 )
@@ -32,6 +33,8 @@ import (
 type Configuration struct {
 	Capacity *int `config:"capacity"`
 	FailFast bool `config:"failFast"`
+	// OutlierDetection ejects peers that fail too many requests.
+	OutlierDetection *abstractlist.OutlierDetectionConfig `config:"outlierDetection"`
 }
 
 // Spec returns a configuration specification for the "fewest pending requests
@@ -78,6 +81,14 @@ func SpecWithOptions(options ...ListOption) yarpcconfig.PeerListSpec {
 				opts = append(opts, FailFast())
 			}
 
+			if cfg.OutlierDetection != nil {
+				if err := cfg.OutlierDetection.Validate(); err != nil {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"invalid outlierDetection: %v", err)
+				}
+				opts = append(opts, OutlierDetection(*cfg.OutlierDetection))
+			}
+
 			return New(t, opts...), nil
 		},
 	}
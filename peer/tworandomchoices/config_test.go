@@ -23,6 +23,7 @@ package tworandomchoices
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/yarpc/yarpcconfig"
 	"go.uber.org/yarpc/yarpctest"
@@ -53,3 +54,35 @@ func TestConfig(t *testing.T) {
 	require.NotNil(t, config.Outbounds["their-service"])
 	require.NotNil(t, config.Outbounds["their-service"].Unary)
 }
+
+func TestConfigOutlierDetection(t *testing.T) {
+	cfg := yarpcconfig.New()
+	cfg.RegisterPeerList(Spec())
+	cfg.RegisterTransport(yarpctest.FakeTransportSpec())
+
+	load := func(outlierDetection attrs) error {
+		_, err := cfg.LoadConfig("our-service", attrs{
+			"outbounds": attrs{
+				"their-service": attrs{
+					"fake-transport": attrs{
+						"two-random-choices": attrs{
+							"peers":            []string{"1.1.1.1:1111"},
+							"outlierDetection": outlierDetection,
+						},
+					},
+				},
+			},
+		})
+		return err
+	}
+
+	assert.NoError(t, load(attrs{
+		"consecutiveFailures": 5,
+		"failurePercentage":   50,
+		"baseEjectionTime":    "10s",
+	}))
+
+	err := load(attrs{"maxEjectionPercent": -1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid outlierDetection: maxEjectionPercent must be between 0 and 100")
+}
@@ -33,10 +33,11 @@ import (
 )
 
 type listOptions struct {
-	capacity int
-	source   rand.Source
-	failFast bool
-	logger   *zap.Logger
+	capacity         int
+	source           rand.Source
+	failFast         bool
+	logger           *zap.Logger
+	outlierDetection *abstractlist.OutlierDetectionConfig
 }
 
 var defaultListOptions = listOptions{
@@ -95,6 +96,17 @@ func Logger(logger *zap.Logger) ListOption {
 	})
 }
 
+// OutlierDetection ejects peers that fail too many requests from rotation
+// for an exponentially growing period.
+// See abstractlist.OutlierDetectionConfig for details.
+//
+// Defaults to disabled.
+func OutlierDetection(outlierDetection abstractlist.OutlierDetectionConfig) ListOption {
+	return listOptionFunc(func(options *listOptions) {
+		options.outlierDetection = &outlierDetection
+	})
+}
+
 // New creates a new fewest pending requests of two random peers peer list.
 func New(transport peer.Transport, opts ...ListOption) *List {
 	options := defaultListOptions
@@ -117,6 +129,9 @@ func New(transport peer.Transport, opts ...ListOption) *List {
 	if options.failFast {
 		plOpts = append(plOpts, abstractlist.FailFast())
 	}
+	if options.outlierDetection != nil {
+		plOpts = append(plOpts, abstractlist.OutlierDetection(*options.outlierDetection))
+	}
 
 	return &List{
 		list: abstractlist.New(
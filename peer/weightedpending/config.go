@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedpending
+
+import (
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/internal/weightedlist"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+// Configuration describes how to build a weighted fewest pending requests peer
+// list.
+// See Spec for its fields.
+type Configuration = weightedlist.Configuration
+
+// Spec returns a configuration specification for the weighted fewest pending
+// requests peer list implementation, making it possible to send traffic to the
+// peer with the fewest pending requests relative to its weight with
+// transports that use outbound peer list configuration (like HTTP).
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerList(weightedpending.Spec())
+//
+// This enables the weighted fewest pending requests peer list:
+//
+//	outbounds:
+//	  otherservice:
+//	    unary:
+//	      http:
+//	        url: https://host:port/rpc
+//	        weighted-fewest-pending-requests:
+//	          peers:
+//	            - 127.0.0.1:8080
+//	            - 127.0.0.1:8081
+//	          weights:
+//	            127.0.0.1:8080: 3
+//
+// Other than a specific peer or peers list, use any peer list updater
+// registered with a yarpc Configurator.
+// Updaters may provide weights with identifiers that implement
+// peer.WeightedIdentifier, which take precedence over configured weights.
+// The configuration allows for alternative initial allocation capacity and a
+// fail-fast option.
+// With fail-fast enabled, the peer list will return an error immediately if no
+// peers are available (connected) at the time the request is sent.
+// The default choose timeout enables calls without deadlines, ie streaming, to
+// choose peers without waiting indefinitely.
+// Outlier detection stops choosing peers that fail too many requests for a
+// while.
+//
+//	weighted-fewest-pending-requests:
+//	  peers:
+//	    - 127.0.0.1:8080
+//	  capacity: 1
+//	  failFast: true
+//	  defaultChooseTimeout: 1s
+//	  outlierDetection:
+//	    consecutiveFailures: 5
+//	    baseEjectionTime: 30s
+func Spec() yarpcconfig.PeerListSpec {
+	return SpecWithOptions()
+}
+
+// SpecWithOptions accepts additional list constructor options.
+func SpecWithOptions(options ...ListOption) yarpcconfig.PeerListSpec {
+	apply := func(cfg *weightedlist.Config) {
+		for _, o := range options {
+			o(cfg)
+		}
+	}
+	return weightedlist.Spec("weighted-fewest-pending-requests", apply, func(t peer.Transport, cfg weightedlist.Config) peer.ChooserList {
+		return newList(t, cfg)
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedpending
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+type attrs map[string]interface{}
+
+func TestConfig(t *testing.T) {
+	cfg := yarpcconfig.New()
+	cfg.RegisterPeerList(Spec())
+	cfg.RegisterTransport(yarpctest.FakeTransportSpec())
+
+	load := func(list attrs) error {
+		_, err := cfg.LoadConfig("our-service", attrs{
+			"outbounds": attrs{
+				"their-service": attrs{
+					"fake-transport": attrs{
+						"weighted-fewest-pending-requests": list,
+					},
+				},
+			},
+		})
+		return err
+	}
+
+	assert.NoError(t, load(attrs{
+		"peers":    []string{"1.1.1.1:1111", "2.2.2.2:2222"},
+		"capacity": 5,
+		"failFast": true,
+		"weights":  attrs{"1.1.1.1:1111": 3},
+	}))
+
+	err := load(attrs{
+		"peers":   []string{"1.1.1.1:1111"},
+		"weights": attrs{"1.1.1.1:1111": 0},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `weight of peer "1.1.1.1:1111" must be greater than 0`)
+
+	err = load(attrs{
+		"peers":    []string{"1.1.1.1:1111"},
+		"capacity": 0,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Capacity must be greater than 0")
+
+	err = load(attrs{
+		"peers":            []string{"1.1.1.1:1111"},
+		"outlierDetection": attrs{"failurePercentage": 101},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid outlierDetection")
+}
+
+func TestConfigWeights(t *testing.T) {
+	build := Spec().BuildPeerList.(func(Configuration, peer.Transport, *yarpcconfig.Kit) (peer.ChooserList, error))
+
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	pl, err := build(Configuration{Weights: map[string]int{"1.1.1.1:1111": 3}}, trans, nil)
+	require.NoError(t, err)
+
+	l := pl.(*List)
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{
+			hostport.Identify("1.1.1.1:1111"),
+			hostport.Identify("2.2.2.2:2222"),
+		},
+	}))
+	require.NoError(t, l.Start())
+	defer func() { assert.NoError(t, l.Stop()) }()
+	trans.Flush()
+
+	reqs := make(pending)
+	defer reqs.finish()
+	assert.Equal(t, map[string]int{"1.1.1.1:1111": 30, "2.2.2.2:2222": 10}, reqs.choose(t, l, 40))
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedpending
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// pending tracks requests chosen from a list that have not yet finished.
+type pending map[string][]func(error)
+
+// choose chooses n peers from the list without finishing their requests,
+// returning how many times each peer was chosen.
+func (p pending) choose(t *testing.T, l *List, n int) map[string]int {
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		chosen, onFinish, err := l.Choose(ctx, &transport.Request{})
+		require.NoError(t, err)
+		counts[chosen.Identifier()]++
+		p[chosen.Identifier()] = append(p[chosen.Identifier()], onFinish)
+	}
+	return counts
+}
+
+// finish finishes the pending requests of the given peers, or all pending
+// requests if no peers are given.
+func (p pending) finish(ids ...string) {
+	if len(ids) == 0 {
+		for id := range p {
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range ids {
+		for _, onFinish := range p[id] {
+			onFinish(nil)
+		}
+		delete(p, id)
+	}
+}
+
+func TestWeightedPendingList(t *testing.T) {
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	l := New(trans, Seed(0), Weights(map[string]int{"b": 3, "c": 5}))
+
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{
+			hostport.Identify("a"),
+			hostport.Identify("b"),
+			// Weights from identifiers take precedence over configured weights.
+			hostport.IdentifyWeighted("c", 2),
+		},
+	}))
+	require.NoError(t, l.Start())
+	defer func() { assert.NoError(t, l.Stop()) }()
+	trans.Flush()
+
+	// Pending requests accumulate in proportion to weights.
+	reqs := make(pending)
+	defer reqs.finish()
+	assert.Equal(t, map[string]int{"a": 2, "b": 6, "c": 4}, reqs.choose(t, l, 12))
+
+	t.Run("busy peer", func(t *testing.T) {
+		// With requests to a and c finished, new requests go to those peers
+		// until they are as loaded as b, relative to their weights.
+		reqs.finish("a", "c")
+		assert.Equal(t, map[string]int{"a": 2, "c": 4}, reqs.choose(t, l, 6))
+		assert.Equal(t, map[string]int{"a": 1, "b": 3, "c": 2}, reqs.choose(t, l, 6))
+	})
+
+	t.Run("update weight", func(t *testing.T) {
+		reqs.finish()
+		require.NoError(t, l.Update(peer.ListUpdates{
+			Removals:  []peer.Identifier{hostport.Identify("a")},
+			Additions: []peer.Identifier{hostport.IdentifyWeighted("a", 5)},
+		}))
+		assert.Len(t, l.Peers(), 3)
+		assert.Equal(t, map[string]int{"a": 5, "b": 3, "c": 2}, reqs.choose(t, l, 10))
+	})
+}
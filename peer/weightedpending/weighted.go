@@ -0,0 +1,145 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedpending
+
+import (
+	"sync"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/abstractlist"
+)
+
+// weightedPending chooses the peer with the lowest ratio of pending requests
+// to weight.
+// Among equally loaded peers, it falls back to smooth weighted round-robin:
+// every choice credits each of those peers with its weight and chooses the
+// one with the most credit, which then pays their total weight.
+type weightedPending struct {
+	lock sync.Mutex
+
+	nodes []*node
+}
+
+type node struct {
+	list    *weightedPending
+	peer    peer.StatusPeer
+	weight  int
+	pending int
+	current int
+	index   int
+}
+
+// UpdatePendingRequestCount satisfies abstractlist.Subscriber.
+func (n *node) UpdatePendingRequestCount(pending int) {
+	n.list.lock.Lock()
+	n.pending = pending
+	n.list.lock.Unlock()
+}
+
+// compare returns a negative number if n has fewer pending requests relative
+// to its weight than o, a positive number if it has more, and zero if they
+// are equally loaded.
+func (n *node) compare(o *node) int {
+	return n.pending*o.weight - o.pending*n.weight
+}
+
+var _ abstractlist.Implementation = (*weightedPending)(nil)
+
+// Option configures the peer list implementation constructor.
+type Option interface {
+	apply(*options)
+}
+
+type options struct{}
+
+// NewImplementation creates a new weighted fewest pending requests
+// abstractlist.Implementation.
+//
+// Use this constructor instead of New, when wanting to do custom peer
+// connection management.
+func NewImplementation(opts ...Option) abstractlist.Implementation {
+	return newWeightedPending(10)
+}
+
+func newWeightedPending(capacity int) *weightedPending {
+	return &weightedPending{nodes: make([]*node, 0, capacity)}
+}
+
+func (w *weightedPending) Add(p peer.StatusPeer, pid peer.Identifier) abstractlist.Subscriber {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	n := &node{
+		list:   w,
+		peer:   p,
+		weight: peer.WeightOf(pid),
+		index:  len(w.nodes),
+	}
+	w.nodes = append(w.nodes, n)
+	return n
+}
+
+func (w *weightedPending) Remove(_ peer.StatusPeer, _ peer.Identifier, s abstractlist.Subscriber) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	n, ok := s.(*node)
+	if !ok || n.index >= len(w.nodes) || w.nodes[n.index] != n {
+		return
+	}
+
+	last := len(w.nodes) - 1
+	w.nodes[n.index] = w.nodes[last]
+	w.nodes[n.index].index = n.index
+	w.nodes[last] = nil
+	w.nodes = w.nodes[:last]
+}
+
+func (w *weightedPending) Choose(_ *transport.Request) peer.StatusPeer {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var least *node
+	for _, n := range w.nodes {
+		if least == nil || n.compare(least) < 0 {
+			least = n
+		}
+	}
+	if least == nil {
+		return nil
+	}
+
+	var best *node
+	total := 0
+	for _, n := range w.nodes {
+		if n.compare(least) != 0 {
+			continue
+		}
+		n.current += n.weight
+		total += n.weight
+		if best == nil || n.current > best.current {
+			best = n
+		}
+	}
+	best.current -= total
+	return best.peer
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedpending
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+func chooseN(w *weightedPending, n int) string {
+	var chosen []string
+	for i := 0; i < n; i++ {
+		chosen = append(chosen, w.Choose(nil).Identifier())
+	}
+	return strings.Join(chosen, "")
+}
+
+func TestWeightedPending(t *testing.T) {
+	trans := yarpctest.NewFakeTransport()
+	add := func(w *weightedPending, id string, weight int) *node {
+		pid := hostport.IdentifyWeighted(id, weight)
+		return w.Add(trans.Peer(pid), pid).(*node)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Nil(t, newWeightedPending(0).Choose(nil))
+	})
+
+	t.Run("idle peers", func(t *testing.T) {
+		w := newWeightedPending(0)
+		add(w, "a", 5)
+		add(w, "b", 1)
+		add(w, "c", 1)
+		assert.Equal(t, "aabacaa", chooseN(w, 7))
+	})
+
+	t.Run("fewest pending relative to weight", func(t *testing.T) {
+		w := newWeightedPending(0)
+		a := add(w, "a", 4)
+		b := add(w, "b", 1)
+
+		a.UpdatePendingRequestCount(3)
+		b.UpdatePendingRequestCount(1)
+		assert.Equal(t, "aaaa", chooseN(w, 4), "3/4 is less than 1/1")
+
+		a.UpdatePendingRequestCount(5)
+		assert.Equal(t, "bb", chooseN(w, 2), "1/1 is less than 5/4")
+	})
+
+	t.Run("equally loaded peers", func(t *testing.T) {
+		w := newWeightedPending(0)
+		a := add(w, "a", 2)
+		b := add(w, "b", 1)
+		c := add(w, "c", 1)
+
+		a.UpdatePendingRequestCount(2)
+		b.UpdatePendingRequestCount(1)
+		c.UpdatePendingRequestCount(3)
+		assert.Equal(t, "abaaba", chooseN(w, 6))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		w := newWeightedPending(0)
+		a := add(w, "a", 1)
+		add(w, "b", 1)
+
+		w.Remove(nil, nil, a)
+		w.Remove(nil, nil, a)
+		assert.Equal(t, "bb", chooseN(w, 2))
+	})
+}
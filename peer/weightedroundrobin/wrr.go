@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedroundrobin
+
+import (
+	"sync"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/abstractlist"
+)
+
+// wrr implements smooth weighted round-robin: every choice credits each peer
+// with its weight and chooses the peer with the most credit, which then pays
+// the total weight of all peers.
+// Over any window of total weight choices, each peer is chosen exactly as
+// many times as its weight.
+type wrr struct {
+	lock sync.Mutex
+
+	nodes []*node
+	total int
+}
+
+type node struct {
+	peer    peer.StatusPeer
+	weight  int
+	current int
+	index   int
+}
+
+// UpdatePendingRequestCount satisfies abstractlist.Subscriber.
+// Weighted round robin does not consider pending requests.
+func (n *node) UpdatePendingRequestCount(int) {}
+
+var _ abstractlist.Implementation = (*wrr)(nil)
+
+// Option configures the peer list implementation constructor.
+type Option interface {
+	apply(*options)
+}
+
+type options struct{}
+
+// NewImplementation creates a new weighted round-robin
+// abstractlist.Implementation.
+//
+// Use this constructor instead of New, when wanting to do custom peer
+// connection management.
+func NewImplementation(opts ...Option) abstractlist.Implementation {
+	return newWRR(10)
+}
+
+func newWRR(capacity int) *wrr {
+	return &wrr{nodes: make([]*node, 0, capacity)}
+}
+
+func (w *wrr) Add(p peer.StatusPeer, pid peer.Identifier) abstractlist.Subscriber {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	n := &node{
+		peer:   p,
+		weight: peer.WeightOf(pid),
+		index:  len(w.nodes),
+	}
+	w.nodes = append(w.nodes, n)
+	w.total += n.weight
+	return n
+}
+
+func (w *wrr) Remove(_ peer.StatusPeer, _ peer.Identifier, s abstractlist.Subscriber) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	n, ok := s.(*node)
+	if !ok || n.index >= len(w.nodes) || w.nodes[n.index] != n {
+		return
+	}
+
+	last := len(w.nodes) - 1
+	w.nodes[n.index] = w.nodes[last]
+	w.nodes[n.index].index = n.index
+	w.nodes[last] = nil
+	w.nodes = w.nodes[:last]
+	w.total -= n.weight
+}
+
+func (w *wrr) Choose(_ *transport.Request) peer.StatusPeer {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var best *node
+	for _, n := range w.nodes {
+		n.current += n.weight
+		if best == nil || n.current > best.current {
+			best = n
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.current -= w.total
+	return best.peer
+}
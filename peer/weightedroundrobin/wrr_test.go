@@ -0,0 +1,101 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package weightedroundrobin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/peer/abstractlist"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+func chooseN(w *wrr, n int) string {
+	var chosen []string
+	for i := 0; i < n; i++ {
+		chosen = append(chosen, w.Choose(nil).Identifier())
+	}
+	return strings.Join(chosen, "")
+}
+
+func TestWRR(t *testing.T) {
+	trans := yarpctest.NewFakeTransport()
+	add := func(w *wrr, id string, weight int) abstractlist.Subscriber {
+		pid := hostport.IdentifyWeighted(id, weight)
+		return w.Add(trans.Peer(pid), pid)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		assert.Nil(t, newWRR(0).Choose(nil))
+	})
+
+	t.Run("smooth", func(t *testing.T) {
+		w := newWRR(0)
+		add(w, "a", 5)
+		add(w, "b", 1)
+		add(w, "c", 1)
+		assert.Equal(t, "aabacaa"+"aabacaa", chooseN(w, 14))
+	})
+
+	t.Run("unweighted", func(t *testing.T) {
+		w := newWRR(0)
+		for _, id := range []string{"a", "b", "c"} {
+			pid := hostport.Identify(id)
+			w.Add(trans.Peer(pid), pid)
+		}
+		assert.Equal(t, "abcabc", chooseN(w, 6))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		w := newWRR(0)
+		a := add(w, "a", 2)
+		add(w, "b", 1)
+		c := add(w, "c", 1)
+		assert.Equal(t, "abca", chooseN(w, 4))
+
+		w.Remove(nil, nil, a)
+		assert.Equal(t, 2, w.total)
+		assert.Equal(t, "cbcb", chooseN(w, 4))
+
+		// Removing a peer twice has no effect.
+		w.Remove(nil, nil, a)
+		w.Remove(nil, nil, c)
+		assert.Equal(t, 1, w.total)
+		assert.Equal(t, "bb", chooseN(w, 2))
+	})
+
+	t.Run("proportions", func(t *testing.T) {
+		w := newWRR(0)
+		weights := map[string]int{"a": 7, "b": 3, "c": 2, "d": 1}
+		for id, weight := range weights {
+			add(w, id, weight)
+		}
+		counts := make(map[string]int)
+		for _, c := range chooseN(w, 13*10) {
+			counts[string(c)]++
+		}
+		for id, weight := range weights {
+			assert.Equal(t, weight*10, counts[id], "peer %q", id)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zoneaware
+
+import (
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Configuration describes how to construct a zone-aware peer list.
+type Configuration struct {
+	// Zone is the zone of the caller.
+	Zone string `config:"zone,interpolate"`
+	// Zones assigns zones to peers by address.
+	Zones map[string]string `config:"zones"`
+	// MinLocalAvailable is the fraction of local peers that must be available
+	// to send all requests to local peers.
+	MinLocalAvailable *float64 `config:"minLocalAvailable"`
+	// List is the configuration of the peer list for local and remote peers,
+	// without peers or a peer list updater.
+	List map[string]interface{} `config:"list"`
+}
+
+// Spec returns a configuration specification for the zone-aware peer list,
+// making it possible to prefer peers in the same zone as the caller with
+// transports that use outbound peer list configuration (like HTTP).
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterPeerList(zoneaware.Spec())
+//
+// This enables the zone-aware peer list, which wraps any other registered
+// peer list:
+//
+//	outbounds:
+//	  otherservice:
+//	    unary:
+//	      http:
+//	        url: https://host:port/rpc
+//	        zone-aware:
+//	          zone: ${ZONE:us-east-1a}
+//	          minLocalAvailable: 0.5
+//	          zones:
+//	            10.0.0.1:8080: us-east-1a
+//	            10.0.1.1:8080: us-east-1b
+//	          list:
+//	            round-robin:
+//	              capacity: 10
+//	          peers:
+//	            - 10.0.0.1:8080
+//	            - 10.0.1.1:8080
+//
+// Other than a specific peer or peers list, use any peer list updater
+// registered with a yarpc Configurator.
+// Updaters may provide zones with identifiers that implement ZonedIdentifier,
+// which take precedence over configured zones.
+func Spec() yarpcconfig.PeerListSpec {
+	return SpecWithOptions()
+}
+
+// SpecWithOptions accepts additional list constructor options.
+func SpecWithOptions(options ...ListOption) yarpcconfig.PeerListSpec {
+	return yarpcconfig.PeerListSpec{
+		Name: "zone-aware",
+		BuildPeerList: func(cfg Configuration, t peer.Transport, k *yarpcconfig.Kit) (peer.ChooserList, error) {
+			if cfg.Zone == "" {
+				return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument, "zone is required")
+			}
+			if len(cfg.List) == 0 {
+				return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument, "list is required")
+			}
+
+			opts := make([]ListOption, 0, len(options)+2)
+
+			opts = append(opts, options...)
+
+			if len(cfg.Zones) > 0 {
+				opts = append(opts, Zones(cfg.Zones))
+			}
+			if cfg.MinLocalAvailable != nil {
+				if *cfg.MinLocalAvailable <= 0 || *cfg.MinLocalAvailable > 1 {
+					return nil, yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+						"minLocalAvailable must be greater than 0 and at most 1. Got: %v.", *cfg.MinLocalAvailable)
+				}
+				opts = append(opts, MinLocalAvailable(*cfg.MinLocalAvailable))
+			}
+
+			var err error
+			list := New(cfg.Zone, t, func(t peer.Transport) peer.ChooserList {
+				pl, buildErr := k.BuildPeerList(t, cfg.List)
+				err = multierr.Append(err, buildErr)
+				return pl
+			}, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return list, nil
+		},
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zoneaware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	peerbind "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+type attrs map[string]interface{}
+
+func TestConfig(t *testing.T) {
+	cfg := yarpcconfig.New()
+	cfg.RegisterPeerList(Spec())
+	cfg.RegisterPeerList(roundrobin.Spec())
+	cfg.RegisterTransport(yarpctest.FakeTransportSpec())
+
+	load := func(list attrs) error {
+		_, err := cfg.LoadConfig("our-service", attrs{
+			"outbounds": attrs{
+				"their-service": attrs{
+					"fake-transport": attrs{
+						"zone-aware": list,
+					},
+				},
+			},
+		})
+		return err
+	}
+
+	tests := []struct {
+		msg     string
+		list    attrs
+		wantErr string
+	}{
+		{
+			msg: "valid",
+			list: attrs{
+				"zone":              "a",
+				"minLocalAvailable": 0.3,
+				"zones":             attrs{"1.1.1.1:1111": "a"},
+				"list":              attrs{"round-robin": attrs{"capacity": 5}},
+				"peers":             []string{"1.1.1.1:1111", "2.2.2.2:2222"},
+			},
+		},
+		{
+			msg: "missing zone",
+			list: attrs{
+				"list":  attrs{"round-robin": attrs{}},
+				"peers": []string{"1.1.1.1:1111"},
+			},
+			wantErr: "zone is required",
+		},
+		{
+			msg: "missing list",
+			list: attrs{
+				"zone":  "a",
+				"peers": []string{"1.1.1.1:1111"},
+			},
+			wantErr: "list is required",
+		},
+		{
+			msg: "unknown list",
+			list: attrs{
+				"zone":  "a",
+				"list":  attrs{"least-recently-used": attrs{}},
+				"peers": []string{"1.1.1.1:1111"},
+			},
+			wantErr: `no recognized peer list or chooser "least-recently-used"`,
+		},
+		{
+			msg: "invalid list",
+			list: attrs{
+				"zone":  "a",
+				"list":  attrs{"round-robin": attrs{"capacity": 0}},
+				"peers": []string{"1.1.1.1:1111"},
+			},
+			wantErr: "Capacity must be greater than 0",
+		},
+		{
+			msg: "invalid threshold",
+			list: attrs{
+				"zone":              "a",
+				"minLocalAvailable": 1.5,
+				"list":              attrs{"round-robin": attrs{}},
+				"peers":             []string{"1.1.1.1:1111"},
+			},
+			wantErr: "minLocalAvailable must be greater than 0 and at most 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			err := load(tt.list)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestConfigZones(t *testing.T) {
+	cfg := yarpcconfig.New()
+	cfg.RegisterPeerList(Spec())
+	cfg.RegisterPeerList(roundrobin.Spec())
+	cfg.RegisterTransport(yarpctest.FakeTransportSpec())
+
+	config, err := cfg.LoadConfig("our-service", attrs{
+		"outbounds": attrs{
+			"their-service": attrs{
+				"fake-transport": attrs{
+					"zone-aware": attrs{
+						"zone":  "${ZONE:a}",
+						"zones": attrs{"1.1.1.1:1111": "a"},
+						"list":  attrs{"round-robin": attrs{}},
+						"peers": []string{"1.1.1.1:1111", "2.2.2.2:2222"},
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	chooser := config.Outbounds["their-service"].Unary.(*yarpctest.FakeOutbound).Chooser().(*peerbind.BoundChooser)
+	require.NoError(t, chooser.Start())
+	defer func() { assert.NoError(t, chooser.Stop()) }()
+
+	list := chooser.ChooserList().(*List)
+	assert.Equal(t, "a", list.zone)
+	assert.Equal(t, 1, list.local.members)
+	assert.Equal(t, 1, list.remote.members)
+	assert.IsType(t, &roundrobin.List{}, list.local.list)
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package zoneaware provides a peer list that prefers peers in the same zone
+// as the caller, spilling traffic over to peers in other zones when too few
+// local peers are available.
+//
+// The zone-aware list wraps two peer lists of any kind, one for local peers
+// and one for remote peers, and forwards each peer to one of them by its
+// zone.
+// The zone of a peer comes from its identifier, if it implements
+// ZonedIdentifier, or from a ZoneResolver or the Zones option.
+// Peers of unknown zones are remote.
+//
+// While the fraction of available local peers is at or above the
+// MinLocalAvailable threshold, all requests go to local peers.
+// Below the threshold, the list sends a proportional share of requests to
+// remote peers, and sends all requests to remote peers when no local peer is
+// available.
+package zoneaware
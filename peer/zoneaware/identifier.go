@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zoneaware
+
+import "go.uber.org/yarpc/api/peer"
+
+// ZonedIdentifier is a peer identifier that carries the zone of the peer.
+type ZonedIdentifier interface {
+	peer.Identifier
+
+	// Zone returns the zone of the peer, or an empty string if unknown.
+	Zone() string
+}
+
+// ZoneResolver returns the zone of a peer, or an empty string if unknown.
+type ZoneResolver func(peer.Identifier) string
+
+// IdentifyZone attaches a zone to a peer identifier.
+// The zoned identifier keeps the weight of the original identifier.
+func IdentifyZone(pid peer.Identifier, zone string) ZonedIdentifier {
+	return zonedIdentifier{pid: pid, zone: zone}
+}
+
+type zonedIdentifier struct {
+	pid  peer.Identifier
+	zone string
+}
+
+var _ peer.WeightedIdentifier = zonedIdentifier{}
+
+func (z zonedIdentifier) Identifier() string {
+	return z.pid.Identifier()
+}
+
+func (z zonedIdentifier) Zone() string {
+	return z.zone
+}
+
+func (z zonedIdentifier) Weight() int {
+	return peer.WeightOf(z.pid)
+}
@@ -0,0 +1,267 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zoneaware
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+)
+
+type listOptions struct {
+	resolver          ZoneResolver
+	zones             map[string]string
+	minLocalAvailable float64
+	source            rand.Source
+}
+
+var defaultListOptions = listOptions{
+	minLocalAvailable: 0.5,
+}
+
+// ListOption customizes the behavior of a zone-aware peer list.
+type ListOption interface {
+	apply(*listOptions)
+}
+
+type listOptionFunc func(*listOptions)
+
+func (f listOptionFunc) apply(options *listOptions) { f(options) }
+
+// Resolver specifies a function that returns the zone of peers whose
+// identifiers do not implement ZonedIdentifier.
+func Resolver(resolver ZoneResolver) ListOption {
+	return listOptionFunc(func(options *listOptions) {
+		options.resolver = resolver
+	})
+}
+
+// Zones assigns zones to peers by identifier, for peers whose identifiers do
+// not implement ZonedIdentifier and whose zone the Resolver does not know.
+func Zones(zones map[string]string) ListOption {
+	return listOptionFunc(func(options *listOptions) {
+		options.zones = zones
+	})
+}
+
+// MinLocalAvailable specifies the fraction of local peers that must be
+// available for the list to send all requests to local peers.
+// Below this fraction, the list spills over a proportional share of requests
+// to remote peers.
+//
+// Defaults to 0.5.
+func MinLocalAvailable(fraction float64) ListOption {
+	return listOptionFunc(func(options *listOptions) {
+		options.minLocalAvailable = fraction
+	})
+}
+
+// Seed specifies the seed for choosing whether to spill requests over to
+// remote peers.
+func Seed(seed int64) ListOption {
+	return listOptionFunc(func(options *listOptions) {
+		options.source = rand.NewSource(seed)
+	})
+}
+
+// group is the peer list for either local or remote peers.
+type group struct {
+	list peer.ChooserList
+
+	// The following are guarded by the list lock.
+	members     int
+	available   int
+	subscribers map[subscriberKey]*subscriber
+}
+
+// New creates a zone-aware peer list for a caller in the given zone.
+//
+// The newList function builds the underlying peer lists for local and remote
+// peers with the given transport, like
+//
+//	zoneaware.New("us-east-1a", transport, func(t peer.Transport) peer.ChooserList {
+//		return roundrobin.New(t)
+//	})
+func New(zone string, transport peer.Transport, newList func(peer.Transport) peer.ChooserList, opts ...ListOption) *List {
+	options := defaultListOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	if options.source == nil {
+		options.source = rand.NewSource(time.Now().UnixNano())
+	}
+
+	l := &List{
+		zone:    zone,
+		opts:    options,
+		random:  rand.New(options.source),
+		members: make(map[string]*group),
+	}
+	l.local = l.newGroup(transport, newList)
+	l.remote = l.newGroup(transport, newList)
+	return l
+}
+
+func (l *List) newGroup(transport peer.Transport, newList func(peer.Transport) peer.ChooserList) *group {
+	g := &group{subscribers: make(map[subscriberKey]*subscriber)}
+	g.list = newList(&groupTransport{Transport: transport, list: l, group: g})
+	return g
+}
+
+var _ peer.ChooserList = (*List)(nil)
+
+// List is a peer list that prefers peers in the local zone, spilling over to
+// peers in other zones when too few local peers are available.
+type List struct {
+	zone          string
+	opts          listOptions
+	local, remote *group
+
+	// updateMu serializes updates. members maps the identifier of every peer
+	// in the list to its group, and is guarded by updateMu.
+	updateMu sync.Mutex
+	members  map[string]*group
+
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+// zoneOf returns the zone of the given peer.
+func (l *List) zoneOf(pid peer.Identifier) string {
+	if z, ok := pid.(ZonedIdentifier); ok {
+		return z.Zone()
+	}
+	if l.opts.resolver != nil {
+		if zone := l.opts.resolver(pid); zone != "" {
+			return zone
+		}
+	}
+	return l.opts.zones[pid.Identifier()]
+}
+
+// Start starts the local and remote peer lists.
+func (l *List) Start() error {
+	return multierr.Combine(l.local.list.Start(), l.remote.list.Start())
+}
+
+// Stop stops the local and remote peer lists.
+func (l *List) Stop() error {
+	return multierr.Combine(l.local.list.Stop(), l.remote.list.Stop())
+}
+
+// IsRunning returns whether both the local and remote peer lists are running.
+func (l *List) IsRunning() bool {
+	return l.local.list.IsRunning() && l.remote.list.IsRunning()
+}
+
+// Update forwards the added and removed peers to the local or remote peer
+// list by their zone.
+//
+// Removing and adding the same peer in a single update forwards both to the
+// peer list of its new zone, removing it from the peer list of its old zone
+// if the zone changed.
+func (l *List) Update(updates peer.ListUpdates) error {
+	l.updateMu.Lock()
+	defer l.updateMu.Unlock()
+
+	var (
+		errs          error
+		local, remote peer.ListUpdates
+	)
+	forGroup := func(g *group) *peer.ListUpdates {
+		if g == l.local {
+			return &local
+		}
+		return &remote
+	}
+
+	l.mu.Lock()
+	for _, pid := range updates.Removals {
+		g, ok := l.members[pid.Identifier()]
+		if !ok {
+			errs = multierr.Append(errs, peer.ErrPeerRemoveNotInList(pid.Identifier()))
+			continue
+		}
+		delete(l.members, pid.Identifier())
+		g.members--
+		u := forGroup(g)
+		u.Removals = append(u.Removals, pid)
+	}
+	for _, pid := range updates.Additions {
+		if _, ok := l.members[pid.Identifier()]; ok {
+			errs = multierr.Append(errs, peer.ErrPeerAddAlreadyInList(pid.Identifier()))
+			continue
+		}
+		g := l.remote
+		if l.zoneOf(pid) == l.zone {
+			g = l.local
+		}
+		l.members[pid.Identifier()] = g
+		g.members++
+		u := forGroup(g)
+		u.Additions = append(u.Additions, pid)
+	}
+	l.mu.Unlock()
+
+	if len(local.Additions) > 0 || len(local.Removals) > 0 {
+		errs = multierr.Append(errs, l.local.list.Update(local))
+	}
+	if len(remote.Additions) > 0 || len(remote.Removals) > 0 {
+		errs = multierr.Append(errs, l.remote.list.Update(remote))
+	}
+	return errs
+}
+
+// Choose returns a peer from the local peer list, or from the remote peer
+// list to spill over requests while too few local peers are available.
+func (l *List) Choose(ctx context.Context, req *transport.Request) (peer.Peer, func(error), error) {
+	return l.choose().Choose(ctx, req)
+}
+
+func (l *List) choose() peer.ChooserList {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	local, remote := l.local, l.remote
+	switch {
+	case local.available == 0 && remote.available == 0:
+		// Wait for a peer to become available, locally if possible.
+		if local.members > 0 || remote.members == 0 {
+			return local.list
+		}
+		return remote.list
+	case remote.available == 0:
+		return local.list
+	case local.available == 0:
+		return remote.list
+	}
+
+	healthy := float64(local.available) / float64(local.members)
+	if healthy >= l.opts.minLocalAvailable || l.random.Float64() < healthy/l.opts.minLocalAvailable {
+		return local.list
+	}
+	return remote.list
+}
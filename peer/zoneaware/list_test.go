@@ -0,0 +1,189 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zoneaware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+func newRoundRobin(t peer.Transport) peer.ChooserList {
+	return roundrobin.New(t)
+}
+
+// countChoices chooses n peers from the list and counts how many times each
+// peer was chosen.
+func countChoices(t *testing.T, l *List, n int) map[string]int {
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		p, onFinish, err := l.Choose(ctx, &transport.Request{})
+		require.NoError(t, err)
+		counts[p.Identifier()]++
+		onFinish(nil)
+	}
+	return counts
+}
+
+func TestZoneAwareList(t *testing.T) {
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	l := New("a", trans, newRoundRobin, Seed(0), Zones(map[string]string{
+		"a1": "a",
+		"a2": "a",
+		"a3": "a",
+		"a4": "a",
+		"b1": "b",
+	}))
+
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{
+			hostport.Identify("a1"),
+			hostport.Identify("a2"),
+			hostport.Identify("a3"),
+			hostport.Identify("a4"),
+			hostport.Identify("b1"),
+			// Zones from identifiers take precedence over configured zones.
+			IdentifyZone(hostport.Identify("b2"), "b"),
+		},
+	}))
+	require.NoError(t, l.Start())
+	defer func() { assert.NoError(t, l.Stop()) }()
+	assert.True(t, l.IsRunning())
+	trans.Flush()
+
+	t.Run("local peers available", func(t *testing.T) {
+		assert.Equal(t, map[string]int{"a1": 10, "a2": 10, "a3": 10, "a4": 10}, countChoices(t, l, 40))
+	})
+
+	t.Run("at threshold", func(t *testing.T) {
+		trans.SimulateDisconnect(hostport.Identify("a1"))
+		trans.SimulateDisconnect(hostport.Identify("a2"))
+		counts := countChoices(t, l, 40)
+		assert.Equal(t, map[string]int{"a3": 20, "a4": 20}, counts)
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		trans.SimulateDisconnect(hostport.Identify("a3"))
+
+		// With a quarter of the local peers available, half of the requests
+		// spill over to remote peers.
+		counts := countChoices(t, l, 1000)
+		assert.InDelta(t, 500, counts["a4"], 50, "local requests: %v", counts)
+		assert.InDelta(t, 500, counts["b1"]+counts["b2"], 50, "remote requests: %v", counts)
+	})
+
+	t.Run("no local peers available", func(t *testing.T) {
+		trans.SimulateDisconnect(hostport.Identify("a4"))
+		assert.Equal(t, map[string]int{"b1": 10, "b2": 10}, countChoices(t, l, 20))
+	})
+
+	t.Run("no remote peers available", func(t *testing.T) {
+		trans.SimulateConnect(hostport.Identify("a1"))
+		trans.SimulateDisconnect(hostport.Identify("b1"))
+		trans.SimulateDisconnect(hostport.Identify("b2"))
+		assert.Equal(t, map[string]int{"a1": 20}, countChoices(t, l, 20))
+	})
+
+	t.Run("zone change", func(t *testing.T) {
+		require.NoError(t, l.Update(peer.ListUpdates{
+			Removals:  []peer.Identifier{hostport.Identify("a1")},
+			Additions: []peer.Identifier{IdentifyZone(hostport.Identify("a1"), "b")},
+		}))
+		assert.Equal(t, l.remote, l.members["a1"])
+		assert.Equal(t, 0, l.local.available)
+		assert.Equal(t, 1, l.remote.available)
+
+		trans.SimulateConnect(hostport.Identify("a2"))
+		trans.SimulateConnect(hostport.Identify("a3"))
+		assert.Equal(t, map[string]int{"a2": 10, "a3": 10}, countChoices(t, l, 20))
+	})
+
+	t.Run("invalid updates", func(t *testing.T) {
+		err := l.Update(peer.ListUpdates{
+			Removals:  []peer.Identifier{hostport.Identify("c1")},
+			Additions: []peer.Identifier{hostport.Identify("a4")},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `can't remove peer (c1) because it is not in peerlist`)
+		assert.Contains(t, err.Error(), `can't add peer "a4" because is already in peerlist`)
+	})
+}
+
+func TestResolver(t *testing.T) {
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Available))
+	l := New("a", trans, newRoundRobin,
+		Resolver(func(pid peer.Identifier) string {
+			if pid.Identifier() == "1" {
+				return "a"
+			}
+			return ""
+		}),
+		Zones(map[string]string{"1": "b", "2": "a"}),
+	)
+
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{
+			hostport.Identify("1"),
+			hostport.Identify("2"),
+			hostport.Identify("3"),
+		},
+	}))
+	assert.Equal(t, l.local, l.members["1"], "resolver takes precedence over configured zones")
+	assert.Equal(t, l.local, l.members["2"], "configured zones apply when the resolver does not know")
+	assert.Equal(t, l.remote, l.members["3"], "unknown zones are remote")
+}
+
+func TestWaitForPeers(t *testing.T) {
+	trans := yarpctest.NewFakeTransport(yarpctest.InitialConnectionStatus(peer.Unavailable))
+	l := New("a", trans, newRoundRobin)
+	require.NoError(t, l.Start())
+	defer func() { assert.NoError(t, l.Stop()) }()
+
+	assert.Equal(t, l.local.list, l.choose(), "waits for local peers in an empty list")
+
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{IdentifyZone(hostport.Identify("b1"), "b")},
+	}))
+	assert.Equal(t, l.remote.list, l.choose(), "waits for remote peers without local peers")
+
+	require.NoError(t, l.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{IdentifyZone(hostport.Identify("a1"), "a")},
+	}))
+	assert.Equal(t, l.local.list, l.choose(), "waits for local peers")
+}
+
+func TestIdentifyZone(t *testing.T) {
+	pid := IdentifyZone(hostport.IdentifyWeighted("1.1.1.1:80", 3), "a")
+	assert.Equal(t, "1.1.1.1:80", pid.Identifier())
+	assert.Equal(t, "a", pid.Zone())
+	assert.Equal(t, 3, peer.WeightOf(pid))
+}
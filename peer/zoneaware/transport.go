@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zoneaware
+
+import (
+	"go.uber.org/yarpc/api/peer"
+)
+
+// groupTransport retains peers for the peer list of a group, tracking how
+// many of the peers of the group are available.
+type groupTransport struct {
+	peer.Transport
+
+	list  *List
+	group *group
+}
+
+// subscriberKey identifies a peer retained by a subscriber. Some peer lists
+// subscribe to all of their peers with the same subscriber.
+type subscriberKey struct {
+	id string
+	ps peer.Subscriber
+}
+
+func (t *groupTransport) RetainPeer(pid peer.Identifier, ps peer.Subscriber) (peer.Peer, error) {
+	sub := &subscriber{Subscriber: ps, list: t.list, group: t.group}
+	p, err := t.Transport.RetainPeer(pid, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	t.list.mu.Lock()
+	defer t.list.mu.Unlock()
+
+	sub.peer = p
+	t.group.subscribers[subscriberKey{id: pid.Identifier(), ps: ps}] = sub
+	sub.updateLocked()
+	return p, nil
+}
+
+func (t *groupTransport) ReleasePeer(pid peer.Identifier, ps peer.Subscriber) error {
+	key := subscriberKey{id: pid.Identifier(), ps: ps}
+
+	t.list.mu.Lock()
+	sub, ok := t.group.subscribers[key]
+	if ok {
+		delete(t.group.subscribers, key)
+		if sub.available {
+			t.group.available--
+		}
+		sub.peer = nil
+	}
+	t.list.mu.Unlock()
+
+	if !ok {
+		return t.Transport.ReleasePeer(pid, ps)
+	}
+	return t.Transport.ReleasePeer(pid, sub)
+}
+
+// subscriber forwards status changes to the subscriber of the underlying peer
+// list, tracking whether the peer is available.
+type subscriber struct {
+	peer.Subscriber
+
+	list  *List
+	group *group
+
+	// peer and available are guarded by the list lock.
+	peer      peer.Peer
+	available bool
+}
+
+func (s *subscriber) NotifyStatusChanged(pid peer.Identifier) {
+	s.Subscriber.NotifyStatusChanged(pid)
+
+	s.list.mu.Lock()
+	s.updateLocked()
+	s.list.mu.Unlock()
+}
+
+// updateLocked must be run under the list lock.
+func (s *subscriber) updateLocked() {
+	if s.peer == nil {
+		return
+	}
+	available := s.peer.Status().ConnectionStatus == peer.Available
+	if available == s.available {
+		return
+	}
+	s.available = available
+	if available {
+		s.group.available++
+	} else {
+		s.group.available--
+	}
+}
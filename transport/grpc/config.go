@@ -379,6 +379,10 @@ func (t *transportSpec) buildOutbound(outboundConfig *OutboundConfig, tr transpo
 
 	opts := append(dialOpts, t.DialOptions...)
 	dialer := trans.NewDialer(append([]DialOption{DialerDestinationServiceName(kit.OutboundServiceName())}, opts...)...)
+
+	outboundOpts := []OutboundOption{OutboundCompressor(kit.Compressor(outboundConfig.Compressor))}
+	outboundOpts = append(outboundOpts, t.OutboundOptions...)
+
 	var chooser peer.Chooser
 	if outboundConfig.Empty() {
 		if outboundConfig.Address == "" {
@@ -387,14 +391,14 @@ func (t *transportSpec) buildOutbound(outboundConfig *OutboundConfig, tr transpo
 		chooser = peerchooser.NewSingle(hostport.PeerIdentifier(outboundConfig.Address), dialer)
 	} else {
 		var err error
-		chooser, err = outboundConfig.BuildPeerChooser(dialer, hostport.Identify, kit)
+		chooser, err = outboundConfig.BuildPeerChooser(dialer, hostport.Identify, kit,
+			yarpcconfig.HealthCheckOutbound(func(c peer.Chooser) transport.UnaryOutbound {
+				return trans.NewOutbound(c, outboundOpts...)
+			}))
 		if err != nil {
 			return nil, err
 		}
 	}
-
-	outboundOpts := []OutboundOption{OutboundCompressor(kit.Compressor(outboundConfig.Compressor))}
-	outboundOpts = append(outboundOpts, t.OutboundOptions...)
 	return trans.NewOutbound(chooser, outboundOpts...), nil
 }
 
@@ -29,6 +29,7 @@ import (
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/bufferpool"
 	"go.uber.org/yarpc/internal/grpcerrorcodes"
+	"go.uber.org/yarpc/pkg/procedure"
 	"go.uber.org/yarpc/yarpcerrors"
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
@@ -42,6 +43,17 @@ var (
 	// errInvalidGRPCStream is applied before yarpc so it's a raw GRPC error
 	errInvalidGRPCStream = status.Error(codes.InvalidArgument, "received grpc request with invalid stream")
 	errInvalidGRPCMethod = yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument, "invalid stream method name for request")
+
+	_protoEncoding transport.Encoding = "proto"
+
+	// _standardServices are the standard gRPC services that plain gRPC
+	// clients, like Kubernetes probes and grpcurl, call without YARPC
+	// headers.
+	_standardServices = map[string]struct{}{
+		"grpc.health.v1.Health":                    {},
+		"grpc.reflection.v1.ServerReflection":      {},
+		"grpc.reflection.v1alpha.ServerReflection": {},
+	}
 )
 
 type handler struct {
@@ -100,12 +112,31 @@ func (h *handler) getBasicTransportRequest(ctx context.Context, streamMethod str
 	}
 
 	transportRequest.Procedure = procedure
-	if err := transport.ValidateRequest(transportRequest); err != nil {
+	if err := validateInboundRequest(transportRequest); err != nil {
 		return nil, err
 	}
 	return transportRequest, nil
 }
 
+// validateInboundRequest validates a request, except that requests to
+// standard gRPC services need not specify their caller and service, and
+// default to the proto encoding.
+// The router routes requests without a service to its default service.
+func validateInboundRequest(req *transport.Request) error {
+	if service, _ := procedure.FromName(req.Procedure); isStandardService(service) {
+		if req.Encoding == "" {
+			req.Encoding = _protoEncoding
+		}
+		return nil
+	}
+	return transport.ValidateRequest(req)
+}
+
+func isStandardService(service string) bool {
+	_, ok := _standardServices[service]
+	return ok
+}
+
 // procedureFromStreamMethod converts a GRPC stream method into a yarpc
 // procedure name.  This is mostly copied from the GRPC-go server processing
 // logic here:
@@ -146,7 +177,7 @@ func (h *handler) handleStream(
 	ctx, span := extractOpenTracingSpan.Do(ctx, transportRequest)
 	defer span.Finish()
 
-	stream := newServerStream(ctx, &transport.StreamRequest{Meta: transportRequest.ToRequestMeta()}, serverStream)
+	stream := newServerStream(ctx, &transport.StreamRequest{Meta: transportRequest.ToRequestMeta()}, serverStream, span)
 	tServerStream, err := transport.NewServerStream(stream)
 	if err != nil {
 		return err
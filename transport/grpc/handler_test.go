@@ -27,6 +27,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/yarpcerrors"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -79,6 +80,27 @@ func TestInvalidStreamRequest(t *testing.T) {
 	require.Contains(t, err.Error(), "missing service name, caller name, encoding")
 }
 
+func TestStandardServiceRequest(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	tran := NewTransport()
+	i := tran.NewInbound(listener)
+
+	h := handler{i: i}
+	md := metadata.MD{
+		contentTypeHeader: []string{"application/grpc"},
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	req, err := h.getBasicTransportRequest(ctx, "grpc.health.v1.Health/Check")
+	require.NoError(t, err, "standard services must not require YARPC headers")
+	assert.Equal(t, "grpc.health.v1.Health::Check", req.Procedure)
+	assert.Equal(t, "", req.Service)
+	assert.Equal(t, "", req.Caller)
+	assert.Equal(t, transport.Encoding("proto"), req.Encoding)
+}
+
 func TestInvalidStreamEmptyHeader(t *testing.T) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
@@ -27,12 +27,14 @@ import (
 	"net"
 
 	opentracing "github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/net/metrics"
 	"go.uber.org/yarpc/api/backoff"
 	"go.uber.org/yarpc/api/transport"
 	yarpctls "go.uber.org/yarpc/api/transport/tls"
 	intbackoff "go.uber.org/yarpc/internal/backoff"
 	"go.uber.org/yarpc/transport/internal/tls/dialer"
+	"go.uber.org/yarpc/yarpcotel"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -96,6 +98,16 @@ func Tracer(tracer opentracing.Tracer) TransportOption {
 	}
 }
 
+// TracerProvider specifies an OpenTelemetry TracerProvider to record spans
+// with. Spans are propagated with W3C Trace Context metadata.
+//
+// This replaces any tracer specified with Tracer.
+func TracerProvider(provider trace.TracerProvider) TransportOption {
+	return func(transportOptions *transportOptions) {
+		transportOptions.tracer = yarpcotel.NewTracer(provider)
+	}
+}
+
 // Logger sets a logger to use for internal logging.
 //
 // The default is to not write any logs.
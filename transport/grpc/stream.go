@@ -28,6 +28,7 @@ import (
 
 	"github.com/gogo/status"
 	"github.com/opentracing/opentracing-go"
+	opentracinglog "github.com/opentracing/opentracing-go/log"
 	"go.uber.org/atomic"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/grpcerrorcodes"
@@ -41,17 +42,26 @@ var (
 	_ transport.StreamHeadersReader = (*clientStream)(nil)
 )
 
+const (
+	_messageSent     = "SENT"
+	_messageReceived = "RECEIVED"
+)
+
 type serverStream struct {
-	ctx    context.Context
-	req    *transport.StreamRequest
-	stream grpc.ServerStream
+	ctx      context.Context
+	req      *transport.StreamRequest
+	stream   grpc.ServerStream
+	span     opentracing.Span
+	sent     atomic.Int32
+	received atomic.Int32
 }
 
-func newServerStream(ctx context.Context, req *transport.StreamRequest, stream grpc.ServerStream) *serverStream {
+func newServerStream(ctx context.Context, req *transport.StreamRequest, stream grpc.ServerStream, span opentracing.Span) *serverStream {
 	return &serverStream{
 		ctx:    ctx,
 		req:    req,
 		stream: stream,
+		span:   span,
 	}
 }
 
@@ -70,7 +80,11 @@ func (ss *serverStream) SendMessage(_ context.Context, m *transport.StreamMessag
 	if err != nil {
 		return err
 	}
-	return toYARPCStreamError(ss.stream.SendMsg(msg))
+	if err := ss.stream.SendMsg(msg); err != nil {
+		return toYARPCStreamError(err)
+	}
+	logMessageEvent(ss.span, _messageSent, ss.sent.Inc(), len(msg))
+	return nil
 }
 
 func (ss *serverStream) ReceiveMessage(_ context.Context) (*transport.StreamMessage, error) {
@@ -78,6 +92,7 @@ func (ss *serverStream) ReceiveMessage(_ context.Context) (*transport.StreamMess
 	if err := ss.stream.RecvMsg(&msg); err != nil {
 		return nil, toYARPCStreamError(err)
 	}
+	logMessageEvent(ss.span, _messageReceived, ss.received.Inc(), len(msg))
 	return &transport.StreamMessage{
 		Body:     readCloser{bytes.NewReader(msg)},
 		BodySize: len(msg),
@@ -101,12 +116,14 @@ func (ss *serverStream) SendHeaders(headers transport.Headers) error {
 }
 
 type clientStream struct {
-	ctx     context.Context
-	req     *transport.StreamRequest
-	stream  grpc.ClientStream
-	span    opentracing.Span
-	closed  atomic.Bool
-	release func(error)
+	ctx      context.Context
+	req      *transport.StreamRequest
+	stream   grpc.ClientStream
+	span     opentracing.Span
+	sent     atomic.Int32
+	received atomic.Int32
+	closed   atomic.Bool
+	release  func(error)
 }
 
 func newClientStream(ctx context.Context, req *transport.StreamRequest, stream grpc.ClientStream, span opentracing.Span, release func(error)) *clientStream {
@@ -141,6 +158,7 @@ func (cs *clientStream) SendMessage(_ context.Context, m *transport.StreamMessag
 	if err := cs.stream.SendMsg(msg); err != nil {
 		return toYARPCStreamError(cs.closeWithErr(err))
 	}
+	logMessageEvent(cs.span, _messageSent, cs.sent.Inc(), len(msg))
 	return nil
 }
 
@@ -150,6 +168,7 @@ func (cs *clientStream) ReceiveMessage(context.Context) (*transport.StreamMessag
 	if err := cs.stream.RecvMsg(&msg); err != nil {
 		return nil, toYARPCStreamError(cs.closeWithErr(err))
 	}
+	logMessageEvent(cs.span, _messageReceived, cs.received.Inc(), len(msg))
 	return &transport.StreamMessage{Body: ioutil.NopCloser(bytes.NewReader(msg))}, nil
 }
 
@@ -181,6 +200,18 @@ func (cs *clientStream) closeWithErr(err error) error {
 	return err
 }
 
+// logMessageEvent records a message sent or received on a stream as an event
+// of its span, following the OpenTelemetry semantic conventions for RPC
+// spans. Message IDs start at 1 for each direction.
+func logMessageEvent(span opentracing.Span, messageType string, id int32, size int) {
+	span.LogFields(
+		opentracinglog.String("event", "message"),
+		opentracinglog.String("rpc.message.type", messageType),
+		opentracinglog.Int32("rpc.message.id", id),
+		opentracinglog.Int("rpc.message.uncompressed_size", size),
+	)
+}
+
 func toYARPCStreamError(err error) error {
 	if err == nil {
 		return nil
@@ -21,10 +21,18 @@
 package grpc
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
 	"testing"
 
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/yarpcerrors"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/status"
 )
 
@@ -36,3 +44,57 @@ func TestToYARPCStreamErrorWithInvalidGrpcCode(t *testing.T) {
 	assert.Equal(t, yarpcstatus.Message(), "test")
 	assert.Nil(t, yarpcstatus.Details())
 }
+
+type fakeServerStream struct {
+	grpc.ServerStream
+
+	sent     [][]byte
+	received [][]byte
+}
+
+func (s *fakeServerStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m.([]byte))
+	return nil
+}
+
+func (s *fakeServerStream) RecvMsg(m interface{}) error {
+	*(m.(*[]byte)), s.received = s.received[0], s.received[1:]
+	return nil
+}
+
+func TestServerStreamMessageEvents(t *testing.T) {
+	span := mocktracer.New().StartSpan("stream")
+	grpcStream := &fakeServerStream{received: [][]byte{[]byte("foo"), []byte("hello")}}
+	stream := newServerStream(context.Background(), &transport.StreamRequest{}, grpcStream, span)
+
+	_, err := stream.ReceiveMessage(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMessage(context.Background(), &transport.StreamMessage{
+		Body: ioutil.NopCloser(bytes.NewReader([]byte("bar"))),
+	}))
+	_, err = stream.ReceiveMessage(context.Background())
+	require.NoError(t, err)
+
+	var events [][]log.Field
+	for _, r := range span.(*mocktracer.MockSpan).Logs() {
+		var fields []log.Field
+		for _, f := range r.Fields {
+			fields = append(fields, log.Object(f.Key, f.ValueString))
+		}
+		events = append(events, fields)
+	}
+	assert.Equal(t, [][]log.Field{
+		messageEvent("RECEIVED", "1", "3"),
+		messageEvent("SENT", "1", "3"),
+		messageEvent("RECEIVED", "2", "5"),
+	}, events)
+}
+
+func messageEvent(messageType, id, size string) []log.Field {
+	return []log.Field{
+		log.Object("event", "message"),
+		log.Object("rpc.message.type", messageType),
+		log.Object("rpc.message.id", id),
+		log.Object("rpc.message.uncompressed_size", size),
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"time"
 
+	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
 	yarpctls "go.uber.org/yarpc/api/transport/tls"
 	"go.uber.org/yarpc/peer/hostport"
@@ -74,6 +75,7 @@ func (ts *transportSpec) Spec() yarpcconfig.TransportSpec {
 		BuildInbound:        ts.buildInbound,
 		BuildUnaryOutbound:  ts.buildUnaryOutbound,
 		BuildOnewayOutbound: ts.buildOnewayOutbound,
+		BuildStreamOutbound: ts.buildStreamOutbound,
 	}
 }
 
@@ -94,6 +96,7 @@ func (ts *transportSpec) Spec() yarpcconfig.TransportSpec {
 //	      exponential:
 //	        first: 10ms
 //	        max: 30s
+//	    h2c: false
 //
 // All parameters of TransportConfig are optional. This section may be omitted
 // in the transports section.
@@ -109,6 +112,8 @@ type TransportConfig struct {
 	ResponseHeaderTimeout time.Duration       `config:"responseHeaderTimeout"`
 	ConnTimeout           time.Duration       `config:"connTimeout"`
 	ConnBackoff           yarpcconfig.Backoff `config:"connBackoff"`
+	// Sends requests to http:// URLs over cleartext HTTP/2. See H2C.
+	H2C bool `config:"h2c"`
 }
 
 func (ts *transportSpec) buildTransport(tc *TransportConfig, k *yarpcconfig.Kit) (transport.Transport, error) {
@@ -145,6 +150,9 @@ func (ts *transportSpec) buildTransport(tc *TransportConfig, k *yarpcconfig.Kit)
 	if tc.ConnTimeout > 0 {
 		options.connTimeout = tc.ConnTimeout
 	}
+	if tc.H2C {
+		options.h2c = true
+	}
 
 	strategy, err := tc.ConnBackoff.Strategy()
 	if err != nil {
@@ -164,6 +172,7 @@ func (ts *transportSpec) buildTransport(tc *TransportConfig, k *yarpcconfig.Kit)
 //	      - x-foo
 //	      - x-bar
 //	    shutdownTimeout: 5s
+//	    h2c: true
 type InboundConfig struct {
 	// Address to listen on. This field is required.
 	Address string `config:"address,interpolate"`
@@ -174,6 +183,8 @@ type InboundConfig struct {
 	ShutdownTimeout *time.Duration `config:"shutdownTimeout"`
 	// TLS configuration of the inbound.
 	TLSConfig TLSConfig `config:"tls"`
+	// Accepts cleartext HTTP/2 connections. See InboundH2C.
+	H2C bool `config:"h2c"`
 }
 
 // TLSConfig specifies the TLS configuration of the HTTP inbound.
@@ -201,6 +212,9 @@ func (ts *transportSpec) buildInbound(ic *InboundConfig, t transport.Transport,
 		}
 		inboundOptions = append(inboundOptions, ShutdownTimeout(*ic.ShutdownTimeout))
 	}
+	if ic.H2C {
+		inboundOptions = append(inboundOptions, InboundH2C())
+	}
 
 	return t.(*Transport).NewInbound(ic.Address, inboundOptions...), nil
 }
@@ -316,14 +330,17 @@ func (ts *transportSpec) buildOutbound(oc *OutboundConfig, t transport.Transport
 		return x.NewSingleOutbound(oc.URL, opts...), nil
 	}
 
-	chooser, err := oc.BuildPeerChooser(x, hostport.Identify, k)
-	if err != nil {
-		return nil, fmt.Errorf("cannot configure peer chooser for HTTP outbound: %v", err)
-	}
-
 	if oc.URL != "" {
 		opts = append(opts, URLTemplate(oc.URL))
 	}
+
+	chooser, err := oc.BuildPeerChooser(x, hostport.Identify, k,
+		yarpcconfig.HealthCheckOutbound(func(c peer.Chooser) transport.UnaryOutbound {
+			return x.NewOutbound(c, opts...)
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("cannot configure peer chooser for HTTP outbound: %v", err)
+	}
 	return x.NewOutbound(chooser, opts...), nil
 }
 
@@ -334,3 +351,7 @@ func (ts *transportSpec) buildUnaryOutbound(oc *OutboundConfig, t transport.Tran
 func (ts *transportSpec) buildOnewayOutbound(oc *OutboundConfig, t transport.Transport, k *yarpcconfig.Kit) (transport.OnewayOutbound, error) {
 	return ts.buildOutbound(oc, t, k)
 }
+
+func (ts *transportSpec) buildStreamOutbound(oc *OutboundConfig, t transport.Transport, k *yarpcconfig.Kit) (transport.StreamOutbound, error) {
+	return ts.buildOutbound(oc, t, k)
+}
@@ -81,6 +81,7 @@ func TestTransportSpec(t *testing.T) {
 		GrabHeaders     map[string]struct{}
 		ShutdownTimeout time.Duration
 		TLSMode         yarpctls.Mode
+		H2C             bool
 	}
 
 	type inboundTest struct {
@@ -147,6 +148,7 @@ func TestTransportSpec(t *testing.T) {
 				"disableKeepAlives":     true,
 				"disableCompression":    true,
 				"responseHeaderTimeout": "1s",
+				"h2c":                   true,
 			},
 			wantClient: &wantHTTPClient{
 				KeepAlive:             5 * time.Second,
@@ -157,6 +159,18 @@ func TestTransportSpec(t *testing.T) {
 				DisableKeepAlives:     true,
 				DisableCompression:    true,
 				ResponseHeaderTimeout: 1 * time.Second,
+				H2C:                   true,
+			},
+		},
+		{
+			desc: "h2c transport option",
+			opts: []Option{H2C()},
+			wantClient: &wantHTTPClient{
+				KeepAlive:           30 * time.Second,
+				MaxIdleConnsPerHost: 2,
+				ConnTimeout:         defaultConnTimeout,
+				IdleConnTimeout:     defaultIdleConnTimeout,
+				H2C:                 true,
 			},
 		},
 	}
@@ -175,6 +189,17 @@ func TestTransportSpec(t *testing.T) {
 			cfg:         attrs{"address": ":8080"},
 			wantInbound: &wantInbound{Address: ":8080", ShutdownTimeout: defaultShutdownTimeout},
 		},
+		{
+			desc:        "inbound h2c",
+			cfg:         attrs{"address": ":8080", "h2c": true},
+			wantInbound: &wantInbound{Address: ":8080", ShutdownTimeout: defaultShutdownTimeout, H2C: true},
+		},
+		{
+			desc:        "inbound h2c option",
+			cfg:         attrs{"address": ":8080"},
+			opts:        []Option{InboundH2C()},
+			wantInbound: &wantInbound{Address: ":8080", ShutdownTimeout: defaultShutdownTimeout, H2C: true},
+		},
 		{
 			desc: "inbound tls",
 			cfg: attrs{
@@ -373,6 +398,41 @@ func TestTransportSpec(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "outbound health check",
+			cfg: attrs{
+				"myservice": attrs{
+					"http": attrs{
+						"url":  "http://localhost/yarpc",
+						"peer": "127.0.0.1:8080",
+						"healthCheck": attrs{
+							"procedure": "health",
+							"interval":  "1s",
+						},
+					},
+				},
+			},
+			wantOutbounds: map[string]wantOutbound{
+				"myservice": {
+					URLTemplate: "http://localhost/yarpc",
+				},
+			},
+		},
+		{
+			desc: "outbound invalid health check",
+			cfg: attrs{
+				"myservice": attrs{
+					"http": attrs{
+						"peer":        "127.0.0.1:8080",
+						"healthCheck": attrs{"interval": "1s"},
+					},
+				},
+			},
+			wantErrors: []string{
+				"cannot configure peer chooser for HTTP outbound",
+				"health check must specify a procedure or grpc",
+			},
+		},
 		{
 			desc: "outbound peer build error",
 			cfg: attrs{
@@ -563,6 +623,7 @@ func TestTransportSpec(t *testing.T) {
 				assert.Equal(t, want.ShutdownTimeout, ib.shutdownTimeout, "shutdownTimeout should match")
 				assert.Equal(t, "foo", ib.transport.serviceName, "service name must match")
 				assert.Equal(t, want.TLSMode, ib.tlsMode, "tlsMode should match")
+				assert.Equal(t, want.H2C, ib.h2c, "h2c should match")
 			}
 		}
 
@@ -572,6 +633,9 @@ func TestTransportSpec(t *testing.T) {
 				// Verify that we install a oneway too
 				_, ok := cfg.Outbounds[svc].Oneway.(*Outbound)
 				assert.True(t, ok, "expected *Outbound for %q oneway, got %T", svc, cfg.Outbounds[svc].Oneway)
+				// and a stream
+				_, ok = cfg.Outbounds[svc].Stream.(*Outbound)
+				assert.True(t, ok, "expected *Outbound for %q stream, got %T", svc, cfg.Outbounds[svc].Stream)
 
 				assert.Equal(t, want.URLTemplate, ob.urlTemplate.String(), "outbound URLTemplate should match")
 				assert.Equal(t, want.Headers, ob.headers, "outbound headers should match")
@@ -618,6 +682,7 @@ type wantHTTPClient struct {
 	DisableCompression    bool
 	ResponseHeaderTimeout time.Duration
 	ConnTimeout           time.Duration
+	H2C                   bool
 }
 
 // useFakeBuildClient verifies the configuration we use to build an HTTP
@@ -632,6 +697,7 @@ func useFakeBuildClient(t *testing.T, want *wantHTTPClient) TransportOption {
 		assert.Equal(t, want.DisableCompression, options.disableCompression, "http.Client: DisableCompression should match")
 		assert.Equal(t, want.ResponseHeaderTimeout, options.responseHeaderTimeout, "http.Client: ResponseHeaderTimeout should match")
 		assert.Equal(t, want.ConnTimeout, options.connTimeout, "http.Client: ConnTimeout should match")
+		assert.Equal(t, want.H2C, options.h2c, "http.Client: H2C should match")
 		return buildHTTPClient(options)
 	})
 }
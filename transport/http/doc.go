@@ -20,7 +20,7 @@
 
 // Package http implements a YARPC transport based on the HTTP/1.1 protocol.
 // The HTTP transport provides first class support for Unary RPCs and
-// experimental support for Oneway RPCs.
+// experimental support for Oneway and Streaming RPCs.
 //
 // # Usage
 //
@@ -66,6 +66,14 @@
 // the names of these headers. The request and response bodies are sent as-is
 // in the HTTP request or response body.
 //
+// Streams are sent as a single HTTP request with the Content-Type
+// "application/x-yarpc-stream". Messages in either direction are written to
+// the request or response body, each prefixed with its length as a 4-byte
+// big-endian integer. The client ends its side of the stream by ending the
+// request body. The server ends the stream by ending the response body and
+// reports errors in the Rpc-Error-* trailers, with the error details base64
+// encoded.
+//
 // # See Also
 //
 // YARPC Properties: https://github.com/yarpc/yarpc/blob/master/properties.md
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// registerH2C makes the client send requests for http:// URLs over
+// cleartext HTTP/2 with prior knowledge, multiplexing them over a single
+// connection per peer. Requests for https:// URLs are unaffected.
+//
+// It returns the HTTP/2 transport so that its connections can be closed
+// with the client's, or nil if the client does not use an *http.Transport.
+func registerH2C(client *http.Client) *http2.Transport {
+	t, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	h2cTransport := &http2.Transport{
+		AllowHTTP: true,
+		// http2.Transport only dials TLS connections, even for http://
+		// URLs, unless it is given a dialer.
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		},
+		DisableCompression: t.DisableCompression,
+	}
+	t.RegisterProtocol("http", h2cTransport)
+	return h2cTransport
+}
+
+// configureH2C makes the server accept cleartext HTTP/2 connections, both
+// with prior knowledge and through HTTP/1.1 upgrades, in addition to
+// HTTP/1.x.
+func configureH2C(server *http.Server) (*h2cHandler, error) {
+	h2Server := &http2.Server{}
+	// h2c connections are hijacked from the HTTP/1 server, which therefore
+	// does not track them. Configuring the server lets Shutdown send them a
+	// GOAWAY frame.
+	if err := http2.ConfigureServer(server, h2Server); err != nil {
+		return nil, err
+	}
+	h := &h2cHandler{handler: h2c.NewHandler(server.Handler, h2Server)}
+	server.Handler = h
+	return h, nil
+}
+
+// h2cHandler serves h2c connections for the lifetime of the connection
+// from a single call to ServeHTTP. It keeps count of these calls so that
+// shutdown can wait for h2c connections to drain, like http.Server does
+// for the connections it tracks.
+type h2cHandler struct {
+	handler http.Handler
+	active  sync.WaitGroup
+}
+
+func (h *h2cHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.active.Add(1)
+	defer h.active.Done()
+	h.handler.ServeHTTP(w, req)
+}
+
+// wait blocks until all connections have been closed after the server has
+// been shut down, or until the context is done.
+func (h *h2cHandler) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
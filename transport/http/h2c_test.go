@@ -0,0 +1,199 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"go.uber.org/yarpc/api/transport"
+	yarpctls "go.uber.org/yarpc/api/transport/tls"
+	"go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/transport/internal/tls/testscenario"
+)
+
+// protoRecorder records the protocol and client address of requests
+// received by an inbound.
+type protoRecorder struct {
+	mu          sync.Mutex
+	protos      map[string]int
+	remoteAddrs map[string]struct{}
+}
+
+func newProtoRecorder() *protoRecorder {
+	return &protoRecorder{
+		protos:      make(map[string]int),
+		remoteAddrs: make(map[string]struct{}),
+	}
+}
+
+func (r *protoRecorder) Interceptor() InboundOption {
+	return Interceptor(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.mu.Lock()
+			r.protos[req.Proto]++
+			r.remoteAddrs[req.RemoteAddr] = struct{}{}
+			r.mu.Unlock()
+			h.ServeHTTP(w, req)
+		})
+	})
+}
+
+func (r *protoRecorder) Protos() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.protos
+}
+
+func (r *protoRecorder) Connections() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.remoteAddrs)
+}
+
+func callTestFoo(t *testing.T, testEnv *testEnv) {
+	client := json.New(testEnv.ClientConfig)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var response testFooResponse
+	require.NoError(t, client.Call(ctx, "testFoo", &testFooRequest{One: "one"}, &response))
+	assert.Equal(t, testFooResponse{One: "one"}, response)
+}
+
+func TestH2C(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	recorder := newProtoRecorder()
+	doWithTestEnv(t, testEnvOptions{
+		Procedures:       json.Procedure("testFoo", testFooHandler),
+		TransportOptions: []TransportOption{H2C()},
+		InboundOptions:   []InboundOption{InboundH2C(), recorder.Interceptor()},
+	}, func(t *testing.T, testEnv *testEnv) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				callTestFoo(t, testEnv)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, map[string]int{"HTTP/2.0": 10}, recorder.Protos())
+		assert.Equal(t, 1, recorder.Connections(), "requests must share a single connection")
+	})
+}
+
+func TestH2CStream(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	doWithTestEnv(t, testEnvOptions{
+		Procedures: []transport.Procedure{{
+			Name: "echo",
+			HandlerSpec: transport.NewStreamHandlerSpec(streamHandlerFunc(func(s *transport.ServerStream) error {
+				msg, err := s.ReceiveMessage(context.Background())
+				if err != nil {
+					return err
+				}
+				return s.SendMessage(context.Background(), msg)
+			})),
+		}},
+		TransportOptions: []TransportOption{H2C()},
+		InboundOptions:   []InboundOption{InboundH2C()},
+	}, func(t *testing.T, testEnv *testEnv) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		stream, err := testEnv.Outbound.CallStream(ctx, streamRequest("echo"))
+		require.NoError(t, err)
+		require.NoError(t, stream.SendMessage(ctx, newStreamMessage("hello")))
+		msg, err := stream.ReceiveMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", readStreamMessage(t, msg))
+		require.NoError(t, stream.Close(ctx))
+	})
+}
+
+func TestH2CInboundServesHTTP1(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	recorder := newProtoRecorder()
+	doWithTestEnv(t, testEnvOptions{
+		Procedures:     json.Procedure("testFoo", testFooHandler),
+		InboundOptions: []InboundOption{InboundH2C(), recorder.Interceptor()},
+	}, func(t *testing.T, testEnv *testEnv) {
+		callTestFoo(t, testEnv)
+		assert.Equal(t, map[string]int{"HTTP/1.1": 1}, recorder.Protos())
+	})
+}
+
+func TestH2CWithTLS(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	scenario := testscenario.Create(t, time.Minute, time.Minute)
+	tests := []struct {
+		desc             string
+		transportOptions []TransportOption
+		wantProto        string
+	}{
+		{
+			desc:             "h2c_client",
+			transportOptions: []TransportOption{H2C()},
+			wantProto:        "HTTP/2.0",
+		},
+		{
+			desc: "tls_client",
+			transportOptions: []TransportOption{
+				DialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return tls.Dial(network, addr, scenario.ClientTLSConfig())
+				}),
+			},
+			wantProto: "HTTP/1.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			recorder := newProtoRecorder()
+			doWithTestEnv(t, testEnvOptions{
+				Procedures: json.Procedure("testFoo", testFooHandler),
+				InboundOptions: []InboundOption{
+					InboundTLSConfiguration(scenario.ServerTLSConfig()),
+					InboundTLSMode(yarpctls.Permissive),
+					InboundH2C(),
+					recorder.Interceptor(),
+				},
+				TransportOptions: tt.transportOptions,
+			}, func(t *testing.T, testEnv *testEnv) {
+				callTestFoo(t, testEnv)
+				assert.Equal(t, map[string]int{tt.wantProto: 1}, recorder.Protos())
+			})
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
@@ -98,7 +99,15 @@ func (h handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 func (h handler) callHandler(responseWriter *responseWriter, req *http.Request, service string, procedure string) (retErr error) {
 	start := time.Now()
-	defer req.Body.Close()
+	if isStreamRequest(req) {
+		// Streams exchange messages in both directions, so the response
+		// must be able to start before the request body has been read.
+		// Closing the body would block until the client ends its side of
+		// the stream; net/http closes it once the response is complete.
+		enableFullDuplex(responseWriter.w)
+	} else {
+		defer req.Body.Close()
+	}
 	if req.Method != http.MethodPost {
 		return yarpcerrors.Newf(yarpcerrors.CodeNotFound, "request method was %s but only %s is allowed", req.Method, http.MethodPost)
 	}
@@ -145,12 +154,19 @@ func (h handler) callHandler(responseWriter *responseWriter, req *http.Request,
 		return err
 	}
 
-	if parseTTLErr != nil {
-		return parseTTLErr
-	}
-	if err := transport.ValidateRequestContext(ctx); err != nil {
+	if err := checkStreamRequest(req, treq, spec.Type()); err != nil {
+		updateSpanWithErr(span, err)
 		return err
 	}
+	// Streams may be long lived and do not require a deadline.
+	if spec.Type() != transport.Streaming {
+		if parseTTLErr != nil {
+			return parseTTLErr
+		}
+		if err := transport.ValidateRequestContext(ctx); err != nil {
+			return err
+		}
+	}
 	switch spec.Type() {
 	case transport.Unary:
 		defer span.Finish()
@@ -167,6 +183,11 @@ func (h handler) callHandler(responseWriter *responseWriter, req *http.Request,
 	case transport.Oneway:
 		err = handleOnewayRequest(span, treq, spec.Oneway(), h.logger)
 
+	case transport.Streaming:
+		defer span.Finish()
+
+		return h.handleStream(ctx, responseWriter, treq, span, spec.Stream())
+
 	default:
 		err = yarpcerrors.Newf(yarpcerrors.CodeUnimplemented, "transport http does not handle %s handlers", spec.Type().String())
 	}
@@ -191,7 +212,7 @@ func handleOnewayRequest(
 
 	// create a new context for oneway requests since the HTTP handler cancels
 	// http.Request's context when ServeHTTP returns
-	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	ctx := transport.ContextWithSpan(context.Background(), span.Tracer(), span)
 
 	go func() {
 		// ensure the span lasts for length of the handler in case of errors
@@ -208,6 +229,42 @@ func handleOnewayRequest(
 	return nil
 }
 
+func (h handler) handleStream(
+	ctx context.Context,
+	responseWriter *responseWriter,
+	treq *transport.Request,
+	span opentracing.Span,
+	streamHandler transport.StreamHandler,
+) error {
+	stream := newServerStream(ctx, &transport.StreamRequest{Meta: treq.ToRequestMeta()}, responseWriter, treq.Body)
+	tServerStream, err := transport.NewServerStream(stream)
+	if err != nil {
+		return err
+	}
+	err = transport.InvokeStreamHandler(transport.StreamInvokeRequest{
+		Stream:  tServerStream,
+		Handler: streamHandler,
+		Logger:  h.logger,
+	})
+	updateSpanWithErr(span, err)
+	return stream.close(err)
+}
+
+// checkStreamRequest verifies that streaming procedures are called with
+// stream requests, and only streaming procedures.
+func checkStreamRequest(req *http.Request, treq *transport.Request, rpcType transport.Type) error {
+	isStream := isStreamRequest(req)
+	if rpcType == transport.Streaming && !isStream {
+		return yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+			"procedure %q is a streaming procedure and must be called with a stream", treq.Procedure)
+	}
+	if rpcType != transport.Streaming && isStream {
+		return yarpcerrors.Newf(yarpcerrors.CodeInvalidArgument,
+			"procedure %q is a %s procedure and cannot be called with a stream", treq.Procedure, strings.ToLower(rpcType.String()))
+	}
+	return nil
+}
+
 func updateSpanWithErr(span opentracing.Span, err error) {
 	if err != nil {
 		span.SetTag("error", true)
@@ -239,7 +296,7 @@ func (h handler) createSpan(ctx context.Context, req *http.Request, treq *transp
 		tags,
 	)
 	ext.PeerService.Set(span, treq.Caller)
-	ctx = opentracing.ContextWithSpan(ctx, span)
+	ctx = transport.ContextWithSpan(ctx, tracer, span)
 	return ctx, span
 }
 
@@ -252,6 +309,10 @@ var (
 type responseWriter struct {
 	w      http.ResponseWriter
 	buffer *bufferpool.Buffer
+
+	// streaming is set once a stream has started writing the response
+	// directly to w.
+	streaming bool
 }
 
 func newResponseWriter(w http.ResponseWriter) *responseWriter {
@@ -308,6 +369,9 @@ func (rw *responseWriter) ResetBuffer() {
 }
 
 func (rw *responseWriter) Close(httpStatusCode int) {
+	if rw.streaming {
+		return
+	}
 	rw.w.WriteHeader(httpStatusCode)
 	if rw.buffer != nil {
 		// TODO: what to do with error?
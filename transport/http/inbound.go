@@ -124,6 +124,18 @@ func InboundTLSMode(mode yarpctls.Mode) InboundOption {
 	}
 }
 
+// InboundH2C returns an InboundOption that makes the inbound accept
+// cleartext HTTP/2 (h2c) connections, both with prior knowledge and through
+// HTTP/1.1 upgrades, so that clients may multiplex many requests over a
+// single connection. HTTP/1.x requests continue to be served.
+//
+// Clients may send h2c with the H2C transport option.
+func InboundH2C() InboundOption {
+	return func(i *Inbound) {
+		i.h2c = true
+	}
+}
+
 // NewInbound builds a new HTTP inbound that listens on the given address and
 // sharing this transport.
 func (t *Transport) NewInbound(addr string, opts ...InboundOption) *Inbound {
@@ -165,6 +177,9 @@ type Inbound struct {
 
 	tlsConfig *tls.Config
 	tlsMode   yarpctls.Mode
+
+	h2c        bool
+	h2cHandler *h2cHandler
 }
 
 // Tracer configures a tracer on this inbound.
@@ -221,10 +236,18 @@ func (i *Inbound) start() error {
 		httpHandler = i.mux
 	}
 
-	i.server = intnet.NewHTTPServer(&http.Server{
+	server := &http.Server{
 		Addr:    i.addr,
 		Handler: httpHandler,
-	})
+	}
+	if i.h2c {
+		h2cHandler, err := configureH2C(server)
+		if err != nil {
+			return err
+		}
+		i.h2cHandler = h2cHandler
+	}
+	i.server = intnet.NewHTTPServer(server)
 
 	addr := i.addr
 	if addr == "" {
@@ -280,7 +303,15 @@ func (i *Inbound) shutdown(ctx context.Context) error {
 			return nil
 		}
 
-		return i.server.Shutdown(ctx)
+		if err := i.server.Shutdown(ctx); err != nil {
+			return err
+		}
+		if i.h2cHandler != nil {
+			// Shutdown does not wait for h2c connections, which close once
+			// their pending calls have completed.
+			return i.h2cHandler.wait(ctx)
+		}
+		return nil
 	})
 }
 
@@ -24,6 +24,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -52,6 +53,7 @@ var (
 	_ transport.Namer                      = (*Outbound)(nil)
 	_ transport.UnaryOutbound              = (*Outbound)(nil)
 	_ transport.OnewayOutbound             = (*Outbound)(nil)
+	_ transport.StreamOutbound             = (*Outbound)(nil)
 	_ introspection.IntrospectableOutbound = (*Outbound)(nil)
 )
 
@@ -291,6 +293,97 @@ func (o *Outbound) CallOneway(ctx context.Context, treq *transport.Request) (tra
 	return time.Now(), nil
 }
 
+// CallStream starts a stream with the server. The stream is carried by a
+// single HTTP request, so the outbound's peers must support full duplex
+// HTTP, as HTTP/2 and the YARPC HTTP inbound do.
+//
+// Unlike unary calls, streams do not require a deadline on the context. The
+// context bounds the lifetime of the whole stream.
+func (o *Outbound) CallStream(ctx context.Context, sreq *transport.StreamRequest) (*transport.ClientStream, error) {
+	if sreq == nil || sreq.Meta == nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf("stream request requires a request metadata")
+	}
+	if err := o.once.WaitUntilRunning(ctx); err != nil {
+		return nil, intyarpcerrors.AnnotateWithInfo(
+			yarpcerrors.FromError(err),
+			"error waiting for HTTP outbound to start for service: %s",
+			sreq.Meta.Service)
+	}
+	return o.stream(ctx, sreq, time.Now())
+}
+
+func (o *Outbound) stream(ctx context.Context, sreq *transport.StreamRequest, start time.Time) (*transport.ClientStream, error) {
+	var ttl time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		ttl = deadline.Sub(start)
+	}
+
+	bodyReader, bodyWriter := io.Pipe()
+	treq := sreq.Meta.ToRequest()
+	treq.Body = bodyReader
+	if err := transport.ValidateRequest(treq); err != nil {
+		return nil, err
+	}
+
+	hreq, err := o.createRequest(treq)
+	if err != nil {
+		return nil, err
+	}
+	ctx, hreq, span, err := o.withOpentracingSpan(ctx, hreq, treq, start)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+	hreq = o.withCoreHeaders(hreq, treq, ttl)
+	hreq.Header.Set("Content-Type", _streamContentType)
+
+	p, onFinish, err := o.getPeerForRequest(ctx, treq)
+	if err != nil {
+		span.Finish()
+		return nil, err
+	}
+
+	stream := newClientStream(ctx, sreq, bodyWriter, span, onFinish)
+	go func() {
+		res, err := o.doWithPeer(ctx, hreq, treq, start, ttl, p, o.client)
+		if err == nil {
+			res, err = checkStreamResponse(treq, res, o.bothResponseError)
+		}
+		if err != nil {
+			// Unblock pending writes to the request body.
+			_ = bodyReader.CloseWithError(err)
+		} else {
+			span.SetTag("http.status_code", res.StatusCode)
+		}
+		stream.setResponse(res, err)
+	}()
+
+	tClientStream, err := transport.NewClientStream(stream)
+	if err != nil {
+		_ = stream.closeWithErr(err)
+		return nil, err
+	}
+	return tClientStream, nil
+}
+
+// checkStreamResponse verifies that the server accepted the stream,
+// returning the error it responded with otherwise.
+func checkStreamResponse(treq *transport.Request, response *http.Response, bothResponseError bool) (*http.Response, error) {
+	if match, resSvcName := checkServiceMatch(treq.Service, response.Header); !match {
+		_ = response.Body.Close()
+		return nil, yarpcerrors.InternalErrorf("service name sent from the request "+
+			"does not match the service name received in the response, sent %q, got: %q", treq.Service, resSvcName)
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+
+	bothResponseError = bothResponseError && response.Header.Get(BothResponseErrorHeader) == AcceptTrue
+	_, err := getYARPCErrorFromResponse(&transport.Response{}, response, bothResponseError)
+	_ = response.Body.Close()
+	return nil, err
+}
+
 func (o *Outbound) call(ctx context.Context, treq *transport.Request) (*transport.Response, error) {
 	start := time.Now()
 	deadline, ok := ctx.Deadline()
@@ -407,7 +500,7 @@ func (o *Outbound) withOpentracingSpan(ctx context.Context, req *http.Request, t
 	// Apply HTTP Context headers for tracing and baggage carried by tracing.
 	tracer := o.tracer
 	var parent opentracing.SpanContext // ok to be nil
-	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
+	if parentSpan := transport.SpanFromContext(ctx, tracer); parentSpan != nil {
 		parent = parentSpan.Context()
 	}
 	tags := opentracing.Tags{
@@ -428,7 +521,7 @@ func (o *Outbound) withOpentracingSpan(ctx context.Context, req *http.Request, t
 	ext.PeerService.Set(span, treq.Service)
 	ext.SpanKindRPCClient.Set(span)
 	ext.HTTPUrl.Set(span, req.URL.String())
-	ctx = opentracing.ContextWithSpan(ctx, span)
+	ctx = transport.ContextWithSpan(ctx, tracer, span)
 
 	err := tracer.Inject(
 		span.Context(),
@@ -0,0 +1,408 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/atomic"
+	"go.uber.org/yarpc/api/transport"
+	intyarpcerrors "go.uber.org/yarpc/internal/yarpcerrors"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Streams are carried by a single HTTP request whose request and response
+// bodies are sequences of frames. Each frame is a 4-byte big-endian length
+// followed by that many bytes of message body.
+//
+// The client ends its side of the stream by closing the request body. The
+// server ends its side by closing the response body and reporting the
+// outcome of the handler in the Rpc-Error-* trailers. Errors that occur
+// before the server has sent anything are reported in the same way as for
+// unary requests.
+const (
+	_streamContentType = "application/x-yarpc-stream"
+
+	_frameHeaderSize = 4
+
+	// _maxFrameSize bounds the size of individual stream messages so that a
+	// corrupt length prefix cannot make us allocate unbounded memory.
+	_maxFrameSize = 64 * 1024 * 1024
+)
+
+var _streamTrailers = strings.Join([]string{
+	ErrorCodeHeader,
+	ErrorNameHeader,
+	ErrorMessageHeader,
+	ErrorDetailsHeader,
+}, ", ")
+
+var (
+	_ transport.StreamHeadersSender = (*serverStream)(nil)
+	_ transport.StreamHeadersReader = (*clientStream)(nil)
+)
+
+// isStreamRequest reports whether the HTTP request carries a stream.
+func isStreamRequest(req *http.Request) bool {
+	return req.Header.Get("Content-Type") == _streamContentType
+}
+
+// writeFrame writes a single message frame to w.
+func writeFrame(w io.Writer, body io.ReadCloser) (int, error) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, _frameHeaderSize))
+	_, err := buf.ReadFrom(body)
+	_ = body.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	frame := buf.Bytes()
+	size := len(frame) - _frameHeaderSize
+	if size > _maxFrameSize {
+		return 0, yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted,
+			"stream message of %d bytes exceeds the maximum of %d bytes", size, _maxFrameSize)
+	}
+	binary.BigEndian.PutUint32(frame, uint32(size))
+	if _, err := w.Write(frame); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// readFrame reads a single message frame from r. It returns io.EOF if r
+// ended cleanly between two frames.
+func readFrame(r io.Reader) (*transport.StreamMessage, error) {
+	var header [_frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, yarpcerrors.InternalErrorf("stream ended in the middle of a message frame")
+		}
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > _maxFrameSize {
+		return nil, yarpcerrors.Newf(yarpcerrors.CodeResourceExhausted,
+			"stream message of %d bytes exceeds the maximum of %d bytes", size, _maxFrameSize)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, yarpcerrors.InternalErrorf("stream ended in the middle of a message frame")
+		}
+		return nil, err
+	}
+	return &transport.StreamMessage{
+		Body:     io.NopCloser(bytes.NewReader(body)),
+		BodySize: int(size),
+	}, nil
+}
+
+// enableFullDuplex allows the handler to write the response while it is
+// still reading the request body. HTTP/1.x servers otherwise consume the
+// rest of the request body before sending the response. HTTP/2 is always
+// full duplex and reports an error here, which is safe to ignore.
+func enableFullDuplex(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).EnableFullDuplex()
+}
+
+// serverStream is the inbound side of an HTTP stream. It writes directly to
+// the underlying http.ResponseWriter, bypassing the buffering used for unary
+// responses.
+type serverStream struct {
+	ctx  context.Context
+	req  *transport.StreamRequest
+	rw   *responseWriter
+	rc   *http.ResponseController
+	body io.Reader
+
+	mu          sync.Mutex
+	wroteHeader bool
+}
+
+func newServerStream(ctx context.Context, req *transport.StreamRequest, rw *responseWriter, body io.Reader) *serverStream {
+	return &serverStream{
+		ctx:  ctx,
+		req:  req,
+		rw:   rw,
+		rc:   http.NewResponseController(rw.w),
+		body: body,
+	}
+}
+
+func (ss *serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+func (ss *serverStream) Request() *transport.StreamRequest {
+	return ss.req
+}
+
+func (ss *serverStream) SendHeaders(headers transport.Headers) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.wroteHeader {
+		return yarpcerrors.InternalErrorf("stream headers have already been sent")
+	}
+	ss.rw.AddHeaders(headers)
+	ss.writeHeader()
+	return ss.flush()
+}
+
+func (ss *serverStream) SendMessage(_ context.Context, m *transport.StreamMessage) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.writeHeader()
+	if _, err := writeFrame(ss.rw.w, m.Body); err != nil {
+		return toStreamError(err)
+	}
+	return ss.flush()
+}
+
+func (ss *serverStream) ReceiveMessage(_ context.Context) (*transport.StreamMessage, error) {
+	msg, err := readFrame(ss.body)
+	return msg, toStreamError(err)
+}
+
+// close ends the stream with the result of the handler. If nothing has been
+// written to the response yet, a non-nil err is returned so that it is sent
+// back like the error of a unary request. Otherwise err is reported in the
+// response trailers and close returns nil.
+func (ss *serverStream) close(err error) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if !ss.wroteHeader {
+		if err != nil {
+			return err
+		}
+		ss.writeHeader()
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+
+	status := yarpcerrors.FromError(err)
+	trailer := ss.rw.w.Header()
+	code, marshalErr := status.Code().MarshalText()
+	if marshalErr != nil {
+		code = []byte("internal")
+	}
+	trailer.Set(ErrorCodeHeader, string(code))
+	if status.Name() != "" {
+		trailer.Set(ErrorNameHeader, status.Name())
+	}
+	trailer.Set(ErrorMessageHeader, status.Message())
+	if details := status.Details(); details != nil {
+		trailer.Set(ErrorDetailsHeader, base64.StdEncoding.EncodeToString(details))
+	}
+	return nil
+}
+
+// writeHeader sends the response headers if they have not been sent yet.
+// It must be called with mu held.
+func (ss *serverStream) writeHeader() {
+	if ss.wroteHeader {
+		return
+	}
+	ss.wroteHeader = true
+	ss.rw.streaming = true
+
+	header := ss.rw.w.Header()
+	header.Set("Content-Type", _streamContentType)
+	header.Set("Trailer", _streamTrailers)
+	ss.rw.w.WriteHeader(http.StatusOK)
+}
+
+func (ss *serverStream) flush() error {
+	return toStreamError(ss.rc.Flush())
+}
+
+// clientStream is the outbound side of an HTTP stream. Messages are written
+// to the request body through a pipe while the response is awaited in the
+// background, so that the caller may send messages before the server
+// responds.
+type clientStream struct {
+	ctx  context.Context
+	req  *transport.StreamRequest
+	span opentracing.Span
+	body *io.PipeWriter
+
+	// ready is closed once response or responseErr has been set.
+	ready       chan struct{}
+	response    *http.Response
+	responseErr error
+
+	mu     sync.Mutex
+	result error
+
+	closed  atomic.Bool
+	release func(error)
+}
+
+func newClientStream(ctx context.Context, req *transport.StreamRequest, body *io.PipeWriter, span opentracing.Span, release func(error)) *clientStream {
+	return &clientStream{
+		ctx:     ctx,
+		req:     req,
+		span:    span,
+		body:    body,
+		ready:   make(chan struct{}),
+		release: release,
+	}
+}
+
+// setResponse records the outcome of the HTTP request carrying the stream.
+func (cs *clientStream) setResponse(res *http.Response, err error) {
+	cs.response = res
+	cs.responseErr = err
+	close(cs.ready)
+}
+
+func (cs *clientStream) Context() context.Context {
+	return cs.ctx
+}
+
+func (cs *clientStream) Request() *transport.StreamRequest {
+	return cs.req
+}
+
+func (cs *clientStream) SendMessage(_ context.Context, m *transport.StreamMessage) error {
+	if cs.closed.Load() { // If the stream is closed, we should not be sending messages on it.
+		return io.EOF
+	}
+	if _, err := writeFrame(cs.body, m.Body); err != nil {
+		return toStreamError(cs.closeWithErr(err))
+	}
+	return nil
+}
+
+func (cs *clientStream) ReceiveMessage(ctx context.Context) (*transport.StreamMessage, error) {
+	res, err := cs.awaitResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.result != nil {
+		return nil, cs.result
+	}
+	msg, err := readFrame(res.Body)
+	if err == nil {
+		return msg, nil
+	}
+	if err == io.EOF {
+		// Trailers are only available once the body has been read in full.
+		err = errorFromTrailer(res.Trailer)
+		if err == nil {
+			cs.finish(res)
+			cs.result = io.EOF
+			_ = cs.closeWithErr(nil)
+			return nil, io.EOF
+		}
+	}
+	cs.finish(res)
+	cs.result = toStreamError(err)
+	return nil, cs.closeWithErr(cs.result)
+}
+
+// finish releases the request and response bodies once the server has
+// ended the stream. There is no point in sending further messages, and
+// ending the request lets the connection be reused.
+func (cs *clientStream) finish(res *http.Response) {
+	_ = res.Body.Close()
+	_ = cs.body.Close()
+}
+
+func (cs *clientStream) Close(context.Context) error {
+	_ = cs.closeWithErr(nil)
+	return cs.body.Close()
+}
+
+func (cs *clientStream) Headers() (transport.Headers, error) {
+	res, err := cs.awaitResponse(cs.ctx)
+	if err != nil {
+		return transport.NewHeaders(), err
+	}
+	return applicationHeaders.FromHTTPHeaders(res.Header, transport.NewHeaders()), nil
+}
+
+func (cs *clientStream) awaitResponse(ctx context.Context) (*http.Response, error) {
+	select {
+	case <-cs.ready:
+	case <-ctx.Done():
+		return nil, yarpcerrors.FromError(ctx.Err())
+	}
+	if cs.responseErr != nil {
+		return nil, cs.closeWithErr(cs.responseErr)
+	}
+	return cs.response, nil
+}
+
+func (cs *clientStream) closeWithErr(err error) error {
+	if !cs.closed.Swap(true) {
+		err = transport.UpdateSpanWithErr(cs.span, err)
+		cs.span.Finish()
+		cs.release(err)
+	}
+	return err
+}
+
+// errorFromTrailer builds the error reported by the server in the trailers
+// of a stream response, or returns nil if the stream ended successfully.
+func errorFromTrailer(trailer http.Header) error {
+	codeText := trailer.Get(ErrorCodeHeader)
+	if codeText == "" {
+		return nil
+	}
+	code := yarpcerrors.CodeUnknown
+	var parsed yarpcerrors.Code
+	if err := parsed.UnmarshalText([]byte(codeText)); err == nil {
+		code = parsed
+	}
+	status := intyarpcerrors.NewWithNamef(code, trailer.Get(ErrorNameHeader), trailer.Get(ErrorMessageHeader))
+	if encoded := trailer.Get(ErrorDetailsHeader); encoded != "" {
+		if details, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			status = status.WithDetails(details)
+		}
+	}
+	return status
+}
+
+func toStreamError(err error) error {
+	if err == nil || err == io.EOF || yarpcerrors.IsStatus(err) {
+		return err
+	}
+	return yarpcerrors.FromError(err)
+}
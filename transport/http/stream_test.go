@@ -0,0 +1,326 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type streamHandlerFunc func(*transport.ServerStream) error
+
+func (f streamHandlerFunc) HandleStream(s *transport.ServerStream) error {
+	return f(s)
+}
+
+func newStreamMessage(s string) *transport.StreamMessage {
+	return &transport.StreamMessage{Body: io.NopCloser(strings.NewReader(s))}
+}
+
+func readStreamMessage(t *testing.T, msg *transport.StreamMessage) string {
+	body, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	require.NoError(t, msg.Body.Close())
+	return string(body)
+}
+
+// startStreamServer starts an HTTP inbound serving the given procedures and
+// returns a running outbound to it.
+func startStreamServer(t *testing.T, procedures ...transport.Procedure) *Outbound {
+	trans := NewTransport()
+	inbound := trans.NewInbound("127.0.0.1:0")
+	inbound.SetRouter(newTestRouter(procedures))
+
+	require.NoError(t, trans.Start())
+	require.NoError(t, inbound.Start())
+	outbound := trans.NewSingleOutbound("http://" + inbound.Addr().String())
+	require.NoError(t, outbound.Start())
+	t.Cleanup(func() {
+		assert.NoError(t, outbound.Stop())
+		assert.NoError(t, inbound.Stop())
+		assert.NoError(t, trans.Stop())
+	})
+	return outbound
+}
+
+func streamRequest(procedure string) *transport.StreamRequest {
+	return &transport.StreamRequest{
+		Meta: &transport.RequestMeta{
+			Caller:    "caller",
+			Service:   "service",
+			Procedure: procedure,
+			Encoding:  "raw",
+		},
+	}
+}
+
+func TestStreamEcho(t *testing.T) {
+	outbound := startStreamServer(t, transport.Procedure{
+		Name: "echo",
+		HandlerSpec: transport.NewStreamHandlerSpec(streamHandlerFunc(func(s *transport.ServerStream) error {
+			if err := s.SendHeaders(transport.NewHeaders().With("foo", "bar")); err != nil {
+				return err
+			}
+			for {
+				msg, err := s.ReceiveMessage(context.Background())
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if err := s.SendMessage(context.Background(), msg); err != nil {
+					return err
+				}
+			}
+		})),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+	stream, err := outbound.CallStream(ctx, streamRequest("echo"))
+	require.NoError(t, err)
+
+	headers, err := stream.Headers()
+	require.NoError(t, err)
+	value, ok := headers.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, "bar", value)
+
+	// Every message must be echoed before the next one is sent, which only
+	// works if the stream is full duplex.
+	for _, want := range []string{"hello", "", "world"} {
+		require.NoError(t, stream.SendMessage(ctx, newStreamMessage(want)))
+		msg, err := stream.ReceiveMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, want, readStreamMessage(t, msg))
+	}
+
+	require.NoError(t, stream.Close(ctx))
+	_, err = stream.ReceiveMessage(ctx)
+	assert.Equal(t, io.EOF, err)
+	_, err = stream.ReceiveMessage(ctx)
+	assert.Equal(t, io.EOF, err, "end of stream must be sticky")
+}
+
+func TestStreamNoDeadline(t *testing.T) {
+	outbound := startStreamServer(t, transport.Procedure{
+		Name: "server-stream",
+		HandlerSpec: transport.NewStreamHandlerSpec(streamHandlerFunc(func(s *transport.ServerStream) error {
+			if _, ok := s.Context().Deadline(); ok {
+				return yarpcerrors.InternalErrorf("unexpected deadline")
+			}
+			for _, m := range []string{"a", "b"} {
+				if err := s.SendMessage(context.Background(), newStreamMessage(m)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})),
+	})
+
+	stream, err := outbound.CallStream(context.Background(), streamRequest("server-stream"))
+	require.NoError(t, err)
+	require.NoError(t, stream.Close(context.Background()))
+
+	var got []string
+	for {
+		msg, err := stream.ReceiveMessage(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, readStreamMessage(t, msg))
+	}
+	assert.Equal(t, []string{"a", "b"}, got)
+}
+
+func TestStreamErrors(t *testing.T) {
+	outbound := startStreamServer(t,
+		transport.Procedure{
+			Name: "fail-midway",
+			HandlerSpec: transport.NewStreamHandlerSpec(streamHandlerFunc(func(s *transport.ServerStream) error {
+				if err := s.SendMessage(context.Background(), newStreamMessage("first")); err != nil {
+					return err
+				}
+				return yarpcerrors.Newf(yarpcerrors.CodeAborted, "stopped midway").
+					WithName("midway").
+					WithDetails([]byte{0x00, 0xff, '\n'})
+			})),
+		},
+		transport.Procedure{
+			Name: "fail-immediately",
+			HandlerSpec: transport.NewStreamHandlerSpec(streamHandlerFunc(func(*transport.ServerStream) error {
+				return yarpcerrors.Newf(yarpcerrors.CodePermissionDenied, "go away")
+			})),
+		},
+		transport.Procedure{
+			Name: "unary",
+			// never called
+			HandlerSpec: transport.NewUnaryHandlerSpec(transporttest.NewMockUnaryHandler(gomock.NewController(t))),
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	t.Run("error after messages", func(t *testing.T) {
+		stream, err := outbound.CallStream(ctx, streamRequest("fail-midway"))
+		require.NoError(t, err)
+
+		msg, err := stream.ReceiveMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "first", readStreamMessage(t, msg))
+
+		_, err = stream.ReceiveMessage(ctx)
+		require.Error(t, err)
+		status := yarpcerrors.FromError(err)
+		assert.Equal(t, yarpcerrors.CodeAborted, status.Code())
+		assert.Equal(t, "midway", status.Name())
+		assert.Equal(t, "stopped midway", status.Message())
+		assert.Equal(t, []byte{0x00, 0xff, '\n'}, status.Details())
+
+		_, err = stream.ReceiveMessage(ctx)
+		assert.Equal(t, yarpcerrors.CodeAborted, yarpcerrors.FromError(err).Code(), "errors must be sticky")
+	})
+
+	t.Run("error before messages", func(t *testing.T) {
+		stream, err := outbound.CallStream(ctx, streamRequest("fail-immediately"))
+		require.NoError(t, err)
+
+		_, err = stream.ReceiveMessage(ctx)
+		require.Error(t, err)
+		assert.Equal(t, yarpcerrors.CodePermissionDenied, yarpcerrors.FromError(err).Code())
+		assert.Equal(t, "go away", yarpcerrors.FromError(err).Message())
+
+		_, err = stream.Headers()
+		assert.Equal(t, yarpcerrors.CodePermissionDenied, yarpcerrors.FromError(err).Code())
+	})
+
+	t.Run("stream to unary procedure", func(t *testing.T) {
+		stream, err := outbound.CallStream(ctx, streamRequest("unary"))
+		require.NoError(t, err)
+
+		_, err = stream.ReceiveMessage(ctx)
+		require.Error(t, err)
+		assert.Equal(t, yarpcerrors.CodeInvalidArgument, yarpcerrors.FromError(err).Code())
+		assert.Contains(t, err.Error(), `procedure "unary" is a unary procedure and cannot be called with a stream`)
+	})
+
+	t.Run("unary to stream procedure", func(t *testing.T) {
+		_, err := outbound.Call(ctx, &transport.Request{
+			Caller:    "caller",
+			Service:   "service",
+			Procedure: "fail-immediately",
+			Encoding:  "raw",
+			Body:      bytes.NewReader(nil),
+		})
+		require.Error(t, err)
+		assert.Equal(t, yarpcerrors.CodeInvalidArgument, yarpcerrors.FromError(err).Code())
+		assert.Contains(t, err.Error(), `procedure "fail-immediately" is a streaming procedure and must be called with a stream`)
+	})
+
+	t.Run("unknown procedure", func(t *testing.T) {
+		stream, err := outbound.CallStream(ctx, streamRequest("unknown"))
+		require.NoError(t, err)
+
+		_, err = stream.ReceiveMessage(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no procedure for name unknown")
+	})
+}
+
+func TestCallStreamInvalidRequest(t *testing.T) {
+	trans := NewTransport()
+	defer trans.Stop()
+	outbound := trans.NewSingleOutbound("http://127.0.0.1:0")
+	require.NoError(t, outbound.Start())
+	defer outbound.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	_, err := outbound.CallStream(ctx, &transport.StreamRequest{})
+	assert.Equal(t, yarpcerrors.CodeInvalidArgument, yarpcerrors.FromError(err).Code())
+
+	_, err = outbound.CallStream(ctx, &transport.StreamRequest{Meta: &transport.RequestMeta{Procedure: "foo"}})
+	assert.Error(t, err)
+}
+
+func TestCallStreamNotRunning(t *testing.T) {
+	outbound := NewTransport().NewSingleOutbound("http://127.0.0.1:0")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := outbound.CallStream(ctx, streamRequest("foo"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error waiting for HTTP outbound to start for service: service")
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		desc    string
+		give    []byte
+		want    string
+		wantErr string
+	}{
+		{desc: "empty", give: nil, wantErr: "EOF"},
+		{desc: "message", give: []byte{0, 0, 0, 2, 'h', 'i'}, want: "hi"},
+		{desc: "truncated header", give: []byte{0, 0}, wantErr: "stream ended in the middle of a message frame"},
+		{desc: "truncated body", give: []byte{0, 0, 0, 3, 'h', 'i'}, wantErr: "stream ended in the middle of a message frame"},
+		{desc: "too large", give: []byte{0xff, 0xff, 0xff, 0xff}, wantErr: "exceeds the maximum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			msg, err := readFrame(bytes.NewReader(tt.give))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.want), msg.BodySize)
+			assert.Equal(t, tt.want, readStreamMessage(t, msg))
+		})
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	var buf bytes.Buffer
+	size, err := writeFrame(&buf, newStreamMessage("hello").Body)
+	require.NoError(t, err)
+	assert.Equal(t, 5, size)
+	assert.Equal(t, []byte{0, 0, 0, 5, 'h', 'e', 'l', 'l', 'o'}, buf.Bytes())
+}
@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/net/metrics"
 	backoffapi "go.uber.org/yarpc/api/backoff"
 	"go.uber.org/yarpc/api/peer"
@@ -36,7 +37,9 @@ import (
 	yarpctls "go.uber.org/yarpc/api/transport/tls"
 	"go.uber.org/yarpc/internal/backoff"
 	"go.uber.org/yarpc/pkg/lifecycle"
+	"go.uber.org/yarpc/yarpcotel"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
 )
 
 type transportOptions struct {
@@ -58,6 +61,7 @@ type transportOptions struct {
 	meter                     *metrics.Scope
 	serviceName               string
 	outboundTLSConfigProvider yarpctls.OutboundTLSConfigProvider
+	h2c                       bool
 }
 
 var defaultTransportOptions = transportOptions{
@@ -143,6 +147,18 @@ func DisableCompression() TransportOption {
 	}
 }
 
+// H2C makes outbounds send requests to http:// URLs over cleartext HTTP/2
+// (h2c) with prior knowledge, multiplexing concurrent requests over a single
+// connection per peer instead of opening a connection for each of them.
+// Requests to https:// URLs, such as those of TLS outbounds, are unaffected.
+//
+// Peers must accept h2c, as HTTP inbounds do with the InboundH2C option.
+func H2C() TransportOption {
+	return func(options *transportOptions) {
+		options.h2c = true
+	}
+}
+
 // ResponseHeaderTimeout if non-zero specifies the amount of time to wait for
 // a server's response headers after fully writing the request (including its
 // body, if any).  This time does not include the time to read the response
@@ -212,6 +228,17 @@ func Tracer(tracer opentracing.Tracer) TransportOption {
 	}
 }
 
+// TracerProvider configures the transport and all its inbounds and outbounds
+// to record spans with the given OpenTelemetry TracerProvider and to
+// propagate them with W3C Trace Context headers.
+//
+// This replaces any tracer configured with Tracer.
+func TracerProvider(provider trace.TracerProvider) TransportOption {
+	return func(options *transportOptions) {
+		options.tracer = yarpcotel.NewTracer(provider)
+	}
+}
+
 // Logger sets a logger to use for internal logging.
 //
 // The default is to not write any logs.
@@ -268,9 +295,15 @@ func (o *transportOptions) newTransport() *Transport {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	client := o.buildClient(o)
+	var h2cTransport *http2.Transport
+	if o.h2c {
+		h2cTransport = registerH2C(client)
+	}
 	return &Transport{
 		once:                     lifecycle.NewOnce(),
-		client:                   o.buildClient(o),
+		client:                   client,
+		h2cTransport:             h2cTransport,
 		connTimeout:              o.connTimeout,
 		connBackoffStrategy:      o.connBackoffStrategy,
 		innocenceWindow:          o.innocenceWindow,
@@ -320,6 +353,9 @@ type Transport struct {
 	client *http.Client
 	peers  map[string]*httpPeer
 
+	// h2cTransport sends the client's plaintext requests if H2C is enabled.
+	h2cTransport *http2.Transport
+
 	connTimeout         time.Duration
 	connBackoffStrategy backoffapi.Strategy
 	connectorsGroup     sync.WaitGroup
@@ -346,6 +382,9 @@ func (a *Transport) Start() error {
 func (a *Transport) Stop() error {
 	return a.once.Stop(func() error {
 		closeIdleConnections(a.client)
+		if a.h2cTransport != nil {
+			a.h2cTransport.CloseIdleConnections()
+		}
 		a.connectorsGroup.Wait()
 		return nil
 	})
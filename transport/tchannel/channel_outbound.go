@@ -112,6 +112,7 @@ func (o *ChannelOutbound) Call(ctx context.Context, req *transport.Request) (*tr
 	if _, ok := ctx.(tchannel.ContextWithHeaders); ok {
 		return nil, errDoNotUseContextWithHeaders
 	}
+	ctx = withParentSpan(ctx, o.transport.tracer)
 
 	// NB(abg): Under the current API, the local service's name is required
 	// twice: once when constructing the TChannel and then again when
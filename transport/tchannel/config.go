@@ -216,7 +216,10 @@ func (ts *transportSpec) buildUnaryOutbound(oc *OutboundConfig, t transport.Tran
 	if err != nil {
 		return nil, err
 	}
-	chooser, err := oc.BuildPeerChooser(pt, hostport.Identify, k)
+	chooser, err := oc.BuildPeerChooser(pt, hostport.Identify, k,
+		yarpcconfig.HealthCheckOutbound(func(c peer.Chooser) transport.UnaryOutbound {
+			return x.NewOutbound(c, WithReuseBuffer(oc.EnableBufferReuse))
+		}))
 	if err != nil {
 		return nil, err
 	}
@@ -194,6 +194,9 @@ func (h handler) callHandler(ctx context.Context, call inboundCall, responseWrit
 	if tcall, ok := call.(tchannelCall); ok {
 		tracer := h.tracer
 		ctx = tchannel.ExtractInboundSpan(ctx, tcall.InboundCall, headers.Items(), tracer)
+		if span := opentracing.SpanFromContext(ctx); span != nil {
+			ctx = transport.ContextWithSpan(ctx, tracer, span)
+		}
 	}
 
 	buf := bufferpool.Get()
@@ -28,10 +28,12 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/tchannel-go"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/net/metrics"
 	backoffapi "go.uber.org/yarpc/api/backoff"
 	yarpctls "go.uber.org/yarpc/api/transport/tls"
 	"go.uber.org/yarpc/internal/backoff"
+	"go.uber.org/yarpc/yarpcotel"
 	"go.uber.org/zap"
 )
 
@@ -95,6 +97,17 @@ func Tracer(tracer opentracing.Tracer) TransportOption {
 	}
 }
 
+// TracerProvider specifies an OpenTelemetry TracerProvider to record spans
+// of RPCs passing through the TChannel transport with. Spans are propagated
+// with W3C Trace Context application headers.
+//
+// This replaces any tracer specified with Tracer.
+func TracerProvider(provider trace.TracerProvider) TransportOption {
+	return func(t *transportOptions) {
+		t.tracer = yarpcotel.NewTracer(provider)
+	}
+}
+
 // Logger sets a logger to use for internal logging.
 //
 // The default is to not write any logs.
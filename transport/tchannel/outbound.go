@@ -26,6 +26,7 @@ import (
 	"io"
 	"strconv"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/uber/tchannel-go"
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
@@ -121,7 +122,20 @@ func (o *Outbound) Call(ctx context.Context, req *transport.Request) (*transport
 
 // Call sends an RPC to this specific peer.
 func (p *tchannelPeer) Call(ctx context.Context, req *transport.Request, reuseBuffer bool) (*transport.Response, error) {
-	return callWithPeer(ctx, req, p.getPeer(), p.transport.headerCase, reuseBuffer)
+	return callWithPeer(withParentSpan(ctx, p.transport.tracer), req, p.getPeer(), p.transport.headerCase, reuseBuffer)
+}
+
+// withParentSpan returns a copy of ctx that makes a span natively tracked by
+// the tracer, like an OpenTelemetry span, the parent of the span TChannel
+// starts for the call.
+func withParentSpan(ctx context.Context, tracer opentracing.Tracer) context.Context {
+	if opentracing.SpanFromContext(ctx) != nil {
+		return ctx
+	}
+	if span := transport.SpanFromContext(ctx, tracer); span != nil {
+		return opentracing.ContextWithSpan(ctx, span)
+	}
+	return ctx
 }
 
 // callWithPeer sends a request with the chosen peer.
@@ -20,5 +20,7 @@
 
 package yarpc // import "go.uber.org/yarpc"
 
+import "go.uber.org/yarpc/internal/version"
+
 // Version is the current version of YARPC.
-const Version = "1.74.0-dev"
+const Version = version.Version
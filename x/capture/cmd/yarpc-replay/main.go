@@ -0,0 +1,146 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Command yarpc-replay replays requests captured by the capture middleware
+// through an outbound declared in a yarpcconfig YAML file, and reports the
+// requests whose outcome differs from the captured outcome.
+//
+//	$ yarpc-replay -dir /var/tmp/capture -config replay.yaml -outbound myservice
+//
+// The configuration may use the HTTP, gRPC and TChannel transports with any
+// of the built-in peer lists.
+//
+//	outbounds:
+//	  myservice:
+//	    grpc:
+//	      address: 127.0.0.1:5435
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/peer/pendingheap"
+	"go.uber.org/yarpc/peer/randpeer"
+	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/peer/tworandomchoices"
+	"go.uber.org/yarpc/transport/grpc"
+	"go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/transport/tchannel"
+	"go.uber.org/yarpc/x/capture"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("yarpc-replay", flag.ContinueOnError)
+	dir := flags.String("dir", "", "directory of the capture log")
+	configFile := flags.String("config", "", "yarpcconfig YAML file declaring the outbound")
+	outbound := flags.String("outbound", "", "name of the outbound to replay requests through")
+	name := flags.String("name", "yarpc-replay", "service name of the replaying dispatcher")
+	rps := flags.Float64("rps", 10, "requests per second, or 0 for no limit")
+	concurrency := flags.Int("concurrency", 10, "maximum number of requests in flight")
+	timeout := flags.Duration("timeout", 5*time.Second, "deadline of every request")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" || *configFile == "" || *outbound == "" {
+		flags.Usage()
+		return errors.New("-dir, -config and -outbound are required")
+	}
+
+	dispatcher, err := newDispatcher(*name, *configFile)
+	if err != nil {
+		return err
+	}
+	if err := dispatcher.Start(); err != nil {
+		return err
+	}
+	defer dispatcher.Stop()
+
+	reader, err := capture.NewReader(*dir, nil)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	report, err := capture.Replay(ctx, reader, dispatcher.ClientConfig(*outbound).GetUnaryOutbound(),
+		capture.Rate(*rps),
+		capture.Concurrency(*concurrency),
+		capture.Timeout(*timeout),
+	)
+	printReport(stdout, report)
+	return err
+}
+
+func newDispatcher(name, configFile string) (*yarpc.Dispatcher, error) {
+	cfg := yarpcconfig.New()
+	cfg.MustRegisterTransport(http.TransportSpec())
+	cfg.MustRegisterTransport(grpc.TransportSpec())
+	cfg.MustRegisterTransport(tchannel.TransportSpec())
+	cfg.MustRegisterPeerList(roundrobin.Spec())
+	cfg.MustRegisterPeerList(randpeer.Spec())
+	cfg.MustRegisterPeerList(pendingheap.Spec())
+	cfg.MustRegisterPeerList(tworandomchoices.Spec())
+
+	file, err := os.Open(configFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config, err := cfg.LoadConfigFromYAML(name, file)
+	if err != nil {
+		return nil, err
+	}
+	return yarpc.NewDispatcher(config), nil
+}
+
+func printReport(w io.Writer, report *capture.Report) {
+	fmt.Fprintf(w, "replayed %d requests, %d matched, %d differed\n",
+		report.Requests, report.Matched, report.Requests-report.Matched)
+	if len(report.Diffs) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nservice\tprocedure\tcaptured\treplayed\tcount")
+	for _, d := range report.Diffs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", d.Service, d.Procedure, d.Captured, d.Replayed, d.Count)
+	}
+	tw.Flush()
+}
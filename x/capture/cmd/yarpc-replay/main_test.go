@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/x/capture"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	// Capture requests to a server.
+	capturer, err := capture.NewInboundMiddleware(filepath.Join(dir, "capture"))
+	require.NoError(t, err)
+
+	inbound := http.NewTransport().NewInbound("127.0.0.1:0")
+	failing := atomic.NewBool(false)
+	server := yarpc.NewDispatcher(yarpc.Config{
+		Name:     "server",
+		Inbounds: yarpc.Inbounds{inbound},
+		InboundMiddleware: yarpc.InboundMiddleware{
+			Unary: capturer,
+		},
+	})
+	server.Register(raw.Procedure("echo", func(_ context.Context, body []byte) ([]byte, error) {
+		if failing.Load() && string(body) == "flaky" {
+			return nil, yarpcerrors.UnavailableErrorf("unavailable")
+		}
+		return body, nil
+	}))
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	outbound := http.NewTransport().NewSingleOutbound(fmt.Sprintf("http://%v", inbound.Addr()))
+	client := yarpc.NewDispatcher(yarpc.Config{
+		Name:      "client",
+		Outbounds: yarpc.Outbounds{"server": {Unary: outbound}},
+	})
+	require.NoError(t, client.Start())
+	defer client.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, body := range []string{"hello", "flaky"} {
+		_, err := raw.New(client.ClientConfig("server")).Call(ctx, "echo", []byte(body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, capturer.Close())
+
+	// Replay them after the server changed.
+	failing.Store(true)
+	configFile := filepath.Join(dir, "replay.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(fmt.Sprintf(`
+outbounds:
+  server:
+    http:
+      url: http://%v
+`, inbound.Addr())), 0644))
+
+	var stdout bytes.Buffer
+	require.NoError(t, run([]string{
+		"-dir", filepath.Join(dir, "capture"),
+		"-config", configFile,
+		"-outbound", "server",
+		"-rps", "0",
+	}, &stdout))
+	assert.Contains(t, stdout.String(), "replayed 2 requests, 1 matched, 1 differed")
+	assert.Regexp(t, `server\s+echo\s+ok\s+unavailable\s+1`, stdout.String())
+}
+
+func TestRunMissingFlags(t *testing.T) {
+	var stdout bytes.Buffer
+	err := run([]string{"-dir", t.TempDir()}, &stdout)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "required")
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package capture records samples of live traffic to disk and replays them
+// against another build of a service.
+//
+// InboundMiddleware samples unary requests to a rotating log in a directory,
+// along with the outcome of handling them, redacting sensitive headers.
+//
+//	capturer, err := capture.NewInboundMiddleware("/var/tmp/capture",
+//		capture.SampleRate(0.01),
+//		capture.RedactHeaders("authorization", "x-api-key"),
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer capturer.Close()
+//	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+//		Name: "myservice",
+//		InboundMiddleware: yarpc.InboundMiddleware{
+//			Unary: capturer,
+//		},
+//	})
+//
+// Replay re-sends the captured requests through an outbound at a controlled
+// rate and reports the requests whose outcome differs from the captured
+// outcome.
+// The yarpc-replay command replays a capture directory through an outbound
+// declared in a yarpcconfig YAML file:
+//
+//	$ go run go.uber.org/yarpc/x/capture/cmd/yarpc-replay \
+//		-dir /var/tmp/capture -config replay.yaml -outbound myservice -rps 50
+//
+// The log is a sequence of files named capture-NNNNNNNNNN.log, in the order
+// of their numbers.
+// Every entry is a 4-byte big-endian length followed by that many bytes: the
+// yarpcerrors code of the outcome, a byte of flags (1 for application
+// errors), and the request and its span context serialized with
+// serialize.ToBytes.
+package capture
@@ -0,0 +1,326 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/serialize"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// RedactedValue replaces the values of redacted headers in the log.
+const RedactedValue = "REDACTED"
+
+const (
+	_filePrefix = "capture-"
+	_fileSuffix = ".log"
+
+	_flagApplicationError = 1 << 0
+
+	// _entryHeaderSize is the size of the code and flags preceding the
+	// serialized request in an entry.
+	_entryHeaderSize = 2
+
+	// _maxEntrySize bounds the size of entries so that a corrupt length
+	// prefix cannot make readers allocate unbounded memory.
+	_maxEntrySize = 64 * 1024 * 1024
+)
+
+// Record is a captured request with the outcome of handling it.
+type Record struct {
+	// SpanContext is the span context of the request, or nil if it had none.
+	SpanContext opentracing.SpanContext
+	Request     *transport.Request
+	// Code is the code of the error returned by the handler, or CodeOK.
+	Code yarpcerrors.Code
+	// ApplicationError indicates that the handler responded with an
+	// application error.
+	ApplicationError bool
+}
+
+// outcome names the outcome of a request for reports.
+func outcome(code yarpcerrors.Code, applicationError bool) string {
+	if code == yarpcerrors.CodeOK && applicationError {
+		return "application-error"
+	}
+	return code.String()
+}
+
+func fileName(index int) string {
+	return fmt.Sprintf("%s%010d%s", _filePrefix, index, _fileSuffix)
+}
+
+// logFiles returns the indexes of the log files in a directory, in order.
+func logFiles(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []int
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, _filePrefix) || !strings.HasSuffix(name, _fileSuffix) {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, _filePrefix), _fileSuffix))
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// logWriter appends entries to the log files of a directory, starting a new
+// file when the current file reaches its maximum size.
+type logWriter struct {
+	dir         string
+	maxFileSize int64
+	maxFiles    int
+
+	mu    sync.Mutex
+	file  *os.File
+	index int
+	size  int64
+}
+
+// openLog opens the log in the given directory, starting a file after any
+// existing files.
+func openLog(dir string, maxFileSize int64, maxFiles int) (*logWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	indexes, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	w := &logWriter{
+		dir:         dir,
+		maxFileSize: maxFileSize,
+		maxFiles:    maxFiles,
+	}
+	if len(indexes) > 0 {
+		w.index = indexes[len(indexes)-1]
+	}
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *logWriter) write(code yarpcerrors.Code, applicationError bool, request []byte) error {
+	if size := _entryHeaderSize + len(request); size > _maxEntrySize {
+		return fmt.Errorf("entry of %d bytes exceeds the maximum of %d bytes", size, _maxEntrySize)
+	}
+	entry := make([]byte, 4+_entryHeaderSize, 4+_entryHeaderSize+len(request))
+	binary.BigEndian.PutUint32(entry, uint32(_entryHeaderSize+len(request)))
+	entry[4] = byte(code)
+	if applicationError {
+		entry[5] |= _flagApplicationError
+	}
+	entry = append(entry, request...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(entry)) > w.maxFileSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := w.file.Write(entry)
+	w.size += int64(n)
+	return err
+}
+
+// rotate closes the current file, if any, and starts the next, deleting the
+// oldest files beyond the maximum number of files.
+//
+// rotate must be called with the writer locked.
+func (w *logWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+
+	w.index++
+	file, err := os.OpenFile(filepath.Join(w.dir, fileName(w.index)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.size = 0
+
+	if w.maxFiles <= 0 {
+		return nil
+	}
+	indexes, err := logFiles(w.dir)
+	if err != nil {
+		return err
+	}
+	for len(indexes) > w.maxFiles {
+		if err := os.Remove(filepath.Join(w.dir, fileName(indexes[0]))); err != nil {
+			return err
+		}
+		indexes = indexes[1:]
+	}
+	return nil
+}
+
+func (w *logWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// Reader reads the records of a capture log, from the oldest to the newest.
+type Reader struct {
+	tracer opentracing.Tracer
+	dir    string
+	files  []int
+
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// NewReader opens the capture log in the given directory.
+//
+// The tracer deserializes the span context of requests, and defaults to
+// opentracing.GlobalTracer() if nil.
+// The reader only reads the log files present when it opens.
+func NewReader(dir string, tracer opentracing.Tracer) (*Reader, error) {
+	files, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if tracer == nil {
+		tracer = opentracing.GlobalTracer()
+	}
+	return &Reader{
+		tracer: tracer,
+		dir:    dir,
+		files:  files,
+	}, nil
+}
+
+// Next returns the next record of the log, or io.EOF after the last record.
+func (r *Reader) Next() (*Record, error) {
+	for {
+		if r.reader == nil {
+			if len(r.files) == 0 {
+				return nil, io.EOF
+			}
+			file, err := os.Open(filepath.Join(r.dir, fileName(r.files[0])))
+			if err != nil {
+				return nil, err
+			}
+			r.files = r.files[1:]
+			r.file = file
+			r.reader = bufio.NewReader(file)
+		}
+
+		record, err := r.next()
+		if err == io.EOF {
+			if err := r.closeFile(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %v: %v", r.file.Name(), err)
+		}
+		return record, nil
+	}
+}
+
+func (r *Reader) next() (*Record, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r.reader, size[:]); err != nil {
+		// io.ReadFull returns io.EOF only if no bytes were read, at the end of
+		// the last complete entry.
+		return nil, err
+	}
+	entrySize := binary.BigEndian.Uint32(size[:])
+	if entrySize > _maxEntrySize {
+		return nil, fmt.Errorf("entry of %d bytes exceeds the maximum of %d bytes", entrySize, _maxEntrySize)
+	}
+	entry := make([]byte, entrySize)
+	if _, err := io.ReadFull(r.reader, entry); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if len(entry) < _entryHeaderSize {
+		return nil, fmt.Errorf("entry of %d bytes is too short", len(entry))
+	}
+
+	spanContext, request, err := serialize.FromBytes(r.tracer, entry[_entryHeaderSize:])
+	if err != nil {
+		return nil, err
+	}
+	return &Record{
+		SpanContext:      spanContext,
+		Request:          request,
+		Code:             yarpcerrors.Code(entry[0]),
+		ApplicationError: entry[1]&_flagApplicationError != 0,
+	}, nil
+}
+
+func (r *Reader) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	r.reader = nil
+	return err
+}
+
+// Close closes the log file being read.
+func (r *Reader) Close() error {
+	r.files = nil
+	return r.closeFile()
+}
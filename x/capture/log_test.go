@@ -0,0 +1,127 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package capture
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func TestLogRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	// Every entry exceeds the maximum file size, so every entry starts a new
+	// file.
+	w, err := openLog(dir, 1, 3)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.write(yarpcerrors.CodeOK, false, []byte("entry")))
+	}
+	require.NoError(t, w.close())
+	assert.Error(t, w.write(yarpcerrors.CodeOK, false, []byte("entry")), "must not write after close")
+
+	indexes, err := logFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 4, 5}, indexes, "must keep the newest files")
+
+	// Reopening the log continues after the existing files.
+	w, err = openLog(dir, 1, 0)
+	require.NoError(t, err)
+	require.NoError(t, w.close())
+	indexes, err = logFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 4, 5, 6}, indexes)
+}
+
+func TestLogFilesIgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"capture-0000000002.log", "capture-0000000010.log", "capture-x.log", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "capture-0000000003.log"), 0755))
+
+	indexes, err := logFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 10}, indexes)
+}
+
+func TestReaderTruncatedEntry(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewInboundMiddleware(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.Handle(context.Background(), newRequest("ok", "hello"), &transporttest.FakeResponseWriter{}, echo))
+	require.NoError(t, m.Close())
+
+	// Cut the last entry short, as if the process died while writing it.
+	path := filepath.Join(dir, fileName(1))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content[:len(content)-1], 0644))
+
+	r, err := NewReader(dir, nil)
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = r.Next()
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "unexpected EOF"), "unexpected error: %v", err)
+}
+
+func TestReaderOversizedEntry(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewInboundMiddleware(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.Handle(context.Background(), newRequest("ok", "hello"), &transporttest.FakeResponseWriter{}, echo))
+	require.NoError(t, m.Close())
+
+	// Corrupt the length prefix of the entry.
+	path := filepath.Join(dir, fileName(1))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	binary.BigEndian.PutUint32(content, math.MaxUint32)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	r, err := NewReader(dir, nil)
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = r.Next()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "entry of 4294967295 bytes exceeds the maximum of 67108864 bytes")
+}
+
+func TestWriterOversizedEntry(t *testing.T) {
+	w, err := openLog(t.TempDir(), _maxEntrySize*2, 1)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, w.close()) }()
+
+	err = w.write(yarpcerrors.CodeOK, false, make([]byte, _maxEntrySize))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum of 67108864 bytes")
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/serialize"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+var (
+	_ middleware.UnaryInbound = (*InboundMiddleware)(nil)
+
+	_randFloat64 = rand.Float64 // for tests
+)
+
+// InboundMiddleware is unary inbound middleware that captures a sample of
+// requests and their outcomes to a rotating log.
+//
+// Failures to capture a request are logged and never fail the request.
+type InboundMiddleware struct {
+	opts options
+	log  *logWriter
+}
+
+// NewInboundMiddleware creates a new capture middleware that writes to log
+// files in the given directory, creating the directory if necessary.
+//
+// The middleware starts a new file after any files already in the
+// directory. Close the middleware to close the current file.
+func NewInboundMiddleware(dir string, opts ...Option) (*InboundMiddleware, error) {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	log, err := openLog(dir, options.maxFileSize, options.maxFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &InboundMiddleware{
+		opts: options,
+		log:  log,
+	}, nil
+}
+
+// Handle implements middleware.UnaryInbound.
+func (m *InboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	if m.opts.sampleRate <= 0 || _randFloat64() >= m.opts.sampleRate {
+		return h.Handle(ctx, req, resw)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body = bytes.NewReader(body)
+
+	captured := *req
+	captured.Headers = m.redact(req.Headers)
+	captured.Body = bytes.NewReader(body)
+
+	w := &writer{ResponseWriter: resw}
+	err = h.Handle(ctx, req, w)
+	m.capture(ctx, &captured, yarpcerrors.FromError(err).Code(), w.isApplicationError)
+	return err
+}
+
+// Close closes the current log file.
+// The middleware captures no requests after it is closed.
+func (m *InboundMiddleware) Close() error {
+	return m.log.close()
+}
+
+func (m *InboundMiddleware) capture(ctx context.Context, req *transport.Request, code yarpcerrors.Code, applicationError bool) {
+	var (
+		tracer      opentracing.Tracer = opentracing.NoopTracer{}
+		spanContext opentracing.SpanContext
+	)
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		tracer, spanContext = span.Tracer(), span.Context()
+	}
+
+	entry, err := serialize.ToBytes(tracer, spanContext, req)
+	if err != nil && spanContext != nil {
+		// Tracers need not support the binary format. Capture the request
+		// without its span context rather than not at all.
+		entry, err = serialize.ToBytes(opentracing.NoopTracer{}, nil, req)
+	}
+	if err == nil {
+		err = m.log.write(code, applicationError, entry)
+	}
+	if err != nil {
+		m.opts.logger.Warn("failed to capture request",
+			zap.String("service", req.Service),
+			zap.String("procedure", req.Procedure),
+			zap.Error(err))
+	}
+}
+
+// redact returns a copy of the headers with the values of redacted headers
+// replaced.
+func (m *InboundMiddleware) redact(headers transport.Headers) transport.Headers {
+	redacted := transport.NewHeadersWithCapacity(headers.Len())
+	for k, v := range headers.Items() {
+		if m.redacts(k) {
+			v = RedactedValue
+		}
+		redacted = redacted.With(k, v)
+	}
+	return redacted
+}
+
+func (m *InboundMiddleware) redacts(key string) bool {
+	if _, ok := m.opts.redactedHeaders[key]; ok {
+		return true
+	}
+	for _, prefix := range m.opts.redactedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writer records whether the handler responded with an application error.
+type writer struct {
+	transport.ResponseWriter
+
+	isApplicationError bool
+}
+
+func (w *writer) SetApplicationError() {
+	w.isApplicationError = true
+	w.ResponseWriter.SetApplicationError()
+}
+
+func (w *writer) SetApplicationErrorMeta(applicationErrorMeta *transport.ApplicationErrorMeta) {
+	if setter, ok := w.ResponseWriter.(transport.ApplicationErrorMetaSetter); ok {
+		setter.SetApplicationErrorMeta(applicationErrorMeta)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type handlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f handlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+func newRequest(procedure, body string) *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "raw",
+		Procedure: procedure,
+		Headers: transport.NewHeaders().
+			With("Authorization", "Bearer secret").
+			With("x-secret-token", "token").
+			With("x-user", "alice"),
+		ShardKey: "shard",
+		Body:     bytes.NewReader([]byte(body)),
+	}
+}
+
+// echo responds with the request body, failing requests to "fail" and
+// responding to "app-error" with an application error.
+var echo = handlerFunc(func(_ context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	switch req.Procedure {
+	case "fail":
+		return yarpcerrors.InternalErrorf("failed")
+	case "app-error":
+		resw.SetApplicationError()
+	}
+	_, err = resw.Write(body)
+	return err
+})
+
+func readAll(t *testing.T, dir string) []*Record {
+	r, err := NewReader(dir, nil)
+	require.NoError(t, err)
+	defer r.Close()
+
+	var records []*Record
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, record)
+	}
+}
+
+func TestInboundMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewInboundMiddleware(dir,
+		RedactHeaders("AUTHORIZATION"),
+		RedactHeaderPrefixes("X-Secret-"),
+	)
+	require.NoError(t, err)
+
+	for _, procedure := range []string{"ok", "fail", "app-error"} {
+		resw := &transporttest.FakeResponseWriter{}
+		err := m.Handle(context.Background(), newRequest(procedure, "hello "+procedure), resw, echo)
+		if procedure == "fail" {
+			assert.Error(t, err)
+		} else {
+			require.NoError(t, err)
+			assert.Equal(t, "hello "+procedure, resw.Body.String(), "the handler must read the whole body")
+		}
+	}
+	require.NoError(t, m.Close())
+
+	records := readAll(t, dir)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, yarpcerrors.CodeOK, records[0].Code)
+	assert.False(t, records[0].ApplicationError)
+	assert.Equal(t, yarpcerrors.CodeInternal, records[1].Code)
+	assert.Equal(t, yarpcerrors.CodeOK, records[2].Code)
+	assert.True(t, records[2].ApplicationError)
+
+	req := records[0].Request
+	assert.Equal(t, "caller", req.Caller)
+	assert.Equal(t, "service", req.Service)
+	assert.Equal(t, transport.Encoding("raw"), req.Encoding)
+	assert.Equal(t, "ok", req.Procedure)
+	assert.Equal(t, "shard", req.ShardKey)
+	assert.Equal(t, map[string]string{
+		"authorization":  RedactedValue,
+		"x-secret-token": RedactedValue,
+		"x-user":         "alice",
+	}, req.Headers.Items())
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello ok", string(body))
+	assert.Nil(t, records[0].SpanContext)
+}
+
+func TestInboundMiddlewareSampleRate(t *testing.T) {
+	defer func(f func() float64) { _randFloat64 = f }(_randFloat64)
+	samples := []float64{0.2, 0.7, 0.5, 0.1}
+	_randFloat64 = func() float64 {
+		s := samples[0]
+		samples = samples[1:]
+		return s
+	}
+
+	dir := t.TempDir()
+	m, err := NewInboundMiddleware(dir, SampleRate(0.5))
+	require.NoError(t, err)
+	defer m.Close()
+
+	for _, procedure := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, m.Handle(context.Background(), newRequest(procedure, ""), &transporttest.FakeResponseWriter{}, echo))
+	}
+
+	var procedures []string
+	for _, record := range readAll(t, dir) {
+		procedures = append(procedures, record.Request.Procedure)
+	}
+	assert.Equal(t, []string{"a", "d"}, procedures)
+}
+
+func TestInboundMiddlewareSpanContext(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewInboundMiddleware(dir)
+	require.NoError(t, err)
+	defer m.Close()
+
+	// The mock tracer does not support the binary format, so the request is
+	// captured without its span context.
+	span := mocktracer.New().StartSpan("test")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	require.NoError(t, m.Handle(ctx, newRequest("ok", "hello"), &transporttest.FakeResponseWriter{}, echo))
+
+	records := readAll(t, dir)
+	require.Len(t, records, 1)
+	assert.Nil(t, records[0].SpanContext)
+	assert.Equal(t, "ok", records[0].Request.Procedure)
+}
+
+func TestInboundMiddlewareClosed(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewInboundMiddleware(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+	require.NoError(t, m.Close())
+
+	resw := &transporttest.FakeResponseWriter{}
+	require.NoError(t, m.Handle(context.Background(), newRequest("ok", "hello"), resw, echo))
+	assert.Equal(t, "hello", resw.Body.String(), "requests must succeed after close")
+	assert.Empty(t, readAll(t, dir))
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package capture
+
+import (
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/zap"
+)
+
+const (
+	_defaultMaxFileSize = 64 << 20 // 64 MiB
+	_defaultMaxFiles    = 10
+)
+
+type options struct {
+	sampleRate       float64
+	redactedHeaders  map[string]struct{}
+	redactedPrefixes []string
+	maxFileSize      int64
+	maxFiles         int
+	logger           *zap.Logger
+}
+
+func newOptions() options {
+	return options{
+		sampleRate:      1,
+		redactedHeaders: make(map[string]struct{}),
+		maxFileSize:     _defaultMaxFileSize,
+		maxFiles:        _defaultMaxFiles,
+		logger:          zap.NewNop(),
+	}
+}
+
+// Option customizes the behavior of the capture middleware.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(opts *options) { f(opts) }
+
+// SampleRate sets the fraction of requests to capture, between 0 and 1.
+//
+// Defaults to 1, capturing every request.
+func SampleRate(rate float64) Option {
+	return optionFunc(func(opts *options) {
+		opts.sampleRate = rate
+	})
+}
+
+// RedactHeaders replaces the values of the given request headers with
+// RedactedValue in the log.
+// Header names are case-insensitive.
+func RedactHeaders(keys ...string) Option {
+	return optionFunc(func(opts *options) {
+		for _, k := range keys {
+			opts.redactedHeaders[transport.CanonicalizeHeaderKey(k)] = struct{}{}
+		}
+	})
+}
+
+// RedactHeaderPrefixes replaces the values of request headers starting with
+// any of the given prefixes with RedactedValue in the log.
+// Prefixes are case-insensitive.
+func RedactHeaderPrefixes(prefixes ...string) Option {
+	return optionFunc(func(opts *options) {
+		for _, p := range prefixes {
+			opts.redactedPrefixes = append(opts.redactedPrefixes, transport.CanonicalizeHeaderKey(p))
+		}
+	})
+}
+
+// MaxFileSize sets the size in bytes beyond which the middleware starts a
+// new log file.
+//
+// Defaults to 64 MiB.
+func MaxFileSize(size int64) Option {
+	return optionFunc(func(opts *options) {
+		opts.maxFileSize = size
+	})
+}
+
+// MaxFiles sets the number of log files to keep, deleting the oldest files
+// when starting new ones.
+// Zero keeps every file.
+//
+// Defaults to 10.
+func MaxFiles(n int) Option {
+	return optionFunc(func(opts *options) {
+		opts.maxFiles = n
+	})
+}
+
+// Logger sets a logger for the middleware, which logs requests that it
+// fails to capture.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(opts *options) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package capture
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type replayOptions struct {
+	rate        float64
+	concurrency int
+	timeout     time.Duration
+}
+
+// ReplayOption customizes the behavior of Replay.
+type ReplayOption interface {
+	applyReplay(*replayOptions)
+}
+
+type replayOptionFunc func(*replayOptions)
+
+func (f replayOptionFunc) applyReplay(opts *replayOptions) { f(opts) }
+
+// Rate sets the number of requests to send per second.
+// Zero or less sends requests as fast as the concurrency allows.
+//
+// Defaults to 10.
+func Rate(rps float64) ReplayOption {
+	return replayOptionFunc(func(opts *replayOptions) {
+		opts.rate = rps
+	})
+}
+
+// Concurrency sets the maximum number of requests in flight.
+//
+// Defaults to 10.
+func Concurrency(n int) ReplayOption {
+	return replayOptionFunc(func(opts *replayOptions) {
+		opts.concurrency = n
+	})
+}
+
+// Timeout sets the deadline of every replayed request.
+//
+// Defaults to 5 seconds.
+func Timeout(timeout time.Duration) ReplayOption {
+	return replayOptionFunc(func(opts *replayOptions) {
+		opts.timeout = timeout
+	})
+}
+
+// Report summarizes a replay.
+type Report struct {
+	// Requests is the number of requests replayed.
+	Requests int
+	// Matched is the number of requests whose outcome matched the captured
+	// outcome.
+	Matched int
+	// Diffs counts the requests whose outcome differed from the captured
+	// outcome, by procedure and outcomes, sorted.
+	Diffs []Diff
+}
+
+// Diff counts the replayed requests to a procedure with the same captured
+// outcome and a different replayed outcome.
+//
+// Outcomes are the names of yarpcerrors codes, like "ok" or "unavailable",
+// or "application-error".
+type Diff struct {
+	Service   string
+	Procedure string
+	Captured  string
+	Replayed  string
+	Count     int
+}
+
+type diffKey struct {
+	service, procedure, captured, replayed string
+}
+
+// Replay sends the requests of a capture log through a unary outbound,
+// comparing the outcome of every request with its captured outcome.
+//
+// The outbound must be started.
+// Replay stops at the end of the log, on the first error reading the log, or
+// when the context ends, and returns the report for the requests replayed so
+// far along with any error.
+func Replay(ctx context.Context, r *Reader, out transport.UnaryOutbound, opts ...ReplayOption) (*Report, error) {
+	options := replayOptions{
+		rate:        10,
+		concurrency: 10,
+		timeout:     5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt.applyReplay(&options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+
+	var tick <-chan time.Time
+	if options.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / options.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var (
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, options.concurrency)
+		mu     sync.Mutex
+		report Report
+		diffs  = make(map[diffKey]int)
+		err    error
+	)
+	for err == nil {
+		var record *Record
+		record, err = r.Next()
+		if err != nil {
+			break
+		}
+		if tick != nil && report.Requests > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				err = ctx.Err()
+				continue
+			}
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+			continue
+		}
+
+		report.Requests++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			replayed := replay(ctx, record.Request, out, options.timeout)
+			captured := outcome(record.Code, record.ApplicationError)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if replayed == captured {
+				report.Matched++
+				return
+			}
+			diffs[diffKey{
+				service:   record.Request.Service,
+				procedure: record.Request.Procedure,
+				captured:  captured,
+				replayed:  replayed,
+			}]++
+		}()
+	}
+	wg.Wait()
+
+	for k, count := range diffs {
+		report.Diffs = append(report.Diffs, Diff{
+			Service:   k.service,
+			Procedure: k.procedure,
+			Captured:  k.captured,
+			Replayed:  k.replayed,
+			Count:     count,
+		})
+	}
+	sort.Slice(report.Diffs, func(i, j int) bool {
+		a, b := report.Diffs[i], report.Diffs[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Procedure != b.Procedure {
+			return a.Procedure < b.Procedure
+		}
+		if a.Captured != b.Captured {
+			return a.Captured < b.Captured
+		}
+		return a.Replayed < b.Replayed
+	})
+
+	if err == io.EOF {
+		err = nil
+	}
+	return &report, err
+}
+
+// replay sends a request and returns its outcome.
+func replay(ctx context.Context, req *transport.Request, out transport.UnaryOutbound, timeout time.Duration) string {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res, err := out.Call(ctx, req)
+	applicationError := false
+	if res != nil {
+		applicationError = res.ApplicationError
+		if res.Body != nil {
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+	}
+	return outcome(yarpcerrors.FromError(err).Code(), applicationError)
+}
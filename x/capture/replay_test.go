@@ -0,0 +1,150 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// captureRequests captures a request to each of the given procedures,
+// redacting the authorization header.
+func captureRequests(t *testing.T, dir string, procedures ...string) {
+	m, err := NewInboundMiddleware(dir, RedactHeaders("authorization"))
+	require.NoError(t, err)
+	defer m.Close()
+
+	for _, procedure := range procedures {
+		m.Handle(context.Background(), newRequest(procedure, "hello"), &transporttest.FakeResponseWriter{}, echo)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	captureRequests(t, dir, "ok", "ok", "fail", "app-error", "changed", "changed")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		mu      sync.Mutex
+		headers []map[string]string
+	)
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Times(6).DoAndReturn(
+		func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok, "replayed requests must have a deadline")
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(body))
+
+			mu.Lock()
+			headers = append(headers, req.Headers.Items())
+			mu.Unlock()
+
+			switch req.Procedure {
+			case "fail":
+				return nil, yarpcerrors.InternalErrorf("failed")
+			case "app-error":
+				return &transport.Response{ApplicationError: true, Body: io.NopCloser(&bytes.Buffer{})}, nil
+			case "changed":
+				return nil, yarpcerrors.UnavailableErrorf("unavailable")
+			}
+			return &transport.Response{Body: io.NopCloser(&bytes.Buffer{})}, nil
+		})
+
+	r, err := NewReader(dir, nil)
+	require.NoError(t, err)
+	defer r.Close()
+
+	report, err := Replay(context.Background(), r, out, Rate(0), Concurrency(3))
+	require.NoError(t, err)
+	assert.Equal(t, &Report{
+		Requests: 6,
+		Matched:  4,
+		Diffs: []Diff{{
+			Service:   "service",
+			Procedure: "changed",
+			Captured:  "ok",
+			Replayed:  "unavailable",
+			Count:     2,
+		}},
+	}, report)
+
+	for _, h := range headers {
+		assert.Equal(t, RedactedValue, h["authorization"], "must replay redacted headers")
+	}
+}
+
+func TestReplayRate(t *testing.T) {
+	dir := t.TempDir()
+	captureRequests(t, dir, "ok", "ok", "ok")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Times(3).Return(&transport.Response{}, nil)
+
+	r, err := NewReader(dir, nil)
+	require.NoError(t, err)
+	defer r.Close()
+
+	start := time.Now()
+	report, err := Replay(context.Background(), r, out, Rate(50))
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Matched)
+	assert.True(t, time.Since(start) >= 40*time.Millisecond, "must send requests at the given rate")
+}
+
+func TestReplayCanceled(t *testing.T) {
+	dir := t.TempDir()
+	captureRequests(t, dir, "ok", "ok")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil)
+
+	r, err := NewReader(dir, nil)
+	require.NoError(t, err)
+	defer r.Close()
+
+	// The first request goes out immediately, and the second waits for the
+	// rate limit until the context ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report, err := Replay(ctx, r, out, Rate(0.1))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, report.Requests)
+	assert.Equal(t, 1, report.Matched)
+}
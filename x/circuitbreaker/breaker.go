@@ -0,0 +1,196 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// _windowBuckets is the number of buckets into which the sliding window is
+// divided.
+const _windowBuckets = 10
+
+// breaker is the circuit breaker for a single service and procedure.
+type breaker struct {
+	opts *options
+
+	// onTransition is called with the lock held whenever the breaker changes
+	// state.
+	onTransition func(from, to State)
+
+	mu    sync.Mutex
+	state State
+
+	// generation changes on every transition, so that requests that were
+	// let through in an earlier state do not count toward the current one.
+	generation uint64
+
+	// closed state
+	window              *window
+	consecutiveFailures uint
+
+	// open state
+	openedAt time.Time
+
+	// half-open state
+	probes         uint
+	probeSuccesses uint
+}
+
+func newBreaker(opts *options, onTransition func(from, to State)) *breaker {
+	return &breaker{
+		opts:         opts,
+		onTransition: onTransition,
+		window:       newWindow(opts.window, _windowBuckets),
+	}
+}
+
+// allow reports whether a request may be sent at the given time.
+// If it may, allow returns a generation that must be passed to record once
+// the request completes.
+func (b *breaker) allow(now time.Time) (generation uint64, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		return b.generation, true
+
+	case StateOpen:
+		if now.Sub(b.openedAt) < b.opts.openTimeout {
+			return 0, false
+		}
+		b.transition(StateHalfOpen, now)
+	}
+
+	// Half-open
+	if b.probes >= b.opts.halfOpenRequests {
+		return 0, false
+	}
+	b.probes++
+	return b.generation, true
+}
+
+// record records the outcome of a request that allow let through.
+//
+// A request is abandoned if the caller gave up on it before it completed.
+// Abandoned requests count as failures in the closed state only if failed
+// is set, and never settle a half-open probe; they only release its slot.
+func (b *breaker) record(generation uint64, now time.Time, failed, abandoned bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		// The breaker changed state while the request was in flight.
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		b.window.record(now, failed)
+		if !failed {
+			b.consecutiveFailures = 0
+			return
+		}
+		b.consecutiveFailures++
+		if b.shouldTrip(now) {
+			b.transition(StateOpen, now)
+		}
+
+	case StateHalfOpen:
+		b.probes--
+		if abandoned {
+			// The probe tells us nothing about whether the procedure has
+			// recovered, so let another request probe it instead.
+			return
+		}
+		if failed {
+			b.transition(StateOpen, now)
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.opts.halfOpenRequests {
+			b.transition(StateClosed, now)
+		}
+	}
+}
+
+// shouldTrip returns whether a closed breaker has seen enough failures to
+// open.
+func (b *breaker) shouldTrip(now time.Time) bool {
+	if n := b.opts.consecutiveFailures; n > 0 && b.consecutiveFailures >= n {
+		return true
+	}
+
+	if b.opts.failureRate <= 0 {
+		return false
+	}
+	requests, failures := b.window.totals(now)
+	if requests == 0 || requests < b.opts.minRequests {
+		return false
+	}
+	return float64(failures)/float64(requests) >= b.opts.failureRate
+}
+
+func (b *breaker) transition(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.generation++
+
+	b.window.reset()
+	b.consecutiveFailures = 0
+	b.probes = 0
+	b.probeSuccesses = 0
+	if to == StateOpen {
+		b.openedAt = now
+	}
+
+	if b.onTransition != nil {
+		b.onTransition(from, to)
+	}
+}
+
+// status returns the state of the breaker and a human-readable description
+// of its counters.
+func (b *breaker) status(now time.Time) (State, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		remaining := b.opts.openTimeout - now.Sub(b.openedAt)
+		if remaining < 0 {
+			remaining = 0
+		}
+		return b.state, fmt.Sprintf("half-open in %v", remaining)
+
+	case StateHalfOpen:
+		return b.state, fmt.Sprintf("probes: %d in flight, %d of %d succeeded",
+			b.probes, b.probeSuccesses, b.opts.halfOpenRequests)
+
+	default:
+		requests, failures := b.window.totals(now)
+		return b.state, fmt.Sprintf("window: %d of %d requests failed; %d consecutive failures",
+			failures, requests, b.consecutiveFailures)
+	}
+}
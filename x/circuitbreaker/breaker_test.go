@@ -0,0 +1,213 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBreaker(opts ...Option) (*breaker, *[]State) {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	var transitions []State
+	b := newBreaker(&options, func(_, to State) {
+		transitions = append(transitions, to)
+	})
+	return b, &transitions
+}
+
+func TestBreakerConsecutiveFailures(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, transitions := newTestBreaker(ConsecutiveFailures(3), FailureRate(0), OpenTimeout(time.Second))
+
+	send := func(failed bool) bool {
+		gen, ok := b.allow(now)
+		if ok {
+			b.record(gen, now, failed, false)
+		}
+		return ok
+	}
+
+	require.True(t, send(true))
+	require.True(t, send(true))
+	require.True(t, send(false), "success resets consecutive failures")
+	require.True(t, send(true))
+	require.True(t, send(true))
+	assert.Empty(t, *transitions)
+
+	require.True(t, send(true))
+	assert.Equal(t, []State{StateOpen}, *transitions)
+	assert.False(t, send(false), "open breaker must reject requests")
+
+	now = now.Add(time.Second)
+	assert.True(t, send(false), "breaker should let a probe through after the open timeout")
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateClosed}, *transitions)
+}
+
+func TestBreakerFailureRate(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, transitions := newTestBreaker(
+		ConsecutiveFailures(0),
+		FailureRate(0.5),
+		MinRequests(4),
+		Window(10*time.Second),
+	)
+
+	for _, failed := range []bool{true, false, true} {
+		gen, ok := b.allow(now)
+		require.True(t, ok)
+		b.record(gen, now, failed, false)
+	}
+	assert.Empty(t, *transitions, "must not trip before the minimum number of requests")
+
+	// The earlier requests slide out of the window, so this does not trip.
+	now = now.Add(time.Minute)
+	gen, ok := b.allow(now)
+	require.True(t, ok)
+	b.record(gen, now, true, false)
+	assert.Empty(t, *transitions)
+
+	for _, failed := range []bool{false, false, true} {
+		gen, ok := b.allow(now)
+		require.True(t, ok)
+		b.record(gen, now, failed, false)
+	}
+	assert.Equal(t, []State{StateOpen}, *transitions)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, transitions := newTestBreaker(ConsecutiveFailures(1), HalfOpenRequests(2), OpenTimeout(time.Second))
+
+	gen, ok := b.allow(now)
+	require.True(t, ok)
+	b.record(gen, now, true, false)
+	require.Equal(t, []State{StateOpen}, *transitions)
+
+	now = now.Add(time.Second)
+	probe1, ok := b.allow(now)
+	require.True(t, ok)
+	probe2, ok := b.allow(now)
+	require.True(t, ok)
+	_, ok = b.allow(now)
+	assert.False(t, ok, "half-open breaker must limit probes in flight")
+
+	b.record(probe1, now, false, false)
+	assert.Equal(t, []State{StateOpen, StateHalfOpen}, *transitions, "one successful probe is not enough")
+
+	b.record(probe2, now, true, false)
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateOpen}, *transitions, "failed probe reopens the breaker")
+
+	_, ok = b.allow(now)
+	assert.False(t, ok)
+
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		gen, ok := b.allow(now)
+		require.True(t, ok)
+		b.record(gen, now, false, false)
+	}
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}, *transitions)
+}
+
+func TestBreakerAbandonedProbe(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, transitions := newTestBreaker(ConsecutiveFailures(1), OpenTimeout(time.Second))
+
+	gen, ok := b.allow(now)
+	require.True(t, ok)
+	b.record(gen, now, true, false)
+	require.Equal(t, []State{StateOpen}, *transitions)
+
+	now = now.Add(time.Second)
+	probe, ok := b.allow(now)
+	require.True(t, ok)
+	_, ok = b.allow(now)
+	require.False(t, ok)
+
+	b.record(probe, now, false, true)
+	assert.Equal(t, []State{StateOpen, StateHalfOpen}, *transitions, "abandoned probe must not close the breaker")
+
+	probe, ok = b.allow(now)
+	require.True(t, ok, "abandoned probe must release its slot")
+	b.record(probe, now, false, false)
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateClosed}, *transitions)
+}
+
+func TestBreakerIgnoresStaleRequests(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, transitions := newTestBreaker(ConsecutiveFailures(1), OpenTimeout(time.Second))
+
+	stale, ok := b.allow(now)
+	require.True(t, ok)
+
+	gen, ok := b.allow(now)
+	require.True(t, ok)
+	b.record(gen, now, true, false)
+	require.Equal(t, []State{StateOpen}, *transitions)
+
+	now = now.Add(time.Second)
+	probe, ok := b.allow(now)
+	require.True(t, ok)
+
+	// A request sent while the breaker was closed completes while it is
+	// half-open. It must not count as the probe.
+	b.record(stale, now, false, false)
+	assert.Equal(t, []State{StateOpen, StateHalfOpen}, *transitions)
+
+	b.record(probe, now, false, false)
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateClosed}, *transitions)
+}
+
+func TestBreakerStatus(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b, _ := newTestBreaker(ConsecutiveFailures(2), OpenTimeout(5*time.Second))
+
+	gen, _ := b.allow(now)
+	b.record(gen, now, true, false)
+	state, details := b.status(now)
+	assert.Equal(t, StateClosed, state)
+	assert.Equal(t, "window: 1 of 1 requests failed; 1 consecutive failures", details)
+
+	gen, _ = b.allow(now)
+	b.record(gen, now, true, false)
+	state, details = b.status(now.Add(2 * time.Second))
+	assert.Equal(t, StateOpen, state)
+	assert.Equal(t, "half-open in 3s", details)
+
+	_, _ = b.allow(now.Add(5 * time.Second))
+	state, details = b.status(now.Add(5 * time.Second))
+	assert.Equal(t, StateHalfOpen, state)
+	assert.Equal(t, "probes: 1 in flight, 0 of 1 succeeded", details)
+}
+
+func TestStateString(t *testing.T) {
+	assert.Equal(t, "closed", StateClosed.String())
+	assert.Equal(t, "open", StateOpen.String())
+	assert.Equal(t, "half-open", StateHalfOpen.String())
+	assert.Equal(t, "42", State(42).String())
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/uber-go/mapdecode"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/config"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Configuration describes circuit breaker thresholds in a form suitable for
+// decoding from YAML.
+//
+//	consecutiveFailures: 10
+//	failureRate: 0.25
+//	minRequests: 50
+//	window: 30s
+//	openTimeout: 1s
+//	halfOpenRequests: 3
+//	failureCodes:
+//	  - unavailable
+//	  - deadline-exceeded
+//
+// Every field is optional and defaults to the default of the corresponding
+// Option.
+type Configuration struct {
+	ConsecutiveFailures *uint         `config:"consecutiveFailures"`
+	FailureRate         *float64      `config:"failureRate"`
+	MinRequests         *uint         `config:"minRequests"`
+	Window              time.Duration `config:"window"`
+	OpenTimeout         time.Duration `config:"openTimeout"`
+	HalfOpenRequests    uint          `config:"halfOpenRequests"`
+	FailureCodes        []code        `config:"failureCodes"`
+}
+
+// code is a yarpcerrors.Code that decodes from its string representation.
+type code yarpcerrors.Code
+
+// mapdecode does not support encoding.TextUnmarshaler, so we decode codes
+// manually.
+func (c *code) Decode(into mapdecode.Into) error {
+	var s string
+	if err := into(&s); err != nil {
+		return fmt.Errorf("could not decode error code: %v", err)
+	}
+	return (*yarpcerrors.Code)(c).UnmarshalText([]byte(s))
+}
+
+// Spec returns a configuration specification for the circuit breaker
+// middleware, making it possible to configure it in the outboundMiddleware
+// section of yarpcconfig.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(circuitbreaker.Spec())
+//
+// This enables circuit breaking for unary and oneway outbounds:
+//
+//	outboundMiddleware:
+//	  circuitbreaker:
+//	    consecutiveFailures: 10
+//	    openTimeout: 1s
+//
+// Options take precedence over the configuration.
+func Spec(opts ...Option) yarpcconfig.OutboundMiddlewareSpec {
+	return yarpcconfig.OutboundMiddlewareSpec{
+		Name: "circuitbreaker",
+		BuildOutboundMiddleware: func(cfg Configuration, _ *yarpcconfig.Kit) (yarpc.OutboundMiddleware, error) {
+			cfgOpts, err := cfg.options()
+			if err != nil {
+				return yarpc.OutboundMiddleware{}, err
+			}
+
+			mw := NewOutboundMiddleware(append(cfgOpts, opts...)...)
+			return yarpc.OutboundMiddleware{Unary: mw, Oneway: mw}, nil
+		},
+	}
+}
+
+// NewOutboundMiddlewareFromConfig builds circuit breaker middleware from
+// configuration data, which must have the shape of a Configuration.
+// Options take precedence over the configuration.
+func NewOutboundMiddlewareFromConfig(src interface{}, opts ...Option) (*OutboundMiddleware, error) {
+	var cfg Configuration
+	if err := config.DecodeInto(&cfg, src); err != nil {
+		return nil, fmt.Errorf("failed to decode circuit breaker configuration: %v", err)
+	}
+
+	cfgOpts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewOutboundMiddleware(append(cfgOpts, opts...)...), nil
+}
+
+func (c Configuration) options() ([]Option, error) {
+	var opts []Option
+	if c.ConsecutiveFailures != nil {
+		opts = append(opts, ConsecutiveFailures(*c.ConsecutiveFailures))
+	}
+	if c.FailureRate != nil {
+		if rate := *c.FailureRate; rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("circuit breaker failureRate must be between 0 and 1, got %v", rate)
+		}
+		opts = append(opts, FailureRate(*c.FailureRate))
+	}
+	if c.MinRequests != nil {
+		opts = append(opts, MinRequests(*c.MinRequests))
+	}
+	if c.Window < 0 {
+		return nil, fmt.Errorf("circuit breaker window must not be negative, got %v", c.Window)
+	}
+	if c.Window > 0 {
+		opts = append(opts, Window(c.Window))
+	}
+	if c.OpenTimeout < 0 {
+		return nil, fmt.Errorf("circuit breaker openTimeout must not be negative, got %v", c.OpenTimeout)
+	}
+	if c.OpenTimeout > 0 {
+		opts = append(opts, OpenTimeout(c.OpenTimeout))
+	}
+	if c.HalfOpenRequests > 0 {
+		opts = append(opts, HalfOpenRequests(c.HalfOpenRequests))
+	}
+	if len(c.FailureCodes) > 0 {
+		codes := make([]yarpcerrors.Code, len(c.FailureCodes))
+		for i, c := range c.FailureCodes {
+			codes[i] = yarpcerrors.Code(c)
+		}
+		opts = append(opts, FailureCodes(codes...))
+	}
+	return opts, nil
+}
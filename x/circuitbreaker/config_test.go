@@ -0,0 +1,179 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNewOutboundMiddlewareFromConfig(t *testing.T) {
+	defaults := newOptions()
+
+	tests := []struct {
+		desc       string
+		give       string
+		opts       []Option
+		want       func(*options)
+		wantErrors []string
+	}{
+		{
+			desc: "empty",
+			want: func(*options) {},
+		},
+		{
+			desc: "everything",
+			give: `
+				consecutiveFailures: 0
+				failureRate: 0.25
+				minRequests: 50
+				window: 30s
+				openTimeout: 1s
+				halfOpenRequests: 3
+				failureCodes:
+					- unavailable
+					- deadline-exceeded
+			`,
+			want: func(o *options) {
+				o.consecutiveFailures = 0
+				o.failureRate = 0.25
+				o.minRequests = 50
+				o.window = 30 * time.Second
+				o.openTimeout = time.Second
+				o.halfOpenRequests = 3
+				o.failureCodes = map[yarpcerrors.Code]struct{}{
+					yarpcerrors.CodeUnavailable:      {},
+					yarpcerrors.CodeDeadlineExceeded: {},
+				}
+			},
+		},
+		{
+			desc: "options take precedence",
+			give: `
+				consecutiveFailures: 10
+			`,
+			opts: []Option{ConsecutiveFailures(3)},
+			want: func(o *options) {
+				o.consecutiveFailures = 3
+			},
+		},
+		{
+			desc: "invalid failure rate",
+			give: `
+				failureRate: 1.5
+			`,
+			wantErrors: []string{"failureRate must be between 0 and 1, got 1.5"},
+		},
+		{
+			desc: "negative window",
+			give: `
+				window: -1s
+			`,
+			wantErrors: []string{"window must not be negative"},
+		},
+		{
+			desc: "negative open timeout",
+			give: `
+				openTimeout: -1s
+			`,
+			wantErrors: []string{"openTimeout must not be negative"},
+		},
+		{
+			desc: "unknown code",
+			give: `
+				failureCodes: [sad]
+			`,
+			wantErrors: []string{"failed to decode circuit breaker configuration", "unknown code string: sad"},
+		},
+		{
+			desc: "unknown field",
+			give: `
+				threshold: 1
+			`,
+			wantErrors: []string{"failed to decode circuit breaker configuration"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var data map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(tt.give)), &data))
+
+			mw, err := NewOutboundMiddlewareFromConfig(data, tt.opts...)
+			if len(tt.wantErrors) > 0 {
+				require.Error(t, err)
+				for _, msg := range tt.wantErrors {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			want := defaults
+			tt.want(&want)
+			want.logger = mw.opts.logger
+			assert.Equal(t, want, mw.opts)
+		})
+	}
+}
+
+func TestSpec(t *testing.T) {
+	var data map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+		outboundMiddleware:
+			circuitbreaker:
+				consecutiveFailures: 3
+				openTimeout: 5s
+	`)), &data))
+
+	cfg := yarpcconfig.New()
+	require.NoError(t, cfg.RegisterOutboundMiddleware(Spec(HalfOpenRequests(2))))
+
+	c, err := cfg.LoadConfig("myservice", data)
+	require.NoError(t, err)
+
+	mw, ok := c.OutboundMiddleware.Unary.(*OutboundMiddleware)
+	require.True(t, ok, "unexpected unary middleware %T", c.OutboundMiddleware.Unary)
+	assert.Equal(t, mw, c.OutboundMiddleware.Oneway)
+	assert.Equal(t, uint(3), mw.opts.consecutiveFailures)
+	assert.Equal(t, 5*time.Second, mw.opts.openTimeout)
+	assert.Equal(t, uint(2), mw.opts.halfOpenRequests)
+
+	t.Run("invalid", func(t *testing.T) {
+		var data map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+			outboundMiddleware:
+				circuitbreaker:
+					failureRate: 2
+		`)), &data))
+
+		_, err := cfg.LoadConfig("myservice", data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failureRate must be between 0 and 1")
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package circuitbreaker provides outbound middleware that stops sending
+// unary and oneway requests to procedures that are failing.
+//
+// The middleware tracks a circuit breaker for every service and procedure.
+// A breaker starts closed and lets requests through, counting failures over
+// a sliding window.
+// When too many consecutive requests fail, or the failure rate over the
+// window exceeds a threshold, the breaker opens and requests fail
+// immediately with an Unavailable error instead of reaching the outbound.
+// After a timeout, the breaker becomes half-open and lets a few requests
+// through to probe the procedure.
+// If those requests succeed, the breaker closes; if any fails, it opens
+// again.
+//
+//	breaker := circuitbreaker.NewOutboundMiddleware(
+//		circuitbreaker.ConsecutiveFailures(10),
+//		circuitbreaker.OpenTimeout(time.Second),
+//	)
+//	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+//		Name: "myservice",
+//		OutboundMiddleware: yarpc.OutboundMiddleware{
+//			Unary:  breaker,
+//			Oneway: breaker,
+//		},
+//	})
+//
+// The middleware may also be configured in the outboundMiddleware section of
+// yarpcconfig by registering Spec with the Configurator.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(circuitbreaker.Spec())
+//
+// By default, only errors that are the fault of the server, like
+// Unavailable, Internal or DeadlineExceeded, count as failures.
+// Client errors, like InvalidArgument, and application errors do not.
+//
+// The state of every breaker is visible through Dispatcher.Introspect and
+// the x/debug page.
+package circuitbreaker
@@ -0,0 +1,101 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/zap"
+)
+
+const (
+	_source    = "source"
+	_dest      = "dest"
+	_procedure = "procedure"
+	_state     = "state"
+)
+
+// observer records circuit breaker metrics.
+type observer struct {
+	logger *zap.Logger
+
+	rejected    *metrics.CounterVector
+	transitions *metrics.CounterVector
+	states      *metrics.GaugeVector
+}
+
+func newObserver(meter *metrics.Scope, logger *zap.Logger) *observer {
+	o := &observer{logger: logger}
+
+	var err error
+	o.rejected, err = meter.CounterVector(metrics.Spec{
+		Name:    "circuit_breaker_rejected",
+		Help:    "Number of requests rejected by an open circuit breaker.",
+		VarTags: []string{_source, _dest, _procedure},
+	})
+	if err != nil {
+		logger.Error("Failed to create circuit breaker rejected counter.", zap.Error(err))
+	}
+	o.transitions, err = meter.CounterVector(metrics.Spec{
+		Name:    "circuit_breaker_transitions",
+		Help:    "Number of times circuit breakers changed state, by the state they entered.",
+		VarTags: []string{_dest, _procedure, _state},
+	})
+	if err != nil {
+		logger.Error("Failed to create circuit breaker transitions counter.", zap.Error(err))
+	}
+	o.states, err = meter.GaugeVector(metrics.Spec{
+		Name:    "circuit_breaker_state",
+		Help:    "State of each circuit breaker: 0 for closed, 1 for open, 2 for half-open.",
+		VarTags: []string{_dest, _procedure},
+	})
+	if err != nil {
+		logger.Error("Failed to create circuit breaker state gauge.", zap.Error(err))
+	}
+	return o
+}
+
+func (o *observer) reject(req *transport.Request) {
+	c, err := o.rejected.Get(_source, req.Caller, _dest, req.Service, _procedure, req.Procedure)
+	if err != nil {
+		o.logger.Error("Failed to get circuit breaker rejected counter.", zap.Error(err))
+		return
+	}
+	c.Inc()
+}
+
+func (o *observer) transition(k key, to State) {
+	if c, err := o.transitions.Get(_dest, k.service, _procedure, k.procedure, _state, to.String()); err == nil {
+		c.Inc()
+	} else {
+		o.logger.Error("Failed to get circuit breaker transitions counter.", zap.Error(err))
+	}
+	o.state(k, to)
+}
+
+func (o *observer) state(k key, s State) {
+	g, err := o.states.Get(_dest, k.service, _procedure, k.procedure)
+	if err != nil {
+		o.logger.Error("Failed to get circuit breaker state gauge.", zap.Error(err))
+		return
+	}
+	g.Store(int64(s))
+}
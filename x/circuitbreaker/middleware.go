@@ -0,0 +1,202 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/x/introspection"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+var (
+	_ middleware.UnaryOutbound               = (*OutboundMiddleware)(nil)
+	_ middleware.OnewayOutbound              = (*OutboundMiddleware)(nil)
+	_ introspection.IntrospectableMiddleware = (*OutboundMiddleware)(nil)
+
+	_timeNow = time.Now // for tests
+)
+
+// key identifies the breaker for a request.
+type key struct {
+	service   string
+	procedure string
+}
+
+// OutboundMiddleware is unary and oneway outbound middleware that tracks a
+// circuit breaker for every service and procedure.
+type OutboundMiddleware struct {
+	opts     options
+	observer *observer
+
+	mu       sync.RWMutex
+	breakers map[key]*breaker
+}
+
+// NewOutboundMiddleware creates a new circuit breaker middleware.
+func NewOutboundMiddleware(opts ...Option) *OutboundMiddleware {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &OutboundMiddleware{
+		opts:     options,
+		observer: newObserver(options.meter, options.logger),
+		breakers: make(map[key]*breaker),
+	}
+}
+
+// Call implements middleware.UnaryOutbound.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	var res *transport.Response
+	err := m.send(ctx, req, func() error {
+		var err error
+		res, err = out.Call(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+// CallOneway implements middleware.OnewayOutbound.
+func (m *OutboundMiddleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	var ack transport.Ack
+	err := m.send(ctx, req, func() error {
+		var err error
+		ack, err = out.CallOneway(ctx, req)
+		return err
+	})
+	return ack, err
+}
+
+// send sends a request through the breaker for its service and procedure,
+// rejecting it if the breaker is open.
+func (m *OutboundMiddleware) send(ctx context.Context, req *transport.Request, send func() error) error {
+	b := m.breaker(req)
+	generation, ok := b.allow(_timeNow())
+	if !ok {
+		return m.reject(req)
+	}
+
+	completed := false
+	defer func() {
+		if !completed {
+			// The outbound panicked. Count it as a failure so that a
+			// half-open breaker does not wait forever for its probe.
+			b.record(generation, _timeNow(), true /* failed */, false /* abandoned */)
+		}
+	}()
+
+	err := send()
+	completed = true
+
+	abandoned := err != nil &&
+		(ctx.Err() != nil || yarpcerrors.FromError(err).Code() == yarpcerrors.CodeCancelled)
+	b.record(generation, _timeNow(), m.isFailure(err), abandoned)
+	return err
+}
+
+// Introspect implements introspection.IntrospectableMiddleware.
+func (m *OutboundMiddleware) Introspect() introspection.MiddlewareStatus {
+	m.mu.RLock()
+	keys := make([]key, 0, len(m.breakers))
+	breakers := make(map[key]*breaker, len(m.breakers))
+	for k, b := range m.breakers {
+		keys = append(keys, k)
+		breakers[k] = b
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service == keys[j].service {
+			return keys[i].procedure < keys[j].procedure
+		}
+		return keys[i].service < keys[j].service
+	})
+
+	now := _timeNow()
+	entries := make([]introspection.MiddlewareEntryStatus, 0, len(keys))
+	for _, k := range keys {
+		state, details := breakers[k].status(now)
+		entries = append(entries, introspection.MiddlewareEntryStatus{
+			Key:     k.service + "/" + k.procedure,
+			State:   state.String(),
+			Details: details,
+		})
+	}
+	return introspection.MiddlewareStatus{
+		Name:    "circuit-breaker",
+		Entries: entries,
+	}
+}
+
+// breaker returns the breaker for the request, creating it if necessary.
+func (m *OutboundMiddleware) breaker(req *transport.Request) *breaker {
+	k := key{service: req.Service, procedure: req.Procedure}
+
+	m.mu.RLock()
+	b, ok := m.breakers[k]
+	m.mu.RUnlock()
+	if ok {
+		return b
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if b, ok := m.breakers[k]; ok {
+		return b
+	}
+	b = newBreaker(&m.opts, func(from, to State) {
+		m.opts.logger.Info("circuit breaker changed state",
+			zap.String("service", k.service),
+			zap.String("procedure", k.procedure),
+			zap.Stringer("from", from),
+			zap.Stringer("to", to))
+		m.observer.transition(k, to)
+	})
+	m.breakers[k] = b
+	m.observer.state(k, StateClosed)
+	return b
+}
+
+func (m *OutboundMiddleware) reject(req *transport.Request) error {
+	m.observer.reject(req)
+	return yarpcerrors.UnavailableErrorf(
+		"circuit breaker is open for service %q procedure %q", req.Service, req.Procedure)
+}
+
+// isFailure returns whether the error counts as a failure for the breaker.
+func (m *OutboundMiddleware) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if m.opts.failureCodes != nil {
+		_, ok := m.opts.failureCodes[yarpcerrors.FromError(err).Code()]
+		return ok
+	}
+	return yarpcerrors.GetFaultTypeFromError(err) != yarpcerrors.ClientFault
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/x/introspection"
+	"go.uber.org/yarpc/internal/testutils"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// stubTime replaces _timeNow with a clock that only moves when advanced.
+func stubTime(t *testing.T) (advance func(time.Duration)) {
+	now := time.Unix(1000, 0)
+	prev := _timeNow
+	_timeNow = func() time.Time { return now }
+	t.Cleanup(func() { _timeNow = prev })
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+// fakeOutbounds returns unary and oneway outbounds that fail with the error
+// returned by errFn, counting calls.
+func fakeOutbounds(errFn func(*transport.Request) error, calls *int) transport.Outbounds {
+	out := yarpctest.NewFakeTransport().NewOutbound(nil,
+		yarpctest.OutboundCallOverride(func(_ context.Context, req *transport.Request) (*transport.Response, error) {
+			*calls++
+			if err := errFn(req); err != nil {
+				return nil, err
+			}
+			return &transport.Response{}, nil
+		}),
+		yarpctest.OutboundCallOnewayOverride(func(_ context.Context, req *transport.Request) (transport.Ack, error) {
+			*calls++
+			return nil, errFn(req)
+		}),
+	)
+	return transport.Outbounds{Unary: out, Oneway: out}
+}
+
+func TestMiddlewareTripsPerProcedure(t *testing.T) {
+	advance := stubTime(t)
+
+	var calls int
+	outs := fakeOutbounds(func(req *transport.Request) error {
+		if req.Procedure == "failing" {
+			return yarpcerrors.UnavailableErrorf("down")
+		}
+		return nil
+	}, &calls)
+
+	mw := NewOutboundMiddleware(ConsecutiveFailures(2), OpenTimeout(time.Second))
+	unary := middleware.ApplyUnaryOutbound(outs.Unary, mw)
+	oneway := middleware.ApplyOnewayOutbound(outs.Oneway, mw)
+
+	call := func(procedure string) error {
+		_, err := unary.Call(context.Background(), &transport.Request{Service: "svc", Procedure: procedure})
+		return err
+	}
+
+	require.Error(t, call("failing"))
+	require.Error(t, call("failing"))
+	require.Equal(t, 2, calls)
+
+	err := call("failing")
+	require.Error(t, err)
+	assert.Equal(t, yarpcerrors.CodeUnavailable, yarpcerrors.FromError(err).Code())
+	assert.Contains(t, err.Error(), "circuit breaker is open")
+	assert.Equal(t, 2, calls, "open breaker must not call the outbound")
+
+	_, err = oneway.CallOneway(context.Background(), &transport.Request{Service: "svc", Procedure: "failing"})
+	assert.Contains(t, err.Error(), "circuit breaker is open", "oneway requests share the breaker")
+	assert.Equal(t, 2, calls)
+
+	assert.NoError(t, call("healthy"), "other procedures must be unaffected")
+	assert.Equal(t, 3, calls)
+
+	advance(time.Second)
+	require.Error(t, call("failing"), "probe fails")
+	assert.Equal(t, 4, calls)
+	require.Error(t, call("failing"))
+	assert.Equal(t, 4, calls, "failed probe must reopen the breaker")
+}
+
+func TestMiddlewareHalfOpenProbeNotCompleted(t *testing.T) {
+	tests := []struct {
+		desc string
+		give func(ctx context.Context, cancel context.CancelFunc) (*transport.Response, error)
+	}{
+		{
+			desc: "cancelled",
+			give: func(context.Context, context.CancelFunc) (*transport.Response, error) {
+				return nil, yarpcerrors.CancelledErrorf("caller gave up")
+			},
+		},
+		{
+			desc: "context done",
+			give: func(ctx context.Context, cancel context.CancelFunc) (*transport.Response, error) {
+				cancel()
+				return nil, ctx.Err()
+			},
+		},
+		{
+			desc: "panic",
+			give: func(context.Context, context.CancelFunc) (*transport.Response, error) {
+				panic("great sadness")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			advance := stubTime(t)
+
+			var (
+				calls  int
+				probe  func(context.Context, context.CancelFunc) (*transport.Response, error)
+				cancel context.CancelFunc
+			)
+			out := yarpctest.NewFakeTransport().NewOutbound(nil,
+				yarpctest.OutboundCallOverride(func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+					calls++
+					if probe != nil {
+						return probe(ctx, cancel)
+					}
+					return nil, yarpcerrors.UnavailableErrorf("down")
+				}),
+			)
+			mw := NewOutboundMiddleware(ConsecutiveFailures(1), OpenTimeout(time.Second))
+			unary := middleware.ApplyUnaryOutbound(out, mw)
+
+			call := func() (err error) {
+				var ctx context.Context
+				ctx, cancel = context.WithCancel(context.Background())
+				defer cancel()
+				defer func() {
+					if r := recover(); r != nil {
+						err = fmt.Errorf("panic: %v", r)
+					}
+				}()
+				_, err = unary.Call(ctx, &transport.Request{Service: "svc", Procedure: "proc"})
+				return err
+			}
+
+			require.Error(t, call())
+			advance(time.Second)
+
+			probe = tt.give
+			require.Error(t, call())
+			require.Equal(t, 2, calls)
+
+			probe = func(context.Context, context.CancelFunc) (*transport.Response, error) {
+				return &transport.Response{}, nil
+			}
+			if tt.desc == "panic" {
+				// A panicking probe counts as a failure, so wait for the
+				// breaker to become half-open again.
+				assert.Contains(t, call().Error(), "circuit breaker is open")
+				advance(time.Second)
+			}
+			require.NoError(t, call(), "breaker must let another probe through")
+			assert.Equal(t, "closed", mw.Introspect().Entries[0].State)
+		})
+	}
+}
+
+func TestMiddlewareFailureClassification(t *testing.T) {
+	stubTime(t)
+
+	tests := []struct {
+		desc      string
+		opts      []Option
+		err       error
+		wantTrips bool
+	}{
+		{
+			desc:      "server fault",
+			err:       yarpcerrors.InternalErrorf("sad"),
+			wantTrips: true,
+		},
+		{
+			desc:      "deadline exceeded",
+			err:       yarpcerrors.DeadlineExceededErrorf("slow"),
+			wantTrips: true,
+		},
+		{
+			desc:      "unknown error",
+			err:       errors.New("great sadness"),
+			wantTrips: true,
+		},
+		{
+			desc: "client fault",
+			err:  yarpcerrors.InvalidArgumentErrorf("bad"),
+		},
+		{
+			desc: "code not in failure codes",
+			opts: []Option{FailureCodes(yarpcerrors.CodeUnavailable)},
+			err:  yarpcerrors.InternalErrorf("sad"),
+		},
+		{
+			desc:      "code in failure codes",
+			opts:      []Option{FailureCodes(yarpcerrors.CodeNotFound)},
+			err:       yarpcerrors.NotFoundErrorf("missing"),
+			wantTrips: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var calls int
+			outs := fakeOutbounds(func(*transport.Request) error { return tt.err }, &calls)
+			mw := NewOutboundMiddleware(append([]Option{ConsecutiveFailures(1)}, tt.opts...)...)
+			out := middleware.ApplyUnaryOutbound(outs.Unary, mw)
+
+			for i := 0; i < 2; i++ {
+				_, err := out.Call(context.Background(), &transport.Request{Service: "svc", Procedure: "proc"})
+				require.Error(t, err)
+			}
+			if tt.wantTrips {
+				assert.Equal(t, 1, calls, "breaker should have opened")
+			} else {
+				assert.Equal(t, 2, calls, "breaker should have stayed closed")
+			}
+		})
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	advance := stubTime(t)
+	root := metrics.New()
+
+	var calls int
+	outs := fakeOutbounds(func(*transport.Request) error { return yarpcerrors.UnavailableErrorf("down") }, &calls)
+	mw := NewOutboundMiddleware(Meter(root.Scope()), ConsecutiveFailures(1), OpenTimeout(time.Second))
+	out := middleware.ApplyUnaryOutbound(outs.Unary, mw)
+
+	req := &transport.Request{Caller: "caller", Service: "svc", Procedure: "proc"}
+	for i := 0; i < 3; i++ {
+		_, _ = out.Call(context.Background(), req)
+	}
+	advance(time.Second)
+	_, _ = out.Call(context.Background(), req) // probe fails
+
+	tags := map[string]string{_dest: "svc", _procedure: "proc"}
+	withState := func(s State) map[string]string {
+		return map[string]string{_dest: "svc", _procedure: "proc", _state: s.String()}
+	}
+
+	snapshot := root.Snapshot()
+	testutils.AssertCounters(t, []testutils.CounterAssertion{
+		{Name: "circuit_breaker_rejected", Tags: map[string]string{_source: "caller", _dest: "svc", _procedure: "proc"}, Value: 2},
+		{Name: "circuit_breaker_transitions", Tags: withState(StateHalfOpen), Value: 1},
+		{Name: "circuit_breaker_transitions", Tags: withState(StateOpen), Value: 2},
+	}, snapshot.Counters)
+	testutils.AssertCounters(t, []testutils.CounterAssertion{
+		{Name: "circuit_breaker_state", Tags: tags, Value: int(StateOpen)},
+	}, snapshot.Gauges)
+}
+
+func TestMiddlewareIntrospect(t *testing.T) {
+	stubTime(t)
+
+	var calls int
+	outs := fakeOutbounds(func(req *transport.Request) error {
+		if req.Service == "bar" {
+			return yarpcerrors.UnavailableErrorf("down")
+		}
+		return nil
+	}, &calls)
+	mw := NewOutboundMiddleware(ConsecutiveFailures(1), OpenTimeout(time.Second))
+	out := middleware.ApplyUnaryOutbound(outs.Unary, mw)
+
+	assert.Equal(t, introspection.MiddlewareStatus{
+		Name:    "circuit-breaker",
+		Entries: []introspection.MiddlewareEntryStatus{},
+	}, mw.Introspect())
+
+	for _, req := range []*transport.Request{
+		{Service: "foo", Procedure: "b"},
+		{Service: "bar", Procedure: "a"},
+		{Service: "foo", Procedure: "a"},
+	} {
+		_, _ = out.Call(context.Background(), req)
+	}
+
+	assert.Equal(t, introspection.MiddlewareStatus{
+		Name: "circuit-breaker",
+		Entries: []introspection.MiddlewareEntryStatus{
+			{Key: "bar/a", State: "open", Details: "half-open in 1s"},
+			{Key: "foo/a", State: "closed", Details: "window: 0 of 1 requests failed; 0 consecutive failures"},
+			{Key: "foo/b", State: "closed", Details: "window: 0 of 1 requests failed; 0 consecutive failures"},
+		},
+	}, mw.Introspect())
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"time"
+
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+type options struct {
+	consecutiveFailures uint
+	failureRate         float64
+	minRequests         uint
+	window              time.Duration
+	openTimeout         time.Duration
+	halfOpenRequests    uint
+
+	// failureCodes is the set of error codes that count as failures.
+	// If nil, errors that are the fault of the server count as failures.
+	failureCodes map[yarpcerrors.Code]struct{}
+
+	meter  *metrics.Scope
+	logger *zap.Logger
+}
+
+func newOptions() options {
+	return options{
+		consecutiveFailures: 5,
+		failureRate:         0.5,
+		minRequests:         20,
+		window:              10 * time.Second,
+		openTimeout:         5 * time.Second,
+		halfOpenRequests:    1,
+		logger:              zap.NewNop(),
+	}
+}
+
+// Option customizes the behavior of the circuit breaker middleware.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(opts *options) { f(opts) }
+
+// ConsecutiveFailures opens a breaker after the given number of consecutive
+// failed requests.
+// Zero disables this threshold.
+//
+// Defaults to 5.
+func ConsecutiveFailures(n uint) Option {
+	return optionFunc(func(opts *options) {
+		opts.consecutiveFailures = n
+	})
+}
+
+// FailureRate opens a breaker when the fraction of failed requests in the
+// sliding window reaches the given rate, between 0 and 1.
+// Zero disables this threshold.
+//
+// Defaults to 0.5.
+func FailureRate(rate float64) Option {
+	return optionFunc(func(opts *options) {
+		opts.failureRate = rate
+	})
+}
+
+// MinRequests is the number of requests the sliding window must hold before
+// the failure rate is considered.
+// This prevents a breaker from opening because a handful of requests failed.
+//
+// Defaults to 20.
+func MinRequests(n uint) Option {
+	return optionFunc(func(opts *options) {
+		opts.minRequests = n
+	})
+}
+
+// Window is the duration of the sliding window over which the failure rate
+// is measured.
+//
+// Defaults to 10s.
+func Window(d time.Duration) Option {
+	return optionFunc(func(opts *options) {
+		if d > 0 {
+			opts.window = d
+		}
+	})
+}
+
+// OpenTimeout is how long a breaker stays open before becoming half-open.
+//
+// Defaults to 5s.
+func OpenTimeout(d time.Duration) Option {
+	return optionFunc(func(opts *options) {
+		opts.openTimeout = d
+	})
+}
+
+// HalfOpenRequests is the number of requests a half-open breaker lets
+// through at a time, and the number that must succeed for the breaker to
+// close.
+//
+// Defaults to 1.
+func HalfOpenRequests(n uint) Option {
+	return optionFunc(func(opts *options) {
+		if n > 0 {
+			opts.halfOpenRequests = n
+		}
+	})
+}
+
+// FailureCodes replaces the set of error codes that count as failures.
+//
+// Defaults to the codes of errors that are the fault of the server, like
+// Unavailable, Internal and DeadlineExceeded.
+func FailureCodes(codes ...yarpcerrors.Code) Option {
+	return optionFunc(func(opts *options) {
+		opts.failureCodes = make(map[yarpcerrors.Code]struct{}, len(codes))
+		for _, code := range codes {
+			opts.failureCodes[code] = struct{}{}
+		}
+	})
+}
+
+// Meter sets the metrics scope on which the middleware records breaker
+// states, transitions and rejected requests.
+//
+// Defaults to no metrics.
+func Meter(meter *metrics.Scope) Option {
+	return optionFunc(func(opts *options) {
+		opts.meter = meter
+	})
+}
+
+// Logger sets a logger for the middleware.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(opts *options) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
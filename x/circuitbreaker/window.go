@@ -0,0 +1,108 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import "time"
+
+// window counts requests and failures over a sliding period of time.
+//
+// The period is divided into buckets, so the window slides one bucket at a
+// time rather than continuously.
+// window is not safe for concurrent use.
+type window struct {
+	bucketWidth time.Duration
+	buckets     []bucket
+
+	// head is the index of the bucket that counts requests made since
+	// headStart.
+	head      int
+	headStart time.Time
+}
+
+type bucket struct {
+	requests uint
+	failures uint
+}
+
+func newWindow(size time.Duration, numBuckets int) *window {
+	width := size / time.Duration(numBuckets)
+	if width <= 0 {
+		width = 1
+	}
+	return &window{
+		bucketWidth: width,
+		buckets:     make([]bucket, numBuckets),
+	}
+}
+
+// advance moves the head of the window to the bucket for the given time,
+// clearing buckets that have slid out of the window.
+func (w *window) advance(now time.Time) {
+	if w.headStart.IsZero() {
+		w.headStart = now
+		return
+	}
+
+	steps := int(now.Sub(w.headStart) / w.bucketWidth)
+	if steps <= 0 {
+		return
+	}
+	if steps >= len(w.buckets) {
+		w.reset()
+		w.headStart = now
+		return
+	}
+
+	for i := 0; i < steps; i++ {
+		w.head = (w.head + 1) % len(w.buckets)
+		w.buckets[w.head] = bucket{}
+	}
+	w.headStart = w.headStart.Add(time.Duration(steps) * w.bucketWidth)
+}
+
+// record counts a request made at the given time.
+func (w *window) record(now time.Time, failed bool) {
+	w.advance(now)
+	w.buckets[w.head].requests++
+	if failed {
+		w.buckets[w.head].failures++
+	}
+}
+
+// totals returns the number of requests and failures in the window as of the
+// given time.
+func (w *window) totals(now time.Time) (requests, failures uint) {
+	w.advance(now)
+	for _, b := range w.buckets {
+		requests += b.requests
+		failures += b.failures
+	}
+	return requests, failures
+}
+
+// reset clears all counts.
+func (w *window) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+	w.head = 0
+	w.headStart = time.Time{}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	start := time.Unix(1000, 0)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	w := newWindow(10*time.Second, 10)
+
+	w.record(at(0), false)
+	w.record(at(500*time.Millisecond), true)
+	w.record(at(3*time.Second), true)
+
+	requests, failures := w.totals(at(3 * time.Second))
+	assert.Equal(t, uint(3), requests)
+	assert.Equal(t, uint(2), failures)
+
+	// The first bucket slides out of the window after 10s.
+	requests, failures = w.totals(at(10 * time.Second))
+	assert.Equal(t, uint(1), requests)
+	assert.Equal(t, uint(1), failures)
+
+	// Everything slides out once a full window has passed.
+	requests, failures = w.totals(at(time.Minute))
+	assert.Equal(t, uint(0), requests)
+	assert.Equal(t, uint(0), failures)
+
+	w.record(at(time.Minute), true)
+	w.reset()
+	requests, failures = w.totals(at(time.Minute))
+	assert.Equal(t, uint(0), requests)
+	assert.Equal(t, uint(0), failures)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package deadline
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+// InboundConfiguration configures the deadline inbound middleware.
+//
+//	minBudget: 10ms
+//	reserve: 5ms
+//
+// See the options of the same name for the meaning of each field.
+type InboundConfiguration struct {
+	MinBudget time.Duration `config:"minBudget"`
+	Reserve   time.Duration `config:"reserve"`
+}
+
+// OutboundConfiguration configures the deadline outbound middleware, which
+// has no parameters: the budget of outbound calls is set by the inbound
+// middleware.
+type OutboundConfiguration struct{}
+
+// InboundSpec returns a configuration specification for the deadline inbound
+// middleware, making it possible to configure it in the inboundMiddleware
+// section of yarpcconfig.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterInboundMiddleware(deadline.InboundSpec(deadline.Meter(scope)))
+//
+// This enables the middleware for unary, oneway and stream inbounds:
+//
+//	inboundMiddleware:
+//	  deadline:
+//	    minBudget: 10ms
+//	    reserve: 5ms
+//
+// Options take precedence over the configuration.
+func InboundSpec(opts ...Option) yarpcconfig.InboundMiddlewareSpec {
+	return yarpcconfig.InboundMiddlewareSpec{
+		Name: "deadline",
+		BuildInboundMiddleware: func(cfg InboundConfiguration, _ *yarpcconfig.Kit) (yarpc.InboundMiddleware, error) {
+			if cfg.MinBudget < 0 {
+				return yarpc.InboundMiddleware{}, fmt.Errorf("minBudget must not be negative, got %v", cfg.MinBudget)
+			}
+			if cfg.Reserve < 0 {
+				return yarpc.InboundMiddleware{}, fmt.Errorf("reserve must not be negative, got %v", cfg.Reserve)
+			}
+
+			mwOpts := append([]Option{MinBudget(cfg.MinBudget), Reserve(cfg.Reserve)}, opts...)
+			mw := NewInboundMiddleware(mwOpts...)
+			return yarpc.InboundMiddleware{Unary: mw, Oneway: mw, Stream: mw}, nil
+		},
+	}
+}
+
+// OutboundSpec returns a configuration specification for the deadline
+// outbound middleware, making it possible to enable it in the
+// outboundMiddleware section of yarpcconfig.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(deadline.OutboundSpec(deadline.Meter(scope)))
+//
+// This enables the middleware for unary and oneway outbounds:
+//
+//	outboundMiddleware:
+//	  deadline: {}
+func OutboundSpec(opts ...Option) yarpcconfig.OutboundMiddlewareSpec {
+	return yarpcconfig.OutboundMiddlewareSpec{
+		Name: "deadline",
+		BuildOutboundMiddleware: func(OutboundConfiguration, *yarpcconfig.Kit) (yarpc.OutboundMiddleware, error) {
+			mw := NewOutboundMiddleware(opts...)
+			return yarpc.OutboundMiddleware{Unary: mw, Oneway: mw}, nil
+		},
+	}
+}
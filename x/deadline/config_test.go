@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package deadline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcconfig"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSpecs(t *testing.T) {
+	cfg := yarpcconfig.New()
+	require.NoError(t, cfg.RegisterInboundMiddleware(InboundSpec()))
+	require.NoError(t, cfg.RegisterOutboundMiddleware(OutboundSpec()))
+
+	load := func(t *testing.T, give string) (yarpc.Config, error) {
+		var data map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(give)), &data))
+		return cfg.LoadConfig("myservice", data)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		c, err := load(t, `
+			inboundMiddleware:
+				deadline:
+					minBudget: 10ms
+					reserve: 5ms
+			outboundMiddleware:
+				deadline: {}
+		`)
+		require.NoError(t, err)
+
+		inbound, ok := c.InboundMiddleware.Unary.(*InboundMiddleware)
+		require.True(t, ok, "unexpected inbound middleware %T", c.InboundMiddleware.Unary)
+		assert.Equal(t, 10*time.Millisecond, inbound.opts.minBudget)
+		assert.Equal(t, 5*time.Millisecond, inbound.opts.reserve)
+		assert.IsType(t, &OutboundMiddleware{}, c.OutboundMiddleware.Unary)
+	})
+
+	t.Run("negative minBudget", func(t *testing.T) {
+		_, err := load(t, `
+			inboundMiddleware:
+				deadline:
+					minBudget: -1s
+		`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "minBudget must not be negative, got -1s")
+	})
+
+	t.Run("negative reserve", func(t *testing.T) {
+		_, err := load(t, `
+			inboundMiddleware:
+				deadline:
+					reserve: -1s
+		`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "reserve must not be negative, got -1s")
+	})
+
+	t.Run("options take precedence", func(t *testing.T) {
+		cfg := yarpcconfig.New()
+		require.NoError(t, cfg.RegisterInboundMiddleware(InboundSpec(Reserve(time.Second))))
+
+		var data map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+			inboundMiddleware:
+				deadline:
+					reserve: 5ms
+		`)), &data))
+		c, err := cfg.LoadConfig("myservice", data)
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, c.InboundMiddleware.Unary.(*InboundMiddleware).opts.reserve)
+	})
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package deadline provides inbound and outbound middleware that enforce a
+// deadline budget across hops, whichever transport carries the requests.
+//
+// Every transport turns the TTL of a request into the deadline of the
+// context given to its handler. The inbound middleware rejects requests with
+// a DeadlineExceeded error when less than a minimum budget remains before
+// their deadline, since they are unlikely to complete in time, and counts
+// requests that arrive already expired.
+//
+// The inbound middleware also records in the context of unary and oneway
+// handlers the time by which their outbound calls must end: the deadline of
+// the request, minus a reserve left for processing the responses of these
+// calls. The outbound middleware shrinks the deadline of outbound calls made
+// with such a context so that they end within the budget, even if the
+// handler asked for a longer timeout.
+//
+//	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+//		Name: "myservice",
+//		InboundMiddleware: yarpc.InboundMiddleware{
+//			Unary: deadline.NewInboundMiddleware(
+//				deadline.MinBudget(10*time.Millisecond),
+//				deadline.Reserve(5*time.Millisecond),
+//			),
+//		},
+//		OutboundMiddleware: yarpc.OutboundMiddleware{
+//			Unary: deadline.NewOutboundMiddleware(),
+//		},
+//	})
+//
+// Only outbound calls made with the context of the handler, or a context
+// derived from it, are shrunk.
+//
+// The middleware may also be configured in yarpcconfig by registering
+// InboundSpec and OutboundSpec with the Configurator.
+package deadline
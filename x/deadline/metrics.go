@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package deadline
+
+import (
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/zap"
+)
+
+const (
+	_source    = "source"
+	_dest      = "dest"
+	_procedure = "procedure"
+)
+
+// observer records deadline metrics, keyed by caller, service and procedure.
+type observer struct {
+	logger *zap.Logger
+}
+
+func (o *observer) counterVector(meter *metrics.Scope, name, help string) *metrics.CounterVector {
+	vec, err := meter.CounterVector(metrics.Spec{
+		Name:    name,
+		Help:    help,
+		VarTags: []string{_source, _dest, _procedure},
+	})
+	if err != nil {
+		o.logger.Error("Failed to create deadline counter.", zap.String("name", name), zap.Error(err))
+	}
+	return vec
+}
+
+func (o *observer) inc(vec *metrics.CounterVector, req *transport.Request) {
+	c, err := vec.Get(_source, req.Caller, _dest, req.Service, _procedure, req.Procedure)
+	if err != nil {
+		o.logger.Error("Failed to get deadline counter.", zap.Error(err))
+	}
+	c.Inc()
+}
+
+type inboundObserver struct {
+	observer
+
+	expired  *metrics.CounterVector
+	rejected *metrics.CounterVector
+}
+
+func newInboundObserver(meter *metrics.Scope, logger *zap.Logger) *inboundObserver {
+	o := &inboundObserver{observer: observer{logger: logger}}
+	o.expired = o.counterVector(meter, "deadline_expired_requests",
+		"Number of inbound requests that arrived after their deadline.")
+	o.rejected = o.counterVector(meter, "deadline_rejected_requests",
+		"Number of inbound requests rejected for arriving with less than the minimum budget left.")
+	return o
+}
+
+type outboundObserver struct {
+	observer
+
+	shrunk    *metrics.CounterVector
+	exhausted *metrics.CounterVector
+}
+
+func newOutboundObserver(meter *metrics.Scope, logger *zap.Logger) *outboundObserver {
+	o := &outboundObserver{observer: observer{logger: logger}}
+	o.shrunk = o.counterVector(meter, "deadline_shrunk_requests",
+		"Number of outbound requests whose deadline was shrunk to fit the budget of the inbound request.")
+	o.exhausted = o.counterVector(meter, "deadline_exhausted_requests",
+		"Number of outbound requests failed without being sent because the budget of the inbound request was spent.")
+	return o
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package deadline
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+var (
+	_ middleware.UnaryInbound   = (*InboundMiddleware)(nil)
+	_ middleware.OnewayInbound  = (*InboundMiddleware)(nil)
+	_ middleware.StreamInbound  = (*InboundMiddleware)(nil)
+	_ middleware.UnaryOutbound  = (*OutboundMiddleware)(nil)
+	_ middleware.OnewayOutbound = (*OutboundMiddleware)(nil)
+
+	_timeNow = time.Now // for tests
+)
+
+// InboundMiddleware is unary, oneway and stream inbound middleware that
+// rejects requests without enough time left before their deadline, and
+// records the budget of unary and oneway handlers for outbound calls.
+type InboundMiddleware struct {
+	opts     options
+	observer *inboundObserver
+}
+
+// NewInboundMiddleware creates a new deadline middleware for inbounds.
+func NewInboundMiddleware(opts ...Option) *InboundMiddleware {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &InboundMiddleware{
+		opts:     options,
+		observer: newInboundObserver(options.meter, options.logger),
+	}
+}
+
+// Handle implements middleware.UnaryInbound.
+func (m *InboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	ctx, err := m.admit(ctx, req)
+	if err != nil {
+		return err
+	}
+	return h.Handle(ctx, req, resw)
+}
+
+// HandleOneway implements middleware.OnewayInbound.
+func (m *InboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	ctx, err := m.admit(ctx, req)
+	if err != nil {
+		return err
+	}
+	return h.HandleOneway(ctx, req)
+}
+
+// HandleStream implements middleware.StreamInbound.
+//
+// Streams are rejected like other requests, but the context of a stream
+// cannot be replaced, so outbound calls made by stream handlers are not
+// shrunk.
+func (m *InboundMiddleware) HandleStream(s *transport.ServerStream, h transport.StreamHandler) error {
+	if _, err := m.admit(s.Context(), s.Request().Meta.ToRequest()); err != nil {
+		return err
+	}
+	return h.HandleStream(s)
+}
+
+// admit checks the time left before the deadline of the request, returning
+// the context to handle it with.
+func (m *InboundMiddleware) admit(ctx context.Context, req *transport.Request) (context.Context, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, nil
+	}
+
+	remaining := deadline.Sub(_timeNow())
+	if remaining <= 0 {
+		m.observer.inc(m.observer.expired, req)
+		m.observer.logger.Debug("Rejecting request that arrived after its deadline.",
+			zap.String("caller", req.Caller),
+			zap.String("procedure", req.Procedure),
+			zap.Duration("late", -remaining))
+		return nil, yarpcerrors.DeadlineExceededErrorf(
+			"request to procedure %q of service %q from caller %q arrived %v after its deadline",
+			req.Procedure, req.Service, req.Caller, -remaining)
+	}
+	if remaining < m.opts.minBudget {
+		m.observer.inc(m.observer.rejected, req)
+		return nil, yarpcerrors.DeadlineExceededErrorf(
+			"request to procedure %q of service %q from caller %q arrived with %v left before its deadline, less than the minimum of %v",
+			req.Procedure, req.Service, req.Caller, remaining, m.opts.minBudget)
+	}
+	return withBudget(ctx, deadline.Add(-m.opts.reserve)), nil
+}
+
+// OutboundMiddleware is unary and oneway outbound middleware that shrinks
+// the deadline of outbound calls to the budget recorded by the inbound
+// middleware in their context.
+type OutboundMiddleware struct {
+	observer *outboundObserver
+}
+
+// NewOutboundMiddleware creates a new deadline middleware for outbounds.
+func NewOutboundMiddleware(opts ...Option) *OutboundMiddleware {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &OutboundMiddleware{
+		observer: newOutboundObserver(options.meter, options.logger),
+	}
+}
+
+// Call implements middleware.UnaryOutbound.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	ctx, cancel, err := m.shrink(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := out.Call(ctx, req)
+	if res != nil && res.Body != nil {
+		// The body of the response may still be read through the context,
+		// so it is only cancelled once the body is closed.
+		res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	} else {
+		cancel()
+	}
+	return res, err
+}
+
+// CallOneway implements middleware.OnewayOutbound.
+func (m *OutboundMiddleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	ctx, cancel, err := m.shrink(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return out.CallOneway(ctx, req)
+}
+
+// shrink returns a context whose deadline fits the budget of the request, if
+// any, failing the request if its budget is spent.
+func (m *OutboundMiddleware) shrink(ctx context.Context, req *transport.Request) (context.Context, context.CancelFunc, error) {
+	budget, ok := Budget(ctx)
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && !deadline.After(budget) {
+		return ctx, func() {}, nil
+	}
+
+	if !budget.After(_timeNow()) {
+		m.observer.inc(m.observer.exhausted, req)
+		return nil, nil, yarpcerrors.DeadlineExceededErrorf(
+			"no time left in the budget of the inbound request for calling procedure %q of service %q",
+			req.Procedure, req.Service)
+	}
+
+	m.observer.inc(m.observer.shrunk, req)
+	ctx, cancel := context.WithDeadline(ctx, budget)
+	return ctx, cancel, nil
+}
+
+// cancelOnClose cancels the context of a request once the body of its
+// response is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,373 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package deadline
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/testutils"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+type unaryHandlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f unaryHandlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+type onewayHandlerFunc func(context.Context, *transport.Request) error
+
+func (f onewayHandlerFunc) HandleOneway(ctx context.Context, req *transport.Request) error {
+	return f(ctx, req)
+}
+
+type streamHandlerFunc func(*transport.ServerStream) error
+
+func (f streamHandlerFunc) HandleStream(s *transport.ServerStream) error {
+	return f(s)
+}
+
+func newRequest() *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "procedure",
+	}
+}
+
+var _tags = map[string]string{
+	_source:    "caller",
+	_dest:      "service",
+	_procedure: "procedure",
+}
+
+// stubNow fixes the current time of the middleware until the test ends.
+func stubNow(t *testing.T) time.Time {
+	now := time.Now()
+	_timeNow = func() time.Time { return now }
+	t.Cleanup(func() { _timeNow = time.Now })
+	return now
+}
+
+func TestInboundMiddleware(t *testing.T) {
+	tests := []struct {
+		desc string
+		// Deadline of the request relative to now. No deadline if zero.
+		giveRemaining time.Duration
+		wantCode      yarpcerrors.Code
+		wantBudget    time.Duration
+		wantNoBudget  bool
+		wantCounters  []testutils.CounterAssertion
+	}{
+		{
+			desc:         "no deadline",
+			wantNoBudget: true,
+		},
+		{
+			desc:          "enough budget",
+			giveRemaining: time.Second,
+			wantBudget:    time.Second - 5*time.Millisecond,
+		},
+		{
+			desc:          "minimum budget",
+			giveRemaining: 10 * time.Millisecond,
+			wantBudget:    5 * time.Millisecond,
+		},
+		{
+			desc:          "below minimum budget",
+			giveRemaining: 9 * time.Millisecond,
+			wantCode:      yarpcerrors.CodeDeadlineExceeded,
+			wantCounters: []testutils.CounterAssertion{
+				{Name: "deadline_rejected_requests", Tags: _tags, Value: 1},
+			},
+		},
+		{
+			desc:          "expired",
+			giveRemaining: -time.Millisecond,
+			wantCode:      yarpcerrors.CodeDeadlineExceeded,
+			wantCounters: []testutils.CounterAssertion{
+				{Name: "deadline_expired_requests", Tags: _tags, Value: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			now := stubNow(t)
+			root := metrics.New()
+			mw := NewInboundMiddleware(
+				MinBudget(10*time.Millisecond),
+				Reserve(5*time.Millisecond),
+				Meter(root.Scope()),
+			)
+
+			ctx := context.Background()
+			if tt.giveRemaining != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, now.Add(tt.giveRemaining))
+				defer cancel()
+			}
+
+			var (
+				handled   bool
+				budget    time.Time
+				hasBudget bool
+			)
+			err := mw.Handle(ctx, newRequest(), &transporttest.FakeResponseWriter{},
+				unaryHandlerFunc(func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) error {
+					handled = true
+					budget, hasBudget = Budget(ctx)
+					return nil
+				}))
+
+			if tt.wantCode != yarpcerrors.CodeOK {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantCode, yarpcerrors.FromError(err).Code())
+				assert.False(t, handled, "rejected requests must not be handled")
+			} else {
+				require.NoError(t, err)
+				assert.True(t, handled)
+				if tt.wantNoBudget {
+					assert.False(t, hasBudget, "unexpected budget")
+				} else {
+					require.True(t, hasBudget, "expected a budget")
+					assert.Equal(t, now.Add(tt.wantBudget), budget)
+				}
+			}
+			testutils.AssertCounters(t, tt.wantCounters, root.Snapshot().Counters)
+		})
+	}
+}
+
+func TestInboundMiddlewareOnewayAndStream(t *testing.T) {
+	now := stubNow(t)
+	mw := NewInboundMiddleware(MinBudget(time.Second))
+
+	short, cancel := context.WithDeadline(context.Background(), now.Add(time.Millisecond))
+	defer cancel()
+	long, cancel := context.WithDeadline(context.Background(), now.Add(time.Minute))
+	defer cancel()
+
+	t.Run("oneway", func(t *testing.T) {
+		err := mw.HandleOneway(short, newRequest(), onewayHandlerFunc(func(context.Context, *transport.Request) error {
+			t.Fatal("rejected requests must not be handled")
+			return nil
+		}))
+		assert.Equal(t, yarpcerrors.CodeDeadlineExceeded, yarpcerrors.FromError(err).Code())
+
+		var budget time.Time
+		err = mw.HandleOneway(long, newRequest(), onewayHandlerFunc(func(ctx context.Context, _ *transport.Request) error {
+			budget, _ = Budget(ctx)
+			return nil
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(time.Minute), budget)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		newStream := func(ctx context.Context) *transport.ServerStream {
+			mockCtrl := gomock.NewController(t)
+			stream := transporttest.NewMockStream(mockCtrl)
+			stream.EXPECT().Context().Return(ctx).AnyTimes()
+			stream.EXPECT().Request().Return(&transport.StreamRequest{
+				Meta: &transport.RequestMeta{Caller: "caller", Service: "service", Procedure: "procedure"},
+			}).AnyTimes()
+			s, err := transport.NewServerStream(stream)
+			require.NoError(t, err)
+			return s
+		}
+
+		var handled int
+		h := streamHandlerFunc(func(*transport.ServerStream) error {
+			handled++
+			return nil
+		})
+
+		err := mw.HandleStream(newStream(short), h)
+		assert.Equal(t, yarpcerrors.CodeDeadlineExceeded, yarpcerrors.FromError(err).Code())
+		require.NoError(t, mw.HandleStream(newStream(long), h))
+		assert.Equal(t, 1, handled)
+	})
+}
+
+func TestOutboundMiddleware(t *testing.T) {
+	tests := []struct {
+		desc string
+		// Deadline and budget of the request relative to now. None if zero.
+		giveDeadline time.Duration
+		giveBudget   time.Duration
+		wantDeadline time.Duration
+		wantCode     yarpcerrors.Code
+		wantCounters []testutils.CounterAssertion
+	}{
+		{
+			desc:         "no budget",
+			giveDeadline: time.Minute,
+			wantDeadline: time.Minute,
+		},
+		{
+			desc:         "deadline within budget",
+			giveDeadline: time.Second,
+			giveBudget:   time.Minute,
+			wantDeadline: time.Second,
+		},
+		{
+			desc:         "deadline beyond budget",
+			giveDeadline: time.Minute,
+			giveBudget:   time.Second,
+			wantDeadline: time.Second,
+			wantCounters: []testutils.CounterAssertion{
+				{Name: "deadline_shrunk_requests", Tags: _tags, Value: 1},
+			},
+		},
+		{
+			desc:         "no deadline",
+			giveBudget:   time.Second,
+			wantDeadline: time.Second,
+			wantCounters: []testutils.CounterAssertion{
+				{Name: "deadline_shrunk_requests", Tags: _tags, Value: 1},
+			},
+		},
+		{
+			desc:         "budget spent",
+			giveDeadline: time.Minute,
+			giveBudget:   -time.Millisecond,
+			wantCode:     yarpcerrors.CodeDeadlineExceeded,
+			wantCounters: []testutils.CounterAssertion{
+				{Name: "deadline_exhausted_requests", Tags: _tags, Value: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			now := stubNow(t)
+			root := metrics.New()
+			mw := NewOutboundMiddleware(Meter(root.Scope()))
+
+			ctx := context.Background()
+			if tt.giveDeadline != 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, now.Add(tt.giveDeadline))
+				defer cancel()
+			}
+			if tt.giveBudget != 0 {
+				ctx = withBudget(ctx, now.Add(tt.giveBudget))
+			}
+
+			var deadlines []time.Time
+			out := yarpctest.NewFakeTransport().NewOutbound(nil,
+				yarpctest.OutboundCallOverride(func(ctx context.Context, _ *transport.Request) (*transport.Response, error) {
+					d, _ := ctx.Deadline()
+					deadlines = append(deadlines, d)
+					return &transport.Response{Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+				}),
+				yarpctest.OutboundCallOnewayOverride(func(ctx context.Context, _ *transport.Request) (transport.Ack, error) {
+					d, _ := ctx.Deadline()
+					deadlines = append(deadlines, d)
+					return nil, nil
+				}),
+			)
+
+			res, err := middleware.ApplyUnaryOutbound(out, mw).Call(ctx, newRequest())
+			if tt.wantCode != yarpcerrors.CodeOK {
+				assert.Equal(t, tt.wantCode, yarpcerrors.FromError(err).Code())
+				_, err = middleware.ApplyOnewayOutbound(out, mw).CallOneway(ctx, newRequest())
+				assert.Equal(t, tt.wantCode, yarpcerrors.FromError(err).Code())
+				assert.Empty(t, deadlines, "requests without budget must not be sent")
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, res.Body.Close())
+				_, err = middleware.ApplyOnewayOutbound(out, mw).CallOneway(ctx, newRequest())
+				require.NoError(t, err)
+				assert.Equal(t, []time.Time{now.Add(tt.wantDeadline), now.Add(tt.wantDeadline)}, deadlines)
+			}
+
+			// Unary and oneway requests are counted together.
+			for i := range tt.wantCounters {
+				tt.wantCounters[i].Value *= 2
+			}
+			testutils.AssertCounters(t, tt.wantCounters, root.Snapshot().Counters)
+		})
+	}
+}
+
+func TestOutboundMiddlewareCancelsOnBodyClose(t *testing.T) {
+	now := stubNow(t)
+	mw := NewOutboundMiddleware()
+	ctx := withBudget(context.Background(), now.Add(time.Minute))
+
+	var callCtx context.Context
+	out := yarpctest.NewFakeTransport().NewOutbound(nil,
+		yarpctest.OutboundCallOverride(func(ctx context.Context, _ *transport.Request) (*transport.Response, error) {
+			callCtx = ctx
+			return &transport.Response{Body: ioutil.NopCloser(bytes.NewBufferString("body"))}, nil
+		}))
+
+	res, err := mw.Call(ctx, newRequest(), out)
+	require.NoError(t, err)
+	assert.NoError(t, callCtx.Err(), "the body must remain readable")
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, context.Canceled, callCtx.Err())
+}
+
+func TestHandlerOutboundCallsAreShrunk(t *testing.T) {
+	inbound := NewInboundMiddleware(Reserve(100 * time.Millisecond))
+	outbound := NewOutboundMiddleware()
+
+	var inboundDeadline, outboundDeadline time.Time
+	out := middleware.ApplyUnaryOutbound(yarpctest.NewFakeTransport().NewOutbound(nil,
+		yarpctest.OutboundCallOverride(func(ctx context.Context, _ *transport.Request) (*transport.Response, error) {
+			outboundDeadline, _ = ctx.Deadline()
+			return &transport.Response{}, nil
+		})), outbound)
+
+	handler := middleware.ApplyUnaryInbound(unaryHandlerFunc(
+		func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) error {
+			inboundDeadline, _ = ctx.Deadline()
+			// The handler asks for more time than it has left.
+			ctx, cancel := context.WithTimeout(ctx, time.Hour)
+			defer cancel()
+			_, err := out.Call(ctx, &transport.Request{Caller: "service", Service: "downstream", Procedure: "get"})
+			return err
+		}), inbound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, handler.Handle(ctx, newRequest(), &transporttest.FakeResponseWriter{}))
+	assert.Equal(t, inboundDeadline.Add(-100*time.Millisecond), outboundDeadline)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package deadline
+
+import (
+	"time"
+
+	"go.uber.org/net/metrics"
+	"go.uber.org/zap"
+)
+
+type options struct {
+	minBudget time.Duration
+	reserve   time.Duration
+
+	meter  *metrics.Scope
+	logger *zap.Logger
+}
+
+func newOptions() options {
+	return options{logger: zap.NewNop()}
+}
+
+// Option customizes the behavior of the deadline middleware.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(opts *options) { f(opts) }
+
+// MinBudget rejects inbound requests with less than the given time left
+// before their deadline.
+// Only applies to the inbound middleware.
+//
+// Defaults to 0, rejecting only requests that arrive after their deadline.
+func MinBudget(d time.Duration) Option {
+	return optionFunc(func(opts *options) {
+		opts.minBudget = d
+	})
+}
+
+// Reserve sets aside the given time at the end of the deadline of inbound
+// requests for processing the responses of outbound calls, which must end
+// that much earlier.
+// Only applies to the inbound middleware.
+//
+// Defaults to 0.
+func Reserve(d time.Duration) Option {
+	return optionFunc(func(opts *options) {
+		opts.reserve = d
+	})
+}
+
+// Meter sets the metrics scope on which the middleware records expired,
+// rejected and shrunk requests.
+//
+// Defaults to no metrics.
+func Meter(meter *metrics.Scope) Option {
+	return optionFunc(func(opts *options) {
+		opts.meter = meter
+	})
+}
+
+// Logger sets a logger for the middleware.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(opts *options) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
@@ -169,6 +169,26 @@ var (
 		</tbody>
 		{{end}}
 	</table>
+	{{if .OutboundMiddleware}}
+	<h3>Outbound Middleware</h3>
+	{{range .OutboundMiddleware}}
+	<h4>{{.Name}}</h4>
+	<table>
+		<tr>
+			<th>Key</th>
+			<th>State</th>
+			<th>Details</th>
+		</tr>
+		{{range .Entries}}
+		<tr>
+			<td>{{.Key}}</td>
+			<td>{{.State}}</td>
+			<td>{{.Details}}</td>
+		</tr>
+		{{end}}
+	</table>
+	{{end}}
+	{{end}}
 {{end}}
 	</body>
 </html>
@@ -0,0 +1,215 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/config"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+	"gopkg.in/yaml.v2"
+)
+
+// Configuration declares fault injection rules.
+//
+//	rules:
+//	  - service: users
+//	    procedure: Users::get
+//	    percentage: 10
+//	    delay: 200ms
+//	  - caller: batch-job
+//	    headers:
+//	      x-chaos: "true"
+//	    percentage: 50
+//	    code: unavailable
+//	  - procedure: Users::create
+//	    percentage: 5
+//	    applicationError: true
+//	  - procedure: Events::publish
+//	    percentage: 1
+//	    drop: true
+//
+// Codes are spelled as in yarpcerrors, like "unavailable" or
+// "deadline-exceeded".
+// See Rule for the meaning of each field.
+type Configuration struct {
+	Rules []RuleConfiguration `config:"rules"`
+}
+
+// RuleConfiguration declares a single fault injection rule.
+type RuleConfiguration struct {
+	Service          string            `config:"service" yaml:"service,omitempty"`
+	Procedure        string            `config:"procedure" yaml:"procedure,omitempty"`
+	Caller           string            `config:"caller" yaml:"caller,omitempty"`
+	Headers          map[string]string `config:"headers" yaml:"headers,omitempty"`
+	Percentage       float64           `config:"percentage" yaml:"percentage"`
+	Delay            time.Duration     `config:"delay" yaml:"-"`
+	Code             string            `config:"code" yaml:"code,omitempty"`
+	ApplicationError bool              `config:"applicationError" yaml:"applicationError,omitempty"`
+	Drop             bool              `config:"drop" yaml:"drop,omitempty"`
+}
+
+// InboundSpec returns a configuration specification for the fault injection
+// inbound middleware, making it possible to declare rules in the
+// inboundMiddleware section of yarpcconfig.
+//
+//	rules := fault.NewRuleSet()
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterInboundMiddleware(fault.InboundSpec(rules))
+//
+// This enables the middleware for unary and oneway inbounds:
+//
+//	inboundMiddleware:
+//	  fault:
+//	    rules:
+//	      - procedure: Users::get
+//	        percentage: 10
+//	        code: unavailable
+//
+// The declared rules replace the rules of the set.
+// See Configuration for the full shape of the configuration.
+func InboundSpec(rules *RuleSet, opts ...Option) yarpcconfig.InboundMiddlewareSpec {
+	return yarpcconfig.InboundMiddlewareSpec{
+		Name: "fault",
+		BuildInboundMiddleware: func(cfg Configuration, _ *yarpcconfig.Kit) (yarpc.InboundMiddleware, error) {
+			if err := cfg.setInto(rules); err != nil {
+				return yarpc.InboundMiddleware{}, err
+			}
+			mw := NewInboundMiddleware(rules, opts...)
+			return yarpc.InboundMiddleware{Unary: mw, Oneway: mw}, nil
+		},
+	}
+}
+
+// OutboundSpec returns a configuration specification for the fault injection
+// outbound middleware, making it possible to declare rules in the
+// outboundMiddleware section of yarpcconfig.
+//
+//	rules := fault.NewRuleSet()
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(fault.OutboundSpec(rules))
+//
+// This enables the middleware for unary and oneway outbounds:
+//
+//	outboundMiddleware:
+//	  fault:
+//	    rules:
+//	      - service: users
+//	        percentage: 10
+//	        delay: 200ms
+//
+// The declared rules replace the rules of the set.
+// See Configuration for the full shape of the configuration.
+func OutboundSpec(rules *RuleSet, opts ...Option) yarpcconfig.OutboundMiddlewareSpec {
+	return yarpcconfig.OutboundMiddlewareSpec{
+		Name: "fault",
+		BuildOutboundMiddleware: func(cfg Configuration, _ *yarpcconfig.Kit) (yarpc.OutboundMiddleware, error) {
+			if err := cfg.setInto(rules); err != nil {
+				return yarpc.OutboundMiddleware{}, err
+			}
+			mw := NewOutboundMiddleware(rules, opts...)
+			return yarpc.OutboundMiddleware{Unary: mw, Oneway: mw}, nil
+		},
+	}
+}
+
+// ParseRules parses fault injection rules from a YAML document with the
+// shape of a Configuration.
+func ParseRules(data []byte) ([]Rule, error) {
+	var src map[string]interface{}
+	if err := yaml.Unmarshal(data, &src); err != nil {
+		return nil, fmt.Errorf("failed to parse fault rules: %v", err)
+	}
+
+	var cfg Configuration
+	if err := config.DecodeInto(&cfg, src); err != nil {
+		return nil, fmt.Errorf("failed to decode fault rules: %v", err)
+	}
+	return cfg.rules()
+}
+
+// MarshalRules formats fault injection rules as a YAML document with the
+// shape of a Configuration, which ParseRules accepts.
+func MarshalRules(rules []Rule) ([]byte, error) {
+	// Durations are written in their readable form, which yaml.v2 does not
+	// do on its own.
+	type ruleYAML struct {
+		RuleConfiguration `yaml:",inline"`
+		Delay             string `yaml:"delay,omitempty"`
+	}
+	out := struct {
+		Rules []ruleYAML `yaml:"rules"`
+	}{Rules: make([]ruleYAML, 0, len(rules))}
+
+	for _, r := range rules {
+		ry := ruleYAML{RuleConfiguration: RuleConfiguration{
+			Service:          r.Service,
+			Procedure:        r.Procedure,
+			Caller:           r.Caller,
+			Headers:          r.Headers,
+			Percentage:       r.Percentage,
+			ApplicationError: r.ApplicationError,
+			Drop:             r.Drop,
+		}}
+		if r.Delay > 0 {
+			ry.Delay = r.Delay.String()
+		}
+		if r.Code != yarpcerrors.CodeOK {
+			ry.Code = r.Code.String()
+		}
+		out.Rules = append(out.Rules, ry)
+	}
+	return yaml.Marshal(out)
+}
+
+func (c Configuration) rules() ([]Rule, error) {
+	rules := make([]Rule, 0, len(c.Rules))
+	for i, rc := range c.Rules {
+		r := Rule{
+			Service:          rc.Service,
+			Procedure:        rc.Procedure,
+			Caller:           rc.Caller,
+			Headers:          rc.Headers,
+			Percentage:       rc.Percentage,
+			Delay:            rc.Delay,
+			ApplicationError: rc.ApplicationError,
+			Drop:             rc.Drop,
+		}
+		if rc.Code != "" {
+			if err := r.Code.UnmarshalText([]byte(rc.Code)); err != nil {
+				return nil, fmt.Errorf("invalid fault rule %d: %v", i, err)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (c Configuration) setInto(set *RuleSet) error {
+	rules, err := c.rules()
+	if err != nil {
+		return err
+	}
+	return set.Set(rules)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		desc       string
+		give       string
+		want       []Rule
+		wantErrors []string
+	}{
+		{
+			desc: "empty",
+			give: `rules: []`,
+			want: []Rule{},
+		},
+		{
+			desc: "rules",
+			give: `
+				rules:
+					- service: users
+					  procedure: Users::get
+					  percentage: 10
+					  delay: 200ms
+					- caller: batch-job
+					  headers:
+						  x-chaos: "true"
+					  percentage: 50
+					  code: unavailable
+					- procedure: Users::create
+					  percentage: 5
+					  applicationError: true
+					  code: not-found
+					- procedure: Events::publish
+					  percentage: 1
+					  drop: true
+			`,
+			want: []Rule{
+				{Service: "users", Procedure: "Users::get", Percentage: 10, Delay: 200 * time.Millisecond},
+				{Caller: "batch-job", Headers: map[string]string{"x-chaos": "true"}, Percentage: 50, Code: yarpcerrors.CodeUnavailable},
+				{Procedure: "Users::create", Percentage: 5, ApplicationError: true, Code: yarpcerrors.CodeNotFound},
+				{Procedure: "Events::publish", Percentage: 1, Drop: true},
+			},
+		},
+		{
+			desc: "json",
+			give: `{"rules": [{"service": "users", "percentage": 100, "code": "internal"}]}`,
+			want: []Rule{
+				{Service: "users", Percentage: 100, Code: yarpcerrors.CodeInternal},
+			},
+		},
+		{
+			desc: "unknown code",
+			give: `
+				rules:
+					- percentage: 10
+					  code: sadness
+			`,
+			wantErrors: []string{"invalid fault rule 0", "unknown code string: sadness"},
+		},
+		{
+			desc: "unknown field",
+			give: `
+				rules:
+					- percentage: 10
+					  latency: 1s
+			`,
+			wantErrors: []string{"failed to decode fault rules"},
+		},
+		{
+			desc:       "invalid yaml",
+			give:       `rules: [`,
+			wantErrors: []string{"failed to parse fault rules"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rules, err := ParseRules([]byte(whitespace.Expand(tt.give)))
+			if len(tt.wantErrors) > 0 {
+				require.Error(t, err)
+				for _, msg := range tt.wantErrors {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
+
+func TestMarshalRules(t *testing.T) {
+	rules := []Rule{
+		{Service: "users", Procedure: "Users::get", Percentage: 10, Delay: 200 * time.Millisecond},
+		{Caller: "batch-job", Headers: map[string]string{"x-chaos": "true"}, Percentage: 50, Code: yarpcerrors.CodeUnavailable},
+		{Procedure: "Events::publish", Percentage: 1, Drop: true},
+	}
+
+	body, err := MarshalRules(rules)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "delay: 200ms")
+	assert.Contains(t, string(body), "code: unavailable")
+
+	got, err := ParseRules(body)
+	require.NoError(t, err)
+	assert.Equal(t, rules, got)
+}
+
+func TestSpecs(t *testing.T) {
+	var data map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+		inboundMiddleware:
+			fault:
+				rules:
+					- procedure: Users::get
+					  percentage: 10
+					  code: unavailable
+		outboundMiddleware:
+			fault:
+				rules:
+					- service: users
+					  percentage: 20
+					  delay: 1s
+	`)), &data))
+
+	inbound, outbound := NewRuleSet(), NewRuleSet()
+	cfg := yarpcconfig.New()
+	require.NoError(t, cfg.RegisterInboundMiddleware(InboundSpec(inbound)))
+	require.NoError(t, cfg.RegisterOutboundMiddleware(OutboundSpec(outbound)))
+
+	c, err := cfg.LoadConfig("myservice", data)
+	require.NoError(t, err)
+
+	assert.IsType(t, &InboundMiddleware{}, c.InboundMiddleware.Unary)
+	assert.IsType(t, &InboundMiddleware{}, c.InboundMiddleware.Oneway)
+	assert.IsType(t, &OutboundMiddleware{}, c.OutboundMiddleware.Unary)
+	assert.IsType(t, &OutboundMiddleware{}, c.OutboundMiddleware.Oneway)
+
+	assert.Equal(t, []Rule{{Procedure: "Users::get", Percentage: 10, Code: yarpcerrors.CodeUnavailable}}, inbound.Rules())
+	assert.Equal(t, []Rule{{Service: "users", Percentage: 20, Delay: time.Second}}, outbound.Rules())
+
+	t.Run("invalid", func(t *testing.T) {
+		var data map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+			outboundMiddleware:
+				fault:
+					rules:
+						- service: users
+						  percentage: 200
+						  delay: 1s
+		`)), &data))
+
+		_, err := cfg.LoadConfig("myservice", data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "percentage must be between 0 and 100, got 200")
+	})
+}
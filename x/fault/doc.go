@@ -0,0 +1,57 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package fault provides inbound and outbound middleware that injects
+// faults into requests, to test how services and their callers cope with
+// slow or failing dependencies.
+//
+// Rules select the requests to inject faults into by service, procedure,
+// caller and header values, and the percentage of the selected requests
+// that are affected. A rule may delay requests, fail them with an error
+// code or an application error, or drop oneway requests.
+//
+//	rules := fault.NewRuleSet()
+//	err := rules.Set([]fault.Rule{
+//		{
+//			Service:    "users",
+//			Procedure:  "Users::get",
+//			Percentage: 10,
+//			Delay:      200 * time.Millisecond,
+//			Code:       yarpcerrors.CodeUnavailable,
+//		},
+//	})
+//
+//	mw := fault.NewInboundMiddleware(rules, fault.Meter(scope))
+//	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+//		Name: "myservice",
+//		InboundMiddleware: yarpc.InboundMiddleware{
+//			Unary:  mw,
+//			Oneway: mw,
+//		},
+//	})
+//
+// Rules may be parsed from YAML with ParseRules, declared in yarpcconfig by
+// registering InboundSpec and OutboundSpec with the Configurator, and
+// replaced at runtime through the procedures returned by Procedures.
+//
+// Every injected fault is counted by the inbound_fault_injections or
+// outbound_fault_injections metric, tagged with the kind of fault: "delay",
+// "error", "application-error" or "drop".
+package fault
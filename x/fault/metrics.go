@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/zap"
+)
+
+const (
+	_source    = "source"
+	_dest      = "dest"
+	_procedure = "procedure"
+	_fault     = "fault"
+
+	// Kinds of injected faults, used as values of the fault tag.
+	_faultDelay            = "delay"
+	_faultError            = "error"
+	_faultApplicationError = "application-error"
+	_faultDrop             = "drop"
+)
+
+// observer records injected faults, keyed by caller, service, procedure and
+// the kind of fault.
+type observer struct {
+	logger     *zap.Logger
+	injections *metrics.CounterVector
+}
+
+func newObserver(name string, meter *metrics.Scope, logger *zap.Logger) *observer {
+	o := &observer{logger: logger}
+
+	var err error
+	o.injections, err = meter.CounterVector(metrics.Spec{
+		Name:    name,
+		Help:    "Number of faults injected into requests, by kind of fault.",
+		VarTags: []string{_source, _dest, _procedure, _fault},
+	})
+	if err != nil {
+		logger.Error("Failed to create fault injections counter.", zap.Error(err))
+	}
+	return o
+}
+
+func (o *observer) inject(req *transport.Request, fault string) {
+	o.logger.Debug("Injecting fault.",
+		zap.String("caller", req.Caller),
+		zap.String("service", req.Service),
+		zap.String("procedure", req.Procedure),
+		zap.String("fault", fault))
+
+	c, err := o.injections.Get(_source, req.Caller, _dest, req.Service, _procedure, req.Procedure, _fault, fault)
+	if err != nil {
+		o.logger.Error("Failed to get fault injections counter.", zap.Error(err))
+	}
+	c.Inc()
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// ApplicationErrorName is the name of the application errors injected by
+// the middleware, surfaced in the metrics of transports that support it.
+const ApplicationErrorName = "FaultInjected"
+
+var (
+	_ middleware.UnaryInbound   = (*InboundMiddleware)(nil)
+	_ middleware.OnewayInbound  = (*InboundMiddleware)(nil)
+	_ middleware.UnaryOutbound  = (*OutboundMiddleware)(nil)
+	_ middleware.OnewayOutbound = (*OutboundMiddleware)(nil)
+)
+
+// injector looks up the rule that applies to each request and records the
+// faults it injects.
+type injector struct {
+	rules    *RuleSet
+	observer *observer
+}
+
+func newInjector(rules *RuleSet, metricName string, opts []Option) injector {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return injector{
+		rules:    rules,
+		observer: newObserver(metricName, options.meter, options.logger),
+	}
+}
+
+// delay holds the request for the delay of the rule, failing it if its
+// context ends first.
+func (i *injector) delay(ctx context.Context, req *transport.Request, rule *Rule) error {
+	if rule.Delay <= 0 {
+		return nil
+	}
+	i.observer.inject(req, _faultDelay)
+
+	timer := time.NewTimer(rule.Delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return yarpcerrors.DeadlineExceededErrorf("deadline exceeded while injecting a delay of %v", rule.Delay)
+		}
+		return yarpcerrors.CancelledErrorf("request cancelled while injecting a delay of %v", rule.Delay)
+	}
+}
+
+// fail returns the error the rule fails the request with, if any.
+func (i *injector) fail(req *transport.Request, rule *Rule) error {
+	err := rule.err()
+	switch {
+	case err == nil:
+	case rule.ApplicationError:
+		i.observer.inject(req, _faultApplicationError)
+	default:
+		i.observer.inject(req, _faultError)
+	}
+	return err
+}
+
+func applicationErrorMeta(rule *Rule) *transport.ApplicationErrorMeta {
+	meta := &transport.ApplicationErrorMeta{Name: ApplicationErrorName}
+	if rule.Code != yarpcerrors.CodeOK {
+		code := rule.Code
+		meta.Code = &code
+	}
+	return meta
+}
+
+// InboundMiddleware is unary and oneway inbound middleware that injects
+// faults into the requests matching its rules before they are handled.
+type InboundMiddleware struct {
+	injector
+}
+
+// NewInboundMiddleware creates a new fault injection middleware for
+// inbounds, following the given rules.
+//
+// Injected faults are counted by the inbound_fault_injections metric.
+func NewInboundMiddleware(rules *RuleSet, opts ...Option) *InboundMiddleware {
+	return &InboundMiddleware{injector: newInjector(rules, "inbound_fault_injections", opts)}
+}
+
+// Handle implements middleware.UnaryInbound.
+func (m *InboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	rule, ok := m.rules.match(req)
+	if !ok {
+		return h.Handle(ctx, req, resw)
+	}
+	if err := m.delay(ctx, req, &rule); err != nil {
+		return err
+	}
+	if err := m.fail(req, &rule); err != nil {
+		if rule.ApplicationError {
+			resw.SetApplicationError()
+			if setter, ok := resw.(transport.ApplicationErrorMetaSetter); ok {
+				setter.SetApplicationErrorMeta(applicationErrorMeta(&rule))
+			}
+		}
+		return err
+	}
+	return h.Handle(ctx, req, resw)
+}
+
+// HandleOneway implements middleware.OnewayInbound.
+func (m *InboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	rule, ok := m.rules.match(req)
+	if !ok {
+		return h.HandleOneway(ctx, req)
+	}
+	if err := m.delay(ctx, req, &rule); err != nil {
+		return err
+	}
+	if rule.Drop {
+		m.observer.inject(req, _faultDrop)
+		return nil
+	}
+	if err := m.fail(req, &rule); err != nil {
+		return err
+	}
+	return h.HandleOneway(ctx, req)
+}
+
+// OutboundMiddleware is unary and oneway outbound middleware that injects
+// faults into the requests matching its rules before they are sent.
+type OutboundMiddleware struct {
+	injector
+}
+
+// NewOutboundMiddleware creates a new fault injection middleware for
+// outbounds, following the given rules.
+//
+// Injected faults are counted by the outbound_fault_injections metric.
+func NewOutboundMiddleware(rules *RuleSet, opts ...Option) *OutboundMiddleware {
+	return &OutboundMiddleware{injector: newInjector(rules, "outbound_fault_injections", opts)}
+}
+
+// Call implements middleware.UnaryOutbound.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	rule, ok := m.rules.match(req)
+	if !ok {
+		return out.Call(ctx, req)
+	}
+	if err := m.delay(ctx, req, &rule); err != nil {
+		return nil, err
+	}
+	if err := m.fail(req, &rule); err != nil {
+		if !rule.ApplicationError {
+			return nil, err
+		}
+		// Transports return application errors along with a response, so
+		// that encodings may decode the error from its body.
+		return &transport.Response{
+			Body:                 ioutil.NopCloser(&bytes.Buffer{}),
+			ApplicationError:     true,
+			ApplicationErrorMeta: applicationErrorMeta(&rule),
+		}, err
+	}
+	return out.Call(ctx, req)
+}
+
+// CallOneway implements middleware.OnewayOutbound.
+func (m *OutboundMiddleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	rule, ok := m.rules.match(req)
+	if !ok {
+		return out.CallOneway(ctx, req)
+	}
+	if err := m.delay(ctx, req, &rule); err != nil {
+		return nil, err
+	}
+	if rule.Drop {
+		m.observer.inject(req, _faultDrop)
+		return ack{}, nil
+	}
+	if err := m.fail(req, &rule); err != nil {
+		return nil, err
+	}
+	return out.CallOneway(ctx, req)
+}
+
+// ack acknowledges dropped oneway requests.
+type ack struct{}
+
+func (ack) String() string { return "" }
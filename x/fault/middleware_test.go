@@ -0,0 +1,309 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/testutils"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+type unaryHandlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f unaryHandlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+type onewayHandlerFunc func(context.Context, *transport.Request) error
+
+func (f onewayHandlerFunc) HandleOneway(ctx context.Context, req *transport.Request) error {
+	return f(ctx, req)
+}
+
+func newRequest() *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "procedure",
+	}
+}
+
+func newRuleSet(t *testing.T, rules ...Rule) *RuleSet {
+	set := NewRuleSet()
+	require.NoError(t, set.Set(rules))
+	return set
+}
+
+func TestInboundMiddlewareUnary(t *testing.T) {
+	tests := []struct {
+		desc        string
+		give        []Rule
+		wantCode    yarpcerrors.Code
+		wantHandled bool
+		wantAppErr  bool
+		wantMeta    *transport.ApplicationErrorMeta
+		wantFaults  []string
+	}{
+		{
+			desc:        "no rule",
+			wantHandled: true,
+		},
+		{
+			desc:        "rule for another procedure",
+			give:        []Rule{{Procedure: "other", Percentage: 100, Code: yarpcerrors.CodeInternal}},
+			wantHandled: true,
+		},
+		{
+			desc:        "delay",
+			give:        []Rule{{Percentage: 100, Delay: time.Millisecond}},
+			wantHandled: true,
+			wantFaults:  []string{_faultDelay},
+		},
+		{
+			desc:       "error",
+			give:       []Rule{{Percentage: 100, Delay: time.Millisecond, Code: yarpcerrors.CodeUnavailable}},
+			wantCode:   yarpcerrors.CodeUnavailable,
+			wantFaults: []string{_faultDelay, _faultError},
+		},
+		{
+			desc:       "application error",
+			give:       []Rule{{Percentage: 100, ApplicationError: true}},
+			wantCode:   yarpcerrors.CodeUnknown,
+			wantAppErr: true,
+			wantMeta:   &transport.ApplicationErrorMeta{Name: ApplicationErrorName},
+			wantFaults: []string{_faultApplicationError},
+		},
+		{
+			desc:       "application error with code",
+			give:       []Rule{{Percentage: 100, ApplicationError: true, Code: yarpcerrors.CodeNotFound}},
+			wantCode:   yarpcerrors.CodeNotFound,
+			wantAppErr: true,
+			wantMeta: &transport.ApplicationErrorMeta{
+				Name: ApplicationErrorName,
+				Code: func() *yarpcerrors.Code { c := yarpcerrors.CodeNotFound; return &c }(),
+			},
+			wantFaults: []string{_faultApplicationError},
+		},
+		{
+			desc:        "drop applies to oneway requests only",
+			give:        []Rule{{Percentage: 100, Drop: true}},
+			wantHandled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := metrics.New()
+			mw := NewInboundMiddleware(newRuleSet(t, tt.give...), Meter(root.Scope()))
+
+			var handled bool
+			resw := &transporttest.FakeResponseWriter{}
+			err := mw.Handle(context.Background(), newRequest(), resw,
+				unaryHandlerFunc(func(context.Context, *transport.Request, transport.ResponseWriter) error {
+					handled = true
+					return nil
+				}))
+
+			assert.Equal(t, tt.wantCode, yarpcerrors.FromError(err).Code())
+			assert.Equal(t, tt.wantHandled, handled, "handled")
+			assert.Equal(t, tt.wantAppErr, resw.IsApplicationError, "application error")
+			assert.Equal(t, tt.wantMeta, resw.ApplicationErrorMeta)
+			assertInjections(t, "inbound_fault_injections", tt.wantFaults, root)
+		})
+	}
+}
+
+func TestInboundMiddlewareOneway(t *testing.T) {
+	tests := []struct {
+		desc        string
+		give        []Rule
+		wantCode    yarpcerrors.Code
+		wantHandled bool
+		wantFaults  []string
+	}{
+		{
+			desc:        "no rule",
+			wantHandled: true,
+		},
+		{
+			desc:       "drop",
+			give:       []Rule{{Percentage: 100, Drop: true}},
+			wantFaults: []string{_faultDrop},
+		},
+		{
+			desc:       "error",
+			give:       []Rule{{Percentage: 100, Code: yarpcerrors.CodeResourceExhausted}},
+			wantCode:   yarpcerrors.CodeResourceExhausted,
+			wantFaults: []string{_faultError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := metrics.New()
+			mw := NewInboundMiddleware(newRuleSet(t, tt.give...), Meter(root.Scope()))
+
+			var handled bool
+			err := mw.HandleOneway(context.Background(), newRequest(),
+				onewayHandlerFunc(func(context.Context, *transport.Request) error {
+					handled = true
+					return nil
+				}))
+
+			assert.Equal(t, tt.wantCode, yarpcerrors.FromError(err).Code())
+			assert.Equal(t, tt.wantHandled, handled, "handled")
+			assertInjections(t, "inbound_fault_injections", tt.wantFaults, root)
+		})
+	}
+}
+
+func TestInboundMiddlewareDelayExceedsDeadline(t *testing.T) {
+	mw := NewInboundMiddleware(newRuleSet(t, Rule{Percentage: 100, Delay: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := mw.Handle(ctx, newRequest(), &transporttest.FakeResponseWriter{},
+		unaryHandlerFunc(func(context.Context, *transport.Request, transport.ResponseWriter) error {
+			t.Fatal("handler must not be called")
+			return nil
+		}))
+	assert.Equal(t, yarpcerrors.CodeDeadlineExceeded, yarpcerrors.FromError(err).Code())
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = mw.HandleOneway(ctx, newRequest(),
+		onewayHandlerFunc(func(context.Context, *transport.Request) error {
+			t.Fatal("handler must not be called")
+			return nil
+		}))
+	assert.Equal(t, yarpcerrors.CodeCancelled, yarpcerrors.FromError(err).Code())
+}
+
+func TestOutboundMiddleware(t *testing.T) {
+	var calls, onewayCalls atomic.Int32
+	out := yarpctest.NewFakeTransport().NewOutbound(nil,
+		yarpctest.OutboundCallOverride(func(context.Context, *transport.Request) (*transport.Response, error) {
+			calls.Inc()
+			return &transport.Response{}, nil
+		}),
+		yarpctest.OutboundCallOnewayOverride(func(context.Context, *transport.Request) (transport.Ack, error) {
+			onewayCalls.Inc()
+			return nil, nil
+		}),
+	)
+
+	t.Run("error", func(t *testing.T) {
+		mw := NewOutboundMiddleware(newRuleSet(t, Rule{Percentage: 100, Code: yarpcerrors.CodeUnavailable}))
+		res, err := mw.Call(context.Background(), newRequest(), out)
+		assert.Nil(t, res)
+		assert.Equal(t, yarpcerrors.CodeUnavailable, yarpcerrors.FromError(err).Code())
+
+		_, err = mw.CallOneway(context.Background(), newRequest(), out)
+		assert.Equal(t, yarpcerrors.CodeUnavailable, yarpcerrors.FromError(err).Code())
+	})
+
+	t.Run("application error", func(t *testing.T) {
+		mw := NewOutboundMiddleware(newRuleSet(t, Rule{Percentage: 100, ApplicationError: true}))
+		res, err := mw.Call(context.Background(), newRequest(), out)
+		assert.Equal(t, yarpcerrors.CodeUnknown, yarpcerrors.FromError(err).Code())
+		require.NotNil(t, res)
+		assert.True(t, res.ApplicationError)
+		assert.Equal(t, &transport.ApplicationErrorMeta{Name: ApplicationErrorName}, res.ApplicationErrorMeta)
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		root := metrics.New()
+		mw := NewOutboundMiddleware(newRuleSet(t, Rule{Percentage: 100, Drop: true}), Meter(root.Scope()))
+		ack, err := mw.CallOneway(context.Background(), newRequest(), out)
+		require.NoError(t, err)
+		assert.NotNil(t, ack)
+		assertInjections(t, "outbound_fault_injections", []string{_faultDrop}, root)
+	})
+
+	assert.Equal(t, int32(0), calls.Load(), "faulty requests must not be sent")
+	assert.Equal(t, int32(0), onewayCalls.Load(), "faulty oneway requests must not be sent")
+
+	t.Run("delay", func(t *testing.T) {
+		root := metrics.New()
+		mw := NewOutboundMiddleware(newRuleSet(t, Rule{Percentage: 100, Delay: time.Millisecond}), Meter(root.Scope()))
+		_, err := mw.Call(context.Background(), newRequest(), out)
+		require.NoError(t, err)
+		_, err = mw.CallOneway(context.Background(), newRequest(), out)
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Equal(t, int32(1), onewayCalls.Load())
+		testutils.AssertCounters(t, []testutils.CounterAssertion{
+			{Name: "outbound_fault_injections", Tags: injectionTags(_faultDelay), Value: 2},
+		}, root.Snapshot().Counters)
+	})
+}
+
+func TestRulesUpdatedAtRuntime(t *testing.T) {
+	rules := NewRuleSet()
+	mw := NewOutboundMiddleware(rules)
+	out := yarpctest.NewFakeTransport().NewOutbound(nil,
+		yarpctest.OutboundCallOverride(func(context.Context, *transport.Request) (*transport.Response, error) {
+			return &transport.Response{}, nil
+		}))
+
+	_, err := mw.Call(context.Background(), newRequest(), out)
+	require.NoError(t, err)
+
+	require.NoError(t, rules.Set([]Rule{{Percentage: 100, Code: yarpcerrors.CodeAborted}}))
+	_, err = mw.Call(context.Background(), newRequest(), out)
+	assert.Equal(t, yarpcerrors.CodeAborted, yarpcerrors.FromError(err).Code())
+
+	require.NoError(t, rules.Set(nil))
+	_, err = mw.Call(context.Background(), newRequest(), out)
+	require.NoError(t, err)
+}
+
+func injectionTags(fault string) map[string]string {
+	return map[string]string{
+		_source:    "caller",
+		_dest:      "service",
+		_procedure: "procedure",
+		_fault:     fault,
+	}
+}
+
+func assertInjections(t *testing.T, name string, faults []string, root *metrics.Root) {
+	want := make([]testutils.CounterAssertion, 0, len(faults))
+	for _, fault := range faults {
+		want = append(want, testutils.CounterAssertion{Name: name, Tags: injectionTags(fault), Value: 1})
+	}
+	testutils.AssertCounters(t, want, root.Snapshot().Counters)
+}
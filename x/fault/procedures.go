@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"context"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Procedures returns raw procedures that read and replace the rules of a set
+// at runtime, named after the given prefix:
+//
+//	<prefix>::getRules returns the rules of the set as a YAML document with
+//	the shape of a Configuration.
+//
+//	<prefix>::setRules replaces the rules of the set with the ones of the
+//	YAML or JSON document it receives, returning the new rules.
+//
+// Use a different prefix for each set registered with a dispatcher.
+// Anyone who may call these procedures may fail the requests of the service,
+// so they should only be reachable from trusted callers.
+//
+//	dispatcher.Register(fault.Procedures("fault", rules))
+func Procedures(prefix string, rules *RuleSet) []transport.Procedure {
+	a := admin{rules: rules}
+	return append(
+		raw.Procedure(prefix+"::getRules", a.getRules),
+		raw.Procedure(prefix+"::setRules", a.setRules)...,
+	)
+}
+
+type admin struct {
+	rules *RuleSet
+}
+
+func (a admin) getRules(context.Context, []byte) ([]byte, error) {
+	body, err := MarshalRules(a.rules.Rules())
+	if err != nil {
+		return nil, yarpcerrors.InternalErrorf("failed to marshal fault rules: %v", err)
+	}
+	return body, nil
+}
+
+func (a admin) setRules(ctx context.Context, body []byte) ([]byte, error) {
+	rules, err := ParseRules(body)
+	if err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf("%v", err)
+	}
+	if err := a.rules.Set(rules); err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf("%v", err)
+	}
+	return a.getRules(ctx, nil)
+}
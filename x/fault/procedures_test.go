@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func callProcedure(t *testing.T, procedures []transport.Procedure, name, body string) (string, error) {
+	for _, p := range procedures {
+		if p.Name != name {
+			continue
+		}
+		resw := &transporttest.FakeResponseWriter{}
+		err := p.HandlerSpec.Unary().Handle(context.Background(), &transport.Request{
+			Caller:    "admin",
+			Service:   "myservice",
+			Procedure: name,
+			Encoding:  raw.Encoding,
+			Body:      bytes.NewBufferString(body),
+		}, resw)
+		return resw.Body.String(), err
+	}
+	t.Fatalf("unknown procedure %q", name)
+	return "", nil
+}
+
+func TestProcedures(t *testing.T) {
+	rules := NewRuleSet()
+	procedures := Procedures("fault", rules)
+
+	names := make([]string, 0, len(procedures))
+	for _, p := range procedures {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"fault::getRules", "fault::setRules"}, names)
+
+	body, err := callProcedure(t, procedures, "fault::getRules", "")
+	require.NoError(t, err)
+	assert.Equal(t, "rules: []\n", body)
+
+	body, err = callProcedure(t, procedures, "fault::setRules", whitespace.Expand(`
+		rules:
+			- service: users
+			  percentage: 25
+			  code: unavailable
+	`))
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{{Service: "users", Percentage: 25, Code: yarpcerrors.CodeUnavailable}}, rules.Rules())
+
+	got, err := callProcedure(t, procedures, "fault::getRules", "")
+	require.NoError(t, err)
+	assert.Equal(t, body, got, "setRules must return the new rules")
+
+	t.Run("invalid rules", func(t *testing.T) {
+		_, err := callProcedure(t, procedures, "fault::setRules", `{"rules": [{"percentage": 25}]}`)
+		require.Error(t, err)
+		assert.Equal(t, yarpcerrors.CodeInvalidArgument, yarpcerrors.FromError(err).Code())
+		assert.Contains(t, err.Error(), "rule must inject")
+		assert.Len(t, rules.Rules(), 1, "rules must be left unchanged")
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+var _randFloat64 = rand.Float64 // for tests
+
+// Rule injects faults into the requests it matches.
+//
+// A rule matches requests whose service, procedure, caller and headers equal
+// the ones of the rule. Fields left empty match any request.
+type Rule struct {
+	Service   string
+	Procedure string
+	Caller    string
+	Headers   map[string]string
+
+	// Percentage of the matching requests to inject faults into, between 0
+	// and 100.
+	Percentage float64
+
+	// Delay holds requests for the given duration before handling or sending
+	// them, or before failing them if the rule also injects an error.
+	Delay time.Duration
+
+	// Code fails requests with an error of the given code instead of
+	// handling or sending them.
+	Code yarpcerrors.Code
+
+	// ApplicationError fails unary requests with an application error
+	// instead of handling or sending them. Code, if set, is the code of the
+	// application error.
+	ApplicationError bool
+
+	// Drop drops oneway requests instead of handling or sending them,
+	// acknowledging them as if they had been delivered.
+	Drop bool
+}
+
+func (r *Rule) validate() error {
+	var err error
+	if r.Percentage < 0 || r.Percentage > 100 {
+		err = multierr.Append(err, fmt.Errorf("percentage must be between 0 and 100, got %v", r.Percentage))
+	}
+	if r.Delay < 0 {
+		err = multierr.Append(err, fmt.Errorf("delay must not be negative, got %v", r.Delay))
+	}
+	if r.Drop && (r.Code != yarpcerrors.CodeOK || r.ApplicationError) {
+		err = multierr.Append(err, errors.New("drop cannot be combined with an error"))
+	}
+	if r.Delay == 0 && r.Code == yarpcerrors.CodeOK && !r.ApplicationError && !r.Drop {
+		err = multierr.Append(err, errors.New("rule must inject a delay, an error, an application error or drop requests"))
+	}
+	return err
+}
+
+func (r *Rule) matches(req *transport.Request) bool {
+	if r.Service != "" && r.Service != req.Service {
+		return false
+	}
+	if r.Procedure != "" && r.Procedure != req.Procedure {
+		return false
+	}
+	if r.Caller != "" && r.Caller != req.Caller {
+		return false
+	}
+	for k, want := range r.Headers {
+		if got, ok := req.Headers.Get(k); !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// err returns the error that the rule fails requests with, if any.
+func (r *Rule) err() error {
+	switch {
+	case r.ApplicationError:
+		code := r.Code
+		if code == yarpcerrors.CodeOK {
+			code = yarpcerrors.CodeUnknown
+		}
+		return yarpcerrors.Newf(code, "application error injected by fault rule")
+	case r.Code != yarpcerrors.CodeOK:
+		return yarpcerrors.Newf(r.Code, "error injected by fault rule")
+	default:
+		return nil
+	}
+}
+
+// RuleSet holds the fault injection rules of a middleware.
+// Rules may be replaced at any time, including while requests are in
+// flight.
+type RuleSet struct {
+	mu    sync.RWMutex
+	rules []Rule
+}
+
+// NewRuleSet creates a new RuleSet with no rules.
+func NewRuleSet() *RuleSet {
+	return &RuleSet{}
+}
+
+// Set replaces the rules of the set.
+// Rules are evaluated in order; the first matching rule selected by its
+// percentage applies to a request.
+//
+// Returns an error, leaving the rules unchanged, if any rule is invalid.
+func (s *RuleSet) Set(rules []Rule) error {
+	var err error
+	for i := range rules {
+		if e := rules[i].validate(); e != nil {
+			err = multierr.Append(err, fmt.Errorf("invalid fault rule %d: %v", i, e))
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	rules = append([]Rule(nil), rules...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = rules
+	return nil
+}
+
+// Rules returns the rules of the set.
+func (s *RuleSet) Rules() []Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Rule(nil), s.rules...)
+}
+
+// match returns the rule that applies to the request, if any.
+func (s *RuleSet) match(req *transport.Request) (Rule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.rules {
+		if r.matches(req) && _randFloat64()*100 < r.Percentage {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func TestRuleSetValidation(t *testing.T) {
+	tests := []struct {
+		desc       string
+		give       Rule
+		wantErrors []string
+	}{
+		{
+			desc: "delay",
+			give: Rule{Percentage: 100, Delay: time.Second},
+		},
+		{
+			desc: "error",
+			give: Rule{Percentage: 50, Code: yarpcerrors.CodeUnavailable},
+		},
+		{
+			desc: "application error",
+			give: Rule{Percentage: 50, ApplicationError: true},
+		},
+		{
+			desc: "drop",
+			give: Rule{Percentage: 50, Drop: true},
+		},
+		{
+			desc:       "no fault",
+			give:       Rule{Service: "users", Percentage: 100},
+			wantErrors: []string{"rule must inject a delay, an error, an application error or drop requests"},
+		},
+		{
+			desc: "invalid values",
+			give: Rule{Percentage: 101, Delay: -time.Second, Code: yarpcerrors.CodeInternal, Drop: true},
+			wantErrors: []string{
+				"invalid fault rule 0",
+				"percentage must be between 0 and 100, got 101",
+				"delay must not be negative, got -1s",
+				"drop cannot be combined with an error",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rules := NewRuleSet()
+			err := rules.Set([]Rule{tt.give})
+			if len(tt.wantErrors) == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, []Rule{tt.give}, rules.Rules())
+				return
+			}
+			require.Error(t, err)
+			for _, msg := range tt.wantErrors {
+				assert.Contains(t, err.Error(), msg)
+			}
+			assert.Empty(t, rules.Rules(), "invalid rules must not be set")
+		})
+	}
+}
+
+func TestRuleSetMatch(t *testing.T) {
+	defer func(f func() float64) { _randFloat64 = f }(_randFloat64)
+	_randFloat64 = func() float64 { return 0.5 }
+
+	rules := NewRuleSet()
+	require.NoError(t, rules.Set([]Rule{
+		{Service: "users", Procedure: "Users::get", Percentage: 100, Code: yarpcerrors.CodeNotFound},
+		{Caller: "batch", Headers: map[string]string{"x-chaos": "true"}, Percentage: 100, Code: yarpcerrors.CodeUnavailable},
+		{Service: "users", Percentage: 40, Code: yarpcerrors.CodeInternal},
+		{Service: "users", Percentage: 60, Code: yarpcerrors.CodeAborted},
+	}))
+
+	tests := []struct {
+		desc     string
+		give     transport.Request
+		wantCode yarpcerrors.Code
+		wantNone bool
+	}{
+		{
+			desc:     "service and procedure",
+			give:     transport.Request{Service: "users", Procedure: "Users::get"},
+			wantCode: yarpcerrors.CodeNotFound,
+		},
+		{
+			desc: "caller and headers",
+			give: transport.Request{
+				Service: "trips",
+				Caller:  "batch",
+				Headers: transport.NewHeaders().With("X-Chaos", "true"),
+			},
+			wantCode: yarpcerrors.CodeUnavailable,
+		},
+		{
+			desc: "header value mismatch",
+			give: transport.Request{
+				Service: "trips",
+				Caller:  "batch",
+				Headers: transport.NewHeaders().With("x-chaos", "false"),
+			},
+			wantNone: true,
+		},
+		{
+			desc:     "rules not selected by their percentage are skipped",
+			give:     transport.Request{Service: "users", Procedure: "Users::list"},
+			wantCode: yarpcerrors.CodeAborted,
+		},
+		{
+			desc:     "no match",
+			give:     transport.Request{Service: "trips", Caller: "batch"},
+			wantNone: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rule, ok := rules.match(&tt.give)
+			if tt.wantNone {
+				assert.False(t, ok, "unexpected rule %+v", rule)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.wantCode, rule.Code)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpcreflection
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"go.uber.org/yarpc/encoding/protobuf/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// resolver finds descriptors in the files of the ServerMeta of the server,
+// and then in the files registered by generated Go code.
+type resolver []*protoregistry.Files
+
+var _ protodesc.Resolver = resolver(nil)
+
+// newResolver decodes the file descriptors of the given ServerMeta.
+func newResolver(metas []reflection.ServerMeta) (resolver, error) {
+	pending := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, meta := range metas {
+		for _, compressed := range meta.FileDescriptors {
+			fdp, err := decodeFileDescriptor(compressed)
+			if err != nil {
+				return nil, fmt.Errorf("invalid file descriptor for service %q: %v", meta.ServiceName, err)
+			}
+			pending[fdp.GetName()] = fdp
+		}
+	}
+
+	files := new(protoregistry.Files)
+	r := resolver{files, protoregistry.GlobalFiles}
+
+	// Files must be built after their dependencies. Dependencies missing
+	// from the ServerMeta must be registered by generated Go code.
+	var build func(name string) error
+	build = func(name string) error {
+		fdp, ok := pending[name]
+		if !ok {
+			return nil
+		}
+		delete(pending, name)
+
+		for _, dep := range fdp.GetDependency() {
+			if err := build(dep); err != nil {
+				return err
+			}
+		}
+		fd, err := protodesc.NewFile(fdp, r)
+		if err != nil {
+			return fmt.Errorf("invalid file descriptor %q: %v", name, err)
+		}
+		return files.RegisterFile(fd)
+	}
+
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := build(name); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func decodeFileDescriptor(compressed []byte) (*descriptorpb.FileDescriptorProto, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var fdp descriptorpb.FileDescriptorProto
+	if err := proto.Unmarshal(b, &fdp); err != nil {
+		return nil, err
+	}
+	return &fdp, nil
+}
+
+// FindFileByPath implements protodesc.Resolver.
+func (r resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	for _, files := range r {
+		if fd, err := files.FindFileByPath(path); err == nil {
+			return fd, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+// FindDescriptorByName implements protodesc.Resolver.
+func (r resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	for _, files := range r {
+		if d, err := files.FindDescriptorByName(name); err == nil {
+			return d, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+// findService returns whether the named service is known.
+func (r resolver) findService(name string) bool {
+	d, err := r.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return false
+	}
+	_, ok := d.(protoreflect.ServiceDescriptor)
+	return ok
+}
+
+// rangeExtensions calls f for every extension of the given message, until f
+// returns false.
+func (r resolver) rangeExtensions(message protoreflect.FullName, f func(protoreflect.ExtensionDescriptor) bool) {
+	more := true
+	visit := func(exts protoreflect.ExtensionDescriptors) {
+		for i := 0; more && i < exts.Len(); i++ {
+			if ext := exts.Get(i); ext.ContainingMessage().FullName() == message {
+				more = f(ext)
+			}
+		}
+	}
+	var visitMessages func(protoreflect.MessageDescriptors)
+	visitMessages = func(msgs protoreflect.MessageDescriptors) {
+		for i := 0; more && i < msgs.Len(); i++ {
+			visit(msgs.Get(i).Extensions())
+			visitMessages(msgs.Get(i).Messages())
+		}
+	}
+
+	for _, files := range r {
+		files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			visit(fd.Extensions())
+			visitMessages(fd.Messages())
+			return more
+		})
+	}
+}
+
+// fileContainingExtension returns the file that declares the given
+// extension of a message.
+func (r resolver) fileContainingExtension(message protoreflect.FullName, number protoreflect.FieldNumber) (protoreflect.FileDescriptor, error) {
+	var fd protoreflect.FileDescriptor
+	r.rangeExtensions(message, func(ext protoreflect.ExtensionDescriptor) bool {
+		if ext.Number() == number {
+			fd = ext.ParentFile()
+			return false
+		}
+		return true
+	})
+	if fd == nil {
+		return nil, protoregistry.NotFound
+	}
+	return fd, nil
+}
+
+// extensionNumbers returns the numbers of every known extension of a
+// message.
+func (r resolver) extensionNumbers(message protoreflect.FullName) ([]int32, error) {
+	d, err := r.FindDescriptorByName(message)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.(protoreflect.MessageDescriptor); !ok {
+		return nil, protoregistry.NotFound
+	}
+
+	seen := make(map[int32]struct{})
+	numbers := make([]int32, 0)
+	r.rangeExtensions(message, func(ext protoreflect.ExtensionDescriptor) bool {
+		n := int32(ext.Number())
+		if _, ok := seen[n]; !ok {
+			seen[n] = struct{}{}
+			numbers = append(numbers, n)
+		}
+		return true
+	})
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers, nil
+}
+
+// encodeFiles serializes the given file and those of its transitive
+// dependencies that the client has not received yet.
+func encodeFiles(fd protoreflect.FileDescriptor, sent map[string]struct{}) ([][]byte, error) {
+	var (
+		encoded [][]byte
+		visit   func(protoreflect.FileDescriptor, bool) error
+	)
+	visit = func(fd protoreflect.FileDescriptor, always bool) error {
+		if _, ok := sent[fd.Path()]; ok && !always {
+			return nil
+		}
+		sent[fd.Path()] = struct{}{}
+
+		b, err := proto.Marshal(protodesc.ToFileDescriptorProto(fd))
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, b)
+
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			if err := visit(imports.Get(i).FileDescriptor, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Clients always receive the file they asked for.
+	if err := visit(fd, true); err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package grpcreflection implements the gRPC server reflection protocol for
+// the protobuf services of a YARPC dispatcher, so that tools like grpcurl
+// can list these services and describe their procedures and messages.
+//
+// New registers the ServerReflectionInfo procedure of both the v1alpha and
+// v1 versions of the protocol with a dispatcher, describing its services
+// with the reflection.ServerMeta generated for them by protoc-gen-yarpc-go.
+//
+//	dispatcher.Register(kvpb.BuildKeyValueYARPCProcedures(handler))
+//	if _, err := grpcreflection.New(dispatcher, kvpb.KeyValueReflectionMeta); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Reflection clients call the protocol over gRPC inbounds.
+//
+//	$ grpcurl -plaintext localhost:8080 list
+//	grpc.reflection.v1alpha.ServerReflection
+//	keyvalue.KeyValue
+//
+// Only the protobuf services with procedures registered with the dispatcher
+// are listed.
+// Services without ServerMeta are described by the file descriptors that
+// their generated Go code registers with the protobuf runtime, if any.
+//
+// See https://github.com/grpc/grpc/blob/master/doc/server-reflection.md for
+// details about the protocol.
+package grpcreflection
@@ -0,0 +1,229 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpcreflection
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/protobuf/reflection"
+	protobuf "go.uber.org/yarpc/encoding/protobuf/v2"
+	"go.uber.org/yarpc/pkg/procedure"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Names of the services of the gRPC server reflection protocol.
+//
+// Both versions of the protocol exchange the same messages.
+const (
+	ServiceNameV1Alpha = "grpc.reflection.v1alpha.ServerReflection"
+	ServiceNameV1      = "grpc.reflection.v1.ServerReflection"
+)
+
+// Server implements the gRPC server reflection protocol for the protobuf
+// services of a dispatcher.
+type Server struct {
+	dispatcher *yarpc.Dispatcher
+	resolver   resolver
+}
+
+// New builds a reflection Server for the protobuf services of the given
+// dispatcher and registers the procedures of both versions of the
+// reflection protocol with it.
+//
+// The ServerMeta generated for protobuf services, like
+// KeyValueReflectionMeta for a KeyValue service, describe the services and
+// their messages. Services without ServerMeta are described by the file
+// descriptors registered by their generated Go code, if any.
+//
+//	server, err := grpcreflection.New(dispatcher, kvpb.KeyValueReflectionMeta)
+func New(d *yarpc.Dispatcher, metas ...reflection.ServerMeta) (*Server, error) {
+	r, err := newResolver(metas)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{dispatcher: d, resolver: r}
+	d.Register(s.Procedures())
+	return s, nil
+}
+
+// Procedures returns the ServerReflectionInfo procedures of both versions
+// of the gRPC server reflection protocol.
+//
+// New registers these procedures with the dispatcher already.
+func (s *Server) Procedures() []transport.Procedure {
+	var procedures []transport.Procedure
+	for _, serviceName := range []string{ServiceNameV1Alpha, ServiceNameV1} {
+		procedures = append(procedures, protobuf.BuildProcedures(protobuf.BuildProceduresParams{
+			ServiceName: serviceName,
+			StreamHandlerParams: []protobuf.BuildProceduresStreamHandlerParams{
+				{
+					MethodName: "ServerReflectionInfo",
+					Handler: protobuf.NewStreamHandler(protobuf.StreamHandlerParams{
+						Handle: s.serverReflectionInfo,
+					}),
+				},
+			},
+		})...)
+	}
+	return procedures
+}
+
+// Services returns the names of the protobuf services of the dispatcher
+// that the reflection Server describes.
+func (s *Server) Services() []string {
+	seen := make(map[string]struct{})
+	var services []string
+	for _, p := range s.dispatcher.Router().Procedures() {
+		service, method := procedure.FromName(p.Name)
+		if method == "" {
+			continue
+		}
+		if _, ok := seen[service]; ok {
+			continue
+		}
+		seen[service] = struct{}{}
+
+		if s.resolver.findService(service) {
+			services = append(services, service)
+		}
+	}
+	sort.Strings(services)
+	return services
+}
+
+// serverReflectionInfo answers every request of the stream, until the
+// client closes it.
+func (s *Server) serverReflectionInfo(stream *protobuf.ServerStream) error {
+	sent := make(map[string]struct{})
+	for {
+		message, err := stream.Receive(newServerReflectionRequest)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		req, ok := message.(*rpb.ServerReflectionRequest)
+		if !ok {
+			return protobuf.CastError(&rpb.ServerReflectionRequest{}, message)
+		}
+
+		if err := stream.Send(s.respond(req, sent)); err != nil {
+			return err
+		}
+	}
+}
+
+// respond answers a request, keeping track of the files sent to the client.
+func (s *Server) respond(req *rpb.ServerReflectionRequest, sent map[string]struct{}) *rpb.ServerReflectionResponse {
+	res := &rpb.ServerReflectionResponse{
+		ValidHost:       req.Host,
+		OriginalRequest: req,
+	}
+
+	switch r := req.MessageRequest.(type) {
+	case *rpb.ServerReflectionRequest_FileByFilename:
+		fd, err := s.resolver.FindFileByPath(r.FileByFilename)
+		if err != nil {
+			res.MessageResponse = errorResponse(codes.NotFound, "file %q not found", r.FileByFilename)
+			break
+		}
+		setFileResponse(res, fd, sent)
+
+	case *rpb.ServerReflectionRequest_FileContainingSymbol:
+		d, err := s.resolver.FindDescriptorByName(protoreflect.FullName(r.FileContainingSymbol))
+		if err != nil {
+			res.MessageResponse = errorResponse(codes.NotFound, "symbol %q not found", r.FileContainingSymbol)
+			break
+		}
+		setFileResponse(res, d.ParentFile(), sent)
+
+	case *rpb.ServerReflectionRequest_FileContainingExtension:
+		ext := r.FileContainingExtension
+		fd, err := s.resolver.fileContainingExtension(
+			protoreflect.FullName(ext.GetContainingType()),
+			protoreflect.FieldNumber(ext.GetExtensionNumber()),
+		)
+		if err != nil {
+			res.MessageResponse = errorResponse(codes.NotFound, "extension %d of %q not found",
+				ext.GetExtensionNumber(), ext.GetContainingType())
+			break
+		}
+		setFileResponse(res, fd, sent)
+
+	case *rpb.ServerReflectionRequest_AllExtensionNumbersOfType:
+		numbers, err := s.resolver.extensionNumbers(protoreflect.FullName(r.AllExtensionNumbersOfType))
+		if err != nil {
+			res.MessageResponse = errorResponse(codes.NotFound, "type %q not found", r.AllExtensionNumbersOfType)
+			break
+		}
+		res.MessageResponse = &rpb.ServerReflectionResponse_AllExtensionNumbersResponse{
+			AllExtensionNumbersResponse: &rpb.ExtensionNumberResponse{
+				BaseTypeName:    r.AllExtensionNumbersOfType,
+				ExtensionNumber: numbers,
+			},
+		}
+
+	case *rpb.ServerReflectionRequest_ListServices:
+		services := s.Services()
+		list := &rpb.ListServiceResponse{Service: make([]*rpb.ServiceResponse, 0, len(services))}
+		for _, service := range services {
+			list.Service = append(list.Service, &rpb.ServiceResponse{Name: service})
+		}
+		res.MessageResponse = &rpb.ServerReflectionResponse_ListServicesResponse{ListServicesResponse: list}
+
+	default:
+		res.MessageResponse = errorResponse(codes.InvalidArgument, "invalid reflection request %T", req.MessageRequest)
+	}
+	return res
+}
+
+func setFileResponse(res *rpb.ServerReflectionResponse, fd protoreflect.FileDescriptor, sent map[string]struct{}) {
+	encoded, err := encodeFiles(fd, sent)
+	if err != nil {
+		res.MessageResponse = errorResponse(codes.Internal, "failed to encode file %q: %v", fd.Path(), err)
+		return
+	}
+	res.MessageResponse = &rpb.ServerReflectionResponse_FileDescriptorResponse{
+		FileDescriptorResponse: &rpb.FileDescriptorResponse{FileDescriptorProto: encoded},
+	}
+}
+
+func errorResponse(code codes.Code, format string, args ...interface{}) *rpb.ServerReflectionResponse_ErrorResponse {
+	return &rpb.ServerReflectionResponse_ErrorResponse{
+		ErrorResponse: &rpb.ErrorResponse{
+			ErrorCode:    int32(code),
+			ErrorMessage: fmt.Sprintf(format, args...),
+		},
+	}
+}
+
+func newServerReflectionRequest() proto.Message {
+	return &rpb.ServerReflectionRequest{}
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpcreflection_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/encoding/protobuf/reflection"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/internal/examples/protobuf/examplepb"
+	"go.uber.org/yarpc/transport/grpc"
+	"go.uber.org/yarpc/x/grpcreflection"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	_exampleFile     = "internal/examples/protobuf/examplepb/example.proto"
+	_keyValueService = "uber.yarpc.internal.examples.protobuf.example.KeyValue"
+	_fooService      = "uber.yarpc.internal.examples.protobuf.example.Foo"
+)
+
+// newServer starts a dispatcher with the example services and a reflection
+// server, returning a gRPC connection to it.
+func newServer(t *testing.T) *ggrpc.ClientConn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	d := yarpc.NewDispatcher(yarpc.Config{
+		Name:     "myservice",
+		Inbounds: yarpc.Inbounds{grpc.NewTransport().NewInbound(listener)},
+	})
+	d.Register(examplepb.BuildKeyValueYARPCProcedures(nil))
+	d.Register(examplepb.BuildFooYARPCProcedures(nil))
+	d.Register(raw.Procedure("users.Users::Get", func(context.Context, []byte) ([]byte, error) {
+		return nil, nil
+	}))
+
+	_, err = grpcreflection.New(d, examplepb.KeyValueReflectionMeta)
+	require.NoError(t, err)
+
+	require.NoError(t, d.Start())
+	t.Cleanup(func() { assert.NoError(t, d.Stop()) })
+
+	conn, err := ggrpc.Dial(listener.Addr().String(), ggrpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// reflectionStream sends reflection requests over a stream of either
+// version of the protocol.
+type reflectionStream struct {
+	t      *testing.T
+	stream ggrpc.ClientStream
+}
+
+func newReflectionStream(t *testing.T, conn *ggrpc.ClientConn, serviceName string) *reflectionStream {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	stream, err := conn.NewStream(ctx,
+		&ggrpc.StreamDesc{ClientStreams: true, ServerStreams: true},
+		"/"+serviceName+"/ServerReflectionInfo",
+	)
+	require.NoError(t, err)
+	return &reflectionStream{t: t, stream: stream}
+}
+
+func (s *reflectionStream) call(req *rpb.ServerReflectionRequest) *rpb.ServerReflectionResponse {
+	require.NoError(s.t, s.stream.SendMsg(req))
+	var res rpb.ServerReflectionResponse
+	require.NoError(s.t, s.stream.RecvMsg(&res))
+	assert.True(s.t, proto.Equal(req, res.OriginalRequest), "responses must include their request")
+	return &res
+}
+
+func (s *reflectionStream) files(req *rpb.ServerReflectionRequest) []*descriptorpb.FileDescriptorProto {
+	res := s.call(req).GetFileDescriptorResponse()
+	require.NotNil(s.t, res, "expected file descriptors")
+
+	var files []*descriptorpb.FileDescriptorProto
+	for _, b := range res.FileDescriptorProto {
+		var fdp descriptorpb.FileDescriptorProto
+		require.NoError(s.t, proto.Unmarshal(b, &fdp))
+		files = append(files, &fdp)
+	}
+	return files
+}
+
+func fileNames(files []*descriptorpb.FileDescriptorProto) []string {
+	names := make([]string, 0, len(files))
+	for _, fdp := range files {
+		names = append(names, fdp.GetName())
+	}
+	return names
+}
+
+func TestServerReflection(t *testing.T) {
+	conn := newServer(t)
+
+	for _, serviceName := range []string{grpcreflection.ServiceNameV1Alpha, grpcreflection.ServiceNameV1} {
+		t.Run(serviceName, func(t *testing.T) {
+			stream := newReflectionStream(t, conn, serviceName)
+
+			t.Run("list services", func(t *testing.T) {
+				res := stream.call(&rpb.ServerReflectionRequest{
+					Host:           "myhost",
+					MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+				})
+				assert.Equal(t, "myhost", res.ValidHost)
+
+				var services []string
+				for _, service := range res.GetListServicesResponse().GetService() {
+					services = append(services, service.Name)
+				}
+				assert.Equal(t, []string{
+					"grpc.reflection.v1alpha.ServerReflection",
+					_fooService,
+					_keyValueService,
+				}, services, "must list the protobuf services of the dispatcher")
+			})
+
+			t.Run("file containing symbol", func(t *testing.T) {
+				files := stream.files(&rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+						FileContainingSymbol: _keyValueService + ".GetValue",
+					},
+				})
+				require.Equal(t, []string{_exampleFile}, fileNames(files))
+
+				var services []string
+				for _, service := range files[0].Service {
+					services = append(services, service.GetName())
+				}
+				assert.Equal(t, []string{"KeyValue", "Foo"}, services)
+			})
+
+			t.Run("file by filename", func(t *testing.T) {
+				files := stream.files(&rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{
+						FileByFilename: _exampleFile,
+					},
+				})
+				assert.Equal(t, []string{_exampleFile}, fileNames(files))
+			})
+
+			t.Run("file with dependencies", func(t *testing.T) {
+				req := &rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+						FileContainingSymbol: "google.rpc.Status",
+					},
+				}
+				assert.Equal(t,
+					[]string{"google/rpc/status.proto", "google/protobuf/any.proto"},
+					fileNames(stream.files(req)),
+					"must send the dependencies of files")
+				assert.Equal(t,
+					[]string{"google/rpc/status.proto"},
+					fileNames(stream.files(req)),
+					"must not send dependencies twice")
+			})
+
+			t.Run("extension numbers", func(t *testing.T) {
+				res := stream.call(&rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_AllExtensionNumbersOfType{
+						AllExtensionNumbersOfType: "google.protobuf.MethodOptions",
+					},
+				})
+				require.NotNil(t, res.GetAllExtensionNumbersResponse())
+				assert.Equal(t, "google.protobuf.MethodOptions", res.GetAllExtensionNumbersResponse().BaseTypeName)
+			})
+
+			t.Run("not found", func(t *testing.T) {
+				tests := []*rpb.ServerReflectionRequest{
+					{MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: "unknown.proto"}},
+					{MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "unknown.Unknown"}},
+					{MessageRequest: &rpb.ServerReflectionRequest_AllExtensionNumbersOfType{AllExtensionNumbersOfType: "unknown.Unknown"}},
+					{MessageRequest: &rpb.ServerReflectionRequest_FileContainingExtension{
+						FileContainingExtension: &rpb.ExtensionRequest{ContainingType: "google.protobuf.MethodOptions", ExtensionNumber: 1},
+					}},
+				}
+				for _, req := range tests {
+					res := stream.call(req)
+					require.NotNil(t, res.GetErrorResponse(), "expected an error for %v", req)
+					assert.Equal(t, int32(codes.NotFound), res.GetErrorResponse().ErrorCode)
+				}
+			})
+
+			require.NoError(t, stream.stream.CloseSend())
+		})
+	}
+}
+
+func TestInvalidServerMeta(t *testing.T) {
+	d := yarpc.NewDispatcher(yarpc.Config{Name: "myservice"})
+	_, err := grpcreflection.New(d, reflection.ServerMeta{
+		ServiceName:     "users.Users",
+		FileDescriptors: [][]byte{[]byte("not gzipped")},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid file descriptor for service "users.Users"`)
+}
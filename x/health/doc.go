@@ -0,0 +1,64 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package health implements the standard gRPC health checking service,
+// grpc.health.v1.Health, for a YARPC dispatcher.
+//
+// Kubernetes probes, service meshes and load balancers use the Check and
+// Watch procedures of this service to find out whether a server is ready to
+// serve requests.
+// New registers these procedures with a dispatcher, which serves them over
+// every inbound: gRPC clients call them like on any gRPC server, and YARPC
+// clients call them with the proto or JSON encodings over HTTP or TChannel.
+// Watch is a streaming procedure, so it is not available over TChannel.
+//
+// The health of services follows the lifecycle of the dispatcher when the
+// dispatcher is started and stopped through the health Server.
+//
+//	server := health.New(dispatcher, health.ShutdownDelay(5*time.Second))
+//
+//	starter, err := dispatcher.PhasedStart()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := server.Start(starter); err != nil {
+//		log.Fatal(err)
+//	}
+//	defer func() {
+//		stopper, err := dispatcher.PhasedStop()
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		if err := server.Stop(stopper); err != nil {
+//			log.Fatal(err)
+//		}
+//	}()
+//
+// Once started, the overall health of the server, reported for the empty
+// service name, the names of the services of the dispatcher and the gRPC
+// services of its procedures are serving.
+// When stopping, they stop serving before the inbounds of the dispatcher
+// stop, so that clients stop sending requests first.
+//
+// Applications may flip the status of a service at any time with
+// SetServingStatus, and of every service with Shutdown and Resume.
+//
+//	server.SetServingStatus("users.Users", health.NotServing)
+package health
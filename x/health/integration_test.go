@@ -0,0 +1,221 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	protobuf "go.uber.org/yarpc/encoding/protobuf/v2"
+	"go.uber.org/yarpc/transport/grpc"
+	"go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/transport/tchannel"
+	"go.uber.org/yarpc/x/health"
+	"go.uber.org/yarpc/yarpcerrors"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGRPCClient(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	d := newDispatcher(yarpc.Config{
+		Name:     "myservice",
+		Inbounds: yarpc.Inbounds{grpc.NewTransport().NewInbound(listener)},
+	})
+	server := health.New(d)
+	start(t, d, server)
+	stopped := false
+	defer func() {
+		if !stopped {
+			stop(t, d, server)
+		}
+	}()
+
+	conn, err := ggrpc.Dial(listener.Addr().String(), ggrpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("check", func(t *testing.T) {
+		res, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, health.Serving, res.Status)
+
+		res, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "users.Users"})
+		require.NoError(t, err)
+		assert.Equal(t, health.Serving, res.Status)
+	})
+
+	t.Run("check unknown service", func(t *testing.T) {
+		_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: "users.Users"})
+		require.NoError(t, err)
+
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, health.Serving, res.Status, "watch must begin with the current status")
+
+		server.SetServingStatus("users.Users", health.NotServing)
+		res, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, health.NotServing, res.Status)
+
+		server.SetServingStatus("users.Users", health.Serving)
+		res, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, health.Serving, res.Status)
+
+		// Stopping the dispatcher reports the service as not serving and
+		// ends the stream.
+		stopped = true
+		stopErr := make(chan error, 1)
+		go func() {
+			stopper, err := d.PhasedStop()
+			if err == nil {
+				err = server.Stop(stopper)
+			}
+			stopErr <- err
+		}()
+
+		res, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, health.NotServing, res.Status)
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.NoError(t, <-stopErr)
+	})
+}
+
+func TestYARPCClients(t *testing.T) {
+	tests := []struct {
+		desc     string
+		inbound  func() transport.Inbound
+		outbound func(transport.Inbound) transport.UnaryOutbound
+	}{
+		{
+			desc: "grpc",
+			inbound: func() transport.Inbound {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				require.NoError(t, err)
+				return grpc.NewTransport().NewInbound(listener)
+			},
+			outbound: func(i transport.Inbound) transport.UnaryOutbound {
+				return grpc.NewTransport().NewSingleOutbound(i.(*grpc.Inbound).Addr().String())
+			},
+		},
+		{
+			desc: "http",
+			inbound: func() transport.Inbound {
+				return http.NewTransport().NewInbound("127.0.0.1:0")
+			},
+			outbound: func(i transport.Inbound) transport.UnaryOutbound {
+				return http.NewTransport().NewSingleOutbound("http://" + i.(*http.Inbound).Addr().String())
+			},
+		},
+		{
+			desc: "tchannel",
+			inbound: func() transport.Inbound {
+				trans, err := tchannel.NewTransport(tchannel.ServiceName("myservice"), tchannel.ListenAddr("127.0.0.1:0"))
+				require.NoError(t, err)
+				return trans.NewInbound()
+			},
+			outbound: func(i transport.Inbound) transport.UnaryOutbound {
+				trans, err := tchannel.NewTransport(tchannel.ServiceName("client"))
+				require.NoError(t, err)
+				addr := i.Transports()[0].(*tchannel.Transport).ListenAddr()
+				return trans.NewSingleOutbound(addr)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			inbound := tt.inbound()
+			d := newDispatcher(yarpc.Config{
+				Name:     "myservice",
+				Inbounds: yarpc.Inbounds{inbound},
+			})
+			server := health.New(d)
+			start(t, d, server)
+			defer stop(t, d, server)
+
+			clientDispatcher := yarpc.NewDispatcher(yarpc.Config{
+				Name: "client",
+				Outbounds: yarpc.Outbounds{
+					"myservice": {Unary: tt.outbound(inbound)},
+				},
+			})
+			require.NoError(t, clientDispatcher.Start())
+			defer func() { assert.NoError(t, clientDispatcher.Stop()) }()
+
+			client := protobuf.NewClient(protobuf.ClientParams{
+				ServiceName:  health.ServiceName,
+				ClientConfig: clientDispatcher.ClientConfig("myservice"),
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			check := func(service string) (health.Status, error) {
+				res, err := client.Call(ctx, "Check",
+					&grpc_health_v1.HealthCheckRequest{Service: service},
+					func() proto.Message { return &grpc_health_v1.HealthCheckResponse{} },
+				)
+				if err != nil {
+					return health.Unknown, err
+				}
+				return res.(*grpc_health_v1.HealthCheckResponse).Status, nil
+			}
+
+			got, err := check("myservice")
+			require.NoError(t, err)
+			assert.Equal(t, health.Serving, got)
+
+			server.SetServingStatus("myservice", health.NotServing)
+			got, err = check("myservice")
+			require.NoError(t, err)
+			assert.Equal(t, health.NotServing, got)
+
+			_, err = check("unknown")
+			assert.Equal(t, yarpcerrors.CodeNotFound, yarpcerrors.FromError(err).Code())
+		})
+	}
+}
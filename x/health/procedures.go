@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health
+
+import (
+	"context"
+
+	"go.uber.org/yarpc/api/transport"
+	protobuf "go.uber.org/yarpc/encoding/protobuf/v2"
+	"go.uber.org/yarpc/yarpcerrors"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ServiceName is the name of the standard gRPC health checking service.
+const ServiceName = "grpc.health.v1.Health"
+
+// Procedures returns the Check and Watch procedures of the
+// grpc.health.v1.Health service.
+//
+// New registers these procedures with the dispatcher already.
+func (s *Server) Procedures() []transport.Procedure {
+	return protobuf.BuildProcedures(protobuf.BuildProceduresParams{
+		ServiceName: ServiceName,
+		UnaryHandlerParams: []protobuf.BuildProceduresUnaryHandlerParams{
+			{
+				MethodName: "Check",
+				Handler: protobuf.NewUnaryHandler(protobuf.UnaryHandlerParams{
+					Handle:     s.check,
+					NewRequest: newHealthCheckRequest,
+				}),
+			},
+		},
+		StreamHandlerParams: []protobuf.BuildProceduresStreamHandlerParams{
+			{
+				MethodName: "Watch",
+				Handler: protobuf.NewStreamHandler(protobuf.StreamHandlerParams{
+					Handle: s.watchStream,
+				}),
+			},
+		},
+	})
+}
+
+func (s *Server) check(_ context.Context, message proto.Message) (proto.Message, error) {
+	req, ok := message.(*grpc_health_v1.HealthCheckRequest)
+	if !ok {
+		return nil, protobuf.CastError(&grpc_health_v1.HealthCheckRequest{}, message)
+	}
+
+	status := s.Status(req.Service)
+	if status == ServiceUnknown {
+		return nil, yarpcerrors.NotFoundErrorf("unknown service %q", req.Service)
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: status}, nil
+}
+
+// watchStream sends the status of a service whenever it changes, until the
+// client cancels the stream or the server stops.
+func (s *Server) watchStream(stream *protobuf.ServerStream) error {
+	message, err := stream.Receive(newHealthCheckRequest)
+	if err != nil {
+		return err
+	}
+	req, ok := message.(*grpc_health_v1.HealthCheckRequest)
+	if !ok {
+		return protobuf.CastError(&grpc_health_v1.HealthCheckRequest{}, message)
+	}
+
+	w, unwatch := s.watch(req.Service)
+	defer unwatch()
+
+	for {
+		select {
+		case status := <-w.updates:
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: status}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return yarpcerrors.CancelledErrorf("health watch cancelled: %v", stream.Context().Err())
+		case <-s.stopped:
+			return yarpcerrors.UnavailableErrorf("health service is stopping")
+		}
+	}
+}
+
+func newHealthCheckRequest() proto.Message {
+	return &grpc_health_v1.HealthCheckRequest{}
+}
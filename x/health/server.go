@@ -0,0 +1,268 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/pkg/procedure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Status is the serving status of a service.
+type Status = grpc_health_v1.HealthCheckResponse_ServingStatus
+
+// Serving statuses reported by the health service.
+const (
+	Unknown        Status = grpc_health_v1.HealthCheckResponse_UNKNOWN
+	Serving        Status = grpc_health_v1.HealthCheckResponse_SERVING
+	NotServing     Status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	ServiceUnknown Status = grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+)
+
+type options struct {
+	shutdownDelay time.Duration
+}
+
+// Option customizes the behavior of a health Server.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(options *options) { f(options) }
+
+// ShutdownDelay is how long Stop waits after reporting that services are
+// not serving and before stopping the inbounds of the dispatcher, giving
+// probes and load balancers time to stop sending requests.
+//
+// Defaults to no delay.
+func ShutdownDelay(d time.Duration) Option {
+	return optionFunc(func(options *options) {
+		options.shutdownDelay = d
+	})
+}
+
+// Server implements the standard gRPC health checking service,
+// grpc.health.v1.Health, for the services of a dispatcher.
+//
+// The serving status of services follows the lifecycle of the dispatcher:
+// services are serving once Start has started the dispatcher, and stop
+// serving as soon as Stop begins stopping it.
+// Applications may override the status of any service with
+// SetServingStatus, or of all services with Shutdown and Resume.
+type Server struct {
+	dispatcher *yarpc.Dispatcher
+	opts       options
+
+	mu       sync.Mutex
+	started  bool
+	shutdown bool
+	services map[string]struct{}
+	statuses map[string]Status
+	watchers map[*watcher]struct{}
+	stopped  chan struct{}
+}
+
+// New builds a health Server for the services of the given dispatcher and
+// registers the grpc.health.v1.Health procedures with it.
+func New(d *yarpc.Dispatcher, opts ...Option) *Server {
+	var options options
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	s := &Server{
+		dispatcher: d,
+		opts:       options,
+		services:   map[string]struct{}{"": {}},
+		statuses:   make(map[string]Status),
+		watchers:   make(map[*watcher]struct{}),
+		stopped:    make(chan struct{}),
+	}
+	d.Register(s.Procedures())
+	return s
+}
+
+// Start runs every phase of the dispatcher startup and then reports its
+// services as serving.
+func (s *Server) Start(starter *yarpc.PhasedStarter) error {
+	if err := starter.StartTransports(); err != nil {
+		return err
+	}
+	if err := starter.StartOutbounds(); err != nil {
+		return err
+	}
+	if err := starter.StartInbounds(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addServices(s.dispatcher.Router().Procedures())
+	s.started = true
+	s.notifyLocked()
+	return nil
+}
+
+// Stop reports the services of the dispatcher as not serving, waits for the
+// ShutdownDelay, ends the Watch streams of the health service and then runs
+// every phase of the dispatcher shutdown.
+func (s *Server) Stop(stopper *yarpc.PhasedStopper) error {
+	s.mu.Lock()
+	s.started = false
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	if s.opts.shutdownDelay > 0 {
+		time.Sleep(s.opts.shutdownDelay)
+	}
+
+	s.mu.Lock()
+	select {
+	case <-s.stopped:
+	default:
+		close(s.stopped)
+	}
+	s.mu.Unlock()
+
+	if err := stopper.StopInbounds(); err != nil {
+		return err
+	}
+	return multierr.Combine(stopper.StopOutbounds(), stopper.StopTransports())
+}
+
+// SetServingStatus sets the serving status of a service, overriding the
+// status that follows the lifecycle of the dispatcher while the dispatcher
+// is running.
+// The empty service is the overall health of the server.
+//
+// Services are the gRPC services of the procedures of the dispatcher, like
+// "users.Users" for the "users.Users::Get" procedure, and the names of the
+// services of the dispatcher.
+// Setting the status of any other service makes it known to the health
+// service.
+func (s *Server) SetServingStatus(service string, status Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.services[service] = struct{}{}
+	s.statuses[service] = status
+	s.notifyLocked()
+}
+
+// Shutdown reports every service as not serving until Resume.
+func (s *Server) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdown = true
+	s.notifyLocked()
+}
+
+// Resume reverts Shutdown, reporting services with their status again.
+func (s *Server) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdown = false
+	s.notifyLocked()
+}
+
+// Status returns the serving status of a service.
+func (s *Server) Status(service string) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.statusLocked(service)
+}
+
+func (s *Server) statusLocked(service string) Status {
+	if _, ok := s.services[service]; !ok {
+		return ServiceUnknown
+	}
+	if !s.started || s.shutdown {
+		return NotServing
+	}
+	if status, ok := s.statuses[service]; ok {
+		return status
+	}
+	return Serving
+}
+
+func (s *Server) addServices(procedures []transport.Procedure) {
+	for _, p := range procedures {
+		s.services[p.Service] = struct{}{}
+		if service, method := procedure.FromName(p.Name); method != "" {
+			s.services[service] = struct{}{}
+		}
+	}
+}
+
+// watcher receives the latest status of a service, whenever it changes.
+type watcher struct {
+	service string
+	last    Status
+	updates chan Status
+}
+
+func (s *Server) watch(service string) (*watcher, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := &watcher{
+		service: service,
+		last:    s.statusLocked(service),
+		updates: make(chan Status, 1),
+	}
+	w.updates <- w.last
+	s.watchers[w] = struct{}{}
+
+	return w, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.watchers, w)
+	}
+}
+
+// notifyLocked sends the new status of their service to watchers, replacing
+// any status they have yet to receive.
+func (s *Server) notifyLocked() {
+	for w := range s.watchers {
+		status := s.statusLocked(w.service)
+		if status == w.last {
+			continue
+		}
+		w.last = status
+
+		select {
+		case <-w.updates:
+		default:
+		}
+		w.updates <- status
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/x/health"
+)
+
+func newDispatcher(cfg yarpc.Config) *yarpc.Dispatcher {
+	d := yarpc.NewDispatcher(cfg)
+	d.Register(raw.Procedure("users.Users::Get", func(context.Context, []byte) ([]byte, error) {
+		return nil, nil
+	}))
+	return d
+}
+
+func start(t *testing.T, d *yarpc.Dispatcher, server *health.Server) {
+	starter, err := d.PhasedStart()
+	require.NoError(t, err)
+	require.NoError(t, server.Start(starter))
+}
+
+func stop(t *testing.T, d *yarpc.Dispatcher, server *health.Server) {
+	stopper, err := d.PhasedStop()
+	require.NoError(t, err)
+	require.NoError(t, server.Stop(stopper))
+}
+
+func assertStatuses(t *testing.T, server *health.Server, want map[string]health.Status, msg string) {
+	got := make(map[string]health.Status, len(want))
+	for service := range want {
+		got[service] = server.Status(service)
+	}
+	assert.Equal(t, want, got, msg)
+}
+
+func TestLifecycle(t *testing.T) {
+	d := newDispatcher(yarpc.Config{Name: "myservice"})
+	server := health.New(d)
+
+	assertStatuses(t, server, map[string]health.Status{
+		"":            health.NotServing,
+		"users.Users": health.ServiceUnknown,
+	}, "services must not be serving before start")
+
+	start(t, d, server)
+	assertStatuses(t, server, map[string]health.Status{
+		"":                      health.Serving,
+		"myservice":             health.Serving,
+		"users.Users":           health.Serving,
+		"grpc.health.v1.Health": health.Serving,
+		"unknown":               health.ServiceUnknown,
+	}, "services must be serving after start")
+
+	server.SetServingStatus("users.Users", health.NotServing)
+	server.SetServingStatus("users.Admin", health.Serving)
+	assertStatuses(t, server, map[string]health.Status{
+		"":            health.Serving,
+		"users.Users": health.NotServing,
+		"users.Admin": health.Serving,
+	}, "applications must be able to set the status of services")
+
+	server.Shutdown()
+	assertStatuses(t, server, map[string]health.Status{
+		"":            health.NotServing,
+		"users.Users": health.NotServing,
+		"users.Admin": health.NotServing,
+	}, "no service must be serving after shutdown")
+
+	server.Resume()
+	assertStatuses(t, server, map[string]health.Status{
+		"":            health.Serving,
+		"users.Users": health.NotServing,
+		"users.Admin": health.Serving,
+	}, "services must be restored on resume")
+
+	stop(t, d, server)
+	assertStatuses(t, server, map[string]health.Status{
+		"":            health.NotServing,
+		"users.Users": health.NotServing,
+		"users.Admin": health.NotServing,
+	}, "no service must be serving after stop")
+}
+
+func TestShutdownDelay(t *testing.T) {
+	d := newDispatcher(yarpc.Config{Name: "myservice"})
+	server := health.New(d, health.ShutdownDelay(50*time.Millisecond))
+	start(t, d, server)
+
+	begin := time.Now()
+	stop(t, d, server)
+	assert.True(t, time.Since(begin) >= 50*time.Millisecond, "stop must wait for the shutdown delay")
+}
+
+func TestStartError(t *testing.T) {
+	d := newDispatcher(yarpc.Config{Name: "myservice"})
+	server := health.New(d)
+
+	starter, err := d.PhasedStart()
+	require.NoError(t, err)
+	require.NoError(t, starter.StartTransports())
+
+	assert.Error(t, server.Start(starter), "transports must not start twice")
+	assert.Equal(t, health.NotServing, server.Status(""))
+}
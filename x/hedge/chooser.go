@@ -0,0 +1,144 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/x/introspection"
+	"go.uber.org/yarpc/peer/abstractlist"
+)
+
+// _maxChooseAttempts bounds the number of peers the Chooser considers for an
+// attempt before settling for a peer that an earlier attempt of the same
+// request already uses.
+const _maxChooseAttempts = 3
+
+var (
+	_ peer.Chooser                        = (*Chooser)(nil)
+	_ introspection.IntrospectableChooser = (*Chooser)(nil)
+)
+
+type attemptsKey struct{}
+
+// attempts records the peers chosen for the attempts of a single request.
+type attempts struct {
+	mu    sync.Mutex
+	peers map[string]struct{}
+}
+
+func withAttempts(ctx context.Context, a *attempts) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, a)
+}
+
+func attemptsFromContext(ctx context.Context) (*attempts, bool) {
+	a, ok := ctx.Value(attemptsKey{}).(*attempts)
+	return a, ok
+}
+
+// claim records that an attempt uses the given peer. It returns false
+// without recording the peer if an earlier attempt already uses it, unless
+// force is set.
+func (a *attempts) claim(id string, force bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.peers[id]; ok && !force {
+		return false
+	}
+	if a.peers == nil {
+		a.peers = make(map[string]struct{})
+	}
+	a.peers[id] = struct{}{}
+	return true
+}
+
+// Chooser wraps the peer.Chooser of an outbound so that the hedged attempt
+// of a request goes to a different peer than its first attempt.
+//
+//	list := roundrobin.New(httpTransport)
+//	outbound := httpTransport.NewOutbound(hedge.NewChooser(list))
+//
+// Without it, hedged attempts go to whichever peer the chooser picks, which
+// may be the peer that is slow to respond to the first attempt.
+//
+// The Chooser asks the wrapped chooser for another peer a few times if it
+// returns a peer already in use by the request, and settles for that peer
+// if it keeps doing so, as choosers that pick peers by shard key do.
+// Requests that are not hedged are passed through unchanged.
+type Chooser struct {
+	chooser peer.Chooser
+}
+
+// NewChooser wraps a peer.Chooser so that hedged attempts prefer a
+// different peer than the first attempt of their request.
+func NewChooser(chooser peer.Chooser) *Chooser {
+	return &Chooser{chooser: chooser}
+}
+
+// Start starts the wrapped chooser.
+func (c *Chooser) Start() error {
+	return c.chooser.Start()
+}
+
+// Stop stops the wrapped chooser.
+func (c *Chooser) Stop() error {
+	return c.chooser.Stop()
+}
+
+// IsRunning returns whether the wrapped chooser is running.
+func (c *Chooser) IsRunning() bool {
+	return c.chooser.IsRunning()
+}
+
+// Choose returns a peer for the request, avoiding the peers used by earlier
+// attempts of a hedged request.
+func (c *Chooser) Choose(ctx context.Context, req *transport.Request) (peer.Peer, func(error), error) {
+	a, ok := attemptsFromContext(ctx)
+	if !ok {
+		return c.chooser.Choose(ctx, req)
+	}
+
+	for i := 1; ; i++ {
+		p, onFinish, err := c.chooser.Choose(ctx, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		if a.claim(p.Identifier(), i >= _maxChooseAttempts) {
+			return p, onFinish, nil
+		}
+		// No request was sent to this peer, so its list must not count a
+		// request outcome or latency.
+		onFinish(abstractlist.ErrRequestNotSent)
+	}
+}
+
+// Introspect returns the status of the wrapped chooser, if it supports
+// introspection.
+func (c *Chooser) Introspect() introspection.ChooserStatus {
+	if ic, ok := c.chooser.(introspection.IntrospectableChooser); ok {
+		return ic.Introspect()
+	}
+	return introspection.ChooserStatus{Name: "Hedge"}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/x/introspection"
+	"go.uber.org/yarpc/peer/abstractlist"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+type stubPeer string
+
+func (p stubPeer) Identifier() string  { return string(p) }
+func (p stubPeer) Status() peer.Status { return peer.Status{ConnectionStatus: peer.Available} }
+func (p stubPeer) StartRequest()       {}
+func (p stubPeer) EndRequest()         {}
+
+// stubChooser returns the given peers in order, recording the peers for which
+// requests finished and the errors they finished with.
+type stubChooser struct {
+	peer.Chooser
+
+	peers    []string
+	err      error
+	finished []string
+	errs     []error
+}
+
+func (c *stubChooser) Choose(context.Context, *transport.Request) (peer.Peer, func(error), error) {
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+	p := c.peers[0]
+	c.peers = c.peers[1:]
+	return stubPeer(p), func(err error) {
+		c.finished = append(c.finished, p)
+		c.errs = append(c.errs, err)
+	}, nil
+}
+
+func TestChooser(t *testing.T) {
+	tests := []struct {
+		desc         string
+		peers        []string
+		wantPeers    []string
+		wantFinished []string
+	}{
+		{
+			desc:      "distinct peers",
+			peers:     []string{"a", "b"},
+			wantPeers: []string{"a", "b"},
+		},
+		{
+			desc:         "skips peer in use",
+			peers:        []string{"a", "a", "b"},
+			wantPeers:    []string{"a", "b"},
+			wantFinished: []string{"a"},
+		},
+		{
+			desc:         "settles for peer in use",
+			peers:        []string{"a", "a", "a", "a"},
+			wantPeers:    []string{"a", "a"},
+			wantFinished: []string{"a", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			stub := &stubChooser{peers: tt.peers}
+			chooser := NewChooser(stub)
+			ctx := withAttempts(context.Background(), &attempts{})
+
+			var got []string
+			for range tt.wantPeers {
+				p, _, err := chooser.Choose(ctx, &transport.Request{})
+				require.NoError(t, err)
+				got = append(got, p.Identifier())
+			}
+			assert.Equal(t, tt.wantPeers, got)
+			assert.Equal(t, tt.wantFinished, stub.finished, "rejected peers should be released")
+			for _, err := range stub.errs {
+				assert.Equal(t, abstractlist.ErrRequestNotSent, err,
+					"rejected peers must be released without recording an outcome")
+			}
+		})
+	}
+}
+
+func TestChooserNotHedged(t *testing.T) {
+	stub := &stubChooser{peers: []string{"a", "a"}}
+	chooser := NewChooser(stub)
+
+	for i := 0; i < 2; i++ {
+		p, _, err := chooser.Choose(context.Background(), &transport.Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "a", p.Identifier())
+	}
+	assert.Empty(t, stub.finished)
+}
+
+func TestChooserError(t *testing.T) {
+	stub := &stubChooser{err: errors.New("no peers")}
+	chooser := NewChooser(stub)
+
+	_, _, err := chooser.Choose(withAttempts(context.Background(), &attempts{}), &transport.Request{})
+	assert.EqualError(t, err, "no peers")
+}
+
+func TestChooserLifecycle(t *testing.T) {
+	fake := yarpctest.NewFakePeerChooser()
+	chooser := NewChooser(fake)
+
+	require.NoError(t, chooser.Start())
+	assert.True(t, chooser.IsRunning())
+	require.NoError(t, chooser.Stop())
+	assert.Equal(t, introspection.ChooserStatus{Name: "Hedge"}, chooser.Introspect())
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/config"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+// Configuration describes hedging policies and the idempotent procedures to
+// which they apply.
+//
+//	policies:
+//	  fast:
+//	    delay: 20ms
+//	  adaptive:
+//	    delay: 50ms
+//	    percentile: 95
+//	idempotent:
+//	  - service: users
+//	    procedure: Users::get
+//	    with: fast
+//	  - service: users
+//	    procedure: Users::list
+//	    with: adaptive
+//
+// Only the procedures listed as idempotent are hedged.
+type Configuration struct {
+	Policies   map[string]PolicyConfiguration `config:"policies"`
+	Idempotent []IdempotentConfiguration      `config:"idempotent"`
+}
+
+// PolicyConfiguration describes a single hedging policy.
+//
+// Each field is optional and defaults to the corresponding NewPolicy default.
+type PolicyConfiguration struct {
+	// Delay is how long to wait for the first attempt before hedging.
+	Delay time.Duration `config:"delay"`
+
+	// Percentile, if set, hedges requests slower than this percentile of the
+	// latencies observed for the procedure. Delay applies until enough
+	// latencies have been observed.
+	Percentile float64 `config:"percentile"`
+}
+
+// IdempotentConfiguration marks a procedure of a service as idempotent,
+// hedging it with a named policy.
+type IdempotentConfiguration struct {
+	Service   string `config:"service"`
+	Procedure string `config:"procedure"`
+	With      string `config:"with"`
+}
+
+// Spec returns a configuration specification for the hedging middleware,
+// making it possible to configure hedging in the outboundMiddleware section
+// of yarpcconfig.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(hedge.Spec(hedge.Meter(scope)))
+//
+// This enables the hedging middleware for unary outbounds:
+//
+//	outboundMiddleware:
+//	  hedge:
+//	    policies:
+//	      default:
+//	        delay: 20ms
+//	    idempotent:
+//	      - service: users
+//	        procedure: Users::get
+//	        with: default
+//
+// See Configuration for the full shape of the configuration.
+func Spec(opts ...MiddlewareOption) yarpcconfig.OutboundMiddlewareSpec {
+	return yarpcconfig.OutboundMiddlewareSpec{
+		Name: "hedge",
+		BuildOutboundMiddleware: func(cfg Configuration, _ *yarpcconfig.Kit) (yarpc.OutboundMiddleware, error) {
+			provider, err := cfg.PolicyProvider()
+			if err != nil {
+				return yarpc.OutboundMiddleware{}, err
+			}
+
+			mwOpts := append([]MiddlewareOption{WithPolicyProvider(provider)}, opts...)
+			return yarpc.OutboundMiddleware{Unary: NewOutboundMiddleware(mwOpts...)}, nil
+		},
+	}
+}
+
+// NewOutboundMiddlewareFromConfig builds hedging middleware from
+// configuration data, which must have the shape of a Configuration.
+// Use it to configure hedging outside yarpcconfig; with yarpcconfig,
+// register Spec instead.
+func NewOutboundMiddlewareFromConfig(src interface{}, opts ...MiddlewareOption) (*OutboundMiddleware, error) {
+	var cfg Configuration
+	if err := config.DecodeInto(&cfg, src); err != nil {
+		return nil, fmt.Errorf("failed to decode hedge configuration: %v", err)
+	}
+
+	provider, err := cfg.PolicyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]MiddlewareOption{WithPolicyProvider(provider)}, opts...)
+	return NewOutboundMiddleware(opts...), nil
+}
+
+// PolicyProvider builds an IdempotentProcedures provider from the
+// configuration.
+func (c Configuration) PolicyProvider() (*IdempotentProcedures, error) {
+	policies := make(map[string]*Policy, len(c.Policies))
+	for name, pc := range c.Policies {
+		policy, err := pc.policy()
+		if err != nil {
+			return nil, fmt.Errorf("invalid hedge policy %q: %v", name, err)
+		}
+		policies[name] = policy
+	}
+
+	provider := NewIdempotentProcedures()
+	for _, ic := range c.Idempotent {
+		if ic.Service == "" || ic.Procedure == "" {
+			return nil, fmt.Errorf("idempotent procedure for hedge policy %q must specify a service and a procedure", ic.With)
+		}
+		policy, ok := policies[ic.With]
+		if !ok {
+			return nil, fmt.Errorf("unknown hedge policy %q; need one of %v", ic.With, policyNames(policies))
+		}
+		provider.Register(ic.Service, ic.Procedure, policy)
+	}
+	return provider, nil
+}
+
+func (pc PolicyConfiguration) policy() (*Policy, error) {
+	var opts []PolicyOption
+	if pc.Delay < 0 {
+		return nil, fmt.Errorf("delay must not be negative, got %v", pc.Delay)
+	}
+	if pc.Delay > 0 {
+		opts = append(opts, Delay(pc.Delay))
+	}
+	if pc.Percentile != 0 {
+		if pc.Percentile < 0 || pc.Percentile >= 100 {
+			return nil, fmt.Errorf("percentile must be greater than 0 and less than 100, got %v", pc.Percentile)
+		}
+		opts = append(opts, LatencyPercentile(pc.Percentile))
+	}
+	return NewPolicy(opts...), nil
+}
+
+func policyNames(policies map[string]*Policy) []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcconfig"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNewOutboundMiddlewareFromConfig(t *testing.T) {
+	type policyAssertion struct {
+		service   string
+		procedure string
+
+		// wantDelay is ignored if wantNil is set.
+		wantNil        bool
+		wantDelay      time.Duration
+		wantPercentile float64
+	}
+
+	tests := []struct {
+		desc       string
+		give       string
+		want       []policyAssertion
+		wantErrors []string
+	}{
+		{
+			desc: "empty",
+			want: []policyAssertion{
+				{service: "foo", procedure: "bar", wantNil: true},
+			},
+		},
+		{
+			desc: "idempotent procedures",
+			give: `
+				policies:
+					fast:
+						delay: 20ms
+					adaptive:
+						percentile: 95
+				idempotent:
+					- service: foo
+					  procedure: get
+					  with: fast
+					- service: foo
+					  procedure: list
+					  with: adaptive
+			`,
+			want: []policyAssertion{
+				{service: "foo", procedure: "get", wantDelay: 20 * time.Millisecond},
+				{service: "foo", procedure: "list", wantDelay: 50 * time.Millisecond, wantPercentile: 95},
+				{service: "foo", procedure: "create", wantNil: true},
+				{service: "bar", procedure: "get", wantNil: true},
+			},
+		},
+		{
+			desc: "unknown policy",
+			give: `
+				idempotent:
+					- service: foo
+					  procedure: get
+					  with: slow
+			`,
+			wantErrors: []string{`unknown hedge policy "slow"`},
+		},
+		{
+			desc: "missing procedure",
+			give: `
+				policies:
+					fast:
+						delay: 20ms
+				idempotent:
+					- service: foo
+					  with: fast
+			`,
+			wantErrors: []string{"must specify a service and a procedure"},
+		},
+		{
+			desc: "negative delay",
+			give: `
+				policies:
+					fast:
+						delay: -1s
+			`,
+			wantErrors: []string{`invalid hedge policy "fast"`, "delay must not be negative"},
+		},
+		{
+			desc: "invalid percentile",
+			give: `
+				policies:
+					fast:
+						percentile: 100
+			`,
+			wantErrors: []string{`invalid hedge policy "fast"`, "percentile must be greater than 0 and less than 100"},
+		},
+		{
+			desc: "unknown field",
+			give: `
+				policies:
+					fast:
+						wait: 1s
+			`,
+			wantErrors: []string{"failed to decode hedge configuration"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var data map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(tt.give)), &data))
+
+			mw, err := NewOutboundMiddlewareFromConfig(data)
+			if len(tt.wantErrors) > 0 {
+				require.Error(t, err)
+				for _, msg := range tt.wantErrors {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			for _, pa := range tt.want {
+				policy := mw.provider.Policy(context.Background(), &transport.Request{
+					Service:   pa.service,
+					Procedure: pa.procedure,
+				})
+				if pa.wantNil {
+					assert.Nil(t, policy, "%v/%v: expected no policy", pa.service, pa.procedure)
+					continue
+				}
+				require.NotNil(t, policy, "%v/%v: expected a policy", pa.service, pa.procedure)
+				assert.Equal(t, pa.wantDelay, policy.opts.delay, "%v/%v: delay", pa.service, pa.procedure)
+				assert.Equal(t, pa.wantPercentile, policy.opts.percentile, "%v/%v: percentile", pa.service, pa.procedure)
+			}
+		})
+	}
+}
+
+func TestSpec(t *testing.T) {
+	var data map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+		outboundMiddleware:
+			hedge:
+				policies:
+					fast:
+						delay: 20ms
+				idempotent:
+					- service: foo
+					  procedure: get
+					  with: fast
+	`)), &data))
+
+	cfg := yarpcconfig.New()
+	require.NoError(t, cfg.RegisterOutboundMiddleware(Spec()))
+
+	c, err := cfg.LoadConfig("myservice", data)
+	require.NoError(t, err)
+	require.NotNil(t, c.OutboundMiddleware.Unary)
+	mw, ok := c.OutboundMiddleware.Unary.(*OutboundMiddleware)
+	require.True(t, ok, "unexpected unary middleware %T", c.OutboundMiddleware.Unary)
+
+	policy := mw.provider.Policy(context.Background(), &transport.Request{Service: "foo", Procedure: "get"})
+	require.NotNil(t, policy)
+	assert.Equal(t, 20*time.Millisecond, policy.opts.delay)
+
+	t.Run("invalid", func(t *testing.T) {
+		var data map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+			outboundMiddleware:
+				hedge:
+					idempotent:
+						- service: foo
+						  procedure: get
+						  with: slow
+		`)), &data))
+
+		_, err := cfg.LoadConfig("myservice", data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown hedge policy "slow"`)
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package hedge provides outbound middleware that hedges slow unary
+// requests.
+//
+// When the first attempt of a request has not succeeded after a delay, the
+// middleware sends the same request again and returns whichever response
+// succeeds first, cancelling the other attempt.
+// Hedging trades extra load for lower tail latency.
+// Since a request may be handled more than once, only procedures marked as
+// idempotent are hedged.
+//
+// A Policy decides when to hedge, either after a fixed delay or once the
+// first attempt is slower than a percentile of the latencies observed for
+// the procedure.
+//
+//	procedures := hedge.NewIdempotentProcedures()
+//	procedures.Register("users", "Users::get", hedge.NewPolicy(
+//		hedge.Delay(20*time.Millisecond),
+//	))
+//	procedures.Register("users", "Users::list", hedge.NewPolicy(
+//		hedge.LatencyPercentile(95),
+//	))
+//
+//	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+//		Name: "myservice",
+//		OutboundMiddleware: yarpc.OutboundMiddleware{
+//			Unary: hedge.NewOutboundMiddleware(hedge.WithPolicyProvider(procedures)),
+//		},
+//	})
+//
+// A hedged attempt is only useful if it goes to a different peer.
+// Wrap the peer list of the outbound in a Chooser so that the attempts of a
+// request avoid peers already used by the other attempt.
+//
+//	list := roundrobin.New(transport)
+//	outbound := transport.NewOutbound(hedge.NewChooser(list))
+//
+// Policies may also be configured in the outboundMiddleware section of
+// yarpcconfig by registering Spec with the Configurator.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(hedge.Spec())
+//
+// The hedging middleware buffers request bodies in memory so that each
+// attempt sends the same payload.
+package hedge
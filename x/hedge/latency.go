@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// _latencyWindow is the number of recent latencies from which
+	// percentiles are estimated.
+	_latencyWindow = 1000
+
+	// _minLatencySamples is the number of latencies that must be observed
+	// before percentiles are estimated.
+	_minLatencySamples = 20
+
+	// _latencyRefresh is the number of latencies observed between two
+	// estimates of a percentile. Sorting the window for every request would
+	// be needlessly expensive.
+	_latencyRefresh = 50
+)
+
+// latencyTracker estimates percentiles of the latencies recently observed
+// for a procedure.
+type latencyTracker struct {
+	mu sync.Mutex
+
+	samples []time.Duration // ring buffer of at most _latencyWindow samples
+	next    int
+
+	// estimate caches the last estimate of estimatePercentile, which is
+	// refreshed after _latencyRefresh new samples.
+	estimate           time.Duration
+	estimatePercentile float64
+	sinceEstimate      int
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{
+		samples: make([]time.Duration, 0, _latencyWindow),
+	}
+}
+
+// observe records the latency of a request.
+func (t *latencyTracker) observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.samples) < _latencyWindow {
+		t.samples = append(t.samples, d)
+	} else {
+		t.samples[t.next] = d
+		t.next = (t.next + 1) % _latencyWindow
+	}
+	t.sinceEstimate++
+}
+
+// percentile returns the p-th percentile of the recent latencies, or false if
+// too few latencies have been observed.
+func (t *latencyTracker) percentile(p float64) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.samples) < _minLatencySamples {
+		return 0, false
+	}
+	if t.estimatePercentile == p && t.sinceEstimate < _latencyRefresh {
+		return t.estimate, true
+	}
+
+	sorted := make([]time.Duration, len(t.samples))
+	copy(sorted, t.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// nearest-rank method
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	t.estimate = sorted[rank-1]
+	t.estimatePercentile = p
+	t.sinceEstimate = 0
+	return t.estimate, true
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyTrackerPercentile(t *testing.T) {
+	tracker := newLatencyTracker()
+	for i := 1; i < _minLatencySamples; i++ {
+		tracker.observe(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := tracker.percentile(50)
+	assert.False(t, ok, "too few latencies observed")
+
+	for i := _minLatencySamples; i <= 100; i++ {
+		tracker.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	d, ok := tracker.percentile(50)
+	require.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, d)
+
+	d, ok = tracker.percentile(95)
+	require.True(t, ok)
+	assert.Equal(t, 95*time.Millisecond, d)
+
+	d, ok = tracker.percentile(0.1)
+	require.True(t, ok)
+	assert.Equal(t, time.Millisecond, d)
+}
+
+func TestLatencyTrackerCachesEstimate(t *testing.T) {
+	tracker := newLatencyTracker()
+	for i := 0; i < _minLatencySamples; i++ {
+		tracker.observe(time.Millisecond)
+	}
+	d, ok := tracker.percentile(50)
+	require.True(t, ok)
+	assert.Equal(t, time.Millisecond, d)
+
+	for i := 0; i < _latencyRefresh-1; i++ {
+		tracker.observe(time.Second)
+	}
+	d, _ = tracker.percentile(50)
+	assert.Equal(t, time.Millisecond, d, "estimate should not be refreshed yet")
+
+	tracker.observe(time.Second)
+	d, _ = tracker.percentile(50)
+	assert.Equal(t, time.Second, d, "estimate should be refreshed")
+}
+
+func TestLatencyTrackerWindow(t *testing.T) {
+	tracker := newLatencyTracker()
+	for i := 0; i < _latencyWindow; i++ {
+		tracker.observe(time.Second)
+	}
+	for i := 0; i < _latencyWindow; i++ {
+		tracker.observe(time.Millisecond)
+	}
+
+	d, ok := tracker.percentile(99)
+	require.True(t, ok)
+	assert.Equal(t, time.Millisecond, d, "old latencies should be forgotten")
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tracker := newLatencyTracker()
+
+	fixed := NewPolicy(Delay(10 * time.Millisecond))
+	assert.Equal(t, 10*time.Millisecond, fixed.delay(nil))
+	assert.Equal(t, 50*time.Millisecond, NewPolicy().delay(nil))
+
+	adaptive := NewPolicy(Delay(10*time.Millisecond), LatencyPercentile(90))
+	assert.Equal(t, 10*time.Millisecond, adaptive.delay(tracker), "too few latencies observed")
+
+	for i := 1; i <= 100; i++ {
+		tracker.observe(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 90*time.Millisecond, adaptive.delay(tracker))
+
+	ignored := NewPolicy(LatencyPercentile(100))
+	assert.Equal(t, 50*time.Millisecond, ignored.delay(tracker), "invalid percentiles are ignored")
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/zap"
+)
+
+const (
+	_source    = "source"
+	_dest      = "dest"
+	_procedure = "procedure"
+)
+
+// observer records hedging metrics, keyed by caller, service and procedure.
+type observer struct {
+	logger *zap.Logger
+
+	requests *metrics.CounterVector
+	hedges   *metrics.CounterVector
+	wins     *metrics.CounterVector
+}
+
+func newObserver(meter *metrics.Scope, logger *zap.Logger) *observer {
+	o := &observer{logger: logger}
+	tags := []string{_source, _dest, _procedure}
+
+	var err error
+	o.requests, err = meter.CounterVector(metrics.Spec{
+		Name:    "hedge_requests",
+		Help:    "Number of requests that may be hedged.",
+		VarTags: tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create hedge requests counter.", zap.Error(err))
+	}
+	o.hedges, err = meter.CounterVector(metrics.Spec{
+		Name:    "hedge_attempts",
+		Help:    "Number of hedged attempts sent after the first attempt of a request was too slow.",
+		VarTags: tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create hedges counter.", zap.Error(err))
+	}
+	o.wins, err = meter.CounterVector(metrics.Spec{
+		Name:    "hedge_wins",
+		Help:    "Number of requests answered by a hedged attempt before the first attempt.",
+		VarTags: tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create hedge wins counter.", zap.Error(err))
+	}
+	return o
+}
+
+// edge captures the counters for a single request.
+type edge struct {
+	requests *metrics.Counter
+	hedges   *metrics.Counter
+	wins     *metrics.Counter
+}
+
+func (o *observer) edge(req *transport.Request) *edge {
+	return &edge{
+		requests: o.counter(o.requests, req),
+		hedges:   o.counter(o.hedges, req),
+		wins:     o.counter(o.wins, req),
+	}
+}
+
+func (o *observer) counter(vec *metrics.CounterVector, req *transport.Request) *metrics.Counter {
+	c, err := vec.Get(_source, req.Caller, _dest, req.Service, _procedure, req.Procedure)
+	if err != nil {
+		o.logger.Error("Failed to get hedge counter.", zap.Error(err))
+	}
+	return c
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+var _ middleware.UnaryOutbound = (*OutboundMiddleware)(nil)
+
+type middlewareOptions struct {
+	provider PolicyProvider
+	meter    *metrics.Scope
+	logger   *zap.Logger
+}
+
+// MiddlewareOption customizes the behavior of the hedging middleware.
+type MiddlewareOption interface {
+	apply(*middlewareOptions)
+}
+
+type middlewareOptionFunc func(*middlewareOptions)
+
+func (f middlewareOptionFunc) apply(opts *middlewareOptions) { f(opts) }
+
+// WithPolicyProvider sets the provider that selects a hedging policy for
+// each request.
+//
+// Defaults to a provider that hedges no requests.
+func WithPolicyProvider(provider PolicyProvider) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.provider = provider
+	})
+}
+
+// Meter sets the metrics scope on which the middleware records hedged
+// requests.
+//
+// Defaults to no metrics.
+func Meter(meter *metrics.Scope) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.meter = meter
+	})
+}
+
+// Logger sets a logger for the middleware.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
+
+// OutboundMiddleware is unary outbound middleware that hedges requests
+// according to the policy a PolicyProvider selects for each request.
+type OutboundMiddleware struct {
+	provider PolicyProvider
+	logger   *zap.Logger
+	observer *observer
+
+	latencies sync.Map // serviceProcedure -> *latencyTracker
+}
+
+// NewOutboundMiddleware creates a new hedging middleware.
+func NewOutboundMiddleware(opts ...MiddlewareOption) *OutboundMiddleware {
+	options := middlewareOptions{
+		provider: PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return nil
+		}),
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &OutboundMiddleware{
+		provider: options.provider,
+		logger:   options.logger,
+		observer: newObserver(options.meter, options.logger),
+	}
+}
+
+// attemptResult is the outcome of a single attempt of a request.
+type attemptResult struct {
+	index   int
+	res     *transport.Response
+	err     error
+	latency time.Duration
+}
+
+// Call implements middleware.UnaryOutbound.
+//
+// Call sends the request, and if the policy for the request calls for it
+// and the request has not succeeded after the policy's delay, sends it again.
+// It returns the first successful response, cancelling the other attempt,
+// or the last error if both attempts fail.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	policy := m.provider.Policy(ctx, req)
+	if policy == nil {
+		return out.Call(ctx, req)
+	}
+
+	// Both attempts must send the same body, so we read it once and replay it
+	// from memory.
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, yarpcerrors.InternalErrorf("hedge middleware failed to read request body: %v", err)
+		}
+	}
+
+	edge := m.observer.edge(req)
+	edge.requests.Inc()
+
+	var latencies *latencyTracker
+	if policy.opts.percentile > 0 {
+		latencies = m.latencyTracker(req)
+	}
+
+	ctx = withAttempts(ctx, &attempts{})
+	results := make(chan attemptResult, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+
+		attemptReq := *req
+		if req.Body != nil {
+			attemptReq.Body = bytes.NewReader(body)
+		}
+		index := len(cancels) - 1
+		go func() {
+			start := time.Now()
+			res, err := out.Call(attemptCtx, &attemptReq)
+			results <- attemptResult{index: index, res: res, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	send()
+	timer := time.NewTimer(policy.delay(latencies))
+	defer timer.Stop()
+
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			m.logger.Debug("hedging request",
+				zap.String("service", req.Service),
+				zap.String("procedure", req.Procedure))
+			edge.hedges.Inc()
+			send()
+			pending++
+
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if latencies != nil {
+					latencies.observe(r.latency)
+				}
+				if r.index > 0 {
+					edge.wins.Inc()
+				}
+				// The context of the winning attempt is left alone since the
+				// caller may still read the response body through it.
+				for i, cancel := range cancels {
+					if i != r.index {
+						cancel()
+					}
+				}
+				if pending > 0 {
+					go discard(results, pending)
+				}
+				return r.res, nil
+			}
+			if pending == 0 {
+				// The first attempt failed before the delay elapsed, or both
+				// attempts failed. Hedging is not meant to retry errors.
+				for _, cancel := range cancels {
+					cancel()
+				}
+				return nil, r.err
+			}
+		}
+	}
+}
+
+// discard waits for the remaining attempts of a request to end, closing the
+// bodies of their responses.
+func discard(results <-chan attemptResult, pending int) {
+	for ; pending > 0; pending-- {
+		r := <-results
+		if r.res != nil && r.res.Body != nil {
+			_ = r.res.Body.Close()
+		}
+	}
+}
+
+func (m *OutboundMiddleware) latencyTracker(req *transport.Request) *latencyTracker {
+	key := serviceProcedure{service: req.Service, procedure: req.Procedure}
+	if t, ok := m.latencies.Load(key); ok {
+		return t.(*latencyTracker)
+	}
+	t, _ := m.latencies.LoadOrStore(key, newLatencyTracker())
+	return t.(*latencyTracker)
+}
@@ -0,0 +1,285 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/internal/testutils"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// attemptFunc handles one attempt of a request, given the number of the
+// attempt, starting at 0.
+type attemptFunc func(ctx context.Context, attempt int) (*transport.Response, error)
+
+// fakeSender hands each attempt to its attemptFunc, recording the body of
+// each attempt.
+type fakeSender struct {
+	attempts []attemptFunc
+
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (s *fakeSender) call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	attempt := len(s.bodies)
+	s.bodies = append(s.bodies, string(b))
+	s.mu.Unlock()
+
+	return s.attempts[attempt](ctx, attempt)
+}
+
+func (s *fakeSender) sentBodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func (s *fakeSender) outbound() transport.UnaryOutbound {
+	return yarpctest.NewFakeTransport().NewOutbound(nil, yarpctest.OutboundCallOverride(s.call))
+}
+
+func succeed(context.Context, int) (*transport.Response, error) {
+	return &transport.Response{Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+}
+
+func newRequest() *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "procedure",
+		Body:      bytes.NewBufferString("body"),
+	}
+}
+
+func newMiddleware(policy *Policy, opts ...MiddlewareOption) *OutboundMiddleware {
+	opts = append(opts, WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+		return policy
+	})))
+	return NewOutboundMiddleware(opts...)
+}
+
+func TestMiddlewareNotHedged(t *testing.T) {
+	sender := &fakeSender{attempts: []attemptFunc{succeed}}
+	mw := NewOutboundMiddleware()
+
+	_, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"body"}, sender.sentBodies())
+}
+
+func TestMiddlewareFirstAttemptSucceeds(t *testing.T) {
+	sender := &fakeSender{attempts: []attemptFunc{succeed}}
+	mw := newMiddleware(NewPolicy(Delay(time.Hour)))
+
+	res, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.Equal(t, []string{"body"}, sender.sentBodies())
+}
+
+func TestMiddlewareFirstAttemptFails(t *testing.T) {
+	giveErr := errors.New("great sadness")
+	sender := &fakeSender{attempts: []attemptFunc{
+		func(context.Context, int) (*transport.Response, error) { return nil, giveErr },
+	}}
+	mw := newMiddleware(NewPolicy(Delay(time.Hour)))
+
+	_, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	assert.Equal(t, giveErr, err)
+	assert.Len(t, sender.sentBodies(), 1, "errors must not be hedged")
+}
+
+func TestMiddlewareHedgeWins(t *testing.T) {
+	cancelled := make(chan struct{})
+	sender := &fakeSender{attempts: []attemptFunc{
+		func(ctx context.Context, _ int) (*transport.Response, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		},
+		succeed,
+	}}
+	mw := newMiddleware(NewPolicy(Delay(time.Millisecond)))
+
+	res, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.Equal(t, []string{"body", "body"}, sender.sentBodies())
+
+	select {
+	case <-cancelled:
+	case <-time.After(testtime.Second):
+		t.Fatal("the slow attempt was not cancelled")
+	}
+}
+
+func TestMiddlewareFirstAttemptWinsAfterHedge(t *testing.T) {
+	hedged := make(chan struct{})
+	hedgeCancelled := make(chan struct{})
+	sender := &fakeSender{attempts: []attemptFunc{
+		func(ctx context.Context, attempt int) (*transport.Response, error) {
+			<-hedged
+			return succeed(ctx, attempt)
+		},
+		func(ctx context.Context, _ int) (*transport.Response, error) {
+			close(hedged)
+			<-ctx.Done()
+			close(hedgeCancelled)
+			return nil, ctx.Err()
+		},
+	}}
+	mw := newMiddleware(NewPolicy(Delay(time.Millisecond)))
+
+	_, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	require.NoError(t, err)
+
+	select {
+	case <-hedgeCancelled:
+	case <-time.After(testtime.Second):
+		t.Fatal("the hedged attempt was not cancelled")
+	}
+}
+
+func TestMiddlewareHedgeFails(t *testing.T) {
+	hedgeFailed := make(chan struct{})
+	sender := &fakeSender{attempts: []attemptFunc{
+		func(ctx context.Context, attempt int) (*transport.Response, error) {
+			<-hedgeFailed
+			return succeed(ctx, attempt)
+		},
+		func(context.Context, int) (*transport.Response, error) {
+			defer close(hedgeFailed)
+			return nil, errors.New("hedge failed")
+		},
+	}}
+	mw := newMiddleware(NewPolicy(Delay(time.Millisecond)))
+
+	_, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	assert.NoError(t, err, "the first attempt should still succeed")
+}
+
+func TestMiddlewareBothAttemptsFail(t *testing.T) {
+	hedgeFailed := make(chan struct{})
+	sender := &fakeSender{attempts: []attemptFunc{
+		func(context.Context, int) (*transport.Response, error) {
+			<-hedgeFailed
+			return nil, errors.New("first failed")
+		},
+		func(context.Context, int) (*transport.Response, error) {
+			defer close(hedgeFailed)
+			return nil, errors.New("hedge failed")
+		},
+	}}
+	mw := newMiddleware(NewPolicy(Delay(time.Millisecond)))
+
+	_, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	assert.EqualError(t, err, "first failed", "should return the last error")
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestMiddlewareBodyReadError(t *testing.T) {
+	sender := &fakeSender{attempts: []attemptFunc{succeed}}
+	mw := newMiddleware(NewPolicy())
+
+	req := newRequest()
+	req.Body = errReader{}
+	_, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), req)
+	assert.EqualError(t, err, "code:internal message:hedge middleware failed to read request body: read failed")
+	assert.Empty(t, sender.sentBodies())
+}
+
+func TestMiddlewareObservesLatency(t *testing.T) {
+	sender := &fakeSender{}
+	for i := 0; i < _minLatencySamples; i++ {
+		sender.attempts = append(sender.attempts, succeed)
+	}
+
+	mw := newMiddleware(NewPolicy(Delay(time.Hour), LatencyPercentile(50)))
+	for i := 0; i < _minLatencySamples; i++ {
+		_, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+		require.NoError(t, err)
+	}
+
+	_, ok := mw.latencyTracker(newRequest()).percentile(50)
+	assert.True(t, ok, "latencies should be observed")
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	root := metrics.New()
+	policy := NewPolicy(Delay(time.Hour))
+	mw := NewOutboundMiddleware(
+		Meter(root.Scope()),
+		WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return policy
+		})),
+	)
+
+	// Succeeds before the delay.
+	sender := &fakeSender{attempts: []attemptFunc{succeed}}
+	_, err := middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	require.NoError(t, err)
+
+	// The hedged attempt wins.
+	policy = NewPolicy(Delay(time.Millisecond))
+	sender = &fakeSender{attempts: []attemptFunc{
+		func(ctx context.Context, _ int) (*transport.Response, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		succeed,
+	}}
+	_, err = middleware.ApplyUnaryOutbound(sender.outbound(), mw).Call(context.Background(), newRequest())
+	require.NoError(t, err)
+
+	tags := map[string]string{
+		_source:    "caller",
+		_dest:      "service",
+		_procedure: "procedure",
+	}
+	testutils.AssertCounters(t, []testutils.CounterAssertion{
+		{Name: "hedge_attempts", Tags: tags, Value: 1},
+		{Name: "hedge_requests", Tags: tags, Value: 2},
+		{Name: "hedge_wins", Tags: tags, Value: 1},
+	}, root.Snapshot().Counters)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import "time"
+
+// Policy describes when to hedge a request.
+type Policy struct {
+	opts policyOptions
+}
+
+type policyOptions struct {
+	// delay is how long to wait for the first attempt before sending a
+	// hedged attempt.
+	delay time.Duration
+
+	// percentile, if positive, derives the delay from the latencies observed
+	// for the procedure instead.
+	percentile float64
+}
+
+func newPolicyOptions() policyOptions {
+	return policyOptions{
+		delay: 50 * time.Millisecond,
+	}
+}
+
+// PolicyOption customizes the behavior of a hedging policy.
+type PolicyOption interface {
+	apply(*policyOptions)
+}
+
+type policyOptionFunc func(*policyOptions)
+
+func (f policyOptionFunc) apply(opts *policyOptions) { f(opts) }
+
+// NewPolicy creates a new hedging Policy.
+//
+// By default, a policy sends a hedged attempt if the first attempt has not
+// succeeded after 50ms.
+func NewPolicy(opts ...PolicyOption) *Policy {
+	options := newPolicyOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &Policy{opts: options}
+}
+
+// Delay is how long to wait for the first attempt of a request before
+// sending a hedged attempt.
+// With LatencyPercentile, the delay applies until enough latencies have been
+// observed for the procedure.
+//
+// Defaults to 50ms.
+func Delay(d time.Duration) PolicyOption {
+	return policyOptionFunc(func(opts *policyOptions) {
+		opts.delay = d
+	})
+}
+
+// LatencyPercentile sends hedged attempts once the first attempt has taken
+// longer than the given percentile of the latencies recently observed for
+// the procedure. For example, 95 hedges the requests slower than the p95.
+// The percentile must be greater than 0 and less than 100, or it is ignored.
+//
+// Latencies are measured for successful attempts made by the middleware, so
+// the percentile only tracks requests sent with this policy.
+//
+// Defaults to using a fixed delay.
+func LatencyPercentile(p float64) PolicyOption {
+	return policyOptionFunc(func(opts *policyOptions) {
+		if p > 0 && p < 100 {
+			opts.percentile = p
+		}
+	})
+}
+
+// delay returns how long to wait before hedging a request, given the
+// latencies observed for its procedure.
+func (p *Policy) delay(latencies *latencyTracker) time.Duration {
+	if p.opts.percentile > 0 && latencies != nil {
+		if d, ok := latencies.percentile(p.opts.percentile); ok {
+			return d
+		}
+	}
+	return p.opts.delay
+}
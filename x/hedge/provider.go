@@ -0,0 +1,85 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package hedge
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// PolicyProvider returns the hedging policy for a request.
+// The provider returns nil for requests that must not be hedged, which
+// should include every request to a procedure that is not idempotent.
+type PolicyProvider interface {
+	Policy(context.Context, *transport.Request) *Policy
+}
+
+// PolicyProviderFunc adapts a function into a PolicyProvider.
+type PolicyProviderFunc func(context.Context, *transport.Request) *Policy
+
+// Policy implements PolicyProvider.
+func (f PolicyProviderFunc) Policy(ctx context.Context, req *transport.Request) *Policy {
+	return f(ctx, req)
+}
+
+type serviceProcedure struct {
+	service   string
+	procedure string
+}
+
+// IdempotentProcedures is a PolicyProvider that hedges only the procedures
+// registered with it as idempotent.
+// Hedging sends the same request more than once, so only procedures that may
+// safely be called several times, like reads, should be registered.
+type IdempotentProcedures struct {
+	mu       sync.RWMutex
+	policies map[serviceProcedure]*Policy
+}
+
+var _ PolicyProvider = (*IdempotentProcedures)(nil)
+
+// NewIdempotentProcedures creates a new IdempotentProcedures with no
+// registered procedures.
+func NewIdempotentProcedures() *IdempotentProcedures {
+	return &IdempotentProcedures{
+		policies: make(map[serviceProcedure]*Policy),
+	}
+}
+
+// Register marks a procedure of a service as idempotent, hedging its
+// requests with the given policy.
+func (p *IdempotentProcedures) Register(service, procedure string, pol *Policy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.policies[serviceProcedure{service: service, procedure: procedure}] = pol
+}
+
+// Policy returns the policy registered for the procedure of the request, or
+// nil if the procedure was not registered.
+func (p *IdempotentProcedures) Policy(_ context.Context, req *transport.Request) *Policy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.policies[serviceProcedure{service: req.Service, procedure: req.Procedure}]
+}
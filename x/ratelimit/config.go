@@ -0,0 +1,162 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/config"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+// Configuration declares the limits enforced by the rate limiting
+// middleware.
+//
+//	global:
+//	  rps: 1000
+//	  maxInFlight: 200
+//	defaultCaller:
+//	  rps: 100
+//	  burst: 20
+//	callers:
+//	  batch-job:
+//	    rps: 10
+//	perProcedure:
+//	  maxInFlight: 50
+//	procedures:
+//	  users:
+//	    Users::search:
+//	      rps: 20
+//
+// Procedures are grouped by the service they belong to.
+// Every field is optional; limits that are not declared do not apply.
+// See the options of the same name for their meaning.
+type Configuration struct {
+	Global        Limit                       `config:"global"`
+	DefaultCaller Limit                       `config:"defaultCaller"`
+	Callers       map[string]Limit            `config:"callers"`
+	PerProcedure  Limit                       `config:"perProcedure"`
+	Procedures    map[string]map[string]Limit `config:"procedures"`
+}
+
+// Spec returns a configuration specification for the rate limiting
+// middleware, making it possible to declare limits in the
+// inboundMiddleware section of yarpcconfig.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterInboundMiddleware(ratelimit.Spec(ratelimit.Meter(scope)))
+//
+// This enables the middleware for unary, oneway and stream inbounds:
+//
+//	inboundMiddleware:
+//	  ratelimit:
+//	    global:
+//	      rps: 1000
+//
+// See Configuration for the full shape of the configuration.
+// Options take precedence over the configuration.
+func Spec(opts ...Option) yarpcconfig.InboundMiddlewareSpec {
+	return yarpcconfig.InboundMiddlewareSpec{
+		Name: "ratelimit",
+		BuildInboundMiddleware: func(cfg Configuration, _ *yarpcconfig.Kit) (yarpc.InboundMiddleware, error) {
+			cfgOpts, err := cfg.options()
+			if err != nil {
+				return yarpc.InboundMiddleware{}, err
+			}
+
+			mw := NewInboundMiddleware(append(cfgOpts, opts...)...)
+			return yarpc.InboundMiddleware{Unary: mw, Oneway: mw, Stream: mw}, nil
+		},
+	}
+}
+
+// NewInboundMiddlewareFromConfig builds rate limiting middleware from
+// configuration data, which must have the shape of a Configuration.
+// Use it to declare limits outside yarpcconfig; with yarpcconfig, register
+// Spec instead.
+// Options take precedence over the configuration.
+func NewInboundMiddlewareFromConfig(src interface{}, opts ...Option) (*InboundMiddleware, error) {
+	var cfg Configuration
+	if err := config.DecodeInto(&cfg, src); err != nil {
+		return nil, fmt.Errorf("failed to decode rate limit configuration: %v", err)
+	}
+
+	cfgOpts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewInboundMiddleware(append(cfgOpts, opts...)...), nil
+}
+
+func (c Configuration) options() ([]Option, error) {
+	var err error
+	check := func(name string, l Limit) {
+		if e := l.validate(); e != nil {
+			err = multierr.Append(err, fmt.Errorf("invalid %v: %v", name, e))
+		}
+	}
+
+	check("global limit", c.Global)
+	check("defaultCaller limit", c.DefaultCaller)
+	check("perProcedure limit", c.PerProcedure)
+	opts := []Option{
+		Global(c.Global),
+		DefaultCaller(c.DefaultCaller),
+		PerProcedure(c.PerProcedure),
+	}
+
+	// Sort the names so that errors are reported in a stable order.
+	for _, caller := range sortedKeys(c.Callers) {
+		l := c.Callers[caller]
+		check(fmt.Sprintf("limit for caller %q", caller), l)
+		opts = append(opts, Caller(caller, l))
+	}
+	services := make([]string, 0, len(c.Procedures))
+	for service := range c.Procedures {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		procedures := c.Procedures[service]
+		for _, procedure := range sortedKeys(procedures) {
+			l := procedures[procedure]
+			check(fmt.Sprintf("limit for procedure %q of service %q", procedure, service), l)
+			opts = append(opts, Procedure(service, procedure, l))
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+func sortedKeys(m map[string]Limit) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcconfig"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNewInboundMiddlewareFromConfig(t *testing.T) {
+	tests := []struct {
+		desc       string
+		give       string
+		opts       []Option
+		want       options
+		wantErrors []string
+	}{
+		{
+			desc: "empty",
+			want: options{},
+		},
+		{
+			desc: "all limits",
+			give: `
+				global:
+					rps: 1000
+					maxInFlight: 200
+				defaultCaller:
+					rps: 100
+					burst: 20
+				callers:
+					batch-job:
+						rps: 10
+					vip: {}
+				perProcedure:
+					maxInFlight: 50
+				procedures:
+					users:
+						Users::search:
+							rps: 20
+			`,
+			want: options{
+				global:        Limit{RPS: 1000, MaxInFlight: 200},
+				defaultCaller: Limit{RPS: 100, Burst: 20},
+				callers: map[string]Limit{
+					"batch-job": {RPS: 10},
+					"vip":       {},
+				},
+				perProcedure: Limit{MaxInFlight: 50},
+				procedures: map[procedureKey]Limit{
+					{service: "users", procedure: "Users::search"}: {RPS: 20},
+				},
+			},
+		},
+		{
+			desc: "options take precedence",
+			give: `
+				global:
+					rps: 1000
+			`,
+			opts: []Option{Global(Limit{RPS: 10})},
+			want: options{global: Limit{RPS: 10}},
+		},
+		{
+			desc: "invalid limits",
+			give: `
+				global:
+					rps: -1
+				callers:
+					foo:
+						burst: -1
+				procedures:
+					baz:
+						bar:
+							maxInFlight: -1
+			`,
+			wantErrors: []string{
+				"invalid global limit: rps must not be negative, got -1",
+				`invalid limit for caller "foo": burst must not be negative, got -1`,
+				`invalid limit for procedure "bar" of service "baz": maxInFlight must not be negative, got -1`,
+			},
+		},
+		{
+			desc: "unknown field",
+			give: `
+				global:
+					qps: 10
+			`,
+			wantErrors: []string{"failed to decode rate limit configuration"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var data map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(tt.give)), &data))
+
+			mw, err := NewInboundMiddlewareFromConfig(data, tt.opts...)
+			if len(tt.wantErrors) > 0 {
+				require.Error(t, err)
+				for _, msg := range tt.wantErrors {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			want := tt.want
+			if want.callers == nil {
+				want.callers = make(map[string]Limit)
+			}
+			if want.procedures == nil {
+				want.procedures = make(map[procedureKey]Limit)
+			}
+			want.logger = mw.opts.logger
+			assert.Equal(t, want, mw.opts)
+		})
+	}
+}
+
+func TestSpec(t *testing.T) {
+	var data map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+		inboundMiddleware:
+			ratelimit:
+				global:
+					rps: 100
+				defaultCaller:
+					maxInFlight: 10
+	`)), &data))
+
+	cfg := yarpcconfig.New()
+	require.NoError(t, cfg.RegisterInboundMiddleware(Spec()))
+
+	c, err := cfg.LoadConfig("myservice", data)
+	require.NoError(t, err)
+
+	mw, ok := c.InboundMiddleware.Unary.(*InboundMiddleware)
+	require.True(t, ok, "unexpected unary middleware %T", c.InboundMiddleware.Unary)
+	assert.Equal(t, mw, c.InboundMiddleware.Oneway)
+	assert.Equal(t, mw, c.InboundMiddleware.Stream)
+	assert.Equal(t, Limit{RPS: 100}, mw.opts.global)
+	assert.Equal(t, Limit{MaxInFlight: 10}, mw.opts.defaultCaller)
+
+	t.Run("invalid", func(t *testing.T) {
+		var data map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+			inboundMiddleware:
+				ratelimit:
+					perProcedure:
+						rps: -5
+		`)), &data))
+
+		_, err := cfg.LoadConfig("myservice", data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid perProcedure limit: rps must not be negative, got -5")
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package ratelimit provides inbound middleware that sheds load by limiting
+// the rate and concurrency of requests.
+//
+// A Limit combines a token bucket, which admits RPS requests per second on
+// average with bursts of up to Burst requests, with a bound on the number of
+// requests handled at once.
+// Limits may apply to all requests globally, to specific callers, to all
+// other callers together, or to every procedure separately, and may be
+// overridden for specific procedures.
+// A request must be admitted by every limit that applies to it; requests
+// that exceed a limit fail immediately with a ResourceExhausted error
+// without reaching the handler.
+//
+//	limiter := ratelimit.NewInboundMiddleware(
+//		ratelimit.Global(ratelimit.Limit{RPS: 1000, MaxInFlight: 200}),
+//		ratelimit.DefaultCaller(ratelimit.Limit{RPS: 100}),
+//		ratelimit.Caller("batch-job", ratelimit.Limit{RPS: 10}),
+//	)
+//	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+//		Name: "myservice",
+//		InboundMiddleware: yarpc.InboundMiddleware{
+//			Unary:  limiter,
+//			Oneway: limiter,
+//			Stream: limiter,
+//		},
+//	})
+//
+// Limits may also be declared in the inboundMiddleware section of
+// yarpcconfig by registering Spec with the Configurator.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterInboundMiddleware(ratelimit.Spec(ratelimit.Meter(scope)))
+//
+// The middleware reports the number of requests in flight and the tokens
+// available for every limit as gauges, and counts rejected requests, on the
+// scope given to Meter.
+// Pass the scope used for the dispatcher's observability metrics,
+// yarpc.Config.Metrics.Metrics, to report them alongside the rest of the
+// dispatcher's metrics.
+package ratelimit
@@ -0,0 +1,200 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Limit bounds the rate and concurrency of the requests it applies to.
+//
+//	rps: 100
+//	burst: 20
+//	maxInFlight: 10
+//
+// The zero value imposes no limit.
+type Limit struct {
+	// RPS is the number of requests admitted per second on average.
+	// Zero disables rate limiting.
+	RPS float64 `config:"rps"`
+
+	// Burst is the number of requests that may be admitted at once when no
+	// requests were made recently.
+	// Defaults to RPS, rounded up.
+	Burst int `config:"burst"`
+
+	// MaxInFlight is the number of requests that may be handled at once.
+	// Zero disables concurrency limiting.
+	MaxInFlight int `config:"maxInFlight"`
+}
+
+func (l Limit) validate() error {
+	switch {
+	case l.RPS < 0:
+		return fmt.Errorf("rps must not be negative, got %v", l.RPS)
+	case l.Burst < 0:
+		return fmt.Errorf("burst must not be negative, got %v", l.Burst)
+	case l.MaxInFlight < 0:
+		return fmt.Errorf("maxInFlight must not be negative, got %v", l.MaxInFlight)
+	}
+	return nil
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.RPS))
+}
+
+// bucket is a token bucket.
+// It starts full and refills at a constant rate up to its capacity.
+type bucket struct {
+	rate     float64
+	capacity float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, capacity float64, now time.Time) *bucket {
+	return &bucket{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     now,
+	}
+}
+
+// take takes a token from the bucket if one is available, returning whether
+// it did and the number of tokens left.
+func (b *bucket) take(now time.Time) (ok bool, tokens float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false, b.tokens
+	}
+	b.tokens--
+	return true, b.tokens
+}
+
+// refund returns a token taken for a request that was not admitted.
+func (b *bucket) refund() {
+	b.mu.Lock()
+	b.tokens = math.Min(b.capacity, b.tokens+1)
+	b.mu.Unlock()
+}
+
+const (
+	_reasonRate        = "rate"
+	_reasonConcurrency = "concurrency"
+)
+
+// limiter enforces a Limit for all requests, or for the requests of a
+// caller or to a procedure.
+type limiter struct {
+	kind    string // one of _limitGlobal, _limitCaller or _limitProcedure
+	service string // service of the procedure
+	key     string // caller or procedure name, empty for default limits
+
+	bucket      *bucket // nil without a rate limit
+	maxInFlight int64   // zero without a concurrency limit
+	inFlight    atomic.Int64
+
+	observer *observer
+	metrics  limiterMetrics
+}
+
+func newLimiter(kind, service, key string, l Limit, now time.Time, o *observer) *limiter {
+	lim := &limiter{
+		kind:        kind,
+		service:     service,
+		key:         key,
+		maxInFlight: int64(l.MaxInFlight),
+		observer:    o,
+		metrics:     o.limiter(kind, service, key),
+	}
+	if l.RPS > 0 {
+		lim.bucket = newBucket(l.RPS, l.burst(), now)
+		lim.metrics.tokens.Store(int64(lim.bucket.capacity))
+	}
+	return lim
+}
+
+// acquire admits a request, returning the reason it was rejected if it was
+// not.
+// Admitted requests must be released.
+func (l *limiter) acquire(now time.Time) (reason string) {
+	inFlight := l.inFlight.Inc()
+	if l.maxInFlight > 0 && inFlight > l.maxInFlight {
+		l.inFlight.Dec()
+		return _reasonConcurrency
+	}
+
+	if l.bucket != nil {
+		ok, tokens := l.bucket.take(now)
+		l.metrics.tokens.Store(int64(tokens))
+		if !ok {
+			l.inFlight.Dec()
+			return _reasonRate
+		}
+	}
+
+	l.metrics.inFlight.Inc()
+	return ""
+}
+
+// release marks an admitted request as done.
+// If the request was not handled because another limiter rejected it, its
+// token is returned to the bucket.
+func (l *limiter) release(handled bool) {
+	l.inFlight.Dec()
+	l.metrics.inFlight.Dec()
+	if !handled && l.bucket != nil {
+		l.bucket.refund()
+	}
+}
+
+// reject records a request from the given caller rejected for the given
+// reason and returns the error to respond with.
+func (l *limiter) reject(reason, caller string) error {
+	l.observer.reject(l.kind, l.service, l.key, reason)
+	switch l.kind {
+	case _limitGlobal:
+		return yarpcerrors.ResourceExhaustedErrorf("%v limit exceeded", reason)
+	case _limitCaller:
+		return yarpcerrors.ResourceExhaustedErrorf("%v limit exceeded for caller %q", reason, caller)
+	default:
+		return yarpcerrors.ResourceExhaustedErrorf("%v limit exceeded for procedure %q", reason, l.key)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newBucket(2 /* rate */, 3 /* capacity */, now)
+
+	for i := 0; i < 3; i++ {
+		ok, _ := b.take(now)
+		assert.True(t, ok, "bucket must start full: take %d", i)
+	}
+	ok, tokens := b.take(now)
+	assert.False(t, ok, "bucket must be empty")
+	assert.Equal(t, float64(0), tokens)
+
+	now = now.Add(250 * time.Millisecond)
+	ok, tokens = b.take(now)
+	assert.False(t, ok, "half a token is not enough")
+	assert.Equal(t, 0.5, tokens)
+
+	now = now.Add(250 * time.Millisecond)
+	ok, tokens = b.take(now)
+	assert.True(t, ok, "a token must have been added after 500ms")
+	assert.Equal(t, float64(0), tokens)
+
+	now = now.Add(time.Hour)
+	ok, tokens = b.take(now)
+	assert.True(t, ok)
+	assert.Equal(t, float64(2), tokens, "bucket must not fill past its capacity")
+
+	b.refund()
+	b.refund()
+	ok, tokens = b.take(now)
+	assert.True(t, ok)
+	assert.Equal(t, float64(2), tokens, "refunds must not fill past its capacity")
+
+	ok, _ = b.take(now.Add(-time.Second))
+	assert.True(t, ok, "time going backwards must not remove tokens")
+}
+
+func TestLimitBurst(t *testing.T) {
+	tests := []struct {
+		give Limit
+		want float64
+	}{
+		{give: Limit{RPS: 10}, want: 10},
+		{give: Limit{RPS: 2.5}, want: 3},
+		{give: Limit{RPS: 0.1}, want: 1},
+		{give: Limit{RPS: 10, Burst: 2}, want: 2},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.give.burst(), "burst of %+v", tt.give)
+	}
+}
+
+func TestLimitValidate(t *testing.T) {
+	assert.NoError(t, Limit{}.validate())
+	assert.NoError(t, Limit{RPS: 1, Burst: 1, MaxInFlight: 1}.validate())
+	assert.EqualError(t, Limit{RPS: -1}.validate(), "rps must not be negative, got -1")
+	assert.EqualError(t, Limit{Burst: -1}.validate(), "burst must not be negative, got -1")
+	assert.EqualError(t, Limit{MaxInFlight: -1}.validate(), "maxInFlight must not be negative, got -1")
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"go.uber.org/net/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	_limit   = "limit"
+	_service = "service"
+	_key     = "key"
+	_reason  = "reason"
+
+	_limitGlobal    = "global"
+	_limitCaller    = "caller"
+	_limitProcedure = "procedure"
+)
+
+// observer records rate limiting metrics, keyed by the kind of limit and the
+// caller or procedure it applies to.
+// The default caller limit, shared by callers without a limit of their own,
+// has an empty key.
+type observer struct {
+	logger *zap.Logger
+
+	inFlight *metrics.GaugeVector
+	tokens   *metrics.GaugeVector
+	rejected *metrics.CounterVector
+}
+
+func newObserver(meter *metrics.Scope, logger *zap.Logger) *observer {
+	o := &observer{logger: logger}
+	tags := []string{_limit, _service, _key}
+
+	var err error
+	o.inFlight, err = meter.GaugeVector(metrics.Spec{
+		Name:    "ratelimit_in_flight",
+		Help:    "Number of requests being handled, for every limit.",
+		VarTags: tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create rate limit in-flight gauge.", zap.Error(err))
+	}
+	o.tokens, err = meter.GaugeVector(metrics.Spec{
+		Name:    "ratelimit_tokens",
+		Help:    "Number of tokens available in the token bucket of every rate limit.",
+		VarTags: tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create rate limit tokens gauge.", zap.Error(err))
+	}
+	o.rejected, err = meter.CounterVector(metrics.Spec{
+		Name:    "ratelimit_rejected",
+		Help:    "Number of requests rejected by a limit, by the reason they were rejected.",
+		VarTags: append(tags, _reason),
+	})
+	if err != nil {
+		logger.Error("Failed to create rate limit rejected counter.", zap.Error(err))
+	}
+	return o
+}
+
+// limiterMetrics holds the gauges of a single limiter.
+type limiterMetrics struct {
+	inFlight *metrics.Gauge
+	tokens   *metrics.Gauge
+}
+
+func (o *observer) limiter(limit, service, key string) limiterMetrics {
+	var (
+		m   limiterMetrics
+		err error
+	)
+	m.inFlight, err = o.inFlight.Get(_limit, limit, _service, service, _key, key)
+	if err != nil {
+		o.logger.Error("Failed to get rate limit in-flight gauge.", zap.Error(err))
+	}
+	m.tokens, err = o.tokens.Get(_limit, limit, _service, service, _key, key)
+	if err != nil {
+		o.logger.Error("Failed to get rate limit tokens gauge.", zap.Error(err))
+	}
+	return m
+}
+
+func (o *observer) reject(limit, service, key, reason string) {
+	c, err := o.rejected.Get(_limit, limit, _service, service, _key, key, _reason, reason)
+	if err != nil {
+		o.logger.Error("Failed to get rate limit rejected counter.", zap.Error(err))
+		return
+	}
+	c.Inc()
+}
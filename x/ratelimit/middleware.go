@@ -0,0 +1,197 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+)
+
+var (
+	_ middleware.UnaryInbound  = (*InboundMiddleware)(nil)
+	_ middleware.OnewayInbound = (*InboundMiddleware)(nil)
+	_ middleware.StreamInbound = (*InboundMiddleware)(nil)
+
+	_timeNow = time.Now // for tests
+)
+
+// InboundMiddleware is unary, oneway and stream inbound middleware that
+// rejects requests exceeding the configured limits.
+type InboundMiddleware struct {
+	opts          options
+	observer      *observer
+	global        *limiter            // nil without a global limit
+	defaultCaller *limiter            // nil without a default caller limit
+	callers       map[string]*limiter // nil for callers exempt from limits
+
+	mu         sync.RWMutex
+	procedures map[procedureKey]*limiter
+}
+
+// NewInboundMiddleware creates a new rate limiting middleware.
+func NewInboundMiddleware(opts ...Option) *InboundMiddleware {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	m := &InboundMiddleware{
+		opts:       options,
+		observer:   newObserver(options.meter, options.logger),
+		callers:    make(map[string]*limiter, len(options.callers)),
+		procedures: make(map[procedureKey]*limiter),
+	}
+
+	// Callers are chosen by clients, so only callers named in the options
+	// get a limiter of their own; this bounds the number of limiters and
+	// metrics.
+	now := _timeNow()
+	m.global = m.newLimiter(_limitGlobal, "", _limitGlobal, options.global, now)
+	m.defaultCaller = m.newLimiter(_limitCaller, "", "", options.defaultCaller, now)
+	for caller, limit := range options.callers {
+		m.callers[caller] = m.newLimiter(_limitCaller, "", caller, limit, now)
+	}
+	return m
+}
+
+// Handle implements middleware.UnaryInbound.
+func (m *InboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	release, err := m.admit(req.Caller, req.Service, req.Procedure)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return h.Handle(ctx, req, resw)
+}
+
+// HandleOneway implements middleware.OnewayInbound.
+func (m *InboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	release, err := m.admit(req.Caller, req.Service, req.Procedure)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return h.HandleOneway(ctx, req)
+}
+
+// HandleStream implements middleware.StreamInbound.
+//
+// A stream counts as in flight until its handler returns.
+func (m *InboundMiddleware) HandleStream(s *transport.ServerStream, h transport.StreamHandler) error {
+	meta := s.Request().Meta
+	release, err := m.admit(meta.Caller, meta.Service, meta.Procedure)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return h.HandleStream(s)
+}
+
+// admit admits a request through every limit that applies to it, returning
+// a function that must be called once the request has been handled.
+func (m *InboundMiddleware) admit(caller, service, procedure string) (release func(), err error) {
+	limiters := make([]*limiter, 0, 3)
+	if l := m.caller(caller); l != nil {
+		limiters = append(limiters, l)
+	}
+	if l := m.procedure(service, procedure); l != nil {
+		limiters = append(limiters, l)
+	}
+	if m.global != nil {
+		limiters = append(limiters, m.global)
+	}
+
+	now := _timeNow()
+	for i, l := range limiters {
+		if reason := l.acquire(now); reason != "" {
+			// Limiters that admitted the request get their tokens back so
+			// that rejected requests do not count against them.
+			for _, admitted := range limiters[:i] {
+				admitted.release(false /* handled */)
+			}
+			return nil, l.reject(reason, caller)
+		}
+	}
+
+	return func() {
+		for _, l := range limiters {
+			l.release(true /* handled */)
+		}
+	}, nil
+}
+
+func (m *InboundMiddleware) caller(caller string) *limiter {
+	if l, ok := m.callers[caller]; ok {
+		return l
+	}
+	return m.defaultCaller
+}
+
+// procedure returns the limiter for the given procedure of the given
+// service, creating it if necessary, or nil if the procedure is not limited.
+//
+// Limiters are created lazily for every procedure that receives requests.
+// Inbound middleware only sees requests for registered procedures, which
+// bounds their number.
+func (m *InboundMiddleware) procedure(service, procedure string) *limiter {
+	key := procedureKey{service: service, procedure: procedure}
+	limit, ok := m.opts.procedures[key]
+	if !ok {
+		limit = m.opts.perProcedure
+	}
+	if !limits(limit) {
+		return nil
+	}
+
+	m.mu.RLock()
+	l, ok := m.procedures[key]
+	m.mu.RUnlock()
+	if ok {
+		return l
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.procedures[key]; ok {
+		return l
+	}
+	l = m.newLimiter(_limitProcedure, service, procedure, limit, _timeNow())
+	m.procedures[key] = l
+	return l
+}
+
+// newLimiter builds a limiter for the given Limit, or returns nil if the
+// limit does not limit anything.
+func (m *InboundMiddleware) newLimiter(kind, service, key string, limit Limit, now time.Time) *limiter {
+	if !limits(limit) {
+		return nil
+	}
+	return newLimiter(kind, service, key, limit, now, m.observer)
+}
+
+// limits reports whether the given Limit limits anything.
+func limits(l Limit) bool {
+	return l.RPS > 0 || l.MaxInFlight > 0
+}
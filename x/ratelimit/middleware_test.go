@@ -0,0 +1,303 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// stubTime replaces _timeNow with a clock that only moves when advanced.
+func stubTime(t *testing.T) (advance func(time.Duration)) {
+	now := time.Unix(1000, 0)
+	prev := _timeNow
+	_timeNow = func() time.Time { return now }
+	t.Cleanup(func() { _timeNow = prev })
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+type unaryHandlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f unaryHandlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+// handler returns a unary handler behind the middleware that runs the given
+// function for every request.
+func handler(mw middleware.UnaryInbound, f func(*transport.Request)) transport.UnaryHandler {
+	return middleware.ApplyUnaryInbound(unaryHandlerFunc(func(_ context.Context, req *transport.Request, _ transport.ResponseWriter) error {
+		if f != nil {
+			f(req)
+		}
+		return nil
+	}), mw)
+}
+
+func handle(h transport.UnaryHandler, caller, procedure string) error {
+	return h.Handle(context.Background(), &transport.Request{Caller: caller, Procedure: procedure}, nil)
+}
+
+func assertRejected(t *testing.T, err error, msg string, msgAndArgs ...interface{}) {
+	t.Helper()
+	require.Error(t, err, msgAndArgs...)
+	assert.Equal(t, yarpcerrors.CodeResourceExhausted, yarpcerrors.FromError(err).Code())
+	assert.Equal(t, msg, yarpcerrors.FromError(err).Message())
+}
+
+func TestMiddlewareNoLimits(t *testing.T) {
+	h := handler(NewInboundMiddleware(), nil)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, handle(h, "caller", "proc"))
+	}
+}
+
+func TestMiddlewareGlobalRateLimit(t *testing.T) {
+	advance := stubTime(t)
+
+	var calls int
+	h := handler(NewInboundMiddleware(Global(Limit{RPS: 2})), func(*transport.Request) { calls++ })
+
+	require.NoError(t, handle(h, "a", "proc"))
+	require.NoError(t, handle(h, "b", "other"))
+	assertRejected(t, handle(h, "c", "proc"), "rate limit exceeded")
+	assert.Equal(t, 2, calls, "rejected requests must not reach the handler")
+
+	advance(500 * time.Millisecond)
+	require.NoError(t, handle(h, "c", "proc"))
+	assertRejected(t, handle(h, "c", "proc"), "rate limit exceeded")
+	assert.Equal(t, 3, calls)
+}
+
+func TestMiddlewareConcurrencyLimit(t *testing.T) {
+	stubTime(t)
+
+	mw := NewInboundMiddleware(Global(Limit{MaxInFlight: 1}))
+
+	var nestedErr error
+	var h transport.UnaryHandler
+	h = handler(mw, func(req *transport.Request) {
+		if req.Procedure == "outer" {
+			nestedErr = handle(h, "caller", "inner")
+		}
+	})
+
+	require.NoError(t, handle(h, "caller", "outer"))
+	assertRejected(t, nestedErr, "concurrency limit exceeded")
+	assert.NoError(t, handle(h, "caller", "inner"), "request must be admitted once the first one is done")
+}
+
+func TestMiddlewareCallers(t *testing.T) {
+	stubTime(t)
+
+	mw := NewInboundMiddleware(
+		DefaultCaller(Limit{RPS: 1}),
+		Caller("batch", Limit{RPS: 2}),
+		Caller("vip", Limit{}),
+	)
+	h := handler(mw, nil)
+
+	require.NoError(t, handle(h, "a", "proc"))
+	assertRejected(t, handle(h, "a", "other"), `rate limit exceeded for caller "a"`)
+	assertRejected(t, handle(h, "b", "proc"), `rate limit exceeded for caller "b"`,
+		"callers without a limit of their own must share the default limit")
+
+	require.NoError(t, handle(h, "batch", "proc"))
+	require.NoError(t, handle(h, "batch", "proc"))
+	assertRejected(t, handle(h, "batch", "proc"), `rate limit exceeded for caller "batch"`)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, handle(h, "vip", "proc"), "overrides without limits must not limit")
+	}
+
+	for i := 0; i < 10; i++ {
+		_ = handle(h, fmt.Sprintf("caller-%d", i), "proc")
+	}
+	assert.Len(t, mw.callers, 2, "unknown callers must not get limiters of their own")
+}
+
+func TestMiddlewarePerProcedure(t *testing.T) {
+	stubTime(t)
+
+	h := handler(NewInboundMiddleware(
+		PerProcedure(Limit{RPS: 1}),
+		Procedure("svc", "bulk", Limit{RPS: 2}),
+	), nil)
+
+	handle := func(service, procedure string) error {
+		return h.Handle(context.Background(), &transport.Request{Service: service, Procedure: procedure}, nil)
+	}
+
+	require.NoError(t, handle("svc", "get"))
+	assertRejected(t, handle("svc", "get"), `rate limit exceeded for procedure "get"`)
+	require.NoError(t, handle("svc", "set"), "procedures must be limited separately")
+	require.NoError(t, handle("other", "get"), "procedures of different services must be limited separately")
+
+	require.NoError(t, handle("svc", "bulk"))
+	require.NoError(t, handle("svc", "bulk"))
+	assertRejected(t, handle("svc", "bulk"), `rate limit exceeded for procedure "bulk"`)
+
+	require.NoError(t, handle("other", "bulk"))
+	assertRejected(t, handle("other", "bulk"), `rate limit exceeded for procedure "bulk"`,
+		"overrides must only apply to the procedure of their service")
+}
+
+func TestMiddlewareRejectionRefundsTokens(t *testing.T) {
+	stubTime(t)
+
+	mw := NewInboundMiddleware(Caller("b", Limit{RPS: 1}), Global(Limit{MaxInFlight: 1}))
+
+	var nestedErr error
+	var h transport.UnaryHandler
+	h = handler(mw, func(req *transport.Request) {
+		if req.Caller == "a" {
+			nestedErr = handle(h, "b", "proc")
+		}
+	})
+
+	require.NoError(t, handle(h, "a", "proc"))
+	assertRejected(t, nestedErr, "concurrency limit exceeded")
+	assert.NoError(t, handle(h, "b", "proc"),
+		"a request rejected by the global limit must not use the caller's token")
+}
+
+func TestMiddlewareReleasesOnPanic(t *testing.T) {
+	stubTime(t)
+
+	h := handler(NewInboundMiddleware(Global(Limit{MaxInFlight: 1})), func(req *transport.Request) {
+		if req.Procedure == "panic" {
+			panic("great sadness")
+		}
+	})
+
+	assert.Panics(t, func() { _ = handle(h, "caller", "panic") })
+	assert.NoError(t, handle(h, "caller", "proc"), "a panicking handler must release its slot")
+}
+
+func TestMiddlewareOneway(t *testing.T) {
+	stubTime(t)
+
+	ctrl := gomock.NewController(t)
+	oneway := transporttest.NewMockOnewayHandler(ctrl)
+	oneway.EXPECT().HandleOneway(gomock.Any(), gomock.Any()).Return(nil)
+
+	h := middleware.ApplyOnewayInbound(oneway, NewInboundMiddleware(DefaultCaller(Limit{RPS: 1})))
+	req := &transport.Request{Caller: "caller", Procedure: "proc"}
+	require.NoError(t, h.HandleOneway(context.Background(), req))
+	assertRejected(t, h.HandleOneway(context.Background(), req), `rate limit exceeded for caller "caller"`)
+}
+
+func TestMiddlewareStream(t *testing.T) {
+	stubTime(t)
+
+	ctrl := gomock.NewController(t)
+	stream := transporttest.NewMockStream(ctrl)
+	stream.EXPECT().Request().Return(&transport.StreamRequest{
+		Meta: &transport.RequestMeta{Caller: "caller", Procedure: "proc"},
+	}).AnyTimes()
+	serverStream, err := transport.NewServerStream(stream)
+	require.NoError(t, err)
+
+	mw := NewInboundMiddleware(PerProcedure(Limit{MaxInFlight: 1}))
+
+	var nestedErr error
+	streamHandler := transporttest.NewMockStreamHandler(ctrl)
+	h := middleware.ApplyStreamInbound(streamHandler, mw)
+	streamHandler.EXPECT().HandleStream(serverStream).DoAndReturn(func(s *transport.ServerStream) error {
+		nestedErr = h.HandleStream(s)
+		return nil
+	})
+
+	require.NoError(t, h.HandleStream(serverStream))
+	assertRejected(t, nestedErr, `concurrency limit exceeded for procedure "proc"`)
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	stubTime(t)
+	root := metrics.New()
+
+	mw := NewInboundMiddleware(
+		Meter(root.Scope()),
+		Global(Limit{RPS: 10, MaxInFlight: 5}),
+		Caller("a", Limit{RPS: 1}),
+		DefaultCaller(Limit{RPS: 1}),
+	)
+
+	var gauges []metrics.Snapshot
+	h := handler(mw, func(req *transport.Request) {
+		if req.Procedure == "snapshot" {
+			gauges = root.Snapshot().Gauges
+		}
+	})
+
+	require.NoError(t, handle(h, "a", "snapshot"))
+	assertGauges(t, map[string]int64{
+		"ratelimit_in_flight,key=a,limit=caller":       1,
+		"ratelimit_in_flight,key=default,limit=caller": 0,
+		"ratelimit_in_flight,key=global,limit=global":  1,
+		"ratelimit_tokens,key=a,limit=caller":          0,
+		"ratelimit_tokens,key=default,limit=caller":    1,
+		"ratelimit_tokens,key=global,limit=global":     9,
+	}, gauges)
+
+	assertRejected(t, handle(h, "a", "proc"), `rate limit exceeded for caller "a"`)
+	require.NoError(t, handle(h, "b", "proc"))
+
+	snapshot := root.Snapshot()
+	require.Len(t, snapshot.Counters, 1)
+	assert.Equal(t, "ratelimit_rejected", snapshot.Counters[0].Name)
+	assert.Equal(t, metrics.Tags{_limit: _limitCaller, _service: metrics.DefaultTagValue, _key: "a", _reason: _reasonRate}, snapshot.Counters[0].Tags)
+	assert.Equal(t, int64(1), snapshot.Counters[0].Value)
+
+	assertGauges(t, map[string]int64{
+		"ratelimit_in_flight,key=a,limit=caller":       0,
+		"ratelimit_in_flight,key=default,limit=caller": 0,
+		"ratelimit_in_flight,key=global,limit=global":  0,
+		"ratelimit_tokens,key=a,limit=caller":          0,
+		"ratelimit_tokens,key=default,limit=caller":    0,
+		"ratelimit_tokens,key=global,limit=global":     8,
+	}, snapshot.Gauges)
+}
+
+// assertGauges asserts the values of gauges identified by their name and
+// tags, like "name,tag=value".
+func assertGauges(t *testing.T, want map[string]int64, gauges []metrics.Snapshot) {
+	t.Helper()
+	got := make(map[string]int64, len(gauges))
+	for _, g := range gauges {
+		id := g.Name
+		for _, k := range []string{_key, _limit} {
+			id += "," + k + "=" + g.Tags[k]
+		}
+		got[id] = g.Value
+	}
+	assert.Equal(t, want, got)
+}
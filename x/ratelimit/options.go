@@ -0,0 +1,130 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ratelimit
+
+import (
+	"go.uber.org/net/metrics"
+	"go.uber.org/zap"
+)
+
+type options struct {
+	global        Limit
+	defaultCaller Limit
+	callers       map[string]Limit
+	perProcedure  Limit
+	procedures    map[procedureKey]Limit
+
+	meter  *metrics.Scope
+	logger *zap.Logger
+}
+
+func newOptions() options {
+	return options{
+		callers:    make(map[string]Limit),
+		procedures: make(map[procedureKey]Limit),
+		logger:     zap.NewNop(),
+	}
+}
+
+// Option customizes the behavior of the rate limiting middleware.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(opts *options) { f(opts) }
+
+// Global limits all requests handled by the middleware together.
+//
+// Defaults to no limit.
+func Global(l Limit) Option {
+	return optionFunc(func(opts *options) {
+		opts.global = l
+	})
+}
+
+// DefaultCaller limits the requests of all callers without a Caller limit
+// together.
+//
+// Defaults to no limit.
+func DefaultCaller(l Limit) Option {
+	return optionFunc(func(opts *options) {
+		opts.defaultCaller = l
+	})
+}
+
+// Caller limits the requests of the given caller separately, instead of the
+// DefaultCaller limit.
+// This may be used to give specific callers more or less capacity than
+// others.
+// Only callers named with Caller get a limit of their own, so that callers
+// cannot grow the number of limiters and metrics kept by the middleware.
+func Caller(caller string, l Limit) Option {
+	return optionFunc(func(opts *options) {
+		opts.callers[caller] = l
+	})
+}
+
+// PerProcedure limits the requests to every procedure of every service
+// separately.
+//
+// Defaults to no limit.
+func PerProcedure(l Limit) Option {
+	return optionFunc(func(opts *options) {
+		opts.perProcedure = l
+	})
+}
+
+// Procedure limits the requests to the given procedure of the given service,
+// instead of the PerProcedure limit.
+func Procedure(service, procedure string, l Limit) Option {
+	return optionFunc(func(opts *options) {
+		opts.procedures[procedureKey{service: service, procedure: procedure}] = l
+	})
+}
+
+// procedureKey identifies a procedure of a service.
+type procedureKey struct {
+	service   string
+	procedure string
+}
+
+// Meter sets the metrics scope on which the middleware reports requests in
+// flight, available tokens and rejected requests.
+//
+// Defaults to no metrics.
+func Meter(meter *metrics.Scope) Option {
+	return optionFunc(func(opts *options) {
+		opts.meter = meter
+	})
+}
+
+// Logger sets a logger for the middleware.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(opts *options) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/uber-go/mapdecode"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/config"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Configuration describes retry policies and the services and procedures to
+// which they apply.
+//
+//	policies:
+//	  fast:
+//	    retries: 1
+//	    maxTimeout: 50ms
+//	  persistent:
+//	    retries: 5
+//	    maxTimeout: 100ms
+//	    backoff:
+//	      exponential:
+//	        first: 10ms
+//	        max: 500ms
+//	    retryableCodes:
+//	      - unavailable
+//	      - resource-exhausted
+//	default: fast
+//	overrides:
+//	  - service: users
+//	    with: persistent
+//	  - service: users
+//	    procedure: Users::create
+//	    with: fast
+//
+// The default policy applies to every request that does not match an
+// override.
+// If no default is named, requests that match no override are not retried.
+// Overrides for a service and procedure take precedence over overrides for a
+// service.
+type Configuration struct {
+	Policies  map[string]PolicyConfiguration `config:"policies"`
+	Default   string                         `config:"default"`
+	Overrides []OverrideConfiguration        `config:"overrides"`
+}
+
+// PolicyConfiguration describes a single retry policy.
+//
+// Each field is optional and defaults to the corresponding NewPolicy default.
+type PolicyConfiguration struct {
+	// Retries is the number of times to retry a request after the first
+	// attempt fails.
+	Retries *uint `config:"retries"`
+
+	// MaxTimeout bounds the duration of each attempt.
+	MaxTimeout time.Duration `config:"maxTimeout"`
+
+	// Backoff configures the duration to wait between attempts.
+	Backoff *yarpcconfig.Backoff `config:"backoff"`
+
+	// RetryableCodes lists the names of the error codes that may be retried,
+	// like "unavailable" or "resource-exhausted".
+	RetryableCodes []code `config:"retryableCodes"`
+}
+
+// OverrideConfiguration applies a named policy to a service, or to a single
+// procedure of a service.
+type OverrideConfiguration struct {
+	Service   string `config:"service"`
+	Procedure string `config:"procedure"`
+	With      string `config:"with"`
+}
+
+// code is a yarpcerrors.Code that decodes from its string representation.
+type code yarpcerrors.Code
+
+// mapdecode does not support encoding.TextUnmarshaler, so we decode codes
+// manually.
+func (c *code) Decode(into mapdecode.Into) error {
+	var s string
+	if err := into(&s); err != nil {
+		return fmt.Errorf("could not decode error code: %v", err)
+	}
+	return (*yarpcerrors.Code)(c).UnmarshalText([]byte(s))
+}
+
+// Spec returns a configuration specification for the retry middleware,
+// making it possible to configure retries in the outboundMiddleware section
+// of yarpcconfig.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(retry.Spec(retry.Meter(scope)))
+//
+// This enables the retry middleware for unary and oneway outbounds:
+//
+//	outboundMiddleware:
+//	  retry:
+//	    policies:
+//	      default:
+//	        retries: 2
+//	        maxTimeout: 100ms
+//	    default: default
+//
+// See Configuration for the full shape of the configuration.
+func Spec(opts ...MiddlewareOption) yarpcconfig.OutboundMiddlewareSpec {
+	return yarpcconfig.OutboundMiddlewareSpec{
+		Name: "retry",
+		BuildOutboundMiddleware: func(cfg Configuration, _ *yarpcconfig.Kit) (yarpc.OutboundMiddleware, error) {
+			provider, err := cfg.PolicyProvider()
+			if err != nil {
+				return yarpc.OutboundMiddleware{}, err
+			}
+
+			mwOpts := append([]MiddlewareOption{WithPolicyProvider(provider)}, opts...)
+			mw := NewOutboundMiddleware(mwOpts...)
+			return yarpc.OutboundMiddleware{Unary: mw, Oneway: mw}, nil
+		},
+	}
+}
+
+// NewOutboundMiddlewareFromConfig builds retry middleware from configuration
+// data, which must have the shape of a Configuration.
+// Use it to configure retries outside yarpcconfig, for example from a
+// section of your own configuration; with yarpcconfig, register Spec instead.
+//
+//	var cfg map[string]interface{}
+//	yaml.Unmarshal(data, &cfg)
+//	mw, err := retry.NewOutboundMiddlewareFromConfig(cfg["retry"], retry.Meter(scope))
+func NewOutboundMiddlewareFromConfig(src interface{}, opts ...MiddlewareOption) (*OutboundMiddleware, error) {
+	var cfg Configuration
+	if err := config.DecodeInto(&cfg, src); err != nil {
+		return nil, fmt.Errorf("failed to decode retry configuration: %v", err)
+	}
+
+	provider, err := cfg.PolicyProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]MiddlewareOption{WithPolicyProvider(provider)}, opts...)
+	return NewOutboundMiddleware(opts...), nil
+}
+
+// PolicyProvider builds a ProcedurePolicyProvider from the configuration.
+func (c Configuration) PolicyProvider() (*ProcedurePolicyProvider, error) {
+	policies := make(map[string]*Policy, len(c.Policies))
+	for name, pc := range c.Policies {
+		policy, err := pc.policy()
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry policy %q: %v", name, err)
+		}
+		policies[name] = policy
+	}
+
+	lookup := func(name string) (*Policy, error) {
+		if policy, ok := policies[name]; ok {
+			return policy, nil
+		}
+		return nil, fmt.Errorf("unknown retry policy %q; need one of %v", name, policyNames(policies))
+	}
+
+	provider := NewProcedurePolicyProvider()
+	if c.Default != "" {
+		policy, err := lookup(c.Default)
+		if err != nil {
+			return nil, err
+		}
+		provider.SetDefault(policy)
+	}
+
+	for _, o := range c.Overrides {
+		if o.Service == "" {
+			return nil, fmt.Errorf("retry override for policy %q must specify a service", o.With)
+		}
+		policy, err := lookup(o.With)
+		if err != nil {
+			return nil, err
+		}
+		if o.Procedure == "" {
+			provider.RegisterService(o.Service, policy)
+		} else {
+			provider.RegisterServiceProcedure(o.Service, o.Procedure, policy)
+		}
+	}
+	return provider, nil
+}
+
+func (pc PolicyConfiguration) policy() (*Policy, error) {
+	var opts []PolicyOption
+	if pc.Retries != nil {
+		opts = append(opts, Retries(*pc.Retries))
+	}
+	if pc.MaxTimeout < 0 {
+		return nil, fmt.Errorf("maxTimeout must not be negative, got %v", pc.MaxTimeout)
+	}
+	if pc.MaxTimeout > 0 {
+		opts = append(opts, MaxRequestTimeout(pc.MaxTimeout))
+	}
+	if pc.Backoff != nil {
+		strategy, err := pc.Backoff.Strategy()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, BackoffStrategy(strategy))
+	}
+	if len(pc.RetryableCodes) > 0 {
+		codes := make([]yarpcerrors.Code, len(pc.RetryableCodes))
+		for i, c := range pc.RetryableCodes {
+			codes[i] = yarpcerrors.Code(c)
+		}
+		opts = append(opts, RetryableCodes(codes...))
+	}
+	return NewPolicy(opts...), nil
+}
+
+func policyNames(policies map[string]*Policy) []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpcerrors"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNewOutboundMiddlewareFromConfig(t *testing.T) {
+	type policyAssertion struct {
+		service   string
+		procedure string
+
+		// wantRetries is ignored if wantNil is set.
+		wantNil           bool
+		wantRetries       uint
+		wantMaxTimeout    time.Duration
+		wantRetryableCode yarpcerrors.Code
+	}
+
+	tests := []struct {
+		desc       string
+		give       string
+		want       []policyAssertion
+		wantErrors []string
+	}{
+		{
+			desc: "empty",
+			want: []policyAssertion{
+				{service: "foo", wantNil: true},
+			},
+		},
+		{
+			desc: "default only",
+			give: `
+				policies:
+					fast:
+						retries: 2
+						maxTimeout: 50ms
+				default: fast
+			`,
+			want: []policyAssertion{
+				{
+					service:           "foo",
+					wantRetries:       2,
+					wantMaxTimeout:    50 * time.Millisecond,
+					wantRetryableCode: yarpcerrors.CodeUnavailable,
+				},
+			},
+		},
+		{
+			desc: "overrides",
+			give: `
+				policies:
+					fast:
+						retries: 1
+					persistent:
+						retries: 5
+						backoff:
+							exponential:
+								first: 5ms
+								max: 1s
+						retryableCodes:
+							- unavailable
+							- resource-exhausted
+					never:
+						retries: 0
+				default: fast
+				overrides:
+					- service: users
+					  with: persistent
+					- service: users
+					  procedure: Users::create
+					  with: never
+			`,
+			want: []policyAssertion{
+				{
+					service:           "foo",
+					wantRetries:       1,
+					wantRetryableCode: yarpcerrors.CodeUnavailable,
+				},
+				{
+					service:           "users",
+					procedure:         "Users::get",
+					wantRetries:       5,
+					wantRetryableCode: yarpcerrors.CodeResourceExhausted,
+				},
+				{
+					service:     "users",
+					procedure:   "Users::create",
+					wantRetries: 0,
+				},
+			},
+		},
+		{
+			desc: "overrides without default",
+			give: `
+				policies:
+					fast:
+						retries: 3
+				overrides:
+					- service: users
+					  with: fast
+			`,
+			want: []policyAssertion{
+				{service: "foo", wantNil: true},
+				{
+					service:           "users",
+					wantRetries:       3,
+					wantRetryableCode: yarpcerrors.CodeUnavailable,
+				},
+			},
+		},
+		{
+			desc: "unknown default",
+			give: `
+				policies:
+					fast:
+						retries: 1
+				default: slow
+			`,
+			wantErrors: []string{`unknown retry policy "slow"`, "[fast]"},
+		},
+		{
+			desc: "unknown override",
+			give: `
+				overrides:
+					- service: users
+					  with: slow
+			`,
+			wantErrors: []string{`unknown retry policy "slow"`},
+		},
+		{
+			desc: "override without service",
+			give: `
+				policies:
+					fast:
+						retries: 1
+				overrides:
+					- procedure: Users::get
+					  with: fast
+			`,
+			wantErrors: []string{`retry override for policy "fast" must specify a service`},
+		},
+		{
+			desc: "unknown code",
+			give: `
+				policies:
+					fast:
+						retryableCodes: [sad]
+			`,
+			wantErrors: []string{"failed to decode retry configuration", "unknown code string: sad"},
+		},
+		{
+			desc: "negative timeout",
+			give: `
+				policies:
+					fast:
+						maxTimeout: -1s
+			`,
+			wantErrors: []string{`invalid retry policy "fast"`, "maxTimeout must not be negative"},
+		},
+		{
+			desc: "unknown field",
+			give: `
+				policies:
+					fast:
+						attempts: 1
+			`,
+			wantErrors: []string{"failed to decode retry configuration"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var data map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(tt.give)), &data))
+
+			mw, err := NewOutboundMiddlewareFromConfig(data)
+			if len(tt.wantErrors) > 0 {
+				require.Error(t, err)
+				for _, msg := range tt.wantErrors {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			for _, pa := range tt.want {
+				policy := mw.provider.Policy(context.Background(), &transport.Request{
+					Service:   pa.service,
+					Procedure: pa.procedure,
+				})
+				if pa.wantNil {
+					assert.Nil(t, policy, "%v/%v: expected no policy", pa.service, pa.procedure)
+					continue
+				}
+				require.NotNil(t, policy, "%v/%v: expected a policy", pa.service, pa.procedure)
+				assert.Equal(t, pa.wantRetries, policy.opts.retries, "%v/%v: retries", pa.service, pa.procedure)
+				assert.Equal(t, pa.wantMaxTimeout, policy.opts.maxRequestTimeout, "%v/%v: max timeout", pa.service, pa.procedure)
+				if pa.wantRetryableCode != yarpcerrors.CodeOK {
+					assert.True(t, policy.retryable(pa.wantRetryableCode),
+						"%v/%v: %v should be retryable", pa.service, pa.procedure, pa.wantRetryableCode)
+				}
+			}
+		})
+	}
+}
+
+func TestSpec(t *testing.T) {
+	var data map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+		outboundMiddleware:
+			retry:
+				policies:
+					fast:
+						retries: 2
+				default: fast
+	`)), &data))
+
+	cfg := yarpcconfig.New()
+	require.NoError(t, cfg.RegisterOutboundMiddleware(Spec()))
+
+	c, err := cfg.LoadConfig("myservice", data)
+	require.NoError(t, err)
+	require.NotNil(t, c.OutboundMiddleware.Unary)
+	require.NotNil(t, c.OutboundMiddleware.Oneway)
+
+	mw, ok := c.OutboundMiddleware.Unary.(*OutboundMiddleware)
+	require.True(t, ok, "unexpected unary middleware %T", c.OutboundMiddleware.Unary)
+	assert.Equal(t, mw, c.OutboundMiddleware.Oneway)
+
+	policy := mw.provider.Policy(context.Background(), &transport.Request{Service: "foo"})
+	require.NotNil(t, policy)
+	assert.Equal(t, uint(2), policy.opts.retries)
+
+	t.Run("invalid", func(t *testing.T) {
+		var data map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+			outboundMiddleware:
+				retry:
+					default: slow
+		`)), &data))
+
+		_, err := cfg.LoadConfig("myservice", data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown retry policy "slow"`)
+	})
+}
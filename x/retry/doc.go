@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package retry provides outbound middleware that retries unary and oneway
+// requests that fail with retryable errors.
+//
+// Retries are governed by a Policy, which specifies how many times to retry,
+// how long each attempt may take, how long to back off between attempts, and
+// which error codes may be retried.
+// A PolicyProvider selects the policy for each request, typically by service
+// and procedure.
+//
+//	provider := retry.NewProcedurePolicyProvider()
+//	provider.SetDefault(retry.NewPolicy(
+//		retry.Retries(2),
+//		retry.MaxRequestTimeout(100*time.Millisecond),
+//	))
+//	provider.RegisterServiceProcedure("users", "Users::get", retry.NewPolicy(
+//		retry.Retries(5),
+//	))
+//
+//	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+//		Name: "myservice",
+//		OutboundMiddleware: yarpc.OutboundMiddleware{
+//			Unary:  retry.NewOutboundMiddleware(retry.WithPolicyProvider(provider)),
+//		},
+//	})
+//
+// Policies may also be configured in the outboundMiddleware section of
+// yarpcconfig by registering Spec with the Configurator.
+//
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(retry.Spec())
+//
+// NewOutboundMiddlewareFromConfig builds the middleware from the same
+// configuration for callers that decode it outside yarpcconfig.
+//
+// The retry middleware buffers request bodies in memory so that each attempt
+// sends the same payload.
+// The middleware never exceeds the deadline of the context it receives; each
+// attempt is bounded by the lesser of the policy's maximum request timeout and
+// the time remaining before the deadline, and the middleware stops retrying
+// if the deadline would pass while backing off.
+package retry
@@ -0,0 +1,130 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/zap"
+)
+
+const (
+	_source    = "source"
+	_dest      = "dest"
+	_procedure = "procedure"
+	_reason    = "reason"
+
+	_reasonNotRetryable = "not_retryable"
+	_reasonExhausted    = "retries_exhausted"
+	_reasonDeadline     = "deadline"
+)
+
+// observer records retry metrics, keyed by caller, service and procedure.
+type observer struct {
+	logger *zap.Logger
+
+	attempts            *metrics.CounterVector
+	retries             *metrics.CounterVector
+	successesAfterRetry *metrics.CounterVector
+	failures            *metrics.CounterVector
+}
+
+func newObserver(meter *metrics.Scope, logger *zap.Logger) *observer {
+	o := &observer{logger: logger}
+	tags := []string{_source, _dest, _procedure}
+
+	var err error
+	o.attempts, err = meter.CounterVector(metrics.Spec{
+		Name:    "retry_attempts",
+		Help:    "Number of attempts made by the retry middleware, including first attempts.",
+		VarTags: tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create retry attempts counter.", zap.Error(err))
+	}
+	o.retries, err = meter.CounterVector(metrics.Spec{
+		Name:    "retry_retries",
+		Help:    "Number of attempts made after a failed attempt.",
+		VarTags: tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create retries counter.", zap.Error(err))
+	}
+	o.successesAfterRetry, err = meter.CounterVector(metrics.Spec{
+		Name:    "retry_successes_after_retry",
+		Help:    "Number of requests that succeeded after at least one retry.",
+		VarTags: tags,
+	})
+	if err != nil {
+		logger.Error("Failed to create retry successes counter.", zap.Error(err))
+	}
+	o.failures, err = meter.CounterVector(metrics.Spec{
+		Name:    "retry_failures",
+		Help:    "Number of requests that failed, by the reason the middleware stopped retrying.",
+		VarTags: append(tags, _reason),
+	})
+	if err != nil {
+		logger.Error("Failed to create retry failures counter.", zap.Error(err))
+	}
+	return o
+}
+
+// edge captures the counters for a single request.
+type edge struct {
+	o   *observer
+	req *transport.Request
+
+	attempts            *metrics.Counter
+	retries             *metrics.Counter
+	successesAfterRetry *metrics.Counter
+}
+
+func (o *observer) edge(req *transport.Request) *edge {
+	return &edge{
+		o:                   o,
+		req:                 req,
+		attempts:            o.counter(o.attempts, req),
+		retries:             o.counter(o.retries, req),
+		successesAfterRetry: o.counter(o.successesAfterRetry, req),
+	}
+}
+
+func (o *observer) counter(vec *metrics.CounterVector, req *transport.Request) *metrics.Counter {
+	c, err := vec.Get(_source, req.Caller, _dest, req.Service, _procedure, req.Procedure)
+	if err != nil {
+		o.logger.Error("Failed to get retry counter.", zap.Error(err))
+	}
+	return c
+}
+
+func (e *edge) failure(reason string) {
+	c, err := e.o.failures.Get(
+		_source, e.req.Caller,
+		_dest, e.req.Service,
+		_procedure, e.req.Procedure,
+		_reason, reason,
+	)
+	if err != nil {
+		e.o.logger.Error("Failed to get retry failures counter.", zap.Error(err))
+		return
+	}
+	c.Inc()
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"time"
+
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+var (
+	_ middleware.UnaryOutbound  = (*OutboundMiddleware)(nil)
+	_ middleware.OnewayOutbound = (*OutboundMiddleware)(nil)
+
+	_timeNow = time.Now // for tests
+)
+
+type middlewareOptions struct {
+	provider PolicyProvider
+	meter    *metrics.Scope
+	logger   *zap.Logger
+}
+
+// MiddlewareOption customizes the behavior of the retry middleware.
+type MiddlewareOption interface {
+	apply(*middlewareOptions)
+}
+
+type middlewareOptionFunc func(*middlewareOptions)
+
+func (f middlewareOptionFunc) apply(opts *middlewareOptions) { f(opts) }
+
+// WithPolicyProvider sets the provider that selects a retry policy for each
+// request.
+//
+// Defaults to a provider that returns the default policy for every request.
+func WithPolicyProvider(provider PolicyProvider) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.provider = provider
+	})
+}
+
+// Meter sets the metrics scope on which the middleware records retry
+// attempts.
+//
+// Defaults to no metrics.
+func Meter(meter *metrics.Scope) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.meter = meter
+	})
+}
+
+// Logger sets a logger for the middleware.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
+
+// OutboundMiddleware is unary and oneway outbound middleware that retries
+// requests according to the policy a PolicyProvider selects for each
+// request.
+type OutboundMiddleware struct {
+	provider PolicyProvider
+	logger   *zap.Logger
+	observer *observer
+}
+
+// NewOutboundMiddleware creates a new retry middleware.
+func NewOutboundMiddleware(opts ...MiddlewareOption) *OutboundMiddleware {
+	options := middlewareOptions{
+		provider: PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return defaultPolicy
+		}),
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &OutboundMiddleware{
+		provider: options.provider,
+		logger:   options.logger,
+		observer: newObserver(options.meter, options.logger),
+	}
+}
+
+// Call implements middleware.UnaryOutbound.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	var res *transport.Response
+	err := m.retry(ctx, req, func(ctx context.Context, req *transport.Request) error {
+		var err error
+		res, err = out.Call(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+// CallOneway implements middleware.OnewayOutbound.
+func (m *OutboundMiddleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	var ack transport.Ack
+	err := m.retry(ctx, req, func(ctx context.Context, req *transport.Request) error {
+		var err error
+		ack, err = out.CallOneway(ctx, req)
+		return err
+	})
+	return ack, err
+}
+
+// retry sends a request until it succeeds, fails with an error that may not
+// be retried, the policy runs out of retries, or the request deadline would
+// be exceeded.
+func (m *OutboundMiddleware) retry(ctx context.Context, req *transport.Request, send func(context.Context, *transport.Request) error) error {
+	policy := m.provider.Policy(ctx, req)
+	if policy == nil || policy.opts.retries == 0 {
+		return send(ctx, req)
+	}
+
+	// Each attempt must send the same body, so we read it once and replay it
+	// from memory.
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return yarpcerrors.InternalErrorf("retry middleware failed to read request body: %v", err)
+		}
+	}
+
+	edge := m.observer.edge(req)
+	boff := policy.opts.backoffStrategy.Backoff()
+
+	var err error
+	for attempt := uint(0); ; attempt++ {
+		attemptReq := *req
+		if req.Body != nil {
+			attemptReq.Body = bytes.NewReader(body)
+		}
+
+		edge.attempts.Inc()
+		if attempt > 0 {
+			edge.retries.Inc()
+		}
+
+		var attemptTimedOut bool
+		attemptTimedOut, err = m.attempt(ctx, policy, &attemptReq, send)
+		if err == nil {
+			if attempt > 0 {
+				edge.successesAfterRetry.Inc()
+			}
+			return nil
+		}
+
+		if !m.shouldRetry(ctx, policy, err, attemptTimedOut) {
+			edge.failure(_reasonNotRetryable)
+			return err
+		}
+		if attempt >= policy.opts.retries {
+			edge.failure(_reasonExhausted)
+			return err
+		}
+
+		wait := boff.Duration(attempt)
+		if deadline, ok := ctx.Deadline(); ok && !_timeNow().Add(wait).Before(deadline) {
+			edge.failure(_reasonDeadline)
+			return err
+		}
+		if !sleep(ctx, wait) {
+			edge.failure(_reasonDeadline)
+			return err
+		}
+
+		m.logger.Debug("retrying request",
+			zap.String("service", req.Service),
+			zap.String("procedure", req.Procedure),
+			zap.Uint("attempt", attempt+1),
+			zap.Error(err))
+	}
+}
+
+// attempt sends a single attempt of the request, bounded by the maximum
+// request timeout of the policy.
+// It reports whether the attempt ran out of the time the policy gave it,
+// while the request as a whole still had time to spare.
+func (m *OutboundMiddleware) attempt(ctx context.Context, policy *Policy, req *transport.Request, send func(context.Context, *transport.Request) error) (timedOut bool, err error) {
+	timeout := policy.opts.maxRequestTimeout
+	if timeout <= 0 {
+		return false, send(ctx, req)
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(_timeNow()) < timeout {
+		// The parent context expires first, so there is no need for a
+		// separate timeout.
+		return false, send(ctx, req)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err = send(attemptCtx, req)
+	timedOut = err != nil && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	return timedOut, err
+}
+
+// shouldRetry returns whether a failed attempt may be retried under the
+// given policy.
+func (m *OutboundMiddleware) shouldRetry(ctx context.Context, policy *Policy, err error, attemptTimedOut bool) bool {
+	if ctx.Err() != nil {
+		// The caller gave up or ran out of time.
+		return false
+	}
+	if attemptTimedOut {
+		// The attempt ran out of the time we gave it, but the request as a
+		// whole has time to spare.
+		return true
+	}
+	return policy.retryable(yarpcerrors.FromError(err).Code())
+}
+
+// sleep waits for the given duration, returning false if the context is done
+// before the duration elapses.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,421 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/backoff"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/testutils"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// fakeSender returns the given errors in order, recording the body of each
+// attempt, and succeeds once it runs out of errors.
+type fakeSender struct {
+	errs   []error
+	bodies []string
+}
+
+func (s *fakeSender) call(_ context.Context, req *transport.Request) (*transport.Response, error) {
+	return &transport.Response{}, s.send(req)
+}
+
+func (s *fakeSender) callOneway(_ context.Context, req *transport.Request) (transport.Ack, error) {
+	return nil, s.send(req)
+}
+
+func (s *fakeSender) send(req *transport.Request) error {
+	var body string
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		body = string(b)
+	}
+	s.bodies = append(s.bodies, body)
+
+	if len(s.bodies) > len(s.errs) {
+		return nil
+	}
+	return s.errs[len(s.bodies)-1]
+}
+
+func (s *fakeSender) outbound() transport.Outbounds {
+	out := yarpctest.NewFakeTransport().NewOutbound(nil,
+		yarpctest.OutboundCallOverride(s.call),
+		yarpctest.OutboundCallOnewayOverride(s.callOneway),
+	)
+	return transport.Outbounds{Unary: out, Oneway: out}
+}
+
+func newRequest() *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "procedure",
+		Body:      bytes.NewBufferString("body"),
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	unavailable := yarpcerrors.UnavailableErrorf("unavailable")
+	notFound := yarpcerrors.NotFoundErrorf("not found")
+
+	tests := []struct {
+		desc         string
+		policy       *Policy
+		errs         []error
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			desc:         "success on first attempt",
+			policy:       NewPolicy(Retries(2)),
+			wantAttempts: 1,
+		},
+		{
+			desc:         "success after retry",
+			policy:       NewPolicy(Retries(2), BackoffStrategy(backoff.None)),
+			errs:         []error{unavailable, unavailable},
+			wantAttempts: 3,
+		},
+		{
+			desc:         "retries exhausted",
+			policy:       NewPolicy(Retries(2), BackoffStrategy(backoff.None)),
+			errs:         []error{unavailable, unavailable, unavailable},
+			wantErr:      unavailable,
+			wantAttempts: 3,
+		},
+		{
+			desc:         "not retryable",
+			policy:       NewPolicy(Retries(2), BackoffStrategy(backoff.None)),
+			errs:         []error{notFound},
+			wantErr:      notFound,
+			wantAttempts: 1,
+		},
+		{
+			desc:         "non-yarpc errors are not retried",
+			policy:       NewPolicy(Retries(2), BackoffStrategy(backoff.None)),
+			errs:         []error{errors.New("great sadness")},
+			wantErr:      errors.New("great sadness"),
+			wantAttempts: 1,
+		},
+		{
+			desc: "custom retryable codes",
+			policy: NewPolicy(
+				Retries(2),
+				BackoffStrategy(backoff.None),
+				RetryableCodes(yarpcerrors.CodeNotFound),
+			),
+			errs:         []error{notFound, unavailable},
+			wantErr:      unavailable,
+			wantAttempts: 2,
+		},
+		{
+			desc:         "zero retries",
+			policy:       NewPolicy(Retries(0)),
+			errs:         []error{unavailable},
+			wantErr:      unavailable,
+			wantAttempts: 1,
+		},
+		{
+			desc:         "no policy",
+			errs:         []error{unavailable},
+			wantErr:      unavailable,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		provider := PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return tt.policy
+		})
+		mw := NewOutboundMiddleware(WithPolicyProvider(provider))
+
+		t.Run(tt.desc+"/unary", func(t *testing.T) {
+			sender := &fakeSender{errs: tt.errs}
+			out := middleware.ApplyUnaryOutbound(sender.outbound().Unary, mw)
+
+			_, err := out.Call(context.Background(), newRequest())
+			assert.Equal(t, tt.wantErr, err)
+			require.Len(t, sender.bodies, tt.wantAttempts)
+			for _, body := range sender.bodies {
+				assert.Equal(t, "body", body, "every attempt must send the full body")
+			}
+		})
+
+		t.Run(tt.desc+"/oneway", func(t *testing.T) {
+			sender := &fakeSender{errs: tt.errs}
+			out := middleware.ApplyOnewayOutbound(sender.outbound().Oneway, mw)
+
+			_, err := out.CallOneway(context.Background(), newRequest())
+			assert.Equal(t, tt.wantErr, err)
+			require.Len(t, sender.bodies, tt.wantAttempts)
+			for _, body := range sender.bodies {
+				assert.Equal(t, "body", body, "every attempt must send the full body")
+			}
+		})
+	}
+}
+
+func TestMiddlewareDefaultPolicy(t *testing.T) {
+	sender := &fakeSender{errs: []error{yarpcerrors.UnavailableErrorf("unavailable")}}
+	out := middleware.ApplyUnaryOutbound(sender.outbound().Unary, NewOutboundMiddleware())
+
+	_, err := out.Call(context.Background(), newRequest())
+	require.NoError(t, err)
+	assert.Len(t, sender.bodies, 2, "default policy should retry once")
+}
+
+func TestMiddlewareNilBody(t *testing.T) {
+	sender := &fakeSender{errs: []error{yarpcerrors.UnavailableErrorf("unavailable")}}
+	out := middleware.ApplyUnaryOutbound(sender.outbound().Unary, NewOutboundMiddleware(
+		WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return NewPolicy(BackoffStrategy(backoff.None))
+		})),
+	))
+
+	req := newRequest()
+	req.Body = nil
+	_, err := out.Call(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", ""}, sender.bodies)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestMiddlewareBodyReadError(t *testing.T) {
+	sender := &fakeSender{}
+	out := middleware.ApplyUnaryOutbound(sender.outbound().Unary, NewOutboundMiddleware())
+
+	req := newRequest()
+	req.Body = errReader{}
+	_, err := out.Call(context.Background(), req)
+	require.Error(t, err)
+	assert.Equal(t, yarpcerrors.CodeInternal, yarpcerrors.FromError(err).Code())
+	assert.Contains(t, err.Error(), "read failed")
+	assert.Empty(t, sender.bodies, "request must not be sent")
+}
+
+// stubTime replaces _timeNow with a clock that stands still, and returns
+// the time it reports.
+func stubTime(t *testing.T) time.Time {
+	now := time.Now()
+	prev := _timeNow
+	_timeNow = func() time.Time { return now }
+	t.Cleanup(func() { _timeNow = prev })
+	return now
+}
+
+func TestMiddlewareMaxRequestTimeout(t *testing.T) {
+	now := stubTime(t)
+
+	var deadlines []time.Duration
+	out := yarpctest.NewFakeTransport().NewOutbound(nil,
+		yarpctest.OutboundCallOverride(func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok, "attempt must have a deadline")
+			deadlines = append(deadlines, time.Until(deadline))
+			if len(deadlines) == 1 {
+				<-ctx.Done()
+				return nil, yarpcerrors.DeadlineExceededErrorf("timed out")
+			}
+			return &transport.Response{}, nil
+		}),
+	)
+
+	mw := NewOutboundMiddleware(WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+		return NewPolicy(
+			MaxRequestTimeout(10*time.Millisecond),
+			BackoffStrategy(backoff.None),
+		)
+	})))
+
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Minute))
+	defer cancel()
+
+	_, err := middleware.ApplyUnaryOutbound(out, mw).Call(ctx, newRequest())
+	require.NoError(t, err, "attempt that timed out should be retried")
+	require.Len(t, deadlines, 2)
+	for _, d := range deadlines {
+		assert.True(t, d <= 10*time.Millisecond, "attempt deadline %v exceeds maximum request timeout", d)
+	}
+}
+
+func TestMiddlewareServerDeadlineExceeded(t *testing.T) {
+	// A DeadlineExceeded error from the server, rather than from the attempt
+	// timing out, is only retried if the policy allows it.
+	deadlineExceeded := yarpcerrors.DeadlineExceededErrorf("downstream timed out")
+
+	tests := []struct {
+		desc         string
+		policy       *Policy
+		wantAttempts int
+	}{
+		{
+			desc: "not retryable",
+			policy: NewPolicy(
+				MaxRequestTimeout(time.Minute),
+				BackoffStrategy(backoff.None),
+			),
+			wantAttempts: 1,
+		},
+		{
+			desc: "retryable",
+			policy: NewPolicy(
+				MaxRequestTimeout(time.Minute),
+				BackoffStrategy(backoff.None),
+				RetryableCodes(yarpcerrors.CodeDeadlineExceeded),
+			),
+			wantAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			sender := &fakeSender{errs: []error{deadlineExceeded}}
+			mw := NewOutboundMiddleware(WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+				return tt.policy
+			})))
+
+			_, _ = middleware.ApplyOnewayOutbound(sender.outbound().Oneway, mw).CallOneway(context.Background(), newRequest())
+			assert.Len(t, sender.bodies, tt.wantAttempts)
+		})
+	}
+}
+
+func TestMiddlewareNilLogger(t *testing.T) {
+	sender := &fakeSender{errs: []error{yarpcerrors.UnavailableErrorf("unavailable")}}
+	mw := NewOutboundMiddleware(
+		Logger(nil),
+		WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return NewPolicy(BackoffStrategy(backoff.None))
+		})),
+	)
+
+	assert.NotPanics(t, func() {
+		_, err := middleware.ApplyUnaryOutbound(sender.outbound().Unary, mw).Call(context.Background(), newRequest())
+		assert.NoError(t, err)
+	})
+}
+
+func TestMiddlewareRespectsDeadline(t *testing.T) {
+	t.Run("backoff exceeds deadline", func(t *testing.T) {
+		now := stubTime(t)
+
+		sender := &fakeSender{errs: []error{yarpcerrors.UnavailableErrorf("unavailable")}}
+		mw := NewOutboundMiddleware(WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return NewPolicy(BackoffStrategy(fixedBackoff(time.Hour)))
+		})))
+
+		ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Minute))
+		defer cancel()
+
+		_, err := middleware.ApplyUnaryOutbound(sender.outbound().Unary, mw).Call(ctx, newRequest())
+		assert.Equal(t, yarpcerrors.UnavailableErrorf("unavailable"), err)
+		assert.Len(t, sender.bodies, 1, "must not retry if the deadline would pass while backing off")
+	})
+
+	t.Run("context cancelled during backoff", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		out := yarpctest.NewFakeTransport().NewOutbound(nil,
+			yarpctest.OutboundCallOverride(func(context.Context, *transport.Request) (*transport.Response, error) {
+				cancel()
+				return nil, yarpcerrors.UnavailableErrorf("unavailable")
+			}),
+		)
+		mw := NewOutboundMiddleware(WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return NewPolicy(BackoffStrategy(fixedBackoff(time.Hour)))
+		})))
+
+		_, err := middleware.ApplyUnaryOutbound(out, mw).Call(ctx, newRequest())
+		assert.Equal(t, yarpcerrors.UnavailableErrorf("unavailable"), err)
+	})
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	root := metrics.New()
+	unavailable := yarpcerrors.UnavailableErrorf("unavailable")
+
+	mw := NewOutboundMiddleware(
+		Meter(root.Scope()),
+		WithPolicyProvider(PolicyProviderFunc(func(context.Context, *transport.Request) *Policy {
+			return NewPolicy(Retries(2), BackoffStrategy(backoff.None))
+		})),
+	)
+
+	// Succeeds after one retry.
+	sender := &fakeSender{errs: []error{unavailable}}
+	_, err := middleware.ApplyUnaryOutbound(sender.outbound().Unary, mw).Call(context.Background(), newRequest())
+	require.NoError(t, err)
+
+	// Exhausts retries.
+	sender = &fakeSender{errs: []error{unavailable, unavailable, unavailable}}
+	_, err = middleware.ApplyUnaryOutbound(sender.outbound().Unary, mw).Call(context.Background(), newRequest())
+	require.Error(t, err)
+
+	// Fails with an error that may not be retried.
+	sender = &fakeSender{errs: []error{yarpcerrors.InvalidArgumentErrorf("bad")}}
+	_, err = middleware.ApplyUnaryOutbound(sender.outbound().Unary, mw).Call(context.Background(), newRequest())
+	require.Error(t, err)
+
+	tags := map[string]string{
+		_source:    "caller",
+		_dest:      "service",
+		_procedure: "procedure",
+	}
+	withReason := func(reason string) map[string]string {
+		m := map[string]string{_reason: reason}
+		for k, v := range tags {
+			m[k] = v
+		}
+		return m
+	}
+
+	testutils.AssertCounters(t, []testutils.CounterAssertion{
+		{Name: "retry_attempts", Tags: tags, Value: 6},
+		{Name: "retry_failures", Tags: withReason(_reasonNotRetryable), Value: 1},
+		{Name: "retry_failures", Tags: withReason(_reasonExhausted), Value: 1},
+		{Name: "retry_retries", Tags: tags, Value: 3},
+		{Name: "retry_successes_after_retry", Tags: tags, Value: 1},
+	}, root.Snapshot().Counters)
+}
+
+type fixedBackoff time.Duration
+
+func (b fixedBackoff) Backoff() backoff.Backoff { return b }
+
+func (b fixedBackoff) Duration(uint) time.Duration { return time.Duration(b) }
@@ -0,0 +1,160 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"time"
+
+	"go.uber.org/yarpc/api/backoff"
+	ibackoff "go.uber.org/yarpc/internal/backoff"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// DefaultRetryableCodes are the error codes that are retried when a policy
+// does not specify its own.
+//
+// Errors with these codes indicate that the request was not processed by the
+// server and may safely be sent again.
+var DefaultRetryableCodes = []yarpcerrors.Code{
+	yarpcerrors.CodeUnavailable,
+}
+
+// Policy describes how to retry a request.
+type Policy struct {
+	opts policyOptions
+}
+
+type policyOptions struct {
+	// retries is the number of times a request will be retried after the
+	// first attempt fails.
+	retries uint
+
+	// maxRequestTimeout bounds the duration of each attempt.
+	// A zero value means that attempts are only bounded by the deadline of
+	// the request context.
+	maxRequestTimeout time.Duration
+
+	// backoffStrategy determines how long to wait between attempts.
+	backoffStrategy backoff.Strategy
+
+	// retryableCodes is the set of error codes that may be retried.
+	retryableCodes map[yarpcerrors.Code]struct{}
+}
+
+var defaultPolicy = NewPolicy()
+
+func newPolicyOptions() policyOptions {
+	return policyOptions{
+		retries:         1,
+		backoffStrategy: newDefaultBackoff(),
+		retryableCodes:  codeSet(DefaultRetryableCodes),
+	}
+}
+
+func newDefaultBackoff() backoff.Strategy {
+	// NewExponential only fails for invalid options.
+	strategy, _ := ibackoff.NewExponential(
+		ibackoff.FirstBackoff(10*time.Millisecond),
+		ibackoff.MaxBackoff(time.Second),
+	)
+	return strategy
+}
+
+func codeSet(codes []yarpcerrors.Code) map[yarpcerrors.Code]struct{} {
+	set := make(map[yarpcerrors.Code]struct{}, len(codes))
+	for _, code := range codes {
+		set[code] = struct{}{}
+	}
+	return set
+}
+
+// PolicyOption customizes the behavior of a retry policy.
+type PolicyOption interface {
+	apply(*policyOptions)
+}
+
+type policyOptionFunc func(*policyOptions)
+
+func (f policyOptionFunc) apply(opts *policyOptions) { f(opts) }
+
+// NewPolicy creates a new retry Policy.
+//
+// By default, a policy retries a request once, with exponential backoff
+// starting at 10ms, on errors with any of the DefaultRetryableCodes.
+func NewPolicy(opts ...PolicyOption) *Policy {
+	options := newPolicyOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &Policy{opts: options}
+}
+
+// Retries is the number of times a request will be retried after the first
+// attempt fails.
+// A policy with zero retries sends each request exactly once.
+//
+// Defaults to 1.
+func Retries(retries uint) PolicyOption {
+	return policyOptionFunc(func(opts *policyOptions) {
+		opts.retries = retries
+	})
+}
+
+// MaxRequestTimeout bounds the duration of each attempt.
+// The timeout of an attempt never exceeds the time remaining before the
+// deadline of the request context.
+//
+// Defaults to 0, which bounds attempts only by the request deadline.
+// Without a maximum request timeout, an attempt that times out consumes the
+// entire deadline and leaves no time to retry.
+func MaxRequestTimeout(timeout time.Duration) PolicyOption {
+	return policyOptionFunc(func(opts *policyOptions) {
+		opts.maxRequestTimeout = timeout
+	})
+}
+
+// BackoffStrategy sets the strategy used to determine how long to wait
+// before retrying a failed attempt.
+//
+// Defaults to exponential backoff with full jitter, starting at 10ms and
+// growing to at most 1s.
+func BackoffStrategy(strategy backoff.Strategy) PolicyOption {
+	return policyOptionFunc(func(opts *policyOptions) {
+		if strategy != nil {
+			opts.backoffStrategy = strategy
+		}
+	})
+}
+
+// RetryableCodes replaces the set of error codes that may be retried.
+//
+// Defaults to DefaultRetryableCodes.
+func RetryableCodes(codes ...yarpcerrors.Code) PolicyOption {
+	return policyOptionFunc(func(opts *policyOptions) {
+		opts.retryableCodes = codeSet(codes)
+	})
+}
+
+// retryable returns whether the policy permits retrying the given error code.
+func (p *Policy) retryable(code yarpcerrors.Code) bool {
+	_, ok := p.opts.retryableCodes[code]
+	return ok
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// PolicyProvider returns the retry policy for a request.
+// The provider may return nil, in which case the request is not retried.
+type PolicyProvider interface {
+	Policy(context.Context, *transport.Request) *Policy
+}
+
+// PolicyProviderFunc adapts a function into a PolicyProvider.
+type PolicyProviderFunc func(context.Context, *transport.Request) *Policy
+
+// Policy implements PolicyProvider.
+func (f PolicyProviderFunc) Policy(ctx context.Context, req *transport.Request) *Policy {
+	return f(ctx, req)
+}
+
+type serviceProcedure struct {
+	service   string
+	procedure string
+}
+
+// ProcedurePolicyProvider is a PolicyProvider that selects policies by
+// service and procedure.
+//
+// The provider selects the most specific registered policy for a request,
+// preferring a policy registered for the service and procedure, then one
+// registered for the service, then the default.
+type ProcedurePolicyProvider struct {
+	mu sync.RWMutex
+
+	defaultPolicy            *Policy
+	servicePolicies          map[string]*Policy
+	serviceProcedurePolicies map[serviceProcedure]*Policy
+}
+
+var _ PolicyProvider = (*ProcedurePolicyProvider)(nil)
+
+// NewProcedurePolicyProvider creates a new ProcedurePolicyProvider with no
+// registered policies.
+func NewProcedurePolicyProvider() *ProcedurePolicyProvider {
+	return &ProcedurePolicyProvider{
+		servicePolicies:          make(map[string]*Policy),
+		serviceProcedurePolicies: make(map[serviceProcedure]*Policy),
+	}
+}
+
+// SetDefault sets the policy used for requests that match no service or
+// procedure specific policy.
+func (p *ProcedurePolicyProvider) SetDefault(pol *Policy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.defaultPolicy = pol
+}
+
+// RegisterService registers a policy for all procedures of a service.
+func (p *ProcedurePolicyProvider) RegisterService(service string, pol *Policy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.servicePolicies[service] = pol
+}
+
+// RegisterServiceProcedure registers a policy for a single procedure of a
+// service.
+func (p *ProcedurePolicyProvider) RegisterServiceProcedure(service, procedure string, pol *Policy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.serviceProcedurePolicies[serviceProcedure{service: service, procedure: procedure}] = pol
+}
+
+// Policy returns the most specific policy registered for the request.
+func (p *ProcedurePolicyProvider) Policy(_ context.Context, req *transport.Request) *Policy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if pol, ok := p.serviceProcedurePolicies[serviceProcedure{service: req.Service, procedure: req.Procedure}]; ok {
+		return pol
+	}
+	if pol, ok := p.servicePolicies[req.Service]; ok {
+		return pol
+	}
+	return p.defaultPolicy
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package retry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/transport"
+)
+
+func TestProcedurePolicyProvider(t *testing.T) {
+	var (
+		defaultPol   = NewPolicy()
+		servicePol   = NewPolicy(Retries(2))
+		procedurePol = NewPolicy(Retries(3))
+	)
+
+	provider := NewProcedurePolicyProvider()
+	assert.Nil(t, provider.Policy(context.Background(), &transport.Request{Service: "foo"}),
+		"empty provider should return no policy")
+
+	provider.SetDefault(defaultPol)
+	provider.RegisterService("foo", servicePol)
+	provider.RegisterServiceProcedure("foo", "bar", procedurePol)
+
+	tests := []struct {
+		desc      string
+		service   string
+		procedure string
+		want      *Policy
+	}{
+		{
+			desc:      "service and procedure",
+			service:   "foo",
+			procedure: "bar",
+			want:      procedurePol,
+		},
+		{
+			desc:      "service",
+			service:   "foo",
+			procedure: "baz",
+			want:      servicePol,
+		},
+		{
+			desc:      "procedure of another service",
+			service:   "qux",
+			procedure: "bar",
+			want:      defaultPol,
+		},
+		{
+			desc:    "default",
+			service: "qux",
+			want:    defaultPol,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := provider.Policy(context.Background(), &transport.Request{
+				Service:   tt.service,
+				Procedure: tt.procedure,
+			})
+			assert.True(t, tt.want == got, "unexpected policy")
+		})
+	}
+}
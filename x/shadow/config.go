@@ -0,0 +1,126 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+// Configuration describes the outbounds whose requests are shadowed, keyed
+// by the name of the outbound in the outbounds section of yarpcconfig.
+//
+//	outbounds:
+//	  users:
+//	    candidate: users-candidate
+//	    percentage: 5
+//	    timeout: 500ms
+type Configuration struct {
+	Outbounds map[string]OutboundConfiguration `config:"outbounds"`
+}
+
+// OutboundConfiguration describes how the requests sent through an outbound
+// are shadowed.
+type OutboundConfiguration struct {
+	// Candidate is the name of the outbound that receives the shadow
+	// requests.
+	Candidate string `config:"candidate"`
+
+	// Percentage of requests to shadow, between 0 and 100.
+	Percentage float64 `config:"percentage"`
+
+	// Timeout bounds each shadow request. Defaults to one second.
+	Timeout time.Duration `config:"timeout"`
+}
+
+// Spec returns a configuration specification for the shadowing middleware,
+// making it possible to declare shadowed outbounds in the outboundMiddleware
+// section of yarpcconfig.
+//
+//	targets := shadow.NewTargets()
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(shadow.Spec(targets, shadow.Meter(scope)))
+//
+// This shadows 5% of the requests sent through the users outbound to the
+// users-candidate outbound:
+//
+//	outbounds:
+//	  users:
+//	    http:
+//	      url: http://users/
+//	  users-candidate:
+//	    service: users
+//	    http:
+//	      url: http://users-candidate/
+//	outboundMiddleware:
+//	  shadow:
+//	    outbounds:
+//	      users:
+//	        candidate: users-candidate
+//	        percentage: 5
+//
+// The declared outbounds are registered with targets, which must be bound to
+// the outbounds of the loaded configuration with Targets.Bind before they are
+// shadowed.
+// Direct calls through users-candidate are not shadowed.
+func Spec(targets *Targets, opts ...Option) yarpcconfig.OutboundMiddlewareSpec {
+	return yarpcconfig.OutboundMiddlewareSpec{
+		Name: "shadow",
+		BuildOutboundMiddleware: func(cfg Configuration, _ *yarpcconfig.Kit) (yarpc.OutboundMiddleware, error) {
+			if err := cfg.declare(targets); err != nil {
+				return yarpc.OutboundMiddleware{}, err
+			}
+			return yarpc.OutboundMiddleware{Unary: NewOutboundMiddleware(targets, opts...)}, nil
+		},
+	}
+}
+
+// declare validates the configuration, declaring its outbounds with targets.
+func (c Configuration) declare(targets *Targets) error {
+	declared := make(map[string]declaredTarget, len(c.Outbounds))
+	for key, oc := range c.Outbounds {
+		if oc.Candidate == "" {
+			return fmt.Errorf("shadowed outbound %q must specify a candidate outbound", key)
+		}
+		if oc.Candidate == key {
+			return fmt.Errorf("shadowed outbound %q cannot be its own candidate", key)
+		}
+		if oc.Percentage < 0 || oc.Percentage > 100 {
+			return fmt.Errorf("percentage of shadowed outbound %q must be between 0 and 100, got %v", key, oc.Percentage)
+		}
+		if oc.Timeout < 0 {
+			return fmt.Errorf("timeout of shadowed outbound %q must not be negative, got %v", key, oc.Timeout)
+		}
+		declared[key] = declaredTarget{
+			candidate:  oc.Candidate,
+			percentage: oc.Percentage,
+			timeout:    oc.Timeout,
+		}
+	}
+
+	for key, dt := range declared {
+		targets.declare(key, dt)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcconfig"
+	"go.uber.org/yarpc/yarpctest"
+	"gopkg.in/yaml.v2"
+)
+
+func TestSpec(t *testing.T) {
+	tests := []struct {
+		desc       string
+		give       string
+		want       map[string]declaredTarget
+		wantErrors []string
+	}{
+		{
+			desc: "empty",
+			give: `
+				outboundMiddleware:
+					shadow: {}
+			`,
+			want: map[string]declaredTarget{},
+		},
+		{
+			desc: "outbounds",
+			give: `
+				outboundMiddleware:
+					shadow:
+						outbounds:
+							users:
+								candidate: users-candidate
+								percentage: 5
+								timeout: 500ms
+							trips:
+								candidate: trips-candidate
+								percentage: 100
+			`,
+			want: map[string]declaredTarget{
+				"users": {candidate: "users-candidate", percentage: 5, timeout: 500 * time.Millisecond},
+				"trips": {candidate: "trips-candidate", percentage: 100},
+			},
+		},
+		{
+			desc: "missing candidate",
+			give: `
+				outboundMiddleware:
+					shadow:
+						outbounds:
+							users:
+								percentage: 5
+			`,
+			wantErrors: []string{`shadowed outbound "users" must specify a candidate outbound`},
+		},
+		{
+			desc: "own candidate",
+			give: `
+				outboundMiddleware:
+					shadow:
+						outbounds:
+							users:
+								candidate: users
+			`,
+			wantErrors: []string{`shadowed outbound "users" cannot be its own candidate`},
+		},
+		{
+			desc: "invalid percentage",
+			give: `
+				outboundMiddleware:
+					shadow:
+						outbounds:
+							users:
+								candidate: users-candidate
+								percentage: 101
+			`,
+			wantErrors: []string{`percentage of shadowed outbound "users" must be between 0 and 100, got 101`},
+		},
+		{
+			desc: "negative timeout",
+			give: `
+				outboundMiddleware:
+					shadow:
+						outbounds:
+							users:
+								candidate: users-candidate
+								timeout: -1s
+			`,
+			wantErrors: []string{`timeout of shadowed outbound "users" must not be negative`},
+		},
+		{
+			desc: "unknown field",
+			give: `
+				outboundMiddleware:
+					shadow:
+						outbounds:
+							users:
+								candidate: users-candidate
+								rate: 5
+			`,
+			wantErrors: []string{`failed to decode outbound middleware "shadow"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var data map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(tt.give)), &data))
+
+			targets := NewTargets()
+			cfg := yarpcconfig.New()
+			require.NoError(t, cfg.RegisterOutboundMiddleware(Spec(targets)))
+
+			c, err := cfg.LoadConfig("myservice", data)
+			if len(tt.wantErrors) > 0 {
+				require.Error(t, err)
+				for _, msg := range tt.wantErrors {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			mw, ok := c.OutboundMiddleware.Unary.(*OutboundMiddleware)
+			require.True(t, ok, "unexpected unary middleware %T", c.OutboundMiddleware.Unary)
+			assert.Equal(t, targets, mw.targets)
+			assert.Equal(t, tt.want, targets.declared)
+		})
+	}
+}
+
+func TestSpecBind(t *testing.T) {
+	var data map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(whitespace.Expand(`
+		outboundMiddleware:
+			shadow:
+				outbounds:
+					users:
+						candidate: users-candidate
+						percentage: 100
+	`)), &data))
+
+	targets := NewTargets()
+	cfg := yarpcconfig.New()
+	require.NoError(t, cfg.RegisterOutboundMiddleware(Spec(targets)))
+	_, err := cfg.LoadConfig("myservice", data)
+	require.NoError(t, err)
+
+	primary := yarpctest.NewFakeTransport().NewOutbound(nil)
+	candidate := yarpctest.NewFakeTransport().NewOutbound(nil)
+	require.NoError(t, targets.Bind(yarpc.Outbounds{
+		"users":           {ServiceName: "users", Unary: primary},
+		"users-candidate": {ServiceName: "users", Unary: candidate},
+	}))
+
+	target, ok := targets.target(primary)
+	require.True(t, ok)
+	assert.Equal(t, Target{Outbound: candidate, Percentage: 100}, target)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package shadow provides outbound middleware that mirrors a percentage of
+// the unary requests sent through an outbound to a second outbound, like a
+// candidate build of the service being called.
+//
+// The caller only ever sees the response of the primary outbound.
+// Shadow requests are sent asynchronously with their own copy of the request
+// body and their own deadline, so a slow or failing candidate does not slow
+// down or fail the caller.
+// Once both responses are in, the middleware compares their error codes and
+// the hashes of their bodies, logging and counting mismatches.
+//
+// Targets are registered by the name of the outbound they shadow, and bound
+// to the outbounds of the dispatcher's configuration.
+//
+//	targets := shadow.NewTargets()
+//	targets.Register("users", shadow.Target{
+//		Outbound:   candidate,
+//		Percentage: 5,
+//		Timeout:    time.Second,
+//	})
+//
+//	cfg := yarpc.Config{
+//		Name:      "myservice",
+//		Outbounds: outbounds,
+//		OutboundMiddleware: yarpc.OutboundMiddleware{
+//			Unary: shadow.NewOutboundMiddleware(targets, shadow.Meter(scope)),
+//		},
+//	}
+//	if err := targets.Bind(cfg.Outbounds); err != nil {
+//		log.Fatal(err)
+//	}
+//	dispatcher := yarpc.NewDispatcher(cfg)
+//
+// Targets may also be declared per outbound in the outboundMiddleware section
+// of yarpcconfig by registering Spec with the Configurator.
+// The candidate is then another outbound of the configuration.
+//
+//	targets := shadow.NewTargets()
+//	cfg := yarpcconfig.New()
+//	cfg.MustRegisterOutboundMiddleware(shadow.Spec(targets))
+//	c, err := cfg.LoadConfig("myservice", data)
+//	...
+//	if err := targets.Bind(c.Outbounds); err != nil {
+//		log.Fatal(err)
+//	}
+//	dispatcher := yarpc.NewDispatcher(c)
+//
+// The middleware buffers the request bodies of shadowed requests in memory.
+// Primary response bodies are hashed as the caller reads them and compared
+// once the caller closes them; responses whose bodies are not read in full
+// are not compared.
+package shadow
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/zap"
+)
+
+const (
+	_source    = "source"
+	_dest      = "dest"
+	_procedure = "procedure"
+	_reason    = "reason"
+
+	// Reasons for which a shadow response does not match the primary
+	// response.
+	_reasonCode = "code"
+	_reasonBody = "body"
+)
+
+// observer records shadowing metrics, keyed by caller, service and procedure.
+type observer struct {
+	logger *zap.Logger
+
+	requests   *metrics.CounterVector
+	mismatches *metrics.CounterVector
+}
+
+func newObserver(meter *metrics.Scope, logger *zap.Logger) *observer {
+	o := &observer{logger: logger}
+
+	var err error
+	o.requests, err = meter.CounterVector(metrics.Spec{
+		Name:    "shadow_requests",
+		Help:    "Number of requests mirrored to a shadow outbound.",
+		VarTags: []string{_source, _dest, _procedure},
+	})
+	if err != nil {
+		logger.Error("Failed to create shadow requests counter.", zap.Error(err))
+	}
+	o.mismatches, err = meter.CounterVector(metrics.Spec{
+		Name:    "shadow_mismatches",
+		Help:    "Number of shadow responses whose error code or body did not match the primary response.",
+		VarTags: []string{_source, _dest, _procedure, _reason},
+	})
+	if err != nil {
+		logger.Error("Failed to create shadow mismatches counter.", zap.Error(err))
+	}
+	return o
+}
+
+func (o *observer) request(req *transport.Request) {
+	c, err := o.requests.Get(_source, req.Caller, _dest, req.Service, _procedure, req.Procedure)
+	if err != nil {
+		o.logger.Error("Failed to get shadow requests counter.", zap.Error(err))
+	}
+	c.Inc()
+}
+
+func (o *observer) mismatch(req *transport.Request, reason string) {
+	c, err := o.mismatches.Get(_source, req.Caller, _dest, req.Service, _procedure, req.Procedure, _reason, reason)
+	if err != nil {
+		o.logger.Error("Failed to get shadow mismatches counter.", zap.Error(err))
+	}
+	c.Inc()
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"hash"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/outboundmiddleware"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+const _defaultTimeout = time.Second
+
+var (
+	_ middleware.UnaryOutbound = (*OutboundMiddleware)(nil)
+
+	_randFloat64 = rand.Float64 // for tests
+)
+
+type options struct {
+	meter  *metrics.Scope
+	logger *zap.Logger
+}
+
+// Option customizes the behavior of the shadowing middleware.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(opts *options) { f(opts) }
+
+// Meter sets the metrics scope on which the middleware records shadowed
+// requests and mismatched responses.
+//
+// Defaults to no metrics.
+func Meter(meter *metrics.Scope) Option {
+	return optionFunc(func(opts *options) {
+		opts.meter = meter
+	})
+}
+
+// Logger sets a logger for the middleware, which logs mismatched responses.
+//
+// Defaults to no logging.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(opts *options) {
+		if logger != nil {
+			opts.logger = logger
+		}
+	})
+}
+
+// shadowKey marks the contexts of shadow requests so that they are not
+// shadowed again when the candidate outbound goes through this middleware.
+type shadowKey struct{}
+
+// OutboundMiddleware is unary outbound middleware that mirrors requests to
+// the shadow targets of their outbounds.
+type OutboundMiddleware struct {
+	targets  *Targets
+	logger   *zap.Logger
+	observer *observer
+
+	// pending tracks the shadow requests in flight.
+	pending sync.WaitGroup
+}
+
+// NewOutboundMiddleware creates a new shadowing middleware, which shadows
+// requests to the targets bound to their outbounds.
+func NewOutboundMiddleware(targets *Targets, opts ...Option) *OutboundMiddleware {
+	options := options{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &OutboundMiddleware{
+		targets:  targets,
+		logger:   options.logger,
+		observer: newObserver(options.meter, options.logger),
+	}
+}
+
+// outcome summarizes a response for comparison.
+type outcome struct {
+	code             yarpcerrors.Code
+	applicationError bool
+	bodyHash         uint64
+}
+
+// newOutcome summarizes a response, leaving its body hash to the caller.
+func newOutcome(res *transport.Response, err error) outcome {
+	o := outcome{code: yarpcerrors.FromError(err).Code()}
+	if res != nil {
+		o.applicationError = res.ApplicationError
+	}
+	return o
+}
+
+// mismatch returns the reason for which two outcomes differ, if any.
+func (o outcome) mismatch(other outcome) string {
+	switch {
+	case o.code != other.code, o.applicationError != other.applicationError:
+		return _reasonCode
+	case o.bodyHash != other.bodyHash:
+		return _reasonBody
+	default:
+		return ""
+	}
+}
+
+// Call implements middleware.UnaryOutbound.
+//
+// Call sends the request to the next outbound and returns its response.
+// If the request is sampled for shadowing, a copy of it is also sent to the
+// target of its outbound in the background.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	if ctx.Value(shadowKey{}) != nil {
+		return out.Call(ctx, req)
+	}
+	target, ok := m.targets.target(outboundmiddleware.UnaryFinal(out))
+	if !ok || _randFloat64()*100 >= target.Percentage {
+		return out.Call(ctx, req)
+	}
+
+	// Both requests need the same body, so we read it once and replay it
+	// from memory.
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, yarpcerrors.InternalErrorf("shadow middleware failed to read request body: %v", err)
+		}
+	}
+
+	primaryReq := *req
+	shadowReq := *req
+	shadowReq.Headers = transport.HeadersFromMap(req.Headers.OriginalItems())
+	if req.Body != nil {
+		primaryReq.Body = bytes.NewReader(body)
+		shadowReq.Body = bytes.NewReader(body)
+	}
+
+	primary := make(chan outcome, 1)
+	m.pending.Add(1)
+	go m.shadow(target, &shadowReq, primary, ctx.Done())
+
+	res, err := out.Call(ctx, &primaryReq)
+	o := newOutcome(res, err)
+	if res == nil || res.Body == nil {
+		o.bodyHash = fnv.New64a().Sum64()
+		primary <- o
+		return res, err
+	}
+	res.Body = &hashingBody{
+		ReadCloser: res.Body,
+		hash:       fnv.New64a(),
+		outcome:    o,
+		primary:    primary,
+	}
+	return res, err
+}
+
+// hashingBody hashes the body of a primary response as the caller reads it,
+// handing the outcome of the primary request to the shadow request once the
+// body is closed.
+type hashingBody struct {
+	io.ReadCloser
+
+	hash    hash.Hash64
+	eof     bool
+	outcome outcome
+	primary chan<- outcome
+	once    sync.Once
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	_, _ = b.hash.Write(p[:n])
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *hashingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		if !b.eof {
+			// The shadow response cannot be compared to a body that was not
+			// read in full. Closing the channel lets the shadow request end
+			// quietly.
+			close(b.primary)
+			return
+		}
+		b.outcome.bodyHash = b.hash.Sum64()
+		b.primary <- b.outcome
+	})
+	return err
+}
+
+// shadow sends a shadow request to the target and compares its response to
+// the outcome of the primary request, giving up on the comparison if the
+// primary request ends before its outcome is known.
+func (m *OutboundMiddleware) shadow(target Target, req *transport.Request, primary <-chan outcome, primaryDone <-chan struct{}) {
+	defer m.pending.Done()
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = _defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), shadowKey{}, struct{}{}), timeout)
+	defer cancel()
+
+	res, err := target.Outbound.Call(ctx, req)
+	h := fnv.New64a()
+	if res != nil && res.Body != nil {
+		_, readErr := io.Copy(h, res.Body)
+		_ = res.Body.Close()
+		if readErr != nil && err == nil {
+			err = readErr
+		}
+	}
+	got := newOutcome(res, err)
+	got.bodyHash = h.Sum64()
+
+	var (
+		want outcome
+		ok   bool
+	)
+	select {
+	case want, ok = <-primary:
+	case <-primaryDone:
+		// The primary body may have been closed just before its context
+		// was canceled.
+		select {
+		case want, ok = <-primary:
+		default:
+		}
+	}
+	if !ok {
+		return
+	}
+	m.observer.request(req)
+	reason := want.mismatch(got)
+	if reason == "" {
+		return
+	}
+	m.observer.mismatch(req, reason)
+	m.logger.Info("Shadow response did not match primary response.",
+		zap.String("service", req.Service),
+		zap.String("procedure", req.Procedure),
+		zap.String("reason", reason),
+		zap.Stringer("primaryCode", want.code),
+		zap.Stringer("shadowCode", got.code),
+		zap.Bool("primaryApplicationError", want.applicationError),
+		zap.Bool("shadowApplicationError", got.applicationError),
+		zap.Error(err))
+}
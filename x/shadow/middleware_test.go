@@ -0,0 +1,454 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/outboundmiddleware"
+	"go.uber.org/yarpc/internal/testutils"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+// fakeBackend answers requests with a fixed response, recording the bodies
+// and headers it receives.
+type fakeBackend struct {
+	body             string
+	err              error
+	applicationError bool
+
+	mu      sync.Mutex
+	bodies  []string
+	headers []transport.Headers
+}
+
+func (b *fakeBackend) call(_ context.Context, req *transport.Request) (*transport.Response, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.bodies = append(b.bodies, string(body))
+	b.headers = append(b.headers, req.Headers)
+	b.mu.Unlock()
+
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &transport.Response{
+		Body:             ioutil.NopCloser(bytes.NewBufferString(b.body)),
+		ApplicationError: b.applicationError,
+	}, nil
+}
+
+func (b *fakeBackend) calls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.bodies)
+}
+
+func (b *fakeBackend) outbound() transport.UnaryOutbound {
+	return yarpctest.NewFakeTransport().NewOutbound(nil, yarpctest.OutboundCallOverride(b.call))
+}
+
+func newRequest() *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "procedure",
+		Headers:   transport.NewHeaders().With("key", "value"),
+		Body:      bytes.NewBufferString("body"),
+	}
+}
+
+// bind shadows the requests sent through the primary outbound to the target.
+func bind(t *testing.T, primary transport.UnaryOutbound, target Target) *Targets {
+	targets := NewTargets()
+	targets.Register("service", target)
+	require.NoError(t, targets.Bind(yarpc.Outbounds{"service": {Unary: primary}}))
+	return targets
+}
+
+func call(t *testing.T, mw *OutboundMiddleware, primary transport.UnaryOutbound) (string, error) {
+	res, err := middleware.ApplyUnaryOutbound(primary, mw).Call(context.Background(), newRequest())
+	if err != nil {
+		return "", err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	return string(body), nil
+}
+
+func TestMiddlewareNotShadowed(t *testing.T) {
+	primary := &fakeBackend{body: "hello"}
+	candidate := &fakeBackend{body: "hello"}
+
+	targets := bind(t, yarpctest.NewFakeTransport().NewOutbound(nil), Target{Outbound: candidate.outbound(), Percentage: 100})
+	mw := NewOutboundMiddleware(targets)
+
+	body, err := call(t, mw, primary.outbound())
+	require.NoError(t, err)
+	assert.Equal(t, "hello", body)
+
+	mw.pending.Wait()
+	assert.Equal(t, 1, primary.calls())
+	assert.Equal(t, 0, candidate.calls(), "other outbounds must not be shadowed, even to the same service")
+}
+
+func TestMiddlewareShadows(t *testing.T) {
+	tests := []struct {
+		desc         string
+		primary      *fakeBackend
+		candidate    *fakeBackend
+		wantBody     string
+		wantErr      error
+		wantMismatch string
+	}{
+		{
+			desc:      "match",
+			primary:   &fakeBackend{body: "hello"},
+			candidate: &fakeBackend{body: "hello"},
+			wantBody:  "hello",
+		},
+		{
+			desc:         "body mismatch",
+			primary:      &fakeBackend{body: "hello"},
+			candidate:    &fakeBackend{body: "goodbye"},
+			wantBody:     "hello",
+			wantMismatch: _reasonBody,
+		},
+		{
+			desc:         "candidate fails",
+			primary:      &fakeBackend{body: "hello"},
+			candidate:    &fakeBackend{err: yarpcerrors.InternalErrorf("great sadness")},
+			wantBody:     "hello",
+			wantMismatch: _reasonCode,
+		},
+		{
+			desc:         "primary fails",
+			primary:      &fakeBackend{err: yarpcerrors.UnavailableErrorf("great sadness")},
+			candidate:    &fakeBackend{body: "hello"},
+			wantErr:      yarpcerrors.UnavailableErrorf("great sadness"),
+			wantMismatch: _reasonCode,
+		},
+		{
+			desc:      "both fail",
+			primary:   &fakeBackend{err: yarpcerrors.NotFoundErrorf("no such user")},
+			candidate: &fakeBackend{err: yarpcerrors.NotFoundErrorf("user not found")},
+			wantErr:   yarpcerrors.NotFoundErrorf("no such user"),
+		},
+		{
+			desc:         "application error mismatch",
+			primary:      &fakeBackend{body: "hello"},
+			candidate:    &fakeBackend{body: "hello", applicationError: true},
+			wantBody:     "hello",
+			wantMismatch: _reasonCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := metrics.New()
+			primary := tt.primary.outbound()
+			targets := bind(t, primary, Target{Outbound: tt.candidate.outbound(), Percentage: 100})
+			mw := NewOutboundMiddleware(targets, Meter(root.Scope()))
+
+			body, err := call(t, mw, primary)
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantBody, body)
+			}
+			mw.pending.Wait()
+
+			assert.Equal(t, []string{"body"}, tt.primary.bodies, "primary request body")
+			assert.Equal(t, []string{"body"}, tt.candidate.bodies, "shadow request body")
+			require.Len(t, tt.candidate.headers, 1)
+			assert.Equal(t, map[string]string{"key": "value"}, tt.candidate.headers[0].Items(), "shadow request headers")
+
+			tags := map[string]string{
+				_source:    "caller",
+				_dest:      "service",
+				_procedure: "procedure",
+			}
+			want := []testutils.CounterAssertion{
+				{Name: "shadow_requests", Tags: tags, Value: 1},
+			}
+			if tt.wantMismatch != "" {
+				mismatchTags := map[string]string{_reason: tt.wantMismatch}
+				for k, v := range tags {
+					mismatchTags[k] = v
+				}
+				want = []testutils.CounterAssertion{
+					{Name: "shadow_mismatches", Tags: mismatchTags, Value: 1},
+					want[0],
+				}
+			}
+			testutils.AssertCounters(t, want, root.Snapshot().Counters)
+		})
+	}
+}
+
+func TestMiddlewarePercentage(t *testing.T) {
+	defer func(f func() float64) { _randFloat64 = f }(_randFloat64)
+	_randFloat64 = func() float64 { return 0.5 }
+
+	tests := []struct {
+		percentage float64
+		want       int
+	}{
+		{percentage: 0, want: 0},
+		{percentage: 40, want: 0},
+		{percentage: 60, want: 1},
+		{percentage: 100, want: 1},
+	}
+
+	for _, tt := range tests {
+		primary := &fakeBackend{body: "hello"}
+		candidate := &fakeBackend{body: "hello"}
+		out := primary.outbound()
+		mw := NewOutboundMiddleware(bind(t, out, Target{Outbound: candidate.outbound(), Percentage: tt.percentage}))
+
+		_, err := call(t, mw, out)
+		require.NoError(t, err)
+		mw.pending.Wait()
+		assert.Equal(t, tt.want, candidate.calls(), "percentage %v", tt.percentage)
+	}
+}
+
+func TestMiddlewareDoesNotWaitForShadow(t *testing.T) {
+	primary := &fakeBackend{body: "hello"}
+	release := make(chan struct{})
+	var deadline time.Time
+	candidate := yarpctest.NewFakeTransport().NewOutbound(nil, yarpctest.OutboundCallOverride(
+		func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+			deadline, _ = ctx.Deadline()
+			<-release
+			return &transport.Response{Body: ioutil.NopCloser(bytes.NewBufferString("hello"))}, nil
+		}))
+
+	out := primary.outbound()
+	mw := NewOutboundMiddleware(bind(t, out, Target{Outbound: candidate, Percentage: 100, Timeout: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := middleware.ApplyUnaryOutbound(out, mw).Call(ctx, newRequest())
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	close(release)
+	mw.pending.Wait()
+	assert.True(t, deadline.After(time.Now().Add(time.Minute)),
+		"shadow request must have its own deadline, got %v", deadline)
+}
+
+func TestMiddlewareShadowTimeout(t *testing.T) {
+	root := metrics.New()
+	primary := &fakeBackend{body: "hello"}
+	candidate := yarpctest.NewFakeTransport().NewOutbound(nil, yarpctest.OutboundCallOverride(
+		func(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+			<-ctx.Done()
+			return nil, yarpcerrors.DeadlineExceededErrorf("shadow request timed out")
+		}))
+
+	out := primary.outbound()
+	mw := NewOutboundMiddleware(bind(t, out, Target{Outbound: candidate, Percentage: 100, Timeout: time.Millisecond}), Meter(root.Scope()))
+
+	body, err := call(t, mw, out)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", body)
+	mw.pending.Wait()
+
+	snapshot := root.Snapshot().Counters
+	require.Len(t, snapshot, 2)
+	assert.Equal(t, "shadow_mismatches", snapshot[0].Name)
+	assert.Equal(t, _reasonCode, snapshot[0].Tags[_reason])
+}
+
+func TestMiddlewareDoesNotShadowShadowRequests(t *testing.T) {
+	primary := &fakeBackend{body: "hello"}
+	candidate := &fakeBackend{body: "hello"}
+
+	out := primary.outbound()
+	targets := NewTargets()
+	mw := NewOutboundMiddleware(targets)
+	// The candidate goes through the same middleware, as it would when both
+	// are outbounds of a dispatcher.
+	targets.Register("service", Target{
+		Outbound:   middleware.ApplyUnaryOutbound(candidate.outbound(), mw),
+		Percentage: 100,
+	})
+	require.NoError(t, targets.Bind(yarpc.Outbounds{"service": {Unary: out}}))
+
+	_, err := call(t, mw, out)
+	require.NoError(t, err)
+	mw.pending.Wait()
+
+	assert.Equal(t, 1, primary.calls())
+	assert.Equal(t, 1, candidate.calls())
+}
+
+func TestMiddlewareRequestBodyReadError(t *testing.T) {
+	primary := &fakeBackend{body: "hello"}
+	candidate := &fakeBackend{body: "hello"}
+	out := primary.outbound()
+	mw := NewOutboundMiddleware(bind(t, out, Target{Outbound: candidate.outbound(), Percentage: 100}))
+
+	req := newRequest()
+	req.Body = ioutil.NopCloser(errReader{})
+	_, err := middleware.ApplyUnaryOutbound(out, mw).Call(context.Background(), req)
+	require.Error(t, err)
+	assert.Equal(t, yarpcerrors.CodeInternal, yarpcerrors.FromError(err).Code())
+	assert.Equal(t, 0, primary.calls())
+	assert.Equal(t, 0, candidate.calls())
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("great sadness") }
+
+func TestMiddlewareShadowsThroughChain(t *testing.T) {
+	primary := &fakeBackend{body: "hello"}
+	candidate := &fakeBackend{body: "hello"}
+
+	out := primary.outbound()
+	mw := NewOutboundMiddleware(bind(t, out, Target{Outbound: candidate.outbound(), Percentage: 100}))
+	// Other middleware follows the shadowing middleware, as it does in a
+	// dispatcher.
+	chain := outboundmiddleware.UnaryChain(mw, middleware.NopUnaryOutbound)
+
+	res, err := middleware.ApplyUnaryOutbound(out, chain).Call(context.Background(), newRequest())
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	mw.pending.Wait()
+
+	assert.Equal(t, 1, candidate.calls())
+}
+
+func TestMiddlewareCandidateNotShadowed(t *testing.T) {
+	primary := &fakeBackend{body: "hello"}
+	candidate := &fakeBackend{body: "hello"}
+	candidateOut := candidate.outbound()
+
+	targets := NewTargets()
+	targets.declare("users", declaredTarget{candidate: "users-candidate", percentage: 100})
+	require.NoError(t, targets.Bind(yarpc.Outbounds{
+		"users":           {ServiceName: "service", Unary: primary.outbound()},
+		"users-candidate": {ServiceName: "service", Unary: candidateOut},
+	}))
+	mw := NewOutboundMiddleware(targets)
+
+	// Both outbounds call the same service.
+	_, err := call(t, mw, candidateOut)
+	require.NoError(t, err)
+	mw.pending.Wait()
+
+	assert.Equal(t, 0, primary.calls())
+	assert.Equal(t, 1, candidate.calls(), "calls through the candidate outbound must not be shadowed")
+}
+
+func TestMiddlewareComparesOnClose(t *testing.T) {
+	tests := []struct {
+		desc        string
+		read        func(io.Reader) error
+		wantCompare bool
+	}{
+		{
+			desc: "read in full",
+			read: func(r io.Reader) error {
+				_, err := ioutil.ReadAll(r)
+				return err
+			},
+			wantCompare: true,
+		},
+		{
+			desc: "read in part",
+			read: func(r io.Reader) error {
+				_, err := r.Read(make([]byte, 2))
+				return err
+			},
+		},
+		{
+			desc: "not read",
+			read: func(io.Reader) error { return nil },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			root := metrics.New()
+			primary := &fakeBackend{body: "hello"}
+			candidate := &fakeBackend{body: "goodbye"}
+			out := primary.outbound()
+			mw := NewOutboundMiddleware(bind(t, out, Target{Outbound: candidate.outbound(), Percentage: 100}), Meter(root.Scope()))
+
+			res, err := middleware.ApplyUnaryOutbound(out, mw).Call(context.Background(), newRequest())
+			require.NoError(t, err)
+			require.NoError(t, tt.read(res.Body))
+			require.NoError(t, res.Body.Close())
+			require.NoError(t, res.Body.Close(), "closing twice must not panic")
+			mw.pending.Wait()
+
+			assert.Equal(t, 1, candidate.calls())
+			if tt.wantCompare {
+				require.Len(t, root.Snapshot().Counters, 2)
+			} else {
+				assert.Empty(t, root.Snapshot().Counters, "responses must not be compared")
+			}
+		})
+	}
+}
+
+func TestMiddlewareBodyNeverClosed(t *testing.T) {
+	primary := &fakeBackend{body: "hello"}
+	candidate := &fakeBackend{body: "hello"}
+	out := primary.outbound()
+	mw := NewOutboundMiddleware(bind(t, out, Target{Outbound: candidate.outbound(), Percentage: 100}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := middleware.ApplyUnaryOutbound(out, mw).Call(ctx, newRequest())
+	require.NoError(t, err)
+
+	// The shadow request gives up on the comparison once the caller is done
+	// with the primary request.
+	cancel()
+	mw.pending.Wait()
+	assert.Equal(t, 1, candidate.calls())
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+)
+
+// Target describes where and how often the requests sent through an
+// outbound are shadowed.
+type Target struct {
+	// Outbound receives the copies of shadowed requests.
+	Outbound transport.UnaryOutbound
+
+	// Percentage of requests to shadow, between 0 and 100.
+	Percentage float64
+
+	// Timeout bounds each shadow request, regardless of the deadline of the
+	// request it copies.
+	//
+	// Defaults to one second.
+	Timeout time.Duration
+}
+
+// declaredTarget is a target that names its candidate outbound by its key in
+// the dispatcher, waiting for Bind to resolve it.
+type declaredTarget struct {
+	candidate  string
+	percentage float64
+	timeout    time.Duration
+}
+
+// Targets holds the shadow target of each outbound.
+type Targets struct {
+	mu         sync.RWMutex
+	registered map[string]Target                  // outbound key -> target
+	declared   map[string]declaredTarget          // outbound key -> target
+	bound      map[transport.UnaryOutbound]Target // primary outbound -> target
+}
+
+// NewTargets creates a new Targets with no registered targets.
+func NewTargets() *Targets {
+	return &Targets{
+		registered: make(map[string]Target),
+		declared:   make(map[string]declaredTarget),
+		bound:      make(map[transport.UnaryOutbound]Target),
+	}
+}
+
+// Register shadows the requests sent through the outbound with the given key
+// to a target, replacing any target previously registered for the outbound.
+//
+// Requests are not shadowed until the targets are bound to the outbounds
+// with Bind.
+func (t *Targets) Register(outboundKey string, target Target) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.registered[outboundKey] = target
+}
+
+// declare shadows the requests sent through the outbound with the given key
+// to another outbound, once Bind is called.
+func (t *Targets) declare(outboundKey string, target declaredTarget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.declared[outboundKey] = target
+}
+
+// Bind resolves the registered and declared targets against the outbounds
+// passed to the dispatcher in yarpc.Config, replacing the targets of any
+// previous call.
+// Requests are not shadowed until Bind succeeds.
+//
+// The middleware recognizes an outbound by its transport outbound, so
+// outbounds must be those of yarpc.Config rather than those returned by
+// Dispatcher.Outbounds, which are wrapped in outbound middleware.
+// Targets declared in yarpcconfig send shadow requests directly through the
+// transport outbound of their candidate, skipping outbound middleware.
+func (t *Targets) Bind(outbounds yarpc.Outbounds) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var err error
+	bound := make(map[transport.UnaryOutbound]Target, len(t.registered)+len(t.declared))
+	bind := func(key string, target Target) {
+		primary, ok := outbounds[key]
+		if !ok {
+			err = multierr.Append(err, fmt.Errorf("unknown outbound %q", key))
+			return
+		}
+		if primary.Unary == nil {
+			err = multierr.Append(err, fmt.Errorf("outbound %q has no unary outbound", key))
+			return
+		}
+		if !isComparable(primary.Unary) {
+			err = multierr.Append(err, fmt.Errorf("outbound %q cannot be shadowed: %T is not comparable", key, primary.Unary))
+			return
+		}
+		bound[primary.Unary] = target
+	}
+
+	keys := make([]string, 0, len(t.registered))
+	for key := range t.registered {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		bind(key, t.registered[key])
+	}
+
+	keys = keys[:0]
+	for key := range t.declared {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		dt := t.declared[key]
+		candidate, ok := outbounds[dt.candidate]
+		if !ok {
+			err = multierr.Append(err, fmt.Errorf("unknown candidate outbound %q for outbound %q", dt.candidate, key))
+			continue
+		}
+		if candidate.Unary == nil {
+			err = multierr.Append(err, fmt.Errorf("candidate outbound %q for outbound %q has no unary outbound", dt.candidate, key))
+			continue
+		}
+		bind(key, Target{
+			Outbound:   candidate.Unary,
+			Percentage: dt.percentage,
+			Timeout:    dt.timeout,
+		})
+	}
+
+	t.bound = bound
+	return err
+}
+
+// target returns the target bound to the transport outbound, if any.
+func (t *Targets) target(out transport.UnaryOutbound) (Target, bool) {
+	if !isComparable(out) {
+		return Target{}, false
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	target, ok := t.bound[out]
+	return target, ok && target.Outbound != nil
+}
+
+// isComparable reports whether the outbound can be used as a map key without
+// panicking.
+func isComparable(out transport.UnaryOutbound) bool {
+	return out != nil && reflect.ValueOf(out).Comparable()
+}
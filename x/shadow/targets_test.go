@@ -0,0 +1,134 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpctest"
+)
+
+func TestTargetsRegister(t *testing.T) {
+	primary := yarpctest.NewFakeTransport().NewOutbound(nil)
+	candidate := yarpctest.NewFakeTransport().NewOutbound(nil)
+	outbounds := yarpc.Outbounds{"users": {Unary: primary}}
+
+	targets := NewTargets()
+	_, ok := targets.target(primary)
+	assert.False(t, ok, "no target registered")
+
+	targets.Register("users", Target{Percentage: 100})
+	require.NoError(t, targets.Bind(outbounds))
+	_, ok = targets.target(primary)
+	assert.False(t, ok, "targets without an outbound must be ignored")
+
+	targets.Register("users", Target{Outbound: candidate, Percentage: 5, Timeout: time.Second})
+	_, ok = targets.target(primary)
+	assert.False(t, ok, "registered targets must not be shadowed before Bind")
+
+	require.NoError(t, targets.Bind(outbounds))
+	target, ok := targets.target(primary)
+	require.True(t, ok)
+	assert.Equal(t, Target{Outbound: candidate, Percentage: 5, Timeout: time.Second}, target)
+
+	_, ok = targets.target(candidate)
+	assert.False(t, ok, "only the registered outbound must be shadowed")
+}
+
+func TestTargetsBind(t *testing.T) {
+	primary := yarpctest.NewFakeTransport().NewOutbound(nil)
+	candidate := yarpctest.NewFakeTransport().NewOutbound(nil)
+	outbounds := yarpc.Outbounds{
+		"users":           {ServiceName: "users", Unary: primary},
+		"users-candidate": {ServiceName: "users", Unary: candidate},
+		"oneway-only":     {ServiceName: "events"},
+		"uncomparable":    {ServiceName: "users", Unary: uncomparableOutbound{primary, nil}},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		targets := NewTargets()
+		targets.declare("users", declaredTarget{candidate: "users-candidate", percentage: 5, timeout: time.Second})
+
+		_, ok := targets.target(primary)
+		assert.False(t, ok, "declared targets must not be shadowed before Bind")
+
+		require.NoError(t, targets.Bind(outbounds))
+		target, ok := targets.target(primary)
+		require.True(t, ok, "declared targets must be bound to the transport outbound")
+		assert.Equal(t, Target{Outbound: candidate, Percentage: 5, Timeout: time.Second}, target)
+
+		_, ok = targets.target(candidate)
+		assert.False(t, ok, "calls through the candidate outbound must not be shadowed")
+	})
+
+	t.Run("rebind", func(t *testing.T) {
+		targets := NewTargets()
+		targets.declare("users", declaredTarget{candidate: "users-candidate", percentage: 5})
+		require.NoError(t, targets.Bind(outbounds))
+
+		other := yarpctest.NewFakeTransport().NewOutbound(nil)
+		require.NoError(t, targets.Bind(yarpc.Outbounds{
+			"users":           {Unary: other},
+			"users-candidate": {Unary: candidate},
+		}))
+		_, ok := targets.target(primary)
+		assert.False(t, ok, "outbounds of previous calls must be forgotten")
+		_, ok = targets.target(other)
+		assert.True(t, ok)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		targets := NewTargets()
+		targets.Register("registered", Target{Outbound: candidate})
+		targets.declare("unknown", declaredTarget{candidate: "users-candidate"})
+		targets.declare("users", declaredTarget{candidate: "missing"})
+		targets.declare("users-candidate", declaredTarget{candidate: "oneway-only"})
+		targets.declare("oneway-only", declaredTarget{candidate: "users-candidate"})
+		targets.declare("uncomparable", declaredTarget{candidate: "users-candidate"})
+
+		err := targets.Bind(outbounds)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown outbound "registered"`)
+		assert.Contains(t, err.Error(), `unknown outbound "unknown"`)
+		assert.Contains(t, err.Error(), `unknown candidate outbound "missing" for outbound "users"`)
+		assert.Contains(t, err.Error(), `candidate outbound "oneway-only" for outbound "users-candidate" has no unary outbound`)
+		assert.Contains(t, err.Error(), `outbound "oneway-only" has no unary outbound`)
+		assert.Contains(t, err.Error(), `outbound "uncomparable" cannot be shadowed: shadow.uncomparableOutbound is not comparable`)
+	})
+}
+
+func TestTargetsUncomparableOutbound(t *testing.T) {
+	targets := NewTargets()
+	_, ok := targets.target(uncomparableOutbound{})
+	assert.False(t, ok, "looking up uncomparable outbounds must not panic")
+}
+
+// uncomparableOutbound is an outbound that cannot be used as a map key.
+type uncomparableOutbound struct {
+	transport.UnaryOutbound
+
+	funcs []func()
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package yarpcprometheus exports the metrics of YARPC dispatchers to
+// Prometheus.
+//
+// An Exporter collects the metrics of every dispatcher configured with its
+// MetricsConfig: request counts, error counts by code and latency, TTL and
+// payload size histograms per caller, service, procedure, encoding and
+// transport. Latency histograms are measured in milliseconds with buckets
+// suited to RPCs, from 1ms to 10s.
+//
+// Exporters are HTTP handlers serving these metrics in the Prometheus
+// exposition format, and may be mounted next to the x/debug handler:
+//
+//	exporter := yarpcprometheus.NewExporter()
+//	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+//		Name:    "myservice",
+//		Metrics: exporter.MetricsConfig(),
+//		// ...
+//	})
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/debug/yarpc", debug.NewHandler(dispatcher))
+//	mux.Handle("/metrics", exporter)
+//
+// Exporters are also Prometheus collectors, so they may be registered with
+// an existing Prometheus registry instead:
+//
+//	prometheus.MustRegister(exporter)
+package yarpcprometheus
@@ -0,0 +1,146 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcprometheus
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/net/metrics"
+	"go.uber.org/yarpc"
+)
+
+var (
+	_ http.Handler         = (*Exporter)(nil)
+	_ prometheus.Collector = (*Exporter)(nil)
+
+	_errorDesc = prometheus.NewDesc(
+		"yarpc_prometheus_export_error",
+		"Failed to gather YARPC metrics.",
+		nil, nil,
+	)
+)
+
+// Exporter exposes the metrics of YARPC dispatchers to Prometheus.
+type Exporter struct {
+	root *metrics.Root
+}
+
+// NewExporter builds a new Exporter.
+func NewExporter() *Exporter {
+	return &Exporter{root: metrics.New()}
+}
+
+// MetricsConfig returns the metrics configuration of dispatchers whose
+// metrics this Exporter exposes.
+//
+// Dispatchers sharing an Exporter are told apart by their "dispatcher" tag,
+// so they must have different names.
+func (e *Exporter) MetricsConfig() yarpc.MetricsConfig {
+	return yarpc.MetricsConfig{Metrics: e.root.Scope()}
+}
+
+// Scope returns the scope holding all metrics this Exporter exposes. Other
+// components, like x/ratelimit middleware, may record their metrics with it.
+func (e *Exporter) Scope() *metrics.Scope {
+	return e.root.Scope()
+}
+
+// ServeHTTP serves all metrics in the Prometheus exposition format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	e.root.ServeHTTP(w, req)
+}
+
+// Describe implements prometheus.Collector. It describes no metrics since
+// they're only known once recorded, making Exporter an unchecked collector.
+func (e *Exporter) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	families, err := e.gather()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(_errorDesc, err)
+		return
+	}
+	for _, family := range families {
+		for _, m := range family.Metric {
+			labels := make([]string, len(m.Label))
+			for i, l := range m.Label {
+				labels[i] = l.GetName()
+			}
+			ch <- metric{
+				desc:  prometheus.NewDesc(family.GetName(), family.GetHelp(), labels, nil),
+				proto: m,
+			}
+		}
+	}
+}
+
+// gather reads all metrics from the root. The root doesn't expose them other
+// than through its HTTP handler, so they are read from it in the protocol
+// buffer exposition format.
+func (e *Exporter) gather() ([]*dto.MetricFamily, error) {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", string(expfmt.FmtProtoDelim))
+	rec := httptest.NewRecorder()
+	e.root.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return nil, fmt.Errorf("failed to gather metrics: %v", rec.Body.String())
+	}
+
+	var families []*dto.MetricFamily
+	dec := expfmt.NewDecoder(rec.Body, expfmt.ResponseFormat(rec.Header()))
+	for {
+		var family dto.MetricFamily
+		if err := dec.Decode(&family); err == io.EOF {
+			return families, nil
+		} else if err != nil {
+			return nil, err
+		}
+		families = append(families, &family)
+	}
+}
+
+// metric is a Prometheus metric gathered from the root.
+type metric struct {
+	desc  *prometheus.Desc
+	proto *dto.Metric
+}
+
+func (m metric) Desc() *prometheus.Desc {
+	return m.desc
+}
+
+func (m metric) Write(out *dto.Metric) error {
+	out.Label = m.proto.Label
+	out.Counter = m.proto.Counter
+	out.Gauge = m.proto.Gauge
+	out.Histogram = m.proto.Histogram
+	out.Summary = m.proto.Summary
+	out.Untyped = m.proto.Untyped
+	out.TimestampMs = m.proto.TimestampMs
+	return nil
+}
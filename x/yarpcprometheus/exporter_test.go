@@ -0,0 +1,167 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcprometheus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/net/metrics"
+	"go.uber.org/net/metrics/bucket"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/encoding/raw"
+	yarpchttp "go.uber.org/yarpc/transport/http"
+)
+
+// callSelf makes a request from a dispatcher to itself, recording metrics
+// with the exporter.
+func callSelf(t *testing.T, exporter *Exporter) {
+	trans := yarpchttp.NewTransport()
+	inbound := trans.NewInbound("127.0.0.1:0")
+	d := yarpc.NewDispatcher(yarpc.Config{
+		Name:     "myservice",
+		Inbounds: yarpc.Inbounds{inbound},
+		Metrics:  exporter.MetricsConfig(),
+	})
+	d.Register(raw.Procedure("echo", func(_ context.Context, body []byte) ([]byte, error) {
+		return body, nil
+	}))
+	require.NoError(t, d.Start())
+	defer func() { assert.NoError(t, d.Stop()) }()
+
+	client := yarpc.NewDispatcher(yarpc.Config{
+		Name: "client",
+		Outbounds: yarpc.Outbounds{
+			"myservice": {Unary: trans.NewSingleOutbound("http://" + inbound.Addr().String())},
+		},
+		Metrics: exporter.MetricsConfig(),
+	})
+	require.NoError(t, client.Start())
+	defer func() { assert.NoError(t, client.Stop()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := raw.New(client.ClientConfig("myservice")).Call(ctx, "echo", []byte("hello"))
+	require.NoError(t, err)
+}
+
+func findFamily(t *testing.T, families []*dto.MetricFamily, name string) *dto.MetricFamily {
+	for _, f := range families {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	require.FailNow(t, "metric family not found", name)
+	return nil
+}
+
+func labels(m *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(m.Label))
+	for _, l := range m.Label {
+		labels[l.GetName()] = l.GetValue()
+	}
+	return labels
+}
+
+func TestExporterCollect(t *testing.T) {
+	exporter := NewExporter()
+	callSelf(t, exporter)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(exporter))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	calls := findFamily(t, families, "calls")
+	assert.Equal(t, dto.MetricType_COUNTER, calls.GetType())
+	assert.Equal(t, "Total number of RPCs.", calls.GetHelp())
+	require.Len(t, calls.Metric, 2)
+	directions := make(map[string]string)
+	for _, m := range calls.Metric {
+		l := labels(m)
+		assert.Equal(t, "echo", l["procedure"])
+		assert.Equal(t, "client", l["source"])
+		assert.Equal(t, "myservice", l["dest"])
+		assert.Equal(t, 1.0, m.GetCounter().GetValue())
+		directions[l["direction"]] = l["dispatcher"]
+	}
+	assert.Equal(t, map[string]string{"inbound": "myservice", "outbound": "client"}, directions)
+
+	latencies := findFamily(t, families, "success_latency_ms")
+	assert.Equal(t, dto.MetricType_HISTOGRAM, latencies.GetType())
+	require.Len(t, latencies.Metric, 2)
+	for _, m := range latencies.Metric {
+		assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+		assert.Len(t, m.GetHistogram().Bucket, len(bucket.NewRPCLatency()))
+	}
+}
+
+func TestExporterScope(t *testing.T) {
+	exporter := NewExporter()
+	counter, err := exporter.Scope().Counter(metrics.Spec{
+		Name:      "custom",
+		Help:      "Custom metric.",
+		ConstTags: metrics.Tags{"foo": "bar"},
+	})
+	require.NoError(t, err)
+	counter.Add(3)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(exporter))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	custom := findFamily(t, families, "custom")
+	require.Len(t, custom.Metric, 1)
+	assert.Equal(t, map[string]string{"foo": "bar"}, labels(custom.Metric[0]))
+	assert.Equal(t, 3.0, custom.Metric[0].GetCounter().GetValue())
+}
+
+func TestExporterServeHTTP(t *testing.T) {
+	exporter := NewExporter()
+	callSelf(t, exporter)
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "# TYPE calls counter")
+	assert.Contains(t, string(body), "# TYPE success_latency_ms histogram")
+	assert.Contains(t, string(body), `le="10000"`)
+}
+
+func TestExporterEmpty(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(NewExporter()))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	assert.Empty(t, families)
+}
@@ -28,6 +28,7 @@ import (
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/internal/config"
 	peerbind "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/healthcheck"
 )
 
 // PeerChooser facilitates decoding and building peer choosers. A peer chooser
@@ -69,6 +70,20 @@ import (
 // robin peer list. The only remaining key is the name of the peer list
 // updater: `peers` which is just a static list of peers.
 //
+// Any peer chooser may also specify `healthCheck` to actively check the
+// health of its peers, treating unhealthy peers as unavailable, if the
+// transport supports it.
+// See healthcheck.Config for the available parameters.
+//
+//	http:
+//	  round-robin:
+//	    peers:
+//	      - 127.0.0.1:8080
+//	      - 127.0.0.1:8081
+//	  healthCheck:
+//	    procedure: health
+//	    interval: 5s
+//
 // # Integration
 //
 // To integrate peer choosers with your transport, embed this struct into your
@@ -93,6 +108,10 @@ import (
 //		return myTransport.NewOutbound(peerChooser), nil
 //	}
 //
+// To support health checks, pass the HealthCheckOutbound option to
+// BuildPeerChooser with a function that builds an outbound for your
+// transport.
+//
 // The *config.Kit received by the Build*Outbound function MUST be passed to
 // the BuildPeerChooser function as-is.
 //
@@ -105,9 +124,33 @@ type PeerChooser struct {
 // peerChooser is the private representation of PeerChooser that captures
 // decoded configuration without revealing it on the public type.
 type peerChooser struct {
-	Peer   string              `config:"peer,interpolate"`
-	Preset string              `config:"with,interpolate"`
-	Etc    config.AttributeMap `config:",squash"`
+	Peer        string              `config:"peer,interpolate"`
+	Preset      string              `config:"with,interpolate"`
+	HealthCheck *healthcheck.Config `config:"healthCheck"`
+	Etc         config.AttributeMap `config:",squash"`
+}
+
+type peerChooserOptions struct {
+	healthCheckOutbound healthcheck.OutboundFunc
+}
+
+// PeerChooserOption customizes how BuildPeerChooser builds a peer chooser.
+type PeerChooserOption interface {
+	apply(*peerChooserOptions)
+}
+
+type peerChooserOptionFunc func(*peerChooserOptions)
+
+func (f peerChooserOptionFunc) apply(opts *peerChooserOptions) { f(opts) }
+
+// HealthCheckOutbound enables the healthCheck key of the peer chooser
+// configuration, sending health checks to peers through outbounds built by
+// the given function.
+// Without it, BuildPeerChooser rejects configurations with health checks.
+func HealthCheckOutbound(newOutbound healthcheck.OutboundFunc) PeerChooserOption {
+	return peerChooserOptionFunc(func(opts *peerChooserOptions) {
+		opts.healthCheckOutbound = newOutbound
+	})
 }
 
 // Empty returns true if the PeerChooser is empty, i.e., it does not have any
@@ -117,7 +160,7 @@ type peerChooser struct {
 // configuration is specified in a different way than the standard peer
 // configuration.
 func (pc PeerChooser) Empty() bool {
-	return pc.Peer == "" && pc.Preset == "" && len(pc.Etc) == 0
+	return pc.Peer == "" && pc.Preset == "" && pc.HealthCheck == nil && len(pc.Etc) == 0
 }
 
 // BuildPeerChooser translates the decoded configuration into a peer.Chooser.
@@ -127,7 +170,24 @@ func (pc PeerChooser) Empty() bool {
 //
 // The Kit received by the Build*Outbound function MUST be passed to
 // BuildPeerChooser as-is.
-func (pc PeerChooser) BuildPeerChooser(transport peer.Transport, identify func(string) peer.Identifier, kit *Kit) (peer.Chooser, error) {
+func (pc PeerChooser) BuildPeerChooser(transport peer.Transport, identify func(string) peer.Identifier, kit *Kit, opts ...PeerChooserOption) (peer.Chooser, error) {
+	var options peerChooserOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	if pc.HealthCheck != nil {
+		if options.healthCheckOutbound == nil {
+			return nil, fmt.Errorf("health checks are not supported by this transport")
+		}
+		healthTransport, err := pc.HealthCheck.NewTransport(
+			transport, options.healthCheckOutbound, kit.ServiceName(), kit.OutboundServiceName(), nil)
+		if err != nil {
+			return nil, err
+		}
+		transport = healthTransport
+	}
+
 	// Establish a peer selection strategy.
 	switch {
 	case pc.Peer != "":
@@ -216,6 +276,45 @@ func (pc PeerChooser) buildPeerChooser(transport peer.Transport, identify func(s
 	return peerbind.Bind(peerChooser, peerListUpdater), nil
 }
 
+// BuildPeerList builds a peer list from the configuration of a registered
+// peer list, for peer lists that wrap other peer lists. Given,
+//
+//	round-robin:
+//	  capacity: 10
+//
+// BuildPeerList builds a round-robin peer list with a capacity of 10.
+// The configuration must not include peers or a peer list updater; the
+// wrapping peer list forwards updates to the peer lists it builds.
+//
+// The Kit received by the BuildPeerList function of the wrapping PeerListSpec
+// MUST be used as-is.
+func (k *Kit) BuildPeerList(transport peer.Transport, attrs map[string]interface{}) (peer.ChooserList, error) {
+	etc := make(config.AttributeMap, len(attrs))
+	for name, value := range attrs {
+		etc[name] = value
+	}
+
+	peerListName, peerListConfig, err := getPeerListInfo(etc, k)
+	if err != nil {
+		return nil, err
+	}
+
+	peerListSpec, err := k.peerListSpec(peerListName)
+	if err != nil {
+		return nil, err
+	}
+
+	listBuilder, err := peerListSpec.PeerList.Decode(peerListConfig, config.InterpolateWith(k.resolver))
+	if err != nil {
+		return nil, err
+	}
+	result, err := listBuilder.Build(transport, k)
+	if err != nil {
+		return nil, err
+	}
+	return result.(peer.ChooserList), nil
+}
+
 // getPeerListInfo extracts the peer list entry from the given attribute map. It
 // must be the only remaining entry.
 //
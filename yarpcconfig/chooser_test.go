@@ -542,6 +542,24 @@ func TestChooserConfigurator(t *testing.T) {
 				`round-robin`,
 			},
 		},
+		{
+			desc: "health check on transport without health check support",
+			given: whitespace.Expand(`
+				outbounds:
+					their-service:
+						unary:
+							fake-transport:
+								round-robin:
+									peers:
+									- 127.0.0.1:8080
+								healthCheck:
+									procedure: health
+			`),
+			wantErr: []string{
+				`failed to configure unary outbound for "their-service": `,
+				`health checks are not supported by this transport`,
+			},
+		},
 		{
 			desc: "invalid peer list",
 			given: whitespace.Expand(`
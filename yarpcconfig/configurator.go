@@ -29,6 +29,7 @@ import (
 
 	"go.uber.org/multierr"
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/config"
 	"go.uber.org/yarpc/internal/interpolate"
@@ -48,6 +49,12 @@ type Configurator struct {
 	knownPeerListUpdaters map[string]*compiledPeerListUpdaterSpec
 	knownCompressors      map[string]transport.Compressor
 	resolver              interpolate.VariableResolver
+
+	// Middleware are applied in the order in which they were registered.
+	knownInboundMiddleware  map[string]*compiledInboundMiddlewareSpec
+	inboundMiddlewareOrder  []string
+	knownOutboundMiddleware map[string]*compiledOutboundMiddlewareSpec
+	outboundMiddlewareOrder []string
 }
 
 // New sets up a new empty Configurator. The returned Configurator does not
@@ -60,6 +67,9 @@ func New(opts ...Option) *Configurator {
 		knownPeerListUpdaters: make(map[string]*compiledPeerListUpdaterSpec),
 		knownCompressors:      make(map[string]transport.Compressor),
 		resolver:              os.LookupEnv,
+
+		knownInboundMiddleware:  make(map[string]*compiledInboundMiddlewareSpec),
+		knownOutboundMiddleware: make(map[string]*compiledOutboundMiddlewareSpec),
 	}
 
 	for _, opt := range opts {
@@ -207,6 +217,80 @@ func (c *Configurator) MustRegisterPeerListUpdater(s PeerListUpdaterSpec) {
 	}
 }
 
+// RegisterInboundMiddleware registers an InboundMiddlewareSpec with the
+// given Configurator, teaching it how to build inbound middleware of this
+// kind from configuration.
+//
+// Returns an error if the InboundMiddlewareSpec is invalid. Use
+// MustRegisterInboundMiddleware to panic if the registration fails.
+//
+// Configured middleware are applied to inbound requests in the order in
+// which their specs were registered, so the first registered middleware sees
+// each request first. If a middleware with the same name already exists, it
+// will be replaced and keep its position.
+func (c *Configurator) RegisterInboundMiddleware(s InboundMiddlewareSpec) error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+
+	spec, err := compileInboundMiddlewareSpec(&s)
+	if err != nil {
+		return fmt.Errorf("invalid InboundMiddlewareSpec for %q: %v", s.Name, err)
+	}
+
+	if _, ok := c.knownInboundMiddleware[s.Name]; !ok {
+		c.inboundMiddlewareOrder = append(c.inboundMiddlewareOrder, s.Name)
+	}
+	c.knownInboundMiddleware[s.Name] = spec
+	return nil
+}
+
+// MustRegisterInboundMiddleware registers the given InboundMiddlewareSpec
+// with the Configurator. This function panics if the InboundMiddlewareSpec
+// is invalid.
+func (c *Configurator) MustRegisterInboundMiddleware(s InboundMiddlewareSpec) {
+	if err := c.RegisterInboundMiddleware(s); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterOutboundMiddleware registers an OutboundMiddlewareSpec with the
+// given Configurator, teaching it how to build outbound middleware of this
+// kind from configuration.
+//
+// Returns an error if the OutboundMiddlewareSpec is invalid. Use
+// MustRegisterOutboundMiddleware to panic if the registration fails.
+//
+// Configured middleware are applied to outbound requests in the order in
+// which their specs were registered, so the first registered middleware sees
+// each request first. If a middleware with the same name already exists, it
+// will be replaced and keep its position.
+func (c *Configurator) RegisterOutboundMiddleware(s OutboundMiddlewareSpec) error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+
+	spec, err := compileOutboundMiddlewareSpec(&s)
+	if err != nil {
+		return fmt.Errorf("invalid OutboundMiddlewareSpec for %q: %v", s.Name, err)
+	}
+
+	if _, ok := c.knownOutboundMiddleware[s.Name]; !ok {
+		c.outboundMiddlewareOrder = append(c.outboundMiddlewareOrder, s.Name)
+	}
+	c.knownOutboundMiddleware[s.Name] = spec
+	return nil
+}
+
+// MustRegisterOutboundMiddleware registers the given OutboundMiddlewareSpec
+// with the Configurator. This function panics if the OutboundMiddlewareSpec
+// is invalid.
+func (c *Configurator) MustRegisterOutboundMiddleware(s OutboundMiddlewareSpec) {
+	if err := c.RegisterOutboundMiddleware(s); err != nil {
+		panic(err)
+	}
+}
+
 // RegisterCompressor registers the given Compressor for the configurator, so
 // any transport can use the given compression strategy.
 func (c *Configurator) RegisterCompressor(z transport.Compressor) error {
@@ -307,6 +391,16 @@ func (c *Configurator) load(serviceName string, cfg *yarpcConfig) (_ yarpc.Confi
 		err = multierr.Append(err, e)
 	}
 
+	inboundMiddleware, e := c.loadInboundMiddleware(serviceName, cfg.InboundMiddleware)
+	if e != nil {
+		err = multierr.Append(err, e)
+	}
+
+	outboundMiddleware, e := c.loadOutboundMiddleware(serviceName, cfg.OutboundMiddleware)
+	if e != nil {
+		err = multierr.Append(err, e)
+	}
+
 	if err != nil {
 		return yarpc.Config{}, err
 	}
@@ -316,11 +410,122 @@ func (c *Configurator) load(serviceName string, cfg *yarpcConfig) (_ yarpc.Confi
 		return yc, err
 	}
 
+	yc.InboundMiddleware = inboundMiddleware
+	yc.OutboundMiddleware = outboundMiddleware
+
 	cfg.Logging.fill(&yc)
 	cfg.Metrics.fill(&yc)
 	return yc, nil
 }
 
+func (c *Configurator) loadInboundMiddleware(serviceName string, attrs map[string]config.AttributeMap) (yarpc.InboundMiddleware, error) {
+	var err error
+	for name := range attrs {
+		if _, ok := c.knownInboundMiddleware[name]; !ok {
+			err = multierr.Append(err, fmt.Errorf("unknown inbound middleware %q", name))
+		}
+	}
+	if err != nil {
+		return yarpc.InboundMiddleware{}, err
+	}
+
+	var (
+		unary  []middleware.UnaryInbound
+		oneway []middleware.OnewayInbound
+		stream []middleware.StreamInbound
+	)
+	for _, name := range c.inboundMiddlewareOrder {
+		a, ok := attrs[name]
+		if !ok {
+			continue
+		}
+
+		spec := c.knownInboundMiddleware[name]
+		buildable, e := spec.InboundMiddleware.Decode(a, config.InterpolateWith(c.resolver))
+		if e != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to decode inbound middleware %q: %v", name, e))
+			continue
+		}
+
+		result, e := buildable.Build(c.Kit(serviceName))
+		if e != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to build inbound middleware %q: %v", name, e))
+			continue
+		}
+
+		mw := result.(yarpc.InboundMiddleware)
+		unary = append(unary, mw.Unary)
+		oneway = append(oneway, mw.Oneway)
+		stream = append(stream, mw.Stream)
+	}
+	if err != nil {
+		return yarpc.InboundMiddleware{}, err
+	}
+
+	if len(unary) == 0 {
+		return yarpc.InboundMiddleware{}, nil
+	}
+	return yarpc.InboundMiddleware{
+		Unary:  yarpc.UnaryInboundMiddleware(unary...),
+		Oneway: yarpc.OnewayInboundMiddleware(oneway...),
+		Stream: yarpc.StreamInboundMiddleware(stream...),
+	}, nil
+}
+
+func (c *Configurator) loadOutboundMiddleware(serviceName string, attrs map[string]config.AttributeMap) (yarpc.OutboundMiddleware, error) {
+	var err error
+	for name := range attrs {
+		if _, ok := c.knownOutboundMiddleware[name]; !ok {
+			err = multierr.Append(err, fmt.Errorf("unknown outbound middleware %q", name))
+		}
+	}
+	if err != nil {
+		return yarpc.OutboundMiddleware{}, err
+	}
+
+	var (
+		unary  []middleware.UnaryOutbound
+		oneway []middleware.OnewayOutbound
+		stream []middleware.StreamOutbound
+	)
+	for _, name := range c.outboundMiddlewareOrder {
+		a, ok := attrs[name]
+		if !ok {
+			continue
+		}
+
+		spec := c.knownOutboundMiddleware[name]
+		buildable, e := spec.OutboundMiddleware.Decode(a, config.InterpolateWith(c.resolver))
+		if e != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to decode outbound middleware %q: %v", name, e))
+			continue
+		}
+
+		result, e := buildable.Build(c.Kit(serviceName))
+		if e != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to build outbound middleware %q: %v", name, e))
+			continue
+		}
+
+		mw := result.(yarpc.OutboundMiddleware)
+		unary = append(unary, mw.Unary)
+		oneway = append(oneway, mw.Oneway)
+		stream = append(stream, mw.Stream)
+	}
+	if err != nil {
+		return yarpc.OutboundMiddleware{}, err
+	}
+
+	if len(unary) == 0 {
+		return yarpc.OutboundMiddleware{}, nil
+	}
+	return yarpc.OutboundMiddleware{
+		Unary:  yarpc.UnaryOutboundMiddleware(unary...),
+		Oneway: yarpc.OnewayOutboundMiddleware(oneway...),
+		Stream: yarpc.StreamOutboundMiddleware(stream...),
+	}, nil
+}
+
 func (c *Configurator) loadInboundInto(b *builder, i inbound) error {
 	if i.Disabled {
 		return nil
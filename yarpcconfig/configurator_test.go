@@ -21,6 +21,8 @@
 package yarpcconfig
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -30,6 +32,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
 	"go.uber.org/yarpc/internal/interpolate"
 	"go.uber.org/yarpc/internal/whitespace"
@@ -69,6 +73,200 @@ func TestConfiguratorRegisterErrors(t *testing.T) {
 	err = New().RegisterPeerListUpdater(PeerListUpdaterSpec{Name: "test"})
 	require.Error(t, err, "expected failure")
 	assert.Contains(t, err.Error(), "invalid PeerListUpdaterSpec for \"test\":")
+
+	require.Panics(t, func() { New().MustRegisterOutboundMiddleware(OutboundMiddlewareSpec{}) })
+	err = New().RegisterOutboundMiddleware(OutboundMiddlewareSpec{})
+	require.Error(t, err, "expected failure")
+	assert.Contains(t, err.Error(), "name is required")
+	err = New().RegisterOutboundMiddleware(OutboundMiddlewareSpec{Name: "test"})
+	require.Error(t, err, "expected failure")
+	assert.Contains(t, err.Error(), "invalid OutboundMiddlewareSpec for \"test\":")
+
+	require.Panics(t, func() { New().MustRegisterInboundMiddleware(InboundMiddlewareSpec{}) })
+	err = New().RegisterInboundMiddleware(InboundMiddlewareSpec{})
+	require.Error(t, err, "expected failure")
+	assert.Contains(t, err.Error(), "name is required")
+	err = New().RegisterInboundMiddleware(InboundMiddlewareSpec{Name: "test"})
+	require.Error(t, err, "expected failure")
+	assert.Contains(t, err.Error(), "invalid InboundMiddlewareSpec for \"test\":")
+}
+
+func TestConfiguratorInboundMiddleware(t *testing.T) {
+	type tagConfig struct {
+		Tag string `config:"tag,interpolate"`
+	}
+
+	var calls []string
+	tagSpec := func(name string) InboundMiddlewareSpec {
+		return InboundMiddlewareSpec{
+			Name: name,
+			BuildInboundMiddleware: func(cfg tagConfig, k *Kit) (yarpc.InboundMiddleware, error) {
+				if cfg.Tag == "" {
+					return yarpc.InboundMiddleware{}, errors.New("tag is required")
+				}
+				return yarpc.InboundMiddleware{
+					Unary: middleware.UnaryInboundFunc(func(ctx context.Context, req *transport.Request, rw transport.ResponseWriter, h transport.UnaryHandler) error {
+						calls = append(calls, k.ServiceName()+":"+cfg.Tag)
+						return h.Handle(ctx, req, rw)
+					}),
+				}, nil
+			},
+		}
+	}
+
+	newConfigurator := func() *Configurator {
+		c := New(InterpolationResolver(mapVariableResolver(map[string]string{"TAG": "interpolated"})))
+		c.MustRegisterInboundMiddleware(tagSpec("first"))
+		c.MustRegisterInboundMiddleware(tagSpec("second"))
+		c.MustRegisterInboundMiddleware(tagSpec("third"))
+		return c
+	}
+
+	t.Run("applied in registration order", func(t *testing.T) {
+		calls = nil
+		cfg, err := newConfigurator().LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+			inboundMiddleware:
+				third:
+					tag: c
+				first:
+					tag: ${TAG}
+		`)))
+		require.NoError(t, err)
+		require.NotNil(t, cfg.InboundMiddleware.Unary)
+
+		handler := transporttest.NewMockUnaryHandler(gomock.NewController(t))
+		handler.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		err = middleware.ApplyUnaryInbound(handler, cfg.InboundMiddleware.Unary).
+			Handle(context.Background(), &transport.Request{}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"myservice:interpolated", "myservice:c"}, calls)
+	})
+
+	t.Run("none configured", func(t *testing.T) {
+		cfg, err := newConfigurator().LoadConfig("myservice", map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Equal(t, yarpc.InboundMiddleware{}, cfg.InboundMiddleware)
+	})
+
+	t.Run("unknown middleware", func(t *testing.T) {
+		_, err := newConfigurator().LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+			inboundMiddleware:
+				fourth:
+					tag: d
+		`)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown inbound middleware "fourth"`)
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		_, err := newConfigurator().LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+			inboundMiddleware:
+				first:
+					tag: a
+					bogus: b
+		`)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `failed to decode inbound middleware "first"`)
+	})
+
+	t.Run("build error", func(t *testing.T) {
+		_, err := newConfigurator().LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+			inboundMiddleware:
+				second: {}
+		`)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `failed to build inbound middleware "second": tag is required`)
+	})
+}
+
+func TestConfiguratorOutboundMiddleware(t *testing.T) {
+	type tagConfig struct {
+		Tag string `config:"tag,interpolate"`
+	}
+
+	var calls []string
+	tagSpec := func(name string) OutboundMiddlewareSpec {
+		return OutboundMiddlewareSpec{
+			Name: name,
+			BuildOutboundMiddleware: func(cfg tagConfig, k *Kit) (yarpc.OutboundMiddleware, error) {
+				if cfg.Tag == "" {
+					return yarpc.OutboundMiddleware{}, errors.New("tag is required")
+				}
+				return yarpc.OutboundMiddleware{
+					Unary: middleware.UnaryOutboundFunc(func(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+						calls = append(calls, k.ServiceName()+":"+cfg.Tag)
+						return out.Call(ctx, req)
+					}),
+				}, nil
+			},
+		}
+	}
+
+	newConfigurator := func() *Configurator {
+		c := New(InterpolationResolver(mapVariableResolver(map[string]string{"TAG": "interpolated"})))
+		c.MustRegisterOutboundMiddleware(tagSpec("first"))
+		c.MustRegisterOutboundMiddleware(tagSpec("second"))
+		c.MustRegisterOutboundMiddleware(tagSpec("third"))
+		return c
+	}
+
+	t.Run("applied in registration order", func(t *testing.T) {
+		calls = nil
+		cfg, err := newConfigurator().LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+			outboundMiddleware:
+				third:
+					tag: c
+				first:
+					tag: ${TAG}
+		`)))
+		require.NoError(t, err)
+		require.NotNil(t, cfg.OutboundMiddleware.Unary)
+
+		mockOut := transporttest.NewMockUnaryOutbound(gomock.NewController(t))
+		mockOut.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil)
+
+		_, err = middleware.ApplyUnaryOutbound(mockOut, cfg.OutboundMiddleware.Unary).
+			Call(context.Background(), &transport.Request{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"myservice:interpolated", "myservice:c"}, calls)
+	})
+
+	t.Run("none configured", func(t *testing.T) {
+		cfg, err := newConfigurator().LoadConfig("myservice", map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Equal(t, yarpc.OutboundMiddleware{}, cfg.OutboundMiddleware)
+	})
+
+	t.Run("unknown middleware", func(t *testing.T) {
+		_, err := newConfigurator().LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+			outboundMiddleware:
+				fourth:
+					tag: d
+		`)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown outbound middleware "fourth"`)
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		_, err := newConfigurator().LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+			outboundMiddleware:
+				first:
+					tag: a
+					bogus: b
+		`)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `failed to decode outbound middleware "first"`)
+	})
+
+	t.Run("build error", func(t *testing.T) {
+		_, err := newConfigurator().LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+			outboundMiddleware:
+				second: {}
+		`)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `failed to build outbound middleware "second": tag is required`)
+	})
 }
 
 func TestConfigurator(t *testing.T) {
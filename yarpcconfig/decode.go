@@ -36,6 +36,9 @@ type yarpcConfig struct {
 	Transports map[string]config.AttributeMap `config:"transports"`
 	Logging    logging                        `config:"logging"`
 	Metrics    metrics                        `config:"metrics"`
+
+	InboundMiddleware  map[string]config.AttributeMap `config:"inboundMiddleware"`
+	OutboundMiddleware map[string]config.AttributeMap `config:"outboundMiddleware"`
 }
 
 // metrics allows configuring the way metrics are emitted from YAML
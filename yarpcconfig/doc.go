@@ -88,9 +88,14 @@
 //	  # ...
 //	logging:
 //	  # ...
+//	inboundMiddleware:
+//	  # ...
+//	outboundMiddleware:
+//	  # ...
 //
 // See the following sections for details on the logging, transports,
-// inbounds, and outbounds keys in the configuration.
+// inbounds, outbounds, inboundMiddleware, and outboundMiddleware keys in the
+// configuration.
 //
 // # Inbound Configuration
 //
@@ -303,6 +308,32 @@
 //	panic
 //	fatal
 //
+// # Middleware Configuration
+//
+// The 'inboundMiddleware' attribute configures middleware applied to every
+// inbound request. It is a mapping between the name of an
+// InboundMiddlewareSpec registered with the Configurator and its
+// configuration.
+//
+//	inboundMiddleware:
+//	  ratelimit:
+//	    global:
+//	      rps: 1000
+//
+// Similarly, the 'outboundMiddleware' attribute configures middleware
+// applied to every outbound request, keyed by the name of an
+// OutboundMiddlewareSpec.
+//
+//	outboundMiddleware:
+//	  retry:
+//	    policies:
+//	      default:
+//	        retries: 2
+//	    default: default
+//
+// Middleware are applied in the order in which their specs were registered
+// with the Configurator.
+//
 // # Customizing Configuration
 //
 // When building your own TransportSpec, PeerListSpec, or PeerListUpdaterSpec,
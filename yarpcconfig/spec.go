@@ -28,6 +28,7 @@ import (
 
 	"github.com/uber-go/mapdecode"
 	"go.uber.org/multierr"
+	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/config"
@@ -244,6 +245,68 @@ type PeerListUpdaterSpec struct {
 	BuildPeerListUpdater interface{}
 }
 
+// OutboundMiddlewareSpec specifies the configuration parameters for an
+// outbound middleware. These specifications are registered against a
+// Configurator to teach it how to parse the configuration for that middleware
+// and build instances of it.
+//
+// For example, a retry middleware may be configured under its name in the
+// top-level outboundMiddleware section.
+//
+//	outboundMiddleware:
+//	  retry:
+//	    policies:
+//	      default:
+//	        retries: 2
+//	    default: default
+type OutboundMiddlewareSpec struct {
+	// Name of the middleware.
+	Name string
+
+	// A function in the shape,
+	//
+	//  func(C, *config.Kit) (yarpc.OutboundMiddleware, error)
+	//
+	// Where C is a struct or pointer to a struct defining the configuration
+	// parameters accepted by this middleware.
+	//
+	// The returned yarpc.OutboundMiddleware may set any of its unary, oneway,
+	// or stream middleware.
+	//
+	// BuildOutboundMiddleware is required.
+	BuildOutboundMiddleware interface{}
+}
+
+// InboundMiddlewareSpec specifies the configuration parameters for an
+// inbound middleware. These specifications are registered against a
+// Configurator to teach it how to parse the configuration for that middleware
+// and build instances of it.
+//
+// For example, a rate limiting middleware may be configured under its name
+// in the top-level inboundMiddleware section.
+//
+//	inboundMiddleware:
+//	  ratelimit:
+//	    global:
+//	      rps: 1000
+type InboundMiddlewareSpec struct {
+	// Name of the middleware.
+	Name string
+
+	// A function in the shape,
+	//
+	//  func(C, *config.Kit) (yarpc.InboundMiddleware, error)
+	//
+	// Where C is a struct or pointer to a struct defining the configuration
+	// parameters accepted by this middleware.
+	//
+	// The returned yarpc.InboundMiddleware may set any of its unary, oneway,
+	// or stream middleware.
+	//
+	// BuildInboundMiddleware is required.
+	BuildInboundMiddleware interface{}
+}
+
 var (
 	_typeOfError           = reflect.TypeOf((*error)(nil)).Elem()
 	_typeOfTransport       = reflect.TypeOf((*transport.Transport)(nil)).Elem()
@@ -255,6 +318,9 @@ var (
 	_typeOfPeerChooserList = reflect.TypeOf((*peer.ChooserList)(nil)).Elem()
 	_typeOfPeerChooser     = reflect.TypeOf((*peer.Chooser)(nil)).Elem()
 	_typeOfBinder          = reflect.TypeOf((*peer.Binder)(nil)).Elem()
+
+	_typeOfInboundMiddleware  = reflect.TypeOf(yarpc.InboundMiddleware{})
+	_typeOfOutboundMiddleware = reflect.TypeOf(yarpc.OutboundMiddleware{})
 )
 
 // Compiled internal representation of a user-specified TransportSpec.
@@ -681,6 +747,114 @@ func compilePeerListUpdaterConfig(name string, build interface{}) (*configSpec,
 	return &configSpec{inputType: t.In(0), factory: v}, nil
 }
 
+type compiledOutboundMiddlewareSpec struct {
+	Name               string
+	OutboundMiddleware *configSpec
+}
+
+func compileOutboundMiddlewareSpec(spec *OutboundMiddlewareSpec) (*compiledOutboundMiddlewareSpec, error) {
+	out := compiledOutboundMiddlewareSpec{Name: spec.Name}
+
+	if spec.Name == "" {
+		return nil, errors.New("field Name is required")
+	}
+
+	if spec.BuildOutboundMiddleware == nil {
+		return nil, errors.New("field BuildOutboundMiddleware is required")
+	}
+
+	buildOutboundMiddleware, err := compileOutboundMiddlewareConfig(spec.BuildOutboundMiddleware)
+	if err != nil {
+		return nil, err
+	}
+	out.OutboundMiddleware = buildOutboundMiddleware
+
+	return &out, nil
+}
+
+func compileOutboundMiddlewareConfig(build interface{}) (*configSpec, error) {
+	v := reflect.ValueOf(build)
+	t := v.Type()
+
+	var err error
+	switch {
+	case t.Kind() != reflect.Func:
+		err = errors.New("must be a function")
+	case t.NumIn() != 2:
+		err = fmt.Errorf("must accept exactly two arguments, found %v", t.NumIn())
+	case !isDecodable(t.In(0)):
+		err = fmt.Errorf("must accept a struct or struct pointer as its first argument, found %v", t.In(0))
+	case t.In(1) != _typeOfKit:
+		err = fmt.Errorf("must accept a %v as its second argument, found %v", _typeOfKit, t.In(1))
+	case t.NumOut() != 2:
+		err = fmt.Errorf("must return exactly two results, found %v", t.NumOut())
+	case t.Out(0) != _typeOfOutboundMiddleware:
+		err = fmt.Errorf("must return a yarpc.OutboundMiddleware as its first result, found %v", t.Out(0))
+	case t.Out(1) != _typeOfError:
+		err = fmt.Errorf("must return an error as its second result, found %v", t.Out(1))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid BuildOutboundMiddleware %v: %v", t, err)
+	}
+
+	return &configSpec{inputType: t.In(0), factory: v}, nil
+}
+
+type compiledInboundMiddlewareSpec struct {
+	Name              string
+	InboundMiddleware *configSpec
+}
+
+func compileInboundMiddlewareSpec(spec *InboundMiddlewareSpec) (*compiledInboundMiddlewareSpec, error) {
+	out := compiledInboundMiddlewareSpec{Name: spec.Name}
+
+	if spec.Name == "" {
+		return nil, errors.New("field Name is required")
+	}
+
+	if spec.BuildInboundMiddleware == nil {
+		return nil, errors.New("field BuildInboundMiddleware is required")
+	}
+
+	buildInboundMiddleware, err := compileInboundMiddlewareConfig(spec.BuildInboundMiddleware)
+	if err != nil {
+		return nil, err
+	}
+	out.InboundMiddleware = buildInboundMiddleware
+
+	return &out, nil
+}
+
+func compileInboundMiddlewareConfig(build interface{}) (*configSpec, error) {
+	v := reflect.ValueOf(build)
+	t := v.Type()
+
+	var err error
+	switch {
+	case t.Kind() != reflect.Func:
+		err = errors.New("must be a function")
+	case t.NumIn() != 2:
+		err = fmt.Errorf("must accept exactly two arguments, found %v", t.NumIn())
+	case !isDecodable(t.In(0)):
+		err = fmt.Errorf("must accept a struct or struct pointer as its first argument, found %v", t.In(0))
+	case t.In(1) != _typeOfKit:
+		err = fmt.Errorf("must accept a %v as its second argument, found %v", _typeOfKit, t.In(1))
+	case t.NumOut() != 2:
+		err = fmt.Errorf("must return exactly two results, found %v", t.NumOut())
+	case t.Out(0) != _typeOfInboundMiddleware:
+		err = fmt.Errorf("must return a yarpc.InboundMiddleware as its first result, found %v", t.Out(0))
+	case t.Out(1) != _typeOfError:
+		err = fmt.Errorf("must return an error as its second result, found %v", t.Out(1))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid BuildInboundMiddleware %v: %v", t, err)
+	}
+
+	return &configSpec{inputType: t.In(0), factory: v}, nil
+}
+
 // Validated representation of a configuration function specified by the user.
 type configSpec struct {
 	// Type of object expected by the factory function
@@ -28,6 +28,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/config"
@@ -959,6 +960,210 @@ func TestCompilePeerListUpdaterSpec(t *testing.T) {
 	}
 }
 
+func TestCompileInboundMiddlewareSpec(t *testing.T) {
+	tests := []struct {
+		desc     string
+		spec     InboundMiddlewareSpec
+		wantName string
+		wantErr  string
+	}{
+		{
+			desc:    "missing name",
+			wantErr: "field Name is required",
+		},
+		{
+			desc: "missing BuildInboundMiddleware",
+			spec: InboundMiddlewareSpec{
+				Name: "ratelimit",
+			},
+			wantErr: "field BuildInboundMiddleware is required",
+		},
+		{
+			desc: "not a function",
+			spec: InboundMiddlewareSpec{
+				Name:                   "much sadness",
+				BuildInboundMiddleware: 10,
+			},
+			wantErr: "invalid BuildInboundMiddleware int: must be a function",
+		},
+		{
+			desc: "too many arguments",
+			spec: InboundMiddlewareSpec{
+				Name:                   "much sadness",
+				BuildInboundMiddleware: func(a, b, c int) {},
+			},
+			wantErr: "invalid BuildInboundMiddleware func(int, int, int): must accept exactly two arguments, found 3",
+		},
+		{
+			desc: "wrong kind of first argument",
+			spec: InboundMiddlewareSpec{
+				Name:                   "much sadness",
+				BuildInboundMiddleware: func(a, b int) {},
+			},
+			wantErr: "invalid BuildInboundMiddleware func(int, int): must accept a struct or struct pointer as its first argument, found int",
+		},
+		{
+			desc: "wrong kind of second argument",
+			spec: InboundMiddlewareSpec{
+				Name:                   "much sadness",
+				BuildInboundMiddleware: func(a struct{}, b int) {},
+			},
+			wantErr: "invalid BuildInboundMiddleware func(struct {}, int): must accept a *yarpcconfig.Kit as its second argument, found int",
+		},
+		{
+			desc: "wrong number of returns",
+			spec: InboundMiddlewareSpec{
+				Name:                   "much sadness",
+				BuildInboundMiddleware: func(a struct{}, b *Kit) {},
+			},
+			wantErr: "invalid BuildInboundMiddleware func(struct {}, *yarpcconfig.Kit): must return exactly two results, found 0",
+		},
+		{
+			desc: "wrong type of first return",
+			spec: InboundMiddlewareSpec{
+				Name: "much sadness",
+				BuildInboundMiddleware: func(a struct{}, b *Kit) (int, error) {
+					return 0, nil
+				},
+			},
+			wantErr: "invalid BuildInboundMiddleware func(struct {}, *yarpcconfig.Kit) (int, error): must return a yarpc.InboundMiddleware as its first result, found int",
+		},
+		{
+			desc: "wrong type of second return",
+			spec: InboundMiddlewareSpec{
+				Name: "much sadness",
+				BuildInboundMiddleware: func(a struct{}, b *Kit) (yarpc.InboundMiddleware, int) {
+					return yarpc.InboundMiddleware{}, 0
+				},
+			},
+			wantErr: "invalid BuildInboundMiddleware func(struct {}, *yarpcconfig.Kit) (yarpc.InboundMiddleware, int): must return an error as its second result, found int",
+		},
+		{
+			desc: "such gladness",
+			spec: InboundMiddlewareSpec{
+				Name: "such gladness",
+				BuildInboundMiddleware: func(a struct{}, b *Kit) (yarpc.InboundMiddleware, error) {
+					return yarpc.InboundMiddleware{}, nil
+				},
+			},
+			wantName: "such gladness",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			s, err := compileInboundMiddlewareSpec(&tt.spec)
+			if err != nil {
+				assert.Equal(t, tt.wantErr, err.Error(), "expected error")
+			} else {
+				assert.Equal(t, tt.wantName, s.Name, "expected name")
+			}
+		})
+	}
+}
+
+func TestCompileOutboundMiddlewareSpec(t *testing.T) {
+	tests := []struct {
+		desc     string
+		spec     OutboundMiddlewareSpec
+		wantName string
+		wantErr  string
+	}{
+		{
+			desc:    "missing name",
+			wantErr: "field Name is required",
+		},
+		{
+			desc: "missing BuildOutboundMiddleware",
+			spec: OutboundMiddlewareSpec{
+				Name: "retry",
+			},
+			wantErr: "field BuildOutboundMiddleware is required",
+		},
+		{
+			desc: "not a function",
+			spec: OutboundMiddlewareSpec{
+				Name:                    "much sadness",
+				BuildOutboundMiddleware: 10,
+			},
+			wantErr: "invalid BuildOutboundMiddleware int: must be a function",
+		},
+		{
+			desc: "too many arguments",
+			spec: OutboundMiddlewareSpec{
+				Name:                    "much sadness",
+				BuildOutboundMiddleware: func(a, b, c int) {},
+			},
+			wantErr: "invalid BuildOutboundMiddleware func(int, int, int): must accept exactly two arguments, found 3",
+		},
+		{
+			desc: "wrong kind of first argument",
+			spec: OutboundMiddlewareSpec{
+				Name:                    "much sadness",
+				BuildOutboundMiddleware: func(a, b int) {},
+			},
+			wantErr: "invalid BuildOutboundMiddleware func(int, int): must accept a struct or struct pointer as its first argument, found int",
+		},
+		{
+			desc: "wrong kind of second argument",
+			spec: OutboundMiddlewareSpec{
+				Name:                    "much sadness",
+				BuildOutboundMiddleware: func(a struct{}, b int) {},
+			},
+			wantErr: "invalid BuildOutboundMiddleware func(struct {}, int): must accept a *yarpcconfig.Kit as its second argument, found int",
+		},
+		{
+			desc: "wrong number of returns",
+			spec: OutboundMiddlewareSpec{
+				Name:                    "much sadness",
+				BuildOutboundMiddleware: func(a struct{}, b *Kit) {},
+			},
+			wantErr: "invalid BuildOutboundMiddleware func(struct {}, *yarpcconfig.Kit): must return exactly two results, found 0",
+		},
+		{
+			desc: "wrong type of first return",
+			spec: OutboundMiddlewareSpec{
+				Name: "much sadness",
+				BuildOutboundMiddleware: func(a struct{}, b *Kit) (int, error) {
+					return 0, nil
+				},
+			},
+			wantErr: "invalid BuildOutboundMiddleware func(struct {}, *yarpcconfig.Kit) (int, error): must return a yarpc.OutboundMiddleware as its first result, found int",
+		},
+		{
+			desc: "wrong type of second return",
+			spec: OutboundMiddlewareSpec{
+				Name: "much sadness",
+				BuildOutboundMiddleware: func(a struct{}, b *Kit) (yarpc.OutboundMiddleware, int) {
+					return yarpc.OutboundMiddleware{}, 0
+				},
+			},
+			wantErr: "invalid BuildOutboundMiddleware func(struct {}, *yarpcconfig.Kit) (yarpc.OutboundMiddleware, int): must return an error as its second result, found int",
+		},
+		{
+			desc: "such gladness",
+			spec: OutboundMiddlewareSpec{
+				Name: "such gladness",
+				BuildOutboundMiddleware: func(a struct{}, b *Kit) (yarpc.OutboundMiddleware, error) {
+					return yarpc.OutboundMiddleware{}, nil
+				},
+			},
+			wantName: "such gladness",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			s, err := compileOutboundMiddlewareSpec(&tt.spec)
+			if err != nil {
+				assert.Equal(t, tt.wantErr, err.Error(), "expected error")
+			} else {
+				assert.Equal(t, tt.wantName, s.Name, "expected name")
+			}
+		})
+	}
+}
+
 func TestCompilePeerChooserPreset(t *testing.T) {
 	tests := []struct {
 		desc     string
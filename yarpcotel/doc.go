@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package yarpcotel provides OpenTelemetry tracing for YARPC transports.
+//
+// The transports are instrumented with OpenTracing. This package provides an
+// OpenTracing Tracer that records spans with an OpenTelemetry TracerProvider
+// and propagates them with W3C Trace Context (traceparent and tracestate)
+// and W3C Baggage headers. The HTTP, gRPC and TChannel transports accept it
+// through their TracerProvider options:
+//
+//	httpTransport := http.NewTransport(http.TracerProvider(provider))
+//	grpcTransport := grpc.NewTransport(grpc.TracerProvider(provider))
+//	tchannelTransport, err := tchannel.NewTransport(
+//		tchannel.ServiceName("myservice"),
+//		tchannel.TracerProvider(provider),
+//	)
+//
+// Inbound and outbound spans follow the OpenTelemetry semantic conventions
+// for RPC spans, and spans of streaming calls record a "message" event for
+// every message sent or received.
+//
+// # Migrating from OpenTracing
+//
+// Spans started by YARPC are visible to both opentracing.SpanFromContext and
+// trace.SpanFromContext in handlers, and outbound calls use the active span
+// of either API as their parent. To keep existing OpenTracing
+// instrumentation working while migrating, install the same tracer
+// globally:
+//
+//	opentracing.SetGlobalTracer(yarpcotel.NewTracer(provider))
+package yarpcotel
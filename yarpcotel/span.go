@@ -0,0 +1,356 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcotel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// _defaultEventName names events logged without an "event" field.
+	_defaultEventName = "log"
+
+	// _rpcSystem is the rpc.system of spans for transports that don't have
+	// a system of their own in the semantic conventions.
+	_rpcSystem = "yarpc"
+)
+
+// spanContext is the OpenTracing SpanContext of spans started by a Tracer.
+type spanContext struct {
+	otel    trace.SpanContext
+	baggage map[string]string
+}
+
+var _ opentracing.SpanContext = spanContext{}
+
+func (c spanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range c.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+func (c spanContext) baggageCopy() map[string]string {
+	if len(c.baggage) == 0 {
+		return nil
+	}
+	items := make(map[string]string, len(c.baggage))
+	for k, v := range c.baggage {
+		items[k] = v
+	}
+	return items
+}
+
+// context returns a copy of ctx that carries this span context and its
+// baggage. Baggage items that aren't valid W3C baggage members are dropped.
+func (c spanContext) context(ctx context.Context) context.Context {
+	ctx = trace.ContextWithSpanContext(ctx, c.otel)
+	if len(c.baggage) == 0 {
+		return ctx
+	}
+	members := make([]baggage.Member, 0, len(c.baggage))
+	for k, v := range c.baggage {
+		if m, err := baggage.NewMemberRaw(k, v); err == nil {
+			members = append(members, m)
+		}
+	}
+	if b, err := baggage.New(members...); err == nil {
+		ctx = baggage.ContextWithBaggage(ctx, b)
+	}
+	return ctx
+}
+
+// span is an OpenTracing Span backed by an OpenTelemetry span.
+type span struct {
+	tracer *Tracer
+
+	mu      sync.Mutex
+	otel    trace.Span // nil until started
+	baggage map[string]string
+
+	// Describe the OpenTelemetry span until it starts.
+	name      string
+	tags      map[string]interface{}
+	parent    spanContext
+	links     []trace.Link
+	startTime time.Time
+}
+
+var _ opentracing.Span = (*span)(nil)
+
+// otelSpan returns the OpenTelemetry span, starting it if needed.
+func (s *span) otelSpan() trace.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.start()
+}
+
+// start starts the OpenTelemetry span if it isn't already. s.mu must be
+// held.
+func (s *span) start() trace.Span {
+	if s.otel != nil {
+		return s.otel
+	}
+
+	kind := trace.SpanKindInternal
+	var failed bool
+	attrs := make([]attribute.KeyValue, 0, len(s.tags)+3)
+	for k, v := range s.tags {
+		switch k {
+		case string(ext.SpanKind):
+			kind = spanKind(v)
+		case string(ext.Error):
+			failed, _ = v.(bool)
+		default:
+			attrs = append(attrs, toAttribute(k, v))
+		}
+	}
+	if kind == trace.SpanKindClient || kind == trace.SpanKindServer {
+		attrs = append(attrs, rpcAttributes(s.name, kind, s.tags)...)
+	}
+
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(attrs...),
+		trace.WithLinks(s.links...),
+	}
+	if !s.startTime.IsZero() {
+		opts = append(opts, trace.WithTimestamp(s.startTime))
+	}
+	ctx := context.Background()
+	if s.parent.otel.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, s.parent.otel)
+	}
+	_, s.otel = s.tracer.tracer.Start(ctx, s.name, opts...)
+	if failed {
+		s.otel.SetStatus(codes.Error, "")
+	}
+	s.tags = nil
+	s.links = nil
+	return s.otel
+}
+
+func (s *span) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	for _, lr := range opts.LogRecords {
+		s.logAt(lr.Timestamp, lr.Fields)
+	}
+	for _, ld := range opts.BulkLogData {
+		lr := ld.ToLogRecord()
+		s.logAt(lr.Timestamp, lr.Fields)
+	}
+	var endOpts []trace.SpanEndOption
+	if !opts.FinishTime.IsZero() {
+		endOpts = append(endOpts, trace.WithTimestamp(opts.FinishTime))
+	}
+	s.otelSpan().End(endOpts...)
+}
+
+func (s *span) Context() opentracing.SpanContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return spanContext{
+		otel:    s.start().SpanContext(),
+		baggage: spanContext{baggage: s.baggage}.baggageCopy(),
+	}
+}
+
+func (s *span) SetOperationName(operationName string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.otel == nil {
+		s.name = operationName
+	} else {
+		s.otel.SetName(operationName)
+	}
+	return s
+}
+
+func (s *span) SetTag(key string, value interface{}) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.otel == nil {
+		s.tags[key] = value
+		return s
+	}
+	switch key {
+	case string(ext.SpanKind):
+		// The kind of a started OpenTelemetry span can't change.
+	case string(ext.Error):
+		if failed, _ := value.(bool); failed {
+			s.otel.SetStatus(codes.Error, "")
+		}
+	default:
+		s.otel.SetAttributes(toAttribute(key, value))
+	}
+	return s
+}
+
+// LogFields adds an event to the span, named after the "event" field if
+// any.
+func (s *span) LogFields(fields ...log.Field) {
+	s.logAt(time.Time{}, fields)
+}
+
+func (s *span) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.LogFields(log.Error(err), log.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
+}
+
+func (s *span) logAt(ts time.Time, fields []log.Field) {
+	name := _defaultEventName
+	attrs := make([]attribute.KeyValue, 0, len(fields))
+	for _, f := range fields {
+		if f.Key() == "event" {
+			name = fmt.Sprint(f.Value())
+			continue
+		}
+		attrs = append(attrs, toAttribute(f.Key(), f.Value()))
+	}
+	opts := []trace.EventOption{trace.WithAttributes(attrs...)}
+	if !ts.IsZero() {
+		opts = append(opts, trace.WithTimestamp(ts))
+	}
+	s.otelSpan().AddEvent(name, opts...)
+}
+
+func (s *span) SetBaggageItem(key, value string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.baggage == nil {
+		s.baggage = make(map[string]string)
+	}
+	s.baggage[key] = value
+	return s
+}
+
+func (s *span) BaggageItem(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.baggage[key]
+}
+
+func (s *span) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+func (s *span) LogEvent(event string) {
+	s.LogFields(log.String("event", event))
+}
+
+func (s *span) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(log.String("event", event), log.Object("payload", payload))
+}
+
+func (s *span) Log(ld opentracing.LogData) {
+	lr := ld.ToLogRecord()
+	s.logAt(lr.Timestamp, lr.Fields)
+}
+
+// rpcAttributes returns the attributes of RPC spans that follow the
+// semantic conventions and that YARPC doesn't already tag spans with.
+func rpcAttributes(method string, kind trace.SpanKind, tags map[string]interface{}) []attribute.KeyValue {
+	system := _rpcSystem
+	if transport, _ := tags["rpc.transport"].(string); transport == "grpc" {
+		system = transport
+	}
+	attrs := []attribute.KeyValue{
+		semconv.RPCSystemKey.String(system),
+		semconv.RPCMethod(method),
+	}
+	if _, ok := tags[string(semconv.RPCServiceKey)]; !ok && kind == trace.SpanKindClient {
+		// The peer of client spans is the service they call.
+		if service, ok := tags[string(ext.PeerService)].(string); ok {
+			attrs = append(attrs, semconv.RPCService(service))
+		}
+	}
+	return attrs
+}
+
+func spanKind(v interface{}) trace.SpanKind {
+	switch fmt.Sprint(v) {
+	case string(ext.SpanKindRPCClientEnum):
+		return trace.SpanKindClient
+	case string(ext.SpanKindRPCServerEnum):
+		return trace.SpanKindServer
+	case string(ext.SpanKindProducerEnum):
+		return trace.SpanKindProducer
+	case string(ext.SpanKindConsumerEnum):
+		return trace.SpanKindConsumer
+	default:
+		return trace.SpanKindInternal
+	}
+}
+
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int64(key, int64(v))
+	case int16:
+		return attribute.Int64(key, int64(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint8:
+		return attribute.Int64(key, int64(v))
+	case uint16:
+		return attribute.Int64(key, int64(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case error:
+		return attribute.String(key, v.Error())
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcotel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanKind(t *testing.T) {
+	tests := []struct {
+		desc string
+		give func(opentracing.Tracer) opentracing.Span
+		want trace.SpanKind
+	}{
+		{
+			desc: "internal",
+			give: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("span")
+			},
+			want: trace.SpanKindInternal,
+		},
+		{
+			desc: "start option",
+			give: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("span", ext.RPCServerOption(nil))
+			},
+			want: trace.SpanKindServer,
+		},
+		{
+			desc: "tag set after start",
+			give: func(tracer opentracing.Tracer) opentracing.Span {
+				span := tracer.StartSpan("span")
+				ext.SpanKindRPCClient.Set(span)
+				return span
+			},
+			want: trace.SpanKindClient,
+		},
+		{
+			desc: "producer",
+			give: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("span", ext.SpanKindProducer)
+			},
+			want: trace.SpanKindProducer,
+		},
+		{
+			desc: "consumer",
+			give: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("span", opentracing.Tag{Key: string(ext.SpanKind), Value: "consumer"})
+			},
+			want: trace.SpanKindConsumer,
+		},
+		{
+			desc: "tag set once started",
+			give: func(tracer opentracing.Tracer) opentracing.Span {
+				span := tracer.StartSpan("span")
+				span.Context()
+				ext.SpanKindRPCClient.Set(span)
+				return span
+			},
+			want: trace.SpanKindInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			tracer, recorder := newTracer()
+			tt.give(tracer).Finish()
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.want, spans[0].SpanKind())
+		})
+	}
+}
+
+func TestSpanRPCAttributes(t *testing.T) {
+	tests := []struct {
+		desc string
+		kind ext.SpanKindEnum
+		tags opentracing.Tags
+		want map[string]interface{}
+	}{
+		{
+			desc: "yarpc client",
+			kind: ext.SpanKindRPCClientEnum,
+			tags: opentracing.Tags{
+				"rpc.caller":    "client",
+				"rpc.service":   "server",
+				"rpc.transport": "http",
+				"peer.service":  "server",
+			},
+			want: map[string]interface{}{
+				"rpc.caller":    "client",
+				"rpc.service":   "server",
+				"rpc.transport": "http",
+				"peer.service":  "server",
+				"rpc.system":    "yarpc",
+				"rpc.method":    "Service::method",
+			},
+		},
+		{
+			desc: "grpc server",
+			kind: ext.SpanKindRPCServerEnum,
+			tags: opentracing.Tags{
+				"rpc.caller":    "client",
+				"rpc.service":   "server",
+				"rpc.transport": "grpc",
+				"peer.service":  "client",
+			},
+			want: map[string]interface{}{
+				"rpc.caller":    "client",
+				"rpc.service":   "server",
+				"rpc.transport": "grpc",
+				"peer.service":  "client",
+				"rpc.system":    "grpc",
+				"rpc.method":    "Service::method",
+			},
+		},
+		{
+			desc: "client without rpc.service",
+			kind: ext.SpanKindRPCClientEnum,
+			tags: opentracing.Tags{"peer.service": "server", "as": "thrift"},
+			want: map[string]interface{}{
+				"peer.service": "server",
+				"as":           "thrift",
+				"rpc.system":   "yarpc",
+				"rpc.method":   "Service::method",
+				"rpc.service":  "server",
+			},
+		},
+		{
+			desc: "server without rpc.service",
+			kind: ext.SpanKindRPCServerEnum,
+			tags: opentracing.Tags{"peer.service": "client"},
+			want: map[string]interface{}{
+				"peer.service": "client",
+				"rpc.system":   "yarpc",
+				"rpc.method":   "Service::method",
+			},
+		},
+		{
+			desc: "internal",
+			tags: opentracing.Tags{"foo": "bar"},
+			want: map[string]interface{}{"foo": "bar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			tracer, recorder := newTracer()
+			span := tracer.StartSpan("Service::method", tt.tags)
+			if tt.kind != "" {
+				ext.SpanKind.Set(span, tt.kind)
+			}
+			span.Finish()
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.want, attributes(spans[0]))
+		})
+	}
+}
+
+func TestSpanParent(t *testing.T) {
+	tracer, recorder := newTracer()
+
+	parent := tracer.StartSpan("parent")
+	other := tracer.StartSpan("other")
+	child := tracer.StartSpan("child",
+		opentracing.ChildOf(nil),
+		opentracing.FollowsFrom(parent.Context()),
+		opentracing.ChildOf(other.Context()),
+	)
+	child.Finish()
+	parent.Finish()
+	other.Finish()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, parent.Context().(spanContext).otel.SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, parent.Context().(spanContext).otel.TraceID(), spans[0].SpanContext().TraceID())
+	require.Len(t, spans[0].Links(), 1)
+	assert.Equal(t, other.Context().(spanContext).otel, spans[0].Links()[0].SpanContext)
+}
+
+func TestSpanBaggage(t *testing.T) {
+	tracer, _ := newTracer()
+
+	parent := tracer.StartSpan("parent")
+	parent.SetBaggageItem("user", "alice")
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	child.SetBaggageItem("user", "bob")
+
+	assert.Equal(t, "alice", parent.BaggageItem("user"), "children must not change baggage of their parent")
+	assert.Equal(t, "bob", child.BaggageItem("user"))
+	assert.Empty(t, child.BaggageItem("missing"))
+
+	var items []string
+	child.Context().ForeachBaggageItem(func(k, v string) bool {
+		items = append(items, k+"="+v)
+		return false
+	})
+	assert.Equal(t, []string{"user=bob"}, items)
+}
+
+func TestSpanTagsAndLogs(t *testing.T) {
+	tracer, recorder := newTracer()
+	start := time.Unix(1000, 0)
+	logged := time.Unix(1001, 0)
+	finish := time.Unix(1002, 0)
+
+	span := tracer.StartSpan("before", opentracing.StartTime(start), opentracing.Tag{Key: "early", Value: 1})
+	span.SetOperationName("started")
+	span.LogFields(log.String("event", "first"), log.Int("count", 1))
+	span.SetOperationName("renamed")
+	span.SetTag("late", true)
+	ext.SpanKindRPCServer.Set(span)
+	span.LogKV("answer", 42)
+	span.LogKV("odd")
+	span.LogEvent("second")
+	span.LogEventWithPayload("third", "payload")
+	span.Log(opentracing.LogData{Timestamp: logged, Event: "fourth"})
+	assert.Equal(t, tracer, span.Tracer())
+	span.FinishWithOptions(opentracing.FinishOptions{
+		FinishTime:  finish,
+		LogRecords:  []opentracing.LogRecord{{Timestamp: logged, Fields: []log.Field{log.String("event", "fifth")}}},
+		BulkLogData: []opentracing.LogData{{Timestamp: logged, Event: "sixth"}},
+	})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	got := spans[0]
+	assert.Equal(t, "renamed", got.Name())
+	assert.Equal(t, trace.SpanKindInternal, got.SpanKind())
+	assert.Equal(t, start, got.StartTime())
+	assert.Equal(t, finish, got.EndTime())
+	assert.Equal(t, map[string]interface{}{"early": int64(1), "late": true}, attributes(got))
+
+	var names []string
+	for _, e := range got.Events() {
+		names = append(names, e.Name)
+	}
+	assert.Equal(t, []string{"first", "log", "log", "second", "third", "fourth", "fifth", "sixth"}, names)
+	assert.Equal(t, []attribute.KeyValue{attribute.Int("count", 1)}, got.Events()[0].Attributes)
+	assert.Equal(t, []attribute.KeyValue{attribute.Int("answer", 42)}, got.Events()[1].Attributes)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("payload", "payload")}, got.Events()[4].Attributes)
+	assert.Equal(t, logged, got.Events()[5].Time)
+}
+
+func TestSpanError(t *testing.T) {
+	tests := []struct {
+		desc string
+		give func(opentracing.Span)
+		want codes.Code
+	}{
+		{
+			desc: "before start",
+			give: func(span opentracing.Span) { ext.Error.Set(span, true) },
+			want: codes.Error,
+		},
+		{
+			desc: "after start",
+			give: func(span opentracing.Span) {
+				span.LogFields(log.String("event", "great sadness"))
+				ext.Error.Set(span, true)
+			},
+			want: codes.Error,
+		},
+		{
+			desc: "not an error",
+			give: func(span opentracing.Span) {
+				span.Context()
+				ext.Error.Set(span, false)
+			},
+			want: codes.Unset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			tracer, recorder := newTracer()
+			span := tracer.StartSpan("span")
+			tt.give(span)
+			span.Finish()
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.want, spans[0].Status().Code)
+			assert.Empty(t, spans[0].Attributes())
+		})
+	}
+}
+
+type stringer struct{}
+
+func (stringer) String() string { return "stringer" }
+
+func TestToAttribute(t *testing.T) {
+	tests := []struct {
+		give interface{}
+		want attribute.Value
+	}{
+		{give: "foo", want: attribute.StringValue("foo")},
+		{give: true, want: attribute.BoolValue(true)},
+		{give: 1, want: attribute.IntValue(1)},
+		{give: int8(2), want: attribute.Int64Value(2)},
+		{give: int16(3), want: attribute.Int64Value(3)},
+		{give: int32(4), want: attribute.Int64Value(4)},
+		{give: int64(5), want: attribute.Int64Value(5)},
+		{give: uint8(6), want: attribute.Int64Value(6)},
+		{give: uint16(7), want: attribute.Int64Value(7)},
+		{give: uint32(8), want: attribute.Int64Value(8)},
+		{give: float32(0.5), want: attribute.Float64Value(0.5)},
+		{give: 1.5, want: attribute.Float64Value(1.5)},
+		{give: errors.New("great sadness"), want: attribute.StringValue("great sadness")},
+		{give: stringer{}, want: attribute.StringValue("stringer")},
+		{give: uint64(9), want: attribute.StringValue("9")},
+		{give: []int{1}, want: attribute.StringValue("[1]")},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, attribute.KeyValue{Key: "key", Value: tt.want}, toAttribute("key", tt.give), "%T", tt.give)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcotel
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/version"
+)
+
+const _instrumentationName = "go.uber.org/yarpc"
+
+var _ transport.ContextTracer = (*Tracer)(nil)
+
+// Option customizes the behavior of a Tracer.
+type Option interface {
+	apply(*options)
+}
+
+type options struct {
+	propagator propagation.TextMapPropagator
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(o *options) { f(o) }
+
+// Propagator specifies how span contexts are propagated over the wire.
+//
+// Defaults to W3C Trace Context and W3C Baggage.
+func Propagator(propagator propagation.TextMapPropagator) Option {
+	return optionFunc(func(o *options) {
+		if propagator != nil {
+			o.propagator = propagator
+		}
+	})
+}
+
+// Tracer is an OpenTracing Tracer that records spans with an OpenTelemetry
+// TracerProvider.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer builds a Tracer recording spans with the given TracerProvider.
+func NewTracer(provider trace.TracerProvider, opts ...Option) *Tracer {
+	options := options{
+		propagator: propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	}
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &Tracer{
+		tracer: provider.Tracer(
+			_instrumentationName,
+			trace.WithInstrumentationVersion(version.Version),
+		),
+		propagator: options.propagator,
+	}
+}
+
+// StartSpan starts a span with the given operation name.
+//
+// The OpenTelemetry span starts when it's first used rather than right away,
+// so that tags set right after StartSpan, like the span kind, are known when
+// it does.
+func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, opt := range opts {
+		opt.Apply(&sso)
+	}
+
+	s := &span{
+		tracer:    t,
+		name:      operationName,
+		startTime: sso.StartTime,
+		tags:      make(map[string]interface{}, len(sso.Tags)),
+	}
+	for k, v := range sso.Tags {
+		s.tags[k] = v
+	}
+	for _, ref := range sso.References {
+		sc, ok := ref.ReferencedContext.(spanContext)
+		if !ok || !sc.otel.IsValid() {
+			continue
+		}
+		if !s.parent.otel.IsValid() {
+			s.parent = sc
+		} else {
+			s.links = append(s.links, trace.Link{SpanContext: sc.otel})
+		}
+	}
+	s.baggage = s.parent.baggageCopy()
+	return s
+}
+
+// Inject writes the given span context to the carrier. Only the TextMap and
+// HTTPHeaders formats are supported.
+func (t *Tracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	c, ok := sc.(spanContext)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return opentracing.ErrUnsupportedFormat
+	}
+	w, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+	t.propagator.Inject(c.context(context.Background()), injectCarrier{w})
+	return nil
+}
+
+// Extract reads a span context from the carrier. Only the TextMap and
+// HTTPHeaders formats are supported.
+func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+	r, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	// Propagators look up lower case keys but carriers like HTTP headers
+	// canonicalize them.
+	headers := make(propagation.MapCarrier)
+	if err := r.ForeachKey(func(k, v string) error {
+		headers[strings.ToLower(k)] = v
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	ctx := t.propagator.Extract(context.Background(), headers)
+	sc := spanContext{
+		otel:    trace.SpanContextFromContext(ctx),
+		baggage: baggageFromContext(ctx),
+	}
+	if !sc.otel.IsValid() {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	return sc, nil
+}
+
+// ContextWithSpan returns a copy of ctx that carries the given span for
+// OpenTelemetry. Spans not started by a Tracer are ignored.
+func (t *Tracer) ContextWithSpan(ctx context.Context, otSpan opentracing.Span) context.Context {
+	if s, ok := otSpan.(*span); ok {
+		ctx = trace.ContextWithSpan(ctx, s.otelSpan())
+	}
+	return ctx
+}
+
+// SpanFromContext returns the OpenTelemetry span carried by ctx as an
+// OpenTracing span, or nil if there is none.
+func (t *Tracer) SpanFromContext(ctx context.Context) opentracing.Span {
+	otelSpan := trace.SpanFromContext(ctx)
+	if !otelSpan.SpanContext().IsValid() {
+		return nil
+	}
+	return &span{
+		tracer:  t,
+		otel:    otelSpan,
+		baggage: baggageFromContext(ctx),
+	}
+}
+
+func baggageFromContext(ctx context.Context) map[string]string {
+	members := baggage.FromContext(ctx).Members()
+	if len(members) == 0 {
+		return nil
+	}
+	items := make(map[string]string, len(members))
+	for _, m := range members {
+		items[m.Key()] = m.Value()
+	}
+	return items
+}
+
+// injectCarrier adapts an OpenTracing TextMapWriter to the carrier expected
+// by propagators.
+type injectCarrier struct {
+	w opentracing.TextMapWriter
+}
+
+func (c injectCarrier) Get(string) string { return "" }
+
+func (c injectCarrier) Set(k, v string) { c.w.Set(k, v) }
+
+func (c injectCarrier) Keys() []string { return nil }
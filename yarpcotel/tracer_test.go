@@ -0,0 +1,180 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/yarpc/api/transport"
+)
+
+func newTracer(opts ...Option) (*Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewTracer(provider, opts...), recorder
+}
+
+// attributes returns the attributes of a recorded span by key.
+func attributes(span sdktrace.ReadOnlySpan) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return attrs
+}
+
+func TestInjectExtract(t *testing.T) {
+	tracer, _ := newTracer()
+
+	span := tracer.StartSpan("procedure")
+	span.SetBaggageItem("user", "alice")
+	span.Finish()
+	sc := span.Context().(spanContext)
+
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(t, tracer.Inject(sc, opentracing.TextMap, carrier))
+	assert.Equal(t, "00-"+sc.otel.TraceID().String()+"-"+sc.otel.SpanID().String()+"-01", carrier["traceparent"])
+	assert.Equal(t, "user=alice", carrier["baggage"])
+
+	// HTTP headers are canonicalized.
+	headers := opentracing.HTTPHeadersCarrier{}
+	for k, v := range carrier {
+		headers.Set(k, v)
+	}
+	got, err := tracer.Extract(opentracing.HTTPHeaders, headers)
+	require.NoError(t, err)
+	assert.Equal(t, sc.otel.TraceID(), got.(spanContext).otel.TraceID())
+	assert.Equal(t, sc.otel.SpanID(), got.(spanContext).otel.SpanID())
+	assert.True(t, got.(spanContext).otel.IsRemote())
+
+	baggage := make(map[string]string)
+	got.ForeachBaggageItem(func(k, v string) bool {
+		baggage[k] = v
+		return true
+	})
+	assert.Equal(t, map[string]string{"user": "alice"}, baggage)
+}
+
+func TestInjectExtractTraceState(t *testing.T) {
+	tracer, _ := newTracer()
+
+	carrier := opentracing.TextMapCarrier{
+		"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"tracestate":  "congo=t61rcWkgMzE",
+	}
+	sc, err := tracer.Extract(opentracing.TextMap, carrier)
+	require.NoError(t, err)
+
+	span := tracer.StartSpan("procedure", ext.RPCServerOption(sc))
+	defer span.Finish()
+
+	out := opentracing.TextMapCarrier{}
+	require.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, out))
+	assert.Contains(t, out["traceparent"], "0af7651916cd43dd8448eb211c80319c",
+		"child spans must stay in the same trace")
+	assert.Equal(t, "congo=t61rcWkgMzE", out["tracestate"])
+}
+
+func TestPropagator(t *testing.T) {
+	tracer, _ := newTracer(Propagator(propagation.Baggage{}), Propagator(nil))
+
+	span := tracer.StartSpan("procedure")
+	span.SetBaggageItem("user", "alice")
+	defer span.Finish()
+
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, carrier))
+	assert.Equal(t, opentracing.TextMapCarrier{"baggage": "user=alice"}, carrier)
+}
+
+func TestInjectErrors(t *testing.T) {
+	tracer, _ := newTracer()
+	span := tracer.StartSpan("procedure")
+	defer span.Finish()
+
+	assert.Equal(t, opentracing.ErrInvalidSpanContext,
+		tracer.Inject(mocktracer.MockSpanContext{}, opentracing.TextMap, opentracing.TextMapCarrier{}))
+	assert.Equal(t, opentracing.ErrUnsupportedFormat,
+		tracer.Inject(span.Context(), opentracing.Binary, opentracing.TextMapCarrier{}))
+	assert.Equal(t, opentracing.ErrInvalidCarrier,
+		tracer.Inject(span.Context(), opentracing.TextMap, map[string]string{}))
+}
+
+func TestExtractErrors(t *testing.T) {
+	tracer, _ := newTracer()
+
+	_, err := tracer.Extract(opentracing.Binary, opentracing.TextMapCarrier{})
+	assert.Equal(t, opentracing.ErrUnsupportedFormat, err)
+
+	_, err = tracer.Extract(opentracing.TextMap, map[string]string{})
+	assert.Equal(t, opentracing.ErrInvalidCarrier, err)
+
+	_, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{"uber-trace-id": "1:2:0:1"})
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+}
+
+func TestContextInterop(t *testing.T) {
+	tracer, recorder := newTracer()
+
+	t.Run("OpenTelemetry parent", func(t *testing.T) {
+		ctx, parent := tracer.tracer.Start(context.Background(), "parent")
+		parentSpan := transport.SpanFromContext(ctx, tracer)
+		require.NotNil(t, parentSpan, "span must be found through the tracer")
+
+		span := tracer.StartSpan("child", opentracing.ChildOf(parentSpan.Context()))
+		span.Finish()
+		parent.End()
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	})
+
+	t.Run("no span", func(t *testing.T) {
+		assert.Nil(t, transport.SpanFromContext(context.Background(), tracer))
+		assert.Nil(t, transport.SpanFromContext(context.Background(), opentracing.NoopTracer{}))
+	})
+
+	t.Run("OpenTracing span", func(t *testing.T) {
+		span := tracer.StartSpan("span")
+		defer span.Finish()
+
+		ctx := transport.ContextWithSpan(context.Background(), tracer, span)
+		assert.Equal(t, span, opentracing.SpanFromContext(ctx))
+		assert.Equal(t, span.Context().(spanContext).otel, trace.SpanFromContext(ctx).SpanContext())
+	})
+
+	t.Run("foreign span", func(t *testing.T) {
+		span := mocktracer.New().StartSpan("span")
+		ctx := tracer.ContextWithSpan(context.Background(), span)
+		assert.False(t, trace.SpanFromContext(ctx).SpanContext().IsValid())
+	})
+}
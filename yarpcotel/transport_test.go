@@ -0,0 +1,172 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpcotel_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/transport/grpc"
+	"go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/transport/tchannel"
+)
+
+func TestTransports(t *testing.T) {
+	tests := []struct {
+		desc   string
+		system string
+
+		newInbound func(*testing.T, trace.TracerProvider) transport.Inbound
+
+		// newOutbound builds an outbound to the given inbound, once it has
+		// started.
+		newOutbound func(*testing.T, trace.TracerProvider, transport.Inbound) transport.UnaryOutbound
+	}{
+		{
+			desc:   "http",
+			system: "yarpc",
+			newInbound: func(t *testing.T, provider trace.TracerProvider) transport.Inbound {
+				return http.NewTransport(http.TracerProvider(provider)).NewInbound("127.0.0.1:0")
+			},
+			newOutbound: func(t *testing.T, provider trace.TracerProvider, inbound transport.Inbound) transport.UnaryOutbound {
+				addr := inbound.(*http.Inbound).Addr().String()
+				return http.NewTransport(http.TracerProvider(provider)).NewSingleOutbound("http://" + addr)
+			},
+		},
+		{
+			desc:   "grpc",
+			system: "grpc",
+			newInbound: func(t *testing.T, provider trace.TracerProvider) transport.Inbound {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				require.NoError(t, err)
+				return grpc.NewTransport(grpc.TracerProvider(provider)).NewInbound(listener)
+			},
+			newOutbound: func(t *testing.T, provider trace.TracerProvider, inbound transport.Inbound) transport.UnaryOutbound {
+				addr := inbound.(*grpc.Inbound).Addr().String()
+				return grpc.NewTransport(grpc.TracerProvider(provider)).NewSingleOutbound(addr)
+			},
+		},
+		{
+			desc:   "tchannel",
+			system: "yarpc",
+			newInbound: func(t *testing.T, provider trace.TracerProvider) transport.Inbound {
+				trans, err := tchannel.NewTransport(
+					tchannel.ServiceName("server"),
+					tchannel.ListenAddr("127.0.0.1:0"),
+					tchannel.TracerProvider(provider),
+				)
+				require.NoError(t, err)
+				return trans.NewInbound()
+			},
+			newOutbound: func(t *testing.T, provider trace.TracerProvider, inbound transport.Inbound) transport.UnaryOutbound {
+				trans, err := tchannel.NewTransport(
+					tchannel.ServiceName("client"),
+					tchannel.TracerProvider(provider),
+				)
+				require.NoError(t, err)
+				addr := inbound.Transports()[0].(*tchannel.Transport).ListenAddr()
+				return trans.NewSingleOutbound(addr)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			inbound := tt.newInbound(t, provider)
+			server := yarpc.NewDispatcher(yarpc.Config{
+				Name:     "server",
+				Inbounds: yarpc.Inbounds{inbound},
+			})
+			handlerSpans := make(chan trace.SpanContext, 1)
+			server.Register(raw.Procedure("echo", func(ctx context.Context, body []byte) ([]byte, error) {
+				assert.NotNil(t, opentracing.SpanFromContext(ctx), "handlers must see the OpenTracing span")
+				handlerSpans <- trace.SpanFromContext(ctx).SpanContext()
+				return body, nil
+			}))
+			require.NoError(t, server.Start())
+			defer server.Stop()
+
+			client := yarpc.NewDispatcher(yarpc.Config{
+				Name: "client",
+				Outbounds: yarpc.Outbounds{
+					"server": {Unary: tt.newOutbound(t, provider, inbound)},
+				},
+			})
+			require.NoError(t, client.Start())
+			defer client.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			ctx, root := provider.Tracer("test").Start(ctx, "root")
+			_, err := raw.New(client.ClientConfig("server")).Call(ctx, "echo", []byte("hello"))
+			require.NoError(t, err)
+			root.End()
+
+			var clientSpan, serverSpan sdktrace.ReadOnlySpan
+			require.Eventually(t, func() bool {
+				for _, s := range recorder.Ended() {
+					switch s.SpanKind() {
+					case trace.SpanKindClient:
+						clientSpan = s
+					case trace.SpanKindServer:
+						serverSpan = s
+					}
+				}
+				return clientSpan != nil && serverSpan != nil
+			}, time.Second, 10*time.Millisecond, "client and server spans must end")
+
+			assert.Equal(t, root.SpanContext().SpanID(), clientSpan.Parent().SpanID(),
+				"the OpenTelemetry span of the caller must be the parent of the client span")
+			assert.Equal(t, clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID(),
+				"the client span must be propagated to the server")
+			assert.True(t, serverSpan.Parent().IsRemote())
+			assert.Equal(t, root.SpanContext().TraceID(), serverSpan.SpanContext().TraceID())
+			assert.Equal(t, serverSpan.SpanContext(), <-handlerSpans, "handlers must see the OpenTelemetry span")
+
+			for _, s := range []sdktrace.ReadOnlySpan{clientSpan, serverSpan} {
+				attrs := make(map[string]interface{})
+				for _, kv := range s.Attributes() {
+					attrs[string(kv.Key)] = kv.Value.AsInterface()
+				}
+				assert.Equal(t, "echo", s.Name())
+				assert.Equal(t, "echo", attrs["rpc.method"])
+				assert.Equal(t, tt.system, attrs["rpc.system"])
+				if s == clientSpan {
+					assert.Equal(t, "server", attrs["rpc.service"])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// _jsonEncoding is the encoding of both JSON and protobuf-JSON requests.
+const _jsonEncoding transport.Encoding = "json"
+
+// BodyMatcher transforms the body of a request, or of a message sent over a
+// stream, into the form the recorder compares with recorded requests.
+//
+// Matchers may drop volatile fields or canonicalize the body, and must return
+// the body unchanged if they do not apply to the encoding.
+// The recorder still records the original body.
+type BodyMatcher func(encoding transport.Encoding, body []byte) []byte
+
+// IgnoreHeaders leaves the given request headers out when matching requests
+// with recorded requests, so that volatile headers like tracing identifiers
+// do not prevent replay.
+//
+// The headers are still recorded.
+func IgnoreHeaders(keys ...string) Option {
+	return func(cfg *config) {
+		cfg.IgnoredHeaders = append(cfg.IgnoredHeaders, keys...)
+	}
+}
+
+// MatchBody transforms request bodies with the given matcher before matching
+// them with recorded requests.
+//
+// Matchers apply in the order they are given.
+func MatchBody(matcher BodyMatcher) Option {
+	return func(cfg *config) {
+		cfg.BodyMatchers = append(cfg.BodyMatchers, matcher)
+	}
+}
+
+// NormalizeJSON compares the bodies of JSON and protobuf-JSON requests as
+// JSON values, regardless of whitespace and the order of object keys.
+// See JSONBodyMatcher.
+func NormalizeJSON(ignoredFields ...string) Option {
+	return MatchBody(JSONBodyMatcher(ignoredFields...))
+}
+
+// JSONBodyMatcher returns a BodyMatcher that canonicalizes the bodies of JSON
+// and protobuf-JSON requests, leaving out the given fields.
+//
+// Fields are paths of object keys separated by dots, like "metadata.id".
+// Paths that cross arrays apply to every element, so "items.id" leaves out
+// the "id" of every object in the "items" array.
+//
+// Bodies that are not valid JSON are matched as is.
+func JSONBodyMatcher(ignoredFields ...string) BodyMatcher {
+	paths := make([][]string, len(ignoredFields))
+	for i, field := range ignoredFields {
+		paths[i] = strings.Split(field, ".")
+	}
+
+	return func(encoding transport.Encoding, body []byte) []byte {
+		if encoding != _jsonEncoding {
+			return body
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return body
+		}
+		for _, path := range paths {
+			deleteJSONField(value, path)
+		}
+
+		// encoding/json sorts object keys.
+		normalized, err := json.Marshal(value)
+		if err != nil {
+			return body
+		}
+		return normalized
+	}
+}
+
+func deleteJSONField(value interface{}, path []string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(v, path[0])
+			return
+		}
+		deleteJSONField(v[path[0]], path[1:])
+	case []interface{}:
+		for _, elem := range v {
+			deleteJSONField(elem, path)
+		}
+	}
+}
+
+func (r *Recorder) matchBody(encoding transport.Encoding, body []byte) []byte {
+	for _, match := range r.bodyMatchers {
+		body = match(encoding, body)
+	}
+	return body
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package recorder
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/transport"
+)
+
+func TestJSONBodyMatcher(t *testing.T) {
+	tests := []struct {
+		desc     string
+		fields   []string
+		encoding transport.Encoding
+		body     string
+		want     string
+	}{
+		{
+			desc:     "canonical",
+			encoding: "json",
+			body:     `{ "b": 1, "a": {"d": [1, 2], "c": "x"} }`,
+			want:     `{"a":{"c":"x","d":[1,2]},"b":1}`,
+		},
+		{
+			desc:     "large numbers",
+			encoding: "json",
+			body:     `{"id": 12345678901234567890}`,
+			want:     `{"id":12345678901234567890}`,
+		},
+		{
+			desc:     "ignored fields",
+			fields:   []string{"timestamp", "meta.requestId", "items.id", "missing.field"},
+			encoding: "json",
+			body:     `{"timestamp": 1, "meta": {"requestId": "x", "user": "y"}, "items": [{"id": 1, "n": 2}, {"id": 3}]}`,
+			want:     `{"items":[{"n":2},{}],"meta":{"user":"y"}}`,
+		},
+		{
+			desc:     "other encoding",
+			fields:   []string{"a"},
+			encoding: "raw",
+			body:     `{"b": 1, "a": 2}`,
+			want:     `{"b": 1, "a": 2}`,
+		},
+		{
+			desc:     "invalid JSON",
+			encoding: "json",
+			body:     `{"a": `,
+			want:     `{"a": `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := JSONBodyMatcher(tt.fields...)(tt.encoding, []byte(tt.body))
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestHashMatchers(t *testing.T) {
+	hash := func(recorder *Recorder, headers transport.Headers, body string) string {
+		request := transport.Request{
+			Caller:    "client",
+			Service:   "server",
+			Procedure: "hello",
+			Encoding:  "json",
+			Headers:   headers,
+			Body:      io.NopCloser(bytes.NewReader([]byte(body))),
+		}
+		requestRecord := recorder.requestToRequestRecord(&request)
+		return recorder.hashRequestRecord(&requestRecord)
+	}
+
+	t.Run("ignore headers", func(t *testing.T) {
+		recorder := NewRecorder(t, IgnoreHeaders("X-Trace-ID"))
+		reference := hash(recorder, transport.NewHeaders().With("x-trace-id", "1").With("user", "a"), "{}")
+		assert.Equal(t, reference, hash(recorder, transport.NewHeaders().With("x-trace-id", "2").With("user", "a"), "{}"))
+		assert.Equal(t, reference, hash(recorder, transport.NewHeaders().With("user", "a"), "{}"))
+		assert.NotEqual(t, reference, hash(recorder, transport.NewHeaders().With("user", "b"), "{}"))
+	})
+
+	t.Run("normalize JSON", func(t *testing.T) {
+		recorder := NewRecorder(t, NormalizeJSON("nonce"))
+		reference := hash(recorder, transport.NewHeaders(), `{"a": 1, "b": 2, "nonce": 3}`)
+		assert.Equal(t, reference, hash(recorder, transport.NewHeaders(), `{"b":2,"a":1,"nonce":4}`))
+		assert.NotEqual(t, reference, hash(recorder, transport.NewHeaders(), `{"a":2,"b":2}`))
+	})
+
+	t.Run("custom body matcher", func(t *testing.T) {
+		recorder := NewRecorder(t, MatchBody(func(_ transport.Encoding, body []byte) []byte {
+			return bytes.ToLower(body)
+		}))
+		assert.Equal(t, hash(recorder, transport.NewHeaders(), "HELLO"), hash(recorder, transport.NewHeaders(), "hello"))
+	})
+
+	t.Run("default", func(t *testing.T) {
+		recorder := NewRecorder(t)
+		assert.NotEqual(t,
+			hash(recorder, transport.NewHeaders().With("x-trace-id", "1"), `{"a":1}`),
+			hash(recorder, transport.NewHeaders().With("x-trace-id", "2"), `{"a":1}`))
+		assert.NotEqual(t,
+			hash(recorder, transport.NewHeaders(), `{"a":1,"b":2}`),
+			hash(recorder, transport.NewHeaders(), `{"b":2,"a":1}`))
+	})
+}
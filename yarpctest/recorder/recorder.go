@@ -31,23 +31,41 @@
 // NewRecorder() returns a Recorder, in the mode specified by the flag
 // `--recorder=replay|append|overwrite`. `replay` is the default.
 //
-// The new Recorder instance is a yarpc outbound middleware. It takes a
-// `testing.T` or compatible as argument.
+// The new Recorder instance is a yarpc outbound middleware for unary, oneway
+// and streaming requests. It takes a `testing.T` or compatible as argument.
 //
 // Example:
 //
 //	func MyTest(t *testing.T) {
+//	  rec := recorder.NewRecorder(t)
 //	  dispatcher := yarpc.NewDispatcher(yarpc.Config{
 //	  	Name: "...",
 //	  	Outbounds: transport.Outbounds{
 //	  		...
 //	  	},
 //	    OutboundMiddleware: yarpc.OutboundMiddleware {
-//	  	  Unary: recorder.NewRecorder(t),
+//	  	  Unary:  rec,
+//	  	  Oneway: rec,
+//	  	  Stream: rec,
 //	    },
 //	  })
 //	}
 //
+// Oneway requests replay with an empty acknowledgement. Streams record the
+// messages sent and received in order, and replay the received messages after
+// checking that the sent messages match.
+//
+// Requests match recorded requests when all their fields and their body are
+// identical. Volatile fields, like tracing headers or timestamps in the body,
+// can be left out of the comparison with the IgnoreHeaders and MatchBody
+// options, and NormalizeJSON compares JSON bodies regardless of formatting
+// and key order:
+//
+//	recorder.NewRecorder(t,
+//	  recorder.IgnoreHeaders("uber-trace-id"),
+//	  recorder.NormalizeJSON("metadata.timestamp"),
+//	)
+//
 // Running the tests in append mode:
 //
 //	$ go test -v ./... --recorder=append
@@ -70,6 +88,7 @@ import (
 	"strings"
 	"unicode"
 
+	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/transport"
 	"gopkg.in/yaml.v2"
 )
@@ -89,11 +108,19 @@ append: replay existing and record new request/response pairs.`)
 // request Recorder will return the recorded response. Any new request will
 // abort the test by calling logger.Fatal().
 type Recorder struct {
-	mode       Mode
-	logger     TestingT
-	recordsDir string
+	mode           Mode
+	logger         TestingT
+	recordsDir     string
+	ignoredHeaders map[string]struct{}
+	bodyMatchers   []BodyMatcher
 }
 
+var (
+	_ middleware.UnaryOutbound  = (*Recorder)(nil)
+	_ middleware.OnewayOutbound = (*Recorder)(nil)
+	_ middleware.StreamOutbound = (*Recorder)(nil)
+)
+
 const defaultRecorderDir = "testdata/recordings"
 const recordComment = `# In order to update this recording, setup your external dependencies and run
 # ` + "`" + `go test <insert test files here> --recorder=replay|append|overwrite` + "`\n"
@@ -159,8 +186,8 @@ type TestingT interface {
 // NewRecorder returns a Recorder in whatever mode specified via the
 // `--recorder` flag.
 //
-// The new Recorder instance is a yarpc unary outbound middleware. It takes a
-// logger as argument compatible with `testing.T`.
+// The new Recorder instance is a yarpc unary, oneway and stream outbound
+// middleware. It takes a logger as argument compatible with `testing.T`.
 //
 // See package documentation for more details.
 func NewRecorder(logger TestingT, opts ...Option) *Recorder {
@@ -168,15 +195,20 @@ func NewRecorder(logger TestingT, opts ...Option) *Recorder {
 	if err != nil {
 		logger.Fatal(err)
 	}
-	recorder := &Recorder{
-		logger: logger,
-	}
-
 	var cfg config
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
+	recorder := &Recorder{
+		logger:         logger,
+		ignoredHeaders: make(map[string]struct{}, len(cfg.IgnoredHeaders)),
+		bodyMatchers:   cfg.BodyMatchers,
+	}
+	for _, k := range cfg.IgnoredHeaders {
+		recorder.ignoredHeaders[transport.CanonicalizeHeaderKey(k)] = struct{}{}
+	}
+
 	if cfg.RecordsPath != "" {
 		recorder.recordsDir = cfg.RecordsPath
 	} else {
@@ -212,8 +244,10 @@ func RecordsPath(path string) Option {
 type Option func(*config)
 
 type config struct {
-	Mode        Mode
-	RecordsPath string
+	Mode           Mode
+	RecordsPath    string
+	IgnoredHeaders []string
+	BodyMatchers   []BodyMatcher
 }
 
 // SetMode let you choose enable the different replay and recording modes,
@@ -261,6 +295,9 @@ func (r *Recorder) hashRequestRecord(requestRecord *requestRecord) string {
 
 	orderedHeadersKeys := make([]string, 0, len(requestRecord.Headers))
 	for k := range requestRecord.Headers {
+		if _, ignored := r.ignoredHeaders[k]; ignored {
+			continue
+		}
 		orderedHeadersKeys = append(orderedHeadersKeys, k)
 	}
 	sort.Strings(orderedHeadersKeys)
@@ -273,15 +310,15 @@ func (r *Recorder) hashRequestRecord(requestRecord *requestRecord) string {
 	ha(requestRecord.RoutingKey)
 	ha(requestRecord.RoutingDelegate)
 
-	_, err := hash.Write(requestRecord.Body)
+	_, err := hash.Write(r.matchBody(transport.Encoding(requestRecord.Encoding), requestRecord.Body))
 	if err != nil {
 		log.Fatal(err)
 	}
 	return fmt.Sprintf("%x", hash.Sum64())
 }
 
-func (r *Recorder) makeFilePath(request *transport.Request, hash string) string {
-	s := fmt.Sprintf("%s.%s.%s.yaml", request.Service, request.Procedure, hash)
+func (r *Recorder) makeFilePath(requestRecord *requestRecord, hash string) string {
+	s := fmt.Sprintf("%s.%s.%s.yaml", requestRecord.Service, requestRecord.Procedure, hash)
 	return filepath.Join(r.recordsDir, sanitizeFilename(s))
 }
 
@@ -295,7 +332,7 @@ func (r *Recorder) Call(
 	requestRecord := r.requestToRequestRecord(request)
 
 	requestHash := r.hashRequestRecord(&requestRecord)
-	filepath := r.makeFilePath(request, requestHash)
+	filepath := r.makeFilePath(&requestRecord, requestHash)
 
 	switch r.mode {
 	case Replay:
@@ -315,10 +352,53 @@ func (r *Recorder) Call(
 	case Overwrite:
 		response, err := out.Call(ctx, request)
 		if err == nil {
+			responseRecord := r.responseToResponseRecord(response)
 			cachedRecord := record{
 				Version:  currentRecordVersion,
 				Request:  requestRecord,
-				Response: r.responseToResponseRecord(response),
+				Response: &responseRecord,
+			}
+			r.saveRecord(filepath, &cachedRecord)
+		}
+		return response, err
+	default:
+		panic(fmt.Sprintf("invalid record mode: %v", r.mode))
+	}
+}
+
+// CallOneway implements the yarpc transport oneway outbound middleware
+// interface.
+//
+// Only the request is recorded. Replayed requests receive an empty
+// acknowledgement.
+func (r *Recorder) CallOneway(
+	ctx context.Context,
+	request *transport.Request,
+	out transport.OnewayOutbound) (transport.Ack, error) {
+	log := r.logger
+
+	requestRecord := r.requestToRequestRecord(request)
+
+	requestHash := r.hashRequestRecord(&requestRecord)
+	filepath := r.makeFilePath(&requestRecord, requestHash)
+
+	switch r.mode {
+	case Replay:
+		if _, err := r.loadRecord(filepath); err != nil {
+			log.Fatal(err)
+		}
+		return ack{}, nil
+	case Append:
+		if _, err := r.loadRecord(filepath); err == nil {
+			return ack{}, nil
+		}
+		fallthrough
+	case Overwrite:
+		response, err := out.CallOneway(ctx, request)
+		if err == nil {
+			cachedRecord := record{
+				Version: currentRecordVersion,
+				Request: requestRecord,
 			}
 			r.saveRecord(filepath, &cachedRecord)
 		}
@@ -328,7 +408,16 @@ func (r *Recorder) Call(
 	}
 }
 
+// ack is the acknowledgement of replayed oneway requests.
+type ack struct{}
+
+func (ack) String() string { return "" }
+
 func (r *Recorder) recordToResponse(cachedRecord *record) transport.Response {
+	if cachedRecord.Response == nil {
+		r.logger.Fatal(fmt.Sprintf("record for %s::%s has no response",
+			cachedRecord.Request.Service, cachedRecord.Request.Procedure))
+	}
 	response := transport.Response{
 		Headers: transport.HeadersFromMap(cachedRecord.Response.Headers),
 		Body:    io.NopCloser(bytes.NewReader(cachedRecord.Response.Body)),
@@ -394,7 +483,7 @@ func (r *Recorder) loadRecord(filepath string) (*record, error) {
 // saveRecord attempts to save a record to the given file, any error fails the
 // current test.
 func (r *Recorder) saveRecord(filepath string, cachedRecord *record) {
-	if err := os.MkdirAll(defaultRecorderDir, 0775); err != nil {
+	if err := os.MkdirAll(r.recordsDir, 0775); err != nil {
 		r.logger.Fatal(err)
 	}
 
@@ -446,10 +535,21 @@ type responseRecord struct {
 	Body    base64blob
 }
 
+// streamRecord holds the messages of a stream in the order the client sent
+// and received them.
+type streamRecord struct {
+	Headers  map[string]string
+	Sent     []base64blob
+	Received []base64blob
+}
+
+// record is a recorded request with the response for unary requests, the
+// stream for streaming requests, or neither for oneway requests.
 type record struct {
 	Version  uint
 	Request  requestRecord
-	Response responseRecord
+	Response *responseRecord `yaml:",omitempty"`
+	Stream   *streamRecord   `yaml:",omitempty"`
 }
 
 type base64blob []byte
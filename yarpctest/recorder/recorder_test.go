@@ -31,10 +31,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
 	"go.uber.org/yarpc/encoding/raw"
 	"go.uber.org/yarpc/internal/yarpctest"
 	"go.uber.org/yarpc/transport/http"
@@ -350,6 +352,31 @@ func TestRecording(t *testing.T) {
 	assert.Equal(t, refRecordContent, string(recordContent))
 }
 
+func TestRecordingCreatesRecordsDir(t *testing.T) {
+	tMock := testingTMock{t, 0}
+
+	dir, err := os.MkdirTemp("", "yarpcgorecorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // clean up
+
+	recordsDir := path.Join(dir, "nested", "recordings")
+	recorder := NewRecorder(&tMock, RecordMode(Append), RecordsPath(recordsDir))
+
+	withConnectedClient(t, recorder, func(client raw.Client) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		_, err := client.Call(ctx, "hello", []byte("Hello"))
+		require.NoError(t, err)
+	})
+
+	_, err = os.Stat(path.Join(recordsDir, refRecordFilename))
+	require.NoError(t, err, "the configured records directory must be created")
+	assert.Equal(t, 0, tMock.fatalCount)
+}
+
 func TestReplaying(t *testing.T) {
 	tMock := testingTMock{t, 0}
 
@@ -374,3 +401,85 @@ func TestReplaying(t *testing.T) {
 		assert.Equal(t, rbody, []byte("Hello, World"))
 	})
 }
+
+// TestReplayingOldRecord replays a record saved before records held oneway
+// requests and streams, when every record had a response.
+func TestReplayingOldRecord(t *testing.T) {
+	tMock := testingTMock{t, 0}
+	dir := path.Join("testdata", "v1")
+	recorder := NewRecorder(&tMock, RecordMode(Replay), RecordsPath(dir))
+
+	withDisconnectedClient(t, recorder, func(client raw.Client) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		rbody, err := client.Call(ctx, "hello", []byte("Hello"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("Hello, World"), rbody)
+	})
+	assert.Equal(t, 0, tMock.fatalCount)
+
+	// Saving the record again must not change its format.
+	recordPath := path.Join(dir, refRecordFilename)
+	oldRecord, err := os.ReadFile(recordPath)
+	require.NoError(t, err)
+	cachedRecord, err := recorder.loadRecord(recordPath)
+	require.NoError(t, err)
+	require.NotNil(t, cachedRecord.Response)
+	assert.Nil(t, cachedRecord.Stream)
+
+	newPath := path.Join(t.TempDir(), refRecordFilename)
+	recorder.saveRecord(newPath, cachedRecord)
+	newRecord, err := os.ReadFile(newPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(oldRecord), string(newRecord))
+}
+
+func TestOneway(t *testing.T) {
+	tMock := testingTMock{t, 0}
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	newRequest := func() *transport.Request {
+		return &transport.Request{
+			Caller:    "client",
+			Service:   "server",
+			Procedure: "notify",
+			Encoding:  raw.Encoding,
+			Headers:   transport.NewHeaders(),
+			Body:      io.NopCloser(bytes.NewReader([]byte("Hello"))),
+		}
+	}
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	out := transporttest.NewMockOnewayOutbound(mockCtrl)
+	out.EXPECT().CallOneway(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *transport.Request) (transport.Ack, error) {
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "Hello", string(body), "the outbound must receive the full body")
+			return nil, nil
+		})
+
+	// Record the request.
+	recorder := NewRecorder(&tMock, RecordMode(Overwrite), RecordsPath(dir))
+	_, err := recorder.CallOneway(ctx, newRequest(), out)
+	require.NoError(t, err)
+
+	// Replay the request without calling the outbound.
+	recorder = NewRecorder(&tMock, RecordMode(Replay), RecordsPath(dir))
+	ack, err := recorder.CallOneway(ctx, newRequest(), out)
+	require.NoError(t, err)
+	assert.NotNil(t, ack)
+
+	// Unknown requests abort the test.
+	request := newRequest()
+	request.Procedure = "unknown"
+	require.Panics(t, func() {
+		recorder.CallOneway(ctx, request, out)
+	})
+	assert.Equal(t, 1, tMock.fatalCount)
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// CallStream implements the yarpc transport stream outbound middleware
+// interface.
+//
+// Streams match recorded streams by their request metadata. While recording,
+// the messages sent and received are saved once the stream is closed or the
+// server ends it. Replayed streams check that sent messages match the
+// recorded messages, and return the recorded messages in order, followed by
+// io.EOF.
+func (r *Recorder) CallStream(
+	ctx context.Context,
+	request *transport.StreamRequest,
+	out transport.StreamOutbound) (*transport.ClientStream, error) {
+	log := r.logger
+
+	requestRecord := r.metaToRequestRecord(request.Meta)
+
+	requestHash := r.hashRequestRecord(&requestRecord)
+	filepath := r.makeFilePath(&requestRecord, requestHash)
+
+	switch r.mode {
+	case Replay:
+		cachedRecord, err := r.loadRecord(filepath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return r.recordToClientStream(ctx, request, cachedRecord)
+	case Append:
+		cachedRecord, err := r.loadRecord(filepath)
+		if err == nil {
+			return r.recordToClientStream(ctx, request, cachedRecord)
+		}
+		fallthrough
+	case Overwrite:
+		stream, err := out.CallStream(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return transport.NewClientStream(&recordingStream{
+			recorder: r,
+			stream:   stream,
+			filepath: filepath,
+			encoding: request.Meta.Encoding,
+			record: record{
+				Version: currentRecordVersion,
+				Request: requestRecord,
+				Stream:  &streamRecord{},
+			},
+		})
+	default:
+		panic(fmt.Sprintf("invalid record mode: %v", r.mode))
+	}
+}
+
+func (r *Recorder) metaToRequestRecord(meta *transport.RequestMeta) requestRecord {
+	return requestRecord{
+		Caller:          meta.Caller,
+		Service:         meta.Service,
+		Procedure:       meta.Procedure,
+		Encoding:        string(meta.Encoding),
+		Headers:         meta.Headers.Items(),
+		ShardKey:        meta.ShardKey,
+		RoutingKey:      meta.RoutingKey,
+		RoutingDelegate: meta.RoutingDelegate,
+	}
+}
+
+func (r *Recorder) recordToClientStream(ctx context.Context, request *transport.StreamRequest, cachedRecord *record) (*transport.ClientStream, error) {
+	if cachedRecord.Stream == nil {
+		r.logger.Fatal(fmt.Sprintf("record for %s::%s has no stream",
+			cachedRecord.Request.Service, cachedRecord.Request.Procedure))
+	}
+	return transport.NewClientStream(&replayStream{
+		ctx:      ctx,
+		request:  request,
+		recorder: r,
+		record:   cachedRecord.Stream,
+	})
+}
+
+// readMessage reads the body of a stream message, replacing it with a reader
+// of the same bytes.
+func (r *Recorder) readMessage(msg *transport.StreamMessage) []byte {
+	if msg == nil || msg.Body == nil {
+		return nil
+	}
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		r.logger.Fatal(err)
+	}
+	msg.Body = io.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// recordingStream records the messages of a stream to the underlying
+// transport.
+type recordingStream struct {
+	recorder *Recorder
+	stream   *transport.ClientStream
+	filepath string
+	encoding transport.Encoding
+
+	m      sync.Mutex
+	record record
+	// done indicates that the stream was closed or ended by the server, and
+	// that further messages must update the saved record.
+	done bool
+}
+
+var _ transport.StreamHeadersReader = (*recordingStream)(nil)
+
+func (s *recordingStream) Context() context.Context {
+	return s.stream.Context()
+}
+
+func (s *recordingStream) Request() *transport.StreamRequest {
+	return s.stream.Request()
+}
+
+func (s *recordingStream) SendMessage(ctx context.Context, msg *transport.StreamMessage) error {
+	body := s.recorder.readMessage(msg)
+	if err := s.stream.SendMessage(ctx, msg); err != nil {
+		return err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.record.Stream.Sent = append(s.record.Stream.Sent, body)
+	return nil
+}
+
+func (s *recordingStream) ReceiveMessage(ctx context.Context) (*transport.StreamMessage, error) {
+	msg, err := s.stream.ReceiveMessage(ctx)
+	if err == io.EOF {
+		s.finish()
+		return msg, err
+	}
+	if err != nil {
+		return msg, err
+	}
+	body := s.recorder.readMessage(msg)
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.record.Stream.Received = append(s.record.Stream.Received, body)
+	if s.done {
+		s.recorder.saveRecord(s.filepath, &s.record)
+	}
+	return msg, nil
+}
+
+func (s *recordingStream) Close(ctx context.Context) error {
+	if err := s.stream.Close(ctx); err != nil {
+		return err
+	}
+	s.finish()
+	return nil
+}
+
+func (s *recordingStream) Headers() (transport.Headers, error) {
+	return s.stream.Headers()
+}
+
+// finish saves the record of a stream that the client closed or the server
+// ended.
+func (s *recordingStream) finish() {
+	// Streams that do not support headers have none to record.
+	headers, _ := s.stream.Headers()
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.done = true
+	s.record.Stream.Headers = headers.Items()
+	s.recorder.saveRecord(s.filepath, &s.record)
+}
+
+// replayStream replays the recorded messages of a stream.
+type replayStream struct {
+	ctx      context.Context
+	request  *transport.StreamRequest
+	recorder *Recorder
+	record   *streamRecord
+
+	m        sync.Mutex
+	sent     int
+	received int
+}
+
+var _ transport.StreamHeadersReader = (*replayStream)(nil)
+
+func (s *replayStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *replayStream) Request() *transport.StreamRequest {
+	return s.request
+}
+
+func (s *replayStream) SendMessage(ctx context.Context, msg *transport.StreamMessage) error {
+	r := s.recorder
+	body := r.readMessage(msg)
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	meta := s.request.Meta
+	if s.sent >= len(s.record.Sent) {
+		r.logger.Fatal(fmt.Sprintf("stream %s::%s sent more than the %d recorded messages",
+			meta.Service, meta.Procedure, len(s.record.Sent)))
+	}
+	recorded := s.record.Sent[s.sent]
+	if !bytes.Equal(r.matchBody(meta.Encoding, body), r.matchBody(meta.Encoding, recorded)) {
+		r.logger.Fatal(fmt.Sprintf("stream %s::%s message %d does not match the recorded message",
+			meta.Service, meta.Procedure, s.sent))
+	}
+	s.sent++
+	return nil
+}
+
+func (s *replayStream) ReceiveMessage(context.Context) (*transport.StreamMessage, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.received >= len(s.record.Received) {
+		return nil, io.EOF
+	}
+	body := s.record.Received[s.received]
+	s.received++
+	return &transport.StreamMessage{
+		Body:     io.NopCloser(bytes.NewReader(body)),
+		BodySize: len(body),
+	}, nil
+}
+
+func (s *replayStream) Close(context.Context) error {
+	return nil
+}
+
+func (s *replayStream) Headers() (transport.Headers, error) {
+	return transport.HeadersFromMap(s.record.Headers), nil
+}
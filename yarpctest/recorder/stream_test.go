@@ -0,0 +1,193 @@
+// Copyright (c) 2024 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+)
+
+func newStreamRequest(headers transport.Headers) *transport.StreamRequest {
+	return &transport.StreamRequest{
+		Meta: &transport.RequestMeta{
+			Caller:    "client",
+			Service:   "server",
+			Procedure: "count",
+			Encoding:  "raw",
+			Headers:   headers,
+		},
+	}
+}
+
+func sendMessage(t *testing.T, stream *transport.ClientStream, body string) {
+	require.NoError(t, stream.SendMessage(stream.Context(), &transport.StreamMessage{
+		Body:     io.NopCloser(bytes.NewReader([]byte(body))),
+		BodySize: len(body),
+	}))
+}
+
+func receiveMessage(t *testing.T, stream *transport.ClientStream) string {
+	msg, err := stream.ReceiveMessage(stream.Context())
+	require.NoError(t, err)
+	body, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// countServer replies to the first message of the stream with the message
+// followed by each number up to three, then ends the stream.
+func countServer(t *testing.T, server *transport.ServerStream, finish func(error)) {
+	ctx := server.Context()
+	msg, err := server.ReceiveMessage(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, err := io.ReadAll(msg.Body)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, n := range []string{" 1", " 2", " 3"} {
+		reply := string(body) + n
+		// Clients may close the stream before receiving every reply.
+		if err := server.SendMessage(ctx, &transport.StreamMessage{
+			Body:     io.NopCloser(bytes.NewReader([]byte(reply))),
+			BodySize: len(reply),
+		}); err != nil {
+			return
+		}
+	}
+	finish(io.EOF)
+}
+
+func TestStreamRecordAndReplay(t *testing.T) {
+	tMock := testingTMock{t, 0}
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Record the stream.
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	out := transporttest.NewMockStreamOutbound(mockCtrl)
+	out.EXPECT().CallStream(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *transport.StreamRequest) (*transport.ClientStream, error) {
+			client, server, finish, err := transporttest.MessagePipe(ctx, req)
+			require.NoError(t, err)
+			go countServer(t, server, finish)
+			return client, nil
+		})
+
+	recorder := NewRecorder(&tMock, RecordMode(Overwrite), RecordsPath(dir), IgnoreHeaders("Trace-ID"))
+	stream, err := recorder.CallStream(ctx, newStreamRequest(transport.NewHeaders().With("trace-id", "1")), out)
+	require.NoError(t, err)
+	sendMessage(t, stream, "hello")
+	assert.Equal(t, "hello 1", receiveMessage(t, stream))
+	assert.Equal(t, "hello 2", receiveMessage(t, stream))
+	assert.Equal(t, "hello 3", receiveMessage(t, stream))
+	_, err = stream.ReceiveMessage(ctx)
+	assert.Equal(t, io.EOF, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "server.count.*.yaml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1, "must record the stream")
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "stream:")
+	assert.NotContains(t, string(content), "response:")
+
+	// Replay the stream with a different trace ID, without an outbound.
+	recorder = NewRecorder(&tMock, RecordMode(Replay), RecordsPath(dir), IgnoreHeaders("trace-id"))
+	stream, err = recorder.CallStream(ctx, newStreamRequest(transport.NewHeaders().With("trace-id", "2")), nil)
+	require.NoError(t, err)
+	sendMessage(t, stream, "hello")
+	assert.Equal(t, "hello 1", receiveMessage(t, stream))
+	assert.Equal(t, "hello 2", receiveMessage(t, stream))
+	assert.Equal(t, "hello 3", receiveMessage(t, stream))
+	_, err = stream.ReceiveMessage(ctx)
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, stream.Close(ctx))
+	headers, err := stream.Headers()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, headers.Len())
+
+	// Messages that differ from the recording abort the test.
+	stream, err = recorder.CallStream(ctx, newStreamRequest(transport.NewHeaders()), nil)
+	require.NoError(t, err)
+	require.Panics(t, func() {
+		sendMessage(t, stream, "goodbye")
+	})
+	assert.Equal(t, 1, tMock.fatalCount)
+
+	// So do messages beyond the recording.
+	stream, err = recorder.CallStream(ctx, newStreamRequest(transport.NewHeaders()), nil)
+	require.NoError(t, err)
+	sendMessage(t, stream, "hello")
+	require.Panics(t, func() {
+		sendMessage(t, stream, "hello")
+	})
+	assert.Equal(t, 2, tMock.fatalCount)
+}
+
+func TestStreamRecordOnClose(t *testing.T) {
+	tMock := testingTMock{t, 0}
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	out := transporttest.NewMockStreamOutbound(mockCtrl)
+	out.EXPECT().CallStream(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *transport.StreamRequest) (*transport.ClientStream, error) {
+			client, server, finish, err := transporttest.MessagePipe(ctx, req)
+			require.NoError(t, err)
+			go countServer(t, server, finish)
+			return client, nil
+		})
+
+	recorder := NewRecorder(&tMock, RecordMode(Append), RecordsPath(dir))
+	stream, err := recorder.CallStream(ctx, newStreamRequest(transport.NewHeaders()), out)
+	require.NoError(t, err)
+	sendMessage(t, stream, "hi")
+	assert.Equal(t, "hi 1", receiveMessage(t, stream))
+	require.NoError(t, stream.Close(ctx))
+
+	// Append mode replays the stream recorded when the client closed it.
+	stream, err = recorder.CallStream(ctx, newStreamRequest(transport.NewHeaders()), out)
+	require.NoError(t, err)
+	sendMessage(t, stream, "hi")
+	assert.Equal(t, "hi 1", receiveMessage(t, stream))
+	_, err = stream.ReceiveMessage(ctx)
+	assert.Equal(t, io.EOF, err)
+}